@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+)
+
+// cfLabels describes what each column family holds.
+var cfLabels = map[string]string{
+	"default": "values",
+	"lock":    "locks of uncommitted transactions",
+	"write":   "commit records",
+}
+
+// getKeyAllCFs reads the key from every column family through the RawKV API and prints
+// what each of them holds.
+func getKeyAllCFs(ctx context.Context, f *TiKVReaderFlags, rawkey []byte) error {
+	raw, err := client.NewRawKVClient(ctx, f.PDEndpoints, f.TLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PD server(%v): %w", f.PDEndpoints, err)
+	}
+	defer raw.Close()
+	slog.Info("connected to PD servers with RawKV client", slog.String("pd_addr", fmt.Sprintf("%v", f.PDEndpoints)))
+
+	PrintSeparatorLine(60)
+	fmt.Printf("Key: %s\n", f.TargetKey)
+	fmt.Printf("  Hex: %s\n", f.Codec.PrettyPrintKey(rawkey))
+	for _, v := range raw.GetAllCFs(ctx, rawkey) {
+		fmt.Printf("[%s CF] (%s)\n", v.CF, cfLabels[v.CF])
+		switch {
+		case v.Err != nil:
+			fmt.Printf("    <error: %v>\n", v.Err)
+		case !v.Found:
+			fmt.Printf("    <not present>\n")
+		case v.CF == "default":
+			printValue(rawkey, v.Value, f, "    ")
+		default: // lock and write records are not row values
+			fmt.Printf("    Raw(Hex): %X\n", v.Value)
+		}
+	}
+	PrintSeparatorLine(60)
+
+	return nil
+}
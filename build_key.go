@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/urfave/cli/v3"
+)
+
+func runBuildKey(ctx context.Context, cmd *cli.Command) error {
+	var spec codec.KeySpec
+	var err error
+
+	if cmd.IsSet("table") {
+		spec, err = keySpecFromFlags(cmd)
+	} else {
+		spec, err = promptKeySpec(os.Stdin, os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	raw, err := spec.Encode()
+	if err != nil {
+		return err
+	}
+
+	opts := codecOptions(cmd)
+	PrintSeparatorLine(60)
+	fmt.Printf("Key: %s\n", spec.String())
+	fmt.Printf("  Hex: %s\n", opts.PrettyPrintKey(raw))
+	fmt.Printf("  DecodeKey: %s\n", opts.DecodeKey(raw))
+
+	// The one-liner infers int/string types, so typed components might not round-trip.
+	if parsed, err := opts.ParsePrefix(spec.String()); err != nil || !bytes.Equal(parsed, raw) {
+		fmt.Printf("  (Note: the one-liner form doesn't encode to the same bytes, e.g., a string value which looks like an int or contains '_')\n")
+	}
+	PrintSeparatorLine(60)
+
+	return nil
+}
+
+func keySpecFromFlags(cmd *cli.Command) (codec.KeySpec, error) {
+	spec := codec.KeySpec{
+		TableID: cmd.Int64("table"),
+		Kind:    codec.KeyKind(cmd.String("kind")),
+		RowID:   cmd.Int64("row-id"),
+		IndexID: cmd.Int64("index-id"),
+	}
+
+	for _, v := range cmd.StringSlice("value") {
+		c, err := codec.ParseKeyComponent(v)
+		if err != nil {
+			return codec.KeySpec{}, err
+		}
+		spec.Components = append(spec.Components, c)
+	}
+
+	return spec, nil
+}
+
+// promptKeySpec walks the user through the key grammar one field at a time.
+func promptKeySpec(in io.Reader, out io.Writer) (codec.KeySpec, error) {
+	r := bufio.NewReader(in)
+	var spec codec.KeySpec
+
+	tableID, err := promptInt64(r, out, "Table ID: ")
+	if err != nil {
+		return spec, err
+	}
+	spec.TableID = tableID
+
+	kind, err := prompt(r, out, "Kind (record/index) [record]: ")
+	if err != nil {
+		return spec, err
+	}
+	switch kind {
+	case "", "r", string(codec.KindRecord):
+		spec.Kind = codec.KindRecord
+	case "i", string(codec.KindIndex):
+		spec.Kind = codec.KindIndex
+	default:
+		return spec, fmt.Errorf("unknown key kind %q", kind)
+	}
+
+	if spec.Kind == codec.KindRecord {
+		spec.RowID, err = promptInt64(r, out, "Row ID: ")
+		return spec, err
+	}
+
+	spec.IndexID, err = promptInt64(r, out, "Index ID: ")
+	if err != nil {
+		return spec, err
+	}
+
+	for i := 1; ; i++ {
+		v, err := prompt(r, out, fmt.Sprintf("Value #%d as type:value (int:5, string:abc), empty to finish: ", i))
+		if err != nil {
+			return spec, err
+		}
+		if v == "" {
+			return spec, nil
+		}
+
+		c, err := codec.ParseKeyComponent(v)
+		if err != nil {
+			fmt.Fprintf(out, "  %v\n", err)
+			i--
+			continue
+		}
+		spec.Components = append(spec.Components, c)
+	}
+}
+
+func prompt(r *bufio.Reader, out io.Writer, label string) (string, error) {
+	fmt.Fprint(out, label)
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF { // EOF is treated as an empty answer
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+func promptInt64(r *bufio.Reader, out io.Writer, label string) (int64, error) {
+	line, err := prompt(r, out, label)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseInt(line, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q: %v", line, err)
+	}
+
+	return n, nil
+}
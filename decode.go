@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/urfave/cli/v3"
+)
+
+// runDecode decodes a key and/or a value given as hex, e.g., copied from a TiKV log, without
+// connecting to PD or TiKV.
+func runDecode(ctx context.Context, cmd *cli.Command) error {
+	keyHex, valueHex := cmd.String("key"), cmd.String("value")
+	if keyHex == "" && valueHex == "" {
+		return fmt.Errorf("--key or --value is required")
+	}
+
+	return decodePair(cmd, keyHex, valueHex)
+}
+
+// runDecodeValue decodes a value given as an argument in hex. --key gives the key it was
+// stored under, which tells a row from an index entry and the table of --schema.
+func runDecodeValue(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("decode value takes one value, as hex")
+	}
+	if cmd.String("value") != "" {
+		return fmt.Errorf("decode value takes the value as an argument, not --value")
+	}
+
+	return decodePair(cmd, cmd.String("key"), cmd.Args().First())
+}
+
+// decodePair prints the key and/or the value, either of which may be empty.
+func decodePair(cmd *cli.Command, keyHex, valueHex string) error {
+	f := parseFlags(cmd)
+	if err := f.validateRedact(); err != nil {
+		return err
+	}
+	if err := f.loadSchemas(); err != nil {
+		return err
+	}
+
+	var key, value []byte
+	var err error
+	if keyHex != "" {
+		if key, err = decodeKeyInput(f.Codec, keyHex); err != nil {
+			return fmt.Errorf("invalid --key: %w", err)
+		}
+	}
+	if valueHex != "" {
+		if value, err = decodeHexInput(valueHex); err != nil {
+			return fmt.Errorf("invalid --value: %w", err)
+		}
+	}
+
+	PrintSeparatorLine(60)
+	if keyHex != "" {
+		key = printDecodedKey(f.Codec, key, false)
+		printPrimaryKey(f, key, "  ")
+	}
+	if valueHex != "" {
+		fmt.Printf("Value:\n")
+		printValue(key, value, f, "    ")
+	}
+	PrintSeparatorLine(60)
+
+	return nil
+}
+
+// runDecodeKey decodes a key given as an argument, as hex or in the escaped form, e.g.,
+// t\200\000\000\000\000\000\000\204_r\200\000\000\000\000\000\000\001.
+func runDecodeKey(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("decode key takes one key, as hex or escaped")
+	}
+	opts := codecOptions(cmd)
+	key, err := decodeKeyInput(opts, cmd.Args().First())
+	if err != nil {
+		return err
+	}
+
+	PrintSeparatorLine(60)
+	printDecodedKey(opts, key, true)
+	PrintSeparatorLine(60)
+
+	return nil
+}
+
+// printDecodedKey prints the key decoded with its hex, and the escaped form with escaped. A data
+// key of TiKV, e.g., from pd-ctl or a region error, is printed as the key within it, followed by
+// the data key and its MVCC ts, and the key within is returned.
+func printDecodedKey(opts codec.Options, key []byte, escaped bool) []byte {
+	dataKey, isDataKey := codec.DecodeDataKey(key)
+	raw := key
+	if isDataKey {
+		key = dataKey.Key
+	}
+
+	fmt.Printf("Key: %s\n", opts.DecodeKey(key))
+	fmt.Printf("  Hex: %s\n", opts.PrettyPrintKey(key))
+	if escaped {
+		fmt.Printf("  Escaped: %s\n", codec.EscapeKey(key))
+	}
+	if isDataKey {
+		fmt.Printf("  Data key: %s\n", opts.PrettyPrintKey(raw))
+		if dataKey.TS != 0 {
+			fmt.Printf("  MVCC ts: %s\n", client.FormatTSO(dataKey.TS))
+		}
+	}
+
+	return key
+}
+
+// decodeKeyInput decodes a key copied from a log: hex, or the escaped form of TiKV logs and
+// tikv-ctl, which may be quoted. Input made of hex digits only is taken as hex, and the readable
+// form like t132_r1 as that key.
+func decodeKeyInput(opts codec.Options, input string) ([]byte, error) {
+	s := strings.TrimSpace(input)
+	if key, err := decodeHexInput(s); err == nil {
+		return key, nil
+	}
+
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	if key, err := opts.ParseKey(s); err == nil {
+		return key, nil
+	}
+	key, err := codec.UnescapeKey(s)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither hex nor an escaped key: %v", input, err)
+	}
+
+	return key, nil
+}
+
+// decodeBase64Input decodes base64 as printed by kvproto errors and TiDB logs, in the standard or
+// the URL alphabet, with or without the padding.
+func decodeBase64Input(input string) ([]byte, error) {
+	s := strings.TrimRight(strings.TrimSpace(input), "=")
+	enc := base64.RawStdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.RawURLEncoding
+	}
+
+	b, err := enc.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a base64 string: %v", input, err)
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("the key is empty")
+	}
+
+	return b, nil
+}
+
+// decodeHexInput decodes hex as printed by TiKV and TiDB logs, with an optional 0x prefix.
+func decodeHexInput(input string) ([]byte, error) {
+	s := strings.TrimSpace(input)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a hex string: %v", input, err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/urfave/cli/v3"
+)
+
+func runDecodeRange(ctx context.Context, cmd *cli.Command) error {
+	opts := codecOptions(cmd)
+	start, err := parseRangeBound(opts, cmd.String("start"), "-inf")
+	if err != nil {
+		return fmt.Errorf("invalid --start: %w", err)
+	}
+	end, err := parseRangeBound(opts, cmd.String("end"), "+inf")
+	if err != nil {
+		return fmt.Errorf("invalid --end: %w", err)
+	}
+
+	info := opts.ClassifyRange(start, end)
+
+	PrintSeparatorLine(60)
+	fmt.Printf("Classification: %s", info.Kind)
+	if info.TableID != 0 {
+		fmt.Printf(" (table %d, %s)\n", info.TableID, info.Detail)
+	} else {
+		fmt.Printf(" (%s)\n", info.Detail)
+	}
+	printRangeBound(opts, "Start (inclusive)", start, "-inf")
+	printRangeBound(opts, "End (exclusive)", end, "+inf")
+	PrintSeparatorLine(60)
+
+	return nil
+}
+
+// parseRangeBound accepts a hex key (as printed by TiKV and TiDB logs), a key like t1_r5,
+// or the open bound (-inf/+inf). An empty input is the open bound too.
+func parseRangeBound(opts codec.Options, input, open string) ([]byte, error) {
+	input = strings.TrimSpace(input)
+	if input == "" || input == open || input == "inf" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(input, "t") {
+		return opts.ParsePrefix(input)
+	}
+
+	b, err := decodeHexInput(input)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither a hex key, a key like t1_r5, nor %s", input, open)
+	}
+
+	return b, nil
+}
+
+func printRangeBound(opts codec.Options, label string, key []byte, open string) {
+	if key == nil {
+		fmt.Printf("%s: %s\n", label, open)
+		return
+	}
+
+	fmt.Printf("%s: %s\n", label, opts.DecodeKey(key))
+	fmt.Printf("  Hex: %s\n", opts.PrettyPrintKey(key))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/urfave/cli/v3"
+)
+
+// runEncode prints the TiKV bytes of a key like t1_r5, the reverse of decode. The key is given
+// by --key or as the argument.
+func runEncode(ctx context.Context, cmd *cli.Command) error {
+	key, prefix := cmd.String("key"), cmd.String("prefix")
+	switch cmd.Args().Len() {
+	case 0:
+	case 1:
+		if key != "" || prefix != "" {
+			return fmt.Errorf("the key to encode is given either as the argument or by --key/--prefix")
+		}
+		key = cmd.Args().First()
+	default:
+		return fmt.Errorf("encode takes one key, got %d", cmd.Args().Len())
+	}
+	if (key == "") == (prefix == "") {
+		return fmt.Errorf("either --key or --prefix is required")
+	}
+
+	opts := codecOptions(cmd)
+	input, parse := key, opts.ParseKey
+	if prefix != "" {
+		input, parse = prefix, opts.ParsePrefix
+	}
+	raw, err := parse(input)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", input, err)
+	}
+
+	PrintSeparatorLine(60)
+	fmt.Printf("Key: %s\n", input)
+	fmt.Printf("  Hex: %s\n", opts.PrettyPrintKey(raw))
+	fmt.Printf("  Escaped: %s\n", codec.EscapeKey(raw))
+	fmt.Printf("  DecodeKey: %s\n", opts.DecodeKey(raw))
+	PrintSeparatorLine(60)
+
+	return nil
+}
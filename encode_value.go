@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/urfave/cli/v3"
+)
+
+func runEncodeValue(ctx context.Context, cmd *cli.Command) error {
+	typ, err := codec.ParseComponentType(cmd.String("type"))
+	if err != nil {
+		return err
+	}
+
+	c := codec.KeyComponent{Type: typ, Value: cmd.String("value")}
+	encoded, err := codec.EncodeValue(c)
+	if err != nil {
+		return err
+	}
+
+	PrintSeparatorLine(60)
+	fmt.Printf("Value: %s (%s, encoded as %s)\n", c.Value, cmd.String("type"), c.Type)
+	fmt.Printf("  Hex: %s\n", strings.ToUpper(hex.EncodeToString(encoded)))
+	if c.Type == codec.ComponentString {
+		// new collations (e.g., utf8mb4_general_ci) encode a sort key instead of the bytes themselves
+		fmt.Printf("  (Note: a string is encoded as a binary collation; other collations encode a sort key instead)\n")
+	}
+	PrintSeparatorLine(60)
+
+	return nil
+}
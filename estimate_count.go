@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/urfave/cli/v3"
+)
+
+func runEstimateCount(ctx context.Context, cmd *cli.Command) error {
+	f := parseFlags(cmd)
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	tableID := cmd.Int64("table")
+	if tableID <= 0 {
+		return fmt.Errorf("table ID must be greater than 0")
+	}
+
+	// only the record range (t{TableID}_r) holds rows, index entries live under t{TableID}_i
+	start, err := f.Codec.ParsePrefix(fmt.Sprintf("t%d_r", tableID))
+	if err != nil {
+		return fmt.Errorf("failed to build the record range of table %d: %w", tableID, err)
+	}
+	end := codec.PrefixEnd(start)
+
+	slog.Info("Starting estimate-count operation",
+		slog.Int64("table_id", tableID), slog.String("pd_endpoints", fmt.Sprintf("%v", f.PDEndpoints)))
+
+	cli, err := client.NewTiKVClientContext(ctx, f.PDEndpoints, f.clientOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PD server(%v): %w", f.PDEndpoints, err)
+	}
+	defer cli.Close()
+	slog.Info("connected to PD servers", slog.String("pd_addr", fmt.Sprintf("%v", f.PDEndpoints)))
+
+	est, err := cli.EstimateKeys(ctx, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to estimate the row count of table %d: %w", tableID, err)
+	}
+
+	PrintSeparatorLine(60)
+	fmt.Printf("Table: %d\n", tableID)
+	fmt.Printf("  Record range: %s - %s\n", f.Codec.PrettyPrintKey(start), f.Codec.PrettyPrintKey(end))
+	fmt.Printf("  Regions: %d\n", est.Regions)
+	fmt.Printf("  Approximate rows: ~%d\n", est.ApproximateKeys)
+	fmt.Printf("  Approximate size: ~%d MiB\n", est.ApproximateSize)
+	fmt.Printf("  (Note: approximate values from PD region statistics. Regions on the range boundaries\n")
+	fmt.Printf("   are counted as a whole and may include index entries or other tables)\n")
+	PrintSeparatorLine(60)
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/sgykfjsm/tikv-reader/pkg/output"
+	"github.com/urfave/cli/v3"
+)
+
+// The formats of --export-format.
+const (
+	exportCSV     = "csv"
+	exportParquet = "parquet"
+)
+
+// runExport exports every row of the prefix or the range into the files of --export-dir (--dir)
+// in --export-format (--format), as scan --all --export-dir does.
+func runExport(ctx context.Context, cmd *cli.Command) error {
+	f := parseFlags(cmd)
+	// the rows go to the files, so there's nothing to page
+	f.ScanAll, f.Limit, f.Pager = true, math.MaxInt, pagerNever
+	return scan(ctx, cmd, f)
+}
+
+// rowWriter writes the decoded rows of a table into its file of the export.
+type rowWriter interface {
+	Write(values []codec.RowValue) error
+	Flush() error
+}
+
+// tableExporter writes the rows of scan --export-dir, one {db}.{table}.csv file a table for
+// TiDB Lightning or a .parquet one with --export-format parquet, named after --export-db and
+// the table of the schema. A pair which isn't a row of a table with a schema, e.g., an index
+// entry, is skipped. The files are complete once Close returns.
+type tableExporter struct {
+	f       *TiKVReaderFlags
+	files   map[int64]*exportFile
+	order   []int64 // the tables in the order their files were created
+	skipped int
+}
+
+// exportFile is the file of a table.
+type exportFile struct {
+	path string
+	file *os.File
+	w    rowWriter
+	rows int
+}
+
+func newTableExporter(f *TiKVReaderFlags) *tableExporter {
+	return &tableExporter{f: f, files: map[int64]*exportFile{}}
+}
+
+func (e *tableExporter) Write(key, value []byte) error {
+	schema := e.f.schemaFor(key)
+	if schema == nil {
+		e.skip(key, fmt.Errorf("no schema of the table"))
+		return nil
+	}
+	values, err := e.f.Codec.DecodeRowValues(key, value, *schema)
+	if err != nil {
+		e.skip(key, err)
+		return nil
+	}
+
+	file, ok := e.files[schema.TableID]
+	if !ok {
+		if file, err = e.create(*schema); err != nil {
+			return err
+		}
+	}
+	if err := file.w.Write(values); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file.path, err)
+	}
+	file.rows++
+
+	return nil
+}
+
+func (e *tableExporter) skip(key []byte, err error) {
+	e.skipped++
+	slog.Debug("skipped a pair of the export", slog.String("key", e.f.Codec.DecodeKey(key)), slog.String("error", err.Error()))
+}
+
+// create creates the file of the table, replacing the file of an earlier export.
+func (e *tableExporter) create(schema codec.TableSchema) (*exportFile, error) {
+	if err := os.MkdirAll(e.f.ExportDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create --export-dir: %w", err)
+	}
+
+	path := filepath.Join(e.f.ExportDir, fmt.Sprintf("%s.%s.%s", e.f.ExportDB, schema.Name, e.f.ExportFormat))
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the file of table %d: %w", schema.TableID, err)
+	}
+
+	ef := &exportFile{path: path, file: file}
+	if e.f.ExportFormat == exportParquet {
+		ef.w = output.NewParquetWriter(file, schema.Columns)
+	} else {
+		ef.w = output.NewLightningWriter(file)
+	}
+	e.files[schema.TableID] = ef
+	e.order = append(e.order, schema.TableID)
+
+	return ef, nil
+}
+
+// Close flushes and closes the files, and logs the rows written to each and the skipped pairs.
+func (e *tableExporter) Close() error {
+	var errs []error
+	for _, id := range e.order {
+		ef := e.files[id]
+		if err := errors.Join(ef.w.Flush(), ef.file.Close()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write %s: %w", ef.path, err))
+			continue
+		}
+		slog.Info("exported the rows of a table", slog.String("file", ef.path), slog.Int("rows", ef.rows))
+	}
+	if e.skipped > 0 {
+		slog.Warn("some pairs are not rows of a table with a schema and are not exported", slog.Int("skipped", e.skipped))
+	}
+	if len(e.order) == 0 {
+		slog.Warn("no rows are exported", slog.String("export_dir", e.f.ExportDir))
+	}
+
+	return errors.Join(errs...)
+}
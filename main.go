@@ -1,18 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"log/slog"
+	"math"
 	"os"
+	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
+	"text/template"
+	"time"
 
 	pingcaplog "github.com/pingcap/log"
 	"github.com/sgykfjsm/tikv-reader/pkg/client"
 	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/sgykfjsm/tikv-reader/pkg/output"
 	"github.com/urfave/cli/v3"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -42,14 +53,126 @@ func main() {
 				Aliases: []string{"q"},
 				Usage:   "Suppress all log output",
 			},
+			&cli.StringFlag{
+				Name:    "keyspace-name",
+				Aliases: []string{"keyspace"},
+				Usage:   "Read the keyspace of an API V2 (multi-tenant) cluster. The name is resolved to its ID through PD",
+				Sources: cli.EnvVars("TIKV_READER_KEYSPACE_NAME"),
+			},
+			&cli.StringFlag{
+				Name:    "ca",
+				Usage:   "CA certificate (PEM) of mutual TLS with PD and TiKV. Requires --cert and --key-file",
+				Sources: cli.EnvVars("TIKV_READER_CA"),
+			},
+			&cli.StringFlag{
+				Name:    "cert",
+				Usage:   "Client certificate (PEM) of mutual TLS with PD and TiKV",
+				Sources: cli.EnvVars("TIKV_READER_CERT"),
+			},
+			&cli.StringFlag{
+				Name:    "key-file",
+				Usage:   "Private key (PEM) of the client certificate",
+				Sources: cli.EnvVars("TIKV_READER_KEY_FILE"),
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Usage:   "Give up connecting and reading after this duration (e.g., 30s). 0 waits without a limit",
+				Sources: cli.EnvVars("TIKV_READER_TIMEOUT"),
+			},
+			&cli.IntFlag{
+				Name:  "max-retries",
+				Usage: "Retry a read failing with a transient TiKV error (region unavailable, server busy, ...) up to this many times",
+				Value: client.DefaultMaxRetries,
+			},
+			&cli.DurationFlag{
+				Name:  "retry-backoff",
+				Usage: "Wait before the first retry, doubled for each further retry",
+				Value: client.DefaultRetryBackoff,
+			},
+			&cli.StringFlag{
+				Name:    "snapshot-ts",
+				Aliases: []string{"read-ts"},
+				Usage:   "Read at the given TSO (e.g., the value of SELECT @@tidb_current_ts) instead of the latest data",
+				Sources: cli.EnvVars("TIKV_READER_SNAPSHOT_TS"),
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Output format of get and scan. Available formats: text, json, binary (length-prefixed key and value), csv (a col_<id> column for each RowV2 column ID of the first 1000 rows, the IDs first seen later go into the value cell), table, value (the decoded values alone, one per line), sql (INSERT statements, with --schema or --system-table), msgpack, proto (length-delimited Record messages)",
+				Value:   "text",
+			},
+			&cli.StringFlag{
+				Name:    "output-file",
+				Aliases: []string{"out"},
+				Usage:   "Write the result of get and scan to this file instead of stdout, replaced once the command succeeds. The logs stay on stderr",
+			},
+			&cli.StringFlag{
+				Name:  "compress",
+				Usage: "Compress the result of get and scan: gzip or none. Defaults to gzip for an --output-file ending with .gz",
+			},
+			&cli.StringFlag{
+				Name:  "format-template",
+				Usage: "Print each pair of get and scan with this Go template instead of --output, e.g., '{{.Key}} {{.Value.Type}} {{.Value}}'. It has .Index, .Key, .HexKey, .Value.Type and .Value.Payload",
+			},
+			&cli.StringFlag{
+				Name:  "collation",
+				Usage: "Collation of the string values in index keys and clustered primary keys (e.g., utf8mb4_general_ci)",
+				Value: codec.DefaultCollation,
+			},
+			&cli.BoolFlag{
+				Name:  "unsigned",
+				Usage: "Print the row IDs of record keys as unsigned integers, for tables with a BIGINT UNSIGNED primary key",
+			},
+			&cli.IntFlag{
+				Name:  "auto-random",
+				Usage: "Print the row IDs of record keys as the shard and the increment of an AUTO_RANDOM primary key of this many shard bits, e.g., 5 for AUTO_RANDOM(5). 0 prints them as they are",
+			},
+			&cli.BoolFlag{
+				Name:  "full-hex",
+				Usage: "Print the column values which can't be decoded as their complete hex instead of their first and last bytes",
+			},
+			&cli.IntFlag{
+				Name:  "max-hex-bytes",
+				Usage: "Cut the hex of a value after this many bytes, with --full-hex and for raw values",
+				Value: codec.DefaultMaxHexBytes,
+			},
+			&cli.IntFlag{
+				Name:  "max-value-bytes",
+				Usage: "Cut the decoded strings and JSON of values after this many bytes, marked with their total length. 0 prints them whole",
+			},
+			&cli.StringSliceFlag{
+				Name:  "col-types",
+				Usage: "Decode the RowV2 columns of these IDs by their MySQL type without --schema, e.g., 4=datetime,5=timestamp, instead of guessing from their bytes",
+			},
+			&cli.BoolFlag{
+				Name:  "show-types",
+				Usage: "Print the MySQL type of each decoded column given by --schema or --col-types, e.g., name (VARCHAR): \"foo\"",
+			},
+			&cli.StringFlag{
+				Name:  "charset",
+				Usage: "Transcode the strings of the values which aren't UTF-8 from this charset: utf8mb4, gbk or latin1. The string columns of --schema with a CHARACTER SET keep theirs",
+				Value: "utf8mb4",
+			},
+			&cli.BoolFlag{
+				Name:  "redact",
+				Usage: "Mask the strings, the JSON and the undecodable bytes of the values and the keys by their length and hash, keeping the numbers, the times and the key structure",
+			},
+			&cli.BoolFlag{
+				Name:  "self-check",
+				Usage: "Decode round-tripped samples at startup and abort if the codec looks off",
+			},
 		},
 		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			if err := checkCodecOptions(cmd); err != nil {
+				return ctx, err
+			}
+
 			if cmd.Bool("quiet") {
 				// stop all log output
 				slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
 				pingcaplog.ReplaceGlobals(zap.NewNop(), nil)
 
-				return ctx, nil
+				return ctx, runSelfCheck(cmd)
 			}
 
 			var level slog.Level
@@ -93,7 +216,7 @@ func main() {
 			pingcaplog.ReplaceGlobals(pg, nil)
 
 			slog.Debug("PingCAP logger initialized", slog.String("level", zapLevel.String()))
-			return ctx, nil
+			return ctx, runSelfCheck(cmd)
 		},
 		Commands: []*cli.Command{
 			{
@@ -102,9 +225,56 @@ func main() {
 				Action: runGet,
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "key",
-						Usage:    "Key to retrieve (e.g., t1_r123)",
-						Required: true,
+						Name:  "key",
+						Usage: "Key to retrieve (e.g., t1_r123)",
+					},
+					&cli.BoolFlag{
+						Name:  "hex",
+						Usage: "Interpret --key as the hex bytes of the key (e.g., 7480000000000000845F728000000000000001), as printed in the logs",
+					},
+					&cli.StringFlag{
+						Name:  "key-hex",
+						Usage: "Hex bytes of the key to retrieve, as copied from the TiKV logs or pd-ctl, instead of --key (the same as --hex --key)",
+					},
+					&cli.StringFlag{
+						Name:  "key-base64",
+						Usage: "Base64 bytes of the key to retrieve, as printed in kvproto errors and some TiDB logs, instead of --key",
+					},
+					&cli.BoolFlag{
+						Name:  "all-cfs",
+						Usage: "Read the key from each column family (default, lock, write) with the RawKV API",
+					},
+					&cli.BoolFlag{
+						Name:  "versions",
+						Usage: "List every MVCC version of the key kept by TiKV, newest first, instead of reading the latest one",
+					},
+					&cli.IntFlag{
+						Name:  "max-versions",
+						Usage: "With --versions, list at most this many versions, newest first, and report how many older ones were left out (0 lists all)",
+					},
+					&cli.StringFlag{
+						Name:  "assert-value-hex",
+						Usage: "Exit with code 3 unless the stored value equals these hex bytes (for smoke tests)",
+					},
+					&cli.BoolFlag{
+						Name:  "hexdump",
+						Usage: "Print the raw value as a hexdump (offset, hex bytes, ASCII) instead of decoding it",
+					},
+					&cli.Int64SliceFlag{
+						Name:  "show-raw-cols",
+						Usage: "Also print a hexdump of the raw bytes of these RowV2 column IDs (e.g., 2,3)",
+					},
+					&cli.StringFlag{
+						Name:  "raw-out",
+						Usage: "Also write the undecoded value bytes to this file (created or truncated), e.g., to get a blob exactly",
+					},
+					&cli.StringFlag{
+						Name:  "system-table",
+						Usage: "Decode RowV2 values with the bundled schema of a mysql system table (e.g., tidb_background_subtask)",
+					},
+					&cli.StringFlag{
+						Name:  "schema",
+						Usage: "Decode RowV2 values with the column names and types of a JSON schema file keyed by table ID, then column ID",
 					},
 				},
 			},
@@ -114,16 +284,384 @@ func main() {
 				Action: runScan,
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "prefix",
-						Usage:    "Key prefix to scan (e.g., t1)",
-						Required: true,
+						Name:  "prefix",
+						Usage: "Key prefix to scan (e.g., t1, or hex bytes like 7480000000000000845F72). Defaults to the records of --system-table",
+					},
+					&cli.StringFlag{
+						Name:  "prefix-base64",
+						Usage: "Base64 bytes of the key prefix to scan, as printed in kvproto errors and some TiDB logs, instead of --prefix",
+					},
+					&cli.StringFlag{
+						Name:  "start",
+						Usage: "Start key of a range scan (inclusive, e.g., t1_r100, or hex bytes) instead of --prefix. Requires --end",
+					},
+					&cli.StringFlag{
+						Name:  "end",
+						Usage: "End key of a range scan (exclusive, e.g., t1_r500, or hex bytes)",
 					},
 					&cli.IntFlag{
 						Name:     "limit",
-						Usage:    "Number of keys to scan",
+						Usage:    "Number of keys to scan, up to 1000",
 						Value:    10,
 						Required: false,
 					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Scan every key of the prefix or the range, printing them while scanning, instead of up to --limit keys",
+					},
+					&cli.StringFlag{
+						Name:  "filter",
+						Usage: "Print only the pairs whose decoded value matches this regexp (e.g., 'Mueller|Smith'). --limit counts the matches",
+					},
+					&cli.StringFlag{
+						Name:  "filter-on",
+						Usage: "What --filter matches: value or key (the decoded key, e.g., t132_i1_.*)",
+						Value: filterOnValue,
+					},
+					&cli.StringFlag{
+						Name:  "start-after",
+						Usage: "Resume a scan right after this key (e.g., t1_r100, or the hex bytes printed when a scan stops at --limit)",
+					},
+					&cli.BoolFlag{
+						Name:  "hexdump",
+						Usage: "Print the raw value as a hexdump (offset, hex bytes, ASCII) instead of decoding it",
+					},
+					&cli.Int64SliceFlag{
+						Name:  "show-raw-cols",
+						Usage: "Also print a hexdump of the raw bytes of these RowV2 column IDs (e.g., 2,3)",
+					},
+					&cli.StringFlag{
+						Name:  "system-table",
+						Usage: "Decode RowV2 values with the bundled schema of a mysql system table (e.g., tidb_background_subtask)",
+					},
+					&cli.StringFlag{
+						Name:  "schema",
+						Usage: "Decode RowV2 values with the column names and types of a JSON schema file keyed by table ID, then column ID",
+					},
+					&cli.StringFlag{
+						Name:  "out-socket",
+						Usage: "Stream the scan result as JSON Lines to a Unix socket or named pipe while scanning, also with --all",
+					},
+					&cli.DurationFlag{
+						Name:  "max-scan-duration",
+						Usage: "Stop the scan gracefully after this wall-clock budget (e.g., 30s) and print the partial result",
+					},
+					&cli.BoolFlag{
+						Name:  "stats",
+						Usage: "Print the keys, the value bytes, the duration and the regions of the scan after the result",
+					},
+					&cli.IntFlag{
+						Name:  "parallel",
+						Usage: "Scan the regions of the range with this many workers, keeping the key order. 1 scans serially",
+						Value: 1,
+					},
+					&cli.BoolFlag{
+						Name:  "keys-only",
+						Usage: "Read and print the keys only, without the values",
+					},
+					&cli.StringFlag{
+						Name:  "pager",
+						Usage: "Page the text result with $PAGER (less by default) when stdout is a terminal: auto or never",
+						Value: pagerAuto,
+					},
+					&cli.StringFlag{
+						Name:  "sort-by",
+						Usage: "Order the result by key, rowid or col:<id>, the value of a column (typed with --schema), instead of the key order",
+					},
+					&cli.BoolFlag{
+						Name:  "group-by-table",
+						Usage: "Group the printed entries by table with a header and the count of each table",
+					},
+					&cli.StringFlag{
+						Name:  "since-file",
+						Usage: "Watermark file for incremental scans: scan only after the last key kept in the file, then update it",
+					},
+					&cli.BoolFlag{
+						Name:  "resolve-handles",
+						Usage: "For index entries, read and print the row each entry points to (one extra read per entry)",
+					},
+					&cli.StringFlag{
+						Name:  "export-dir",
+						Usage: "Export the rows into this directory, a {db}.{table}.csv file a table for TiDB Lightning (see --export-format). Needs --schema or --system-table",
+					},
+					&cli.StringFlag{
+						Name:  "export-db",
+						Usage: "Database name of the file names of --export-dir",
+					},
+					&cli.StringFlag{
+						Name:  "export-format",
+						Usage: "Format of the files of --export-dir: csv for TiDB Lightning, or parquet",
+						Value: exportCSV,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print the start and end keys of the scan without connecting to TiKV",
+					},
+				},
+			},
+			{
+				Name:   "export",
+				Usage:  "Export every row of a prefix or a range into a CSV or Parquet file a table, the same as scan --all --export-dir",
+				Action: runExport,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "prefix",
+						Usage: "Key prefix to export (e.g., t132_r, or hex bytes). Defaults to the records of --system-table",
+					},
+					&cli.StringFlag{
+						Name:  "start",
+						Usage: "Start key of a range to export (inclusive) instead of --prefix. Requires --end",
+					},
+					&cli.StringFlag{
+						Name:  "end",
+						Usage: "End key of a range to export (exclusive)",
+					},
+					&cli.StringFlag{
+						Name:  "schema",
+						Usage: "Decode RowV2 values with the column names and types of a JSON schema file keyed by table ID, then column ID",
+					},
+					&cli.StringFlag{
+						Name:  "system-table",
+						Usage: "Decode RowV2 values with the bundled schema of a mysql system table (e.g., tidb_background_subtask)",
+					},
+					&cli.StringFlag{
+						Name:     "export-dir",
+						Aliases:  []string{"dir"},
+						Usage:    "Directory of the files, a {db}.{table}.{format} file a table",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "export-db",
+						Aliases:  []string{"db"},
+						Usage:    "Database name of the file names",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:    "export-format",
+						Aliases: []string{"format"},
+						Usage:   "Format of the files: csv for TiDB Lightning, or parquet for DuckDB or Spark",
+						Value:   exportCSV,
+					},
+					&cli.StringFlag{
+						Name:  "since-file",
+						Usage: "Watermark file for incremental exports: export only after the last key kept in the file, then update it",
+					},
+					&cli.DurationFlag{
+						Name:  "max-scan-duration",
+						Usage: "Stop the export gracefully after this wall-clock budget (e.g., 30s), with the files of the rows so far",
+					},
+					&cli.IntFlag{
+						Name:  "parallel",
+						Usage: "Scan the regions of the range with this many workers, keeping the key order. 1 scans serially",
+						Value: 1,
+					},
+					&cli.BoolFlag{
+						Name:  "stats",
+						Usage: "Print the keys, the value bytes, the duration and the regions of the scan after the export",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Print the start and end keys of the export without connecting to TiKV",
+					},
+				},
+			},
+			{
+				Name:   "estimate-count",
+				Usage:  "Estimate the row count of a table from PD region statistics without scanning",
+				Action: runEstimateCount,
+				Flags: []cli.Flag{
+					&cli.Int64Flag{
+						Name:     "table",
+						Usage:    "Table ID",
+						Required: true,
+					},
+				},
+			},
+			{
+				Name:   "store-info",
+				Usage:  "Show the metadata and heartbeat status of a TiKV store from PD",
+				Action: runStoreInfo,
+				Flags: []cli.Flag{
+					&cli.Uint64Flag{
+						Name:     "store-id",
+						Usage:    "Store ID",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the store info as JSON",
+					},
+				},
+			},
+			{
+				Name:   "placement",
+				Usage:  "Show the placement rules of a table from PD (or the default rules when it has none)",
+				Action: runPlacement,
+				Flags: []cli.Flag{
+					&cli.Int64Flag{
+						Name:     "table",
+						Usage:    "Table ID, or the ID of a partition",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the placement as JSON",
+					},
+				},
+			},
+			{
+				Name:   "region",
+				Usage:  "Show the region which holds a key, or the regions spanning a prefix, from PD",
+				Action: runRegion,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "key",
+						Usage: "Key to locate (e.g., t132_r1, or a hex key from the logs)",
+					},
+					&cli.StringFlag{
+						Name:  "prefix",
+						Usage: "Key prefix whose regions are listed (e.g., t132_r)",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Number of regions to list for --prefix",
+						Value: defaultRegionLimit,
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the regions as JSON",
+					},
+				},
+			},
+			{
+				Name:   "decode",
+				Usage:  "Decode a key and/or a value given as hex, without connecting to TiKV",
+				Action: runDecode,
+				Commands: []*cli.Command{
+					{
+						Name:      "key",
+						Usage:     "Decode a key given as hex or in the escaped form of the TiKV logs",
+						ArgsUsage: "<hex|escaped>",
+						Action:    runDecodeKey,
+					},
+					{
+						Name:      "value",
+						Usage:     "Decode a value given as hex as a RowV2 row, an index value or raw bytes, with the options of decode",
+						ArgsUsage: "<hex>",
+						Action:    runDecodeValue,
+					},
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "key",
+						Usage: "Key as hex (e.g., 7480000000000000845F728000000000000001) or escaped (e.g., t\\200\\000...)",
+					},
+					&cli.StringFlag{
+						Name:  "value",
+						Usage: "Value as hex",
+					},
+					&cli.BoolFlag{
+						Name:  "hexdump",
+						Usage: "Print the raw value as a hexdump (offset, hex bytes, ASCII) instead of decoding it",
+					},
+					&cli.Int64SliceFlag{
+						Name:  "show-raw-cols",
+						Usage: "Also print a hexdump of the raw bytes of these RowV2 column IDs (e.g., 2,3)",
+					},
+					&cli.StringFlag{
+						Name:  "schema",
+						Usage: "Decode RowV2 values with the column names and types of a JSON schema file keyed by table ID, then column ID",
+					},
+				},
+			},
+			{
+				Name:      "encode",
+				Usage:     "Print the TiKV bytes of a key like t1_r5 as hex and in the escaped form, without connecting to TiKV",
+				Action:    runEncode,
+				ArgsUsage: "[key]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "key",
+						Usage: "Key to encode (e.g., t126_i1_594692_3769634)",
+					},
+					&cli.StringFlag{
+						Name:  "prefix",
+						Usage: "Key prefix to encode like scan --prefix (e.g., t132_r)",
+					},
+				},
+			},
+			{
+				Name:   "decode-range",
+				Usage:  "Classify a key range (point get, table scan, index range) and decode its bounds",
+				Action: runDecodeRange,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "start",
+						Usage: "Inclusive start key as hex or like t1_r5, or -inf",
+						Value: "-inf",
+					},
+					&cli.StringFlag{
+						Name:  "end",
+						Usage: "Exclusive end key as hex or like t1_r5, or +inf",
+						Value: "+inf",
+					},
+				},
+			},
+			{
+				Name:   "build-key",
+				Usage:  "Build a key step by step (interactively, or from flags for scripting)",
+				Action: runBuildKey,
+				Flags: []cli.Flag{
+					&cli.Int64Flag{
+						Name:  "table",
+						Usage: "Table ID. Prompts for every field when omitted",
+					},
+					&cli.StringFlag{
+						Name:  "kind",
+						Usage: "Key kind: record or index",
+						Value: "record",
+					},
+					&cli.Int64Flag{
+						Name:  "row-id",
+						Usage: "Row ID for a record key",
+					},
+					&cli.Int64Flag{
+						Name:  "index-id",
+						Usage: "Index ID for an index key",
+					},
+					&cli.StringSliceFlag{
+						Name:  "value",
+						Usage: "Index value component as type:value (e.g., int:5, string:abc), or a primary key value of the common handle of a record key. Repeat for each component",
+					},
+				},
+			},
+			{
+				Name:   "encode-value",
+				Usage:  "Print the memcomparable encoding of a column value as it appears in an index key",
+				Action: runEncodeValue,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "type",
+						Usage:    "Column type (e.g., int, bigint unsigned, varchar, double, decimal, datetime)",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "value",
+						Usage:    "Column value (e.g., 42, foo, 3.14, \"2024-01-02 03:04:05\")",
+						Required: true,
+					},
+				},
+			},
+			{
+				Name:   "schema-versions",
+				Usage:  "Show the current schema version and recent schema changes from the meta keys",
+				Action: runSchemaVersions,
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Number of recent schema versions and DDL jobs to show",
+						Value: 20,
+					},
 				},
 			},
 		},
@@ -134,21 +672,204 @@ func main() {
 	}
 }
 
+// Exit codes of get --assert-value-hex, so that a smoke test tells a wrong value from a broken cluster.
+const (
+	exitCodeReadFailed      = 2
+	exitCodeAssertionFailed = 3
+)
+
+const (
+	outputText    = "text"
+	outputJSON    = "json"
+	outputBinary  = "binary"
+	outputCSV     = "csv"
+	outputTable   = "table"
+	outputValue   = "value"
+	outputSQL     = "sql"
+	outputMsgpack = "msgpack"
+	outputProto   = "proto"
+
+	// outputTemplate is the output of --format-template, which is set instead of --output.
+	outputTemplate = "template"
+	// outputExport is the output of scan --export-dir, a file a table.
+	outputExport = "export"
+
+	// valueTemplate is the template of --output value, the value flattened by DecodedValue.String.
+	valueTemplate = "{{.Value}}"
+)
+
+// The compressions of --compress.
+const (
+	compressGzip = "gzip"
+	compressNone = "none"
+)
+
+// maxScanLimit is the maximum --limit of a scan, which keeps the result in memory.
+const maxScanLimit = 1000
+
+// maxScanParallel is the maximum --parallel of a scan.
+const maxScanParallel = 64
+
 type TiKVReaderFlags struct {
-	PDEndpoints  []string
-	TargetKey    string
-	TargetPrefix string
-	Limit        int
+	PDEndpoints     []string
+	KeyspaceName    string
+	TLS             client.TLSFiles
+	Timeout         time.Duration
+	Retry           client.RetryPolicy
+	SnapshotTSInput string
+	SnapshotTS      uint64 // resolved from SnapshotTSInput by Validate. 0 means the latest data.
+	TargetKey       string
+	HexKey          bool
+	TargetPrefix    string
+	RangeStart      string
+	RangeEnd        string
+	Limit           int
+	ScanAll         bool
+	StartAfter      string
+	StartAfterKey   []byte // parsed from StartAfter by runScan
+	Filter          string
+	FilterOn        string
+	filterRegexp    *regexp.Regexp // compiled from Filter by runScan
+	OutSocket       string
+	DryRun          bool
+	MaxScanDuration time.Duration
+	Parallel        int
+	Stats           bool
+	KeysOnly        bool
+	SinceFile       string
+	GroupByTable    bool
+	ResolveHandles  bool
+	Hexdump         bool
+	ShowRawCols     []int64
+	RawOut          string
+	Output          string
+	OutputFile      string
+	Compress        string
+	compress        bool // resolved from Compress and OutputFile by Validate
+	FormatTemplate  string
+	formatTemplate  *template.Template // parsed from FormatTemplate by Validate
+	AssertValueHex  string
+	AllCFs          bool
+	Versions        bool
+	MaxVersions     int
+	SystemTable     string
+	Schema          *codec.TableSchema // resolved from SystemTable by Validate
+	SchemaFile      string
+	Schemas         map[int64]codec.TableSchema // loaded from SchemaFile by Validate
+	ExportDir       string
+	ExportDB        string
+	ExportFormat    string
+	Redact          bool
+	SortBy          string
+	Pager           string
+	sortBy          scanSort // parsed from SortBy by the scan validation
+	ShowTypes       bool
+	ColumnTypeNames map[int64]string // the MySQL type names of --col-types, printed with ShowTypes
+	Codec           codec.Options    // read from the codec flags by parseFlags, checked by Before
 }
 
 // parseFlags parses command-line flags into TiKVReaderFlags.
 func parseFlags(cmd *cli.Command) *TiKVReaderFlags {
 	return &TiKVReaderFlags{
-		PDEndpoints:  cmd.StringSlice("pd"),
-		TargetKey:    cmd.String("key"),
-		TargetPrefix: cmd.String("prefix"),
-		Limit:        cmd.Int("limit"),
+		PDEndpoints:     cmd.StringSlice("pd"),
+		KeyspaceName:    cmd.String("keyspace-name"),
+		Timeout:         cmd.Duration("timeout"),
+		SnapshotTSInput: cmd.String("snapshot-ts"),
+		TargetKey:       cmd.String("key"),
+		HexKey:          cmd.Bool("hex"),
+		TargetPrefix:    cmd.String("prefix"),
+		RangeStart:      cmd.String("start"),
+		RangeEnd:        cmd.String("end"),
+		Limit:           cmd.Int("limit"),
+		ScanAll:         cmd.Bool("all"),
+		StartAfter:      cmd.String("start-after"),
+		Filter:          cmd.String("filter"),
+		FilterOn:        cmd.String("filter-on"),
+		OutSocket:       cmd.String("out-socket"),
+		DryRun:          cmd.Bool("dry-run"),
+		MaxScanDuration: cmd.Duration("max-scan-duration"),
+		Parallel:        cmd.Int("parallel"),
+		Stats:           cmd.Bool("stats"),
+		KeysOnly:        cmd.Bool("keys-only"),
+		ExportDir:       cmd.String("export-dir"),
+		ExportDB:        cmd.String("export-db"),
+		ExportFormat:    cmd.String("export-format"),
+		Redact:          cmd.Bool("redact"),
+		SortBy:          cmd.String("sort-by"),
+		Pager:           cmd.String("pager"),
+		SinceFile:       cmd.String("since-file"),
+		GroupByTable:    cmd.Bool("group-by-table"),
+		ResolveHandles:  cmd.Bool("resolve-handles"),
+		Hexdump:         cmd.Bool("hexdump"),
+		ShowRawCols:     cmd.Int64Slice("show-raw-cols"),
+		RawOut:          cmd.String("raw-out"),
+		Output:          cmd.String("output"),
+		OutputFile:      cmd.String("output-file"),
+		Compress:        cmd.String("compress"),
+		FormatTemplate:  cmd.String("format-template"),
+		AssertValueHex:  cmd.String("assert-value-hex"),
+		AllCFs:          cmd.Bool("all-cfs"),
+		Versions:        cmd.Bool("versions"),
+		MaxVersions:     cmd.Int("max-versions"),
+		SystemTable:     cmd.String("system-table"),
+		SchemaFile:      cmd.String("schema"),
+		Retry: client.RetryPolicy{
+			MaxRetries: cmd.Int("max-retries"),
+			Backoff:    cmd.Duration("retry-backoff"),
+		},
+		TLS: client.TLSFiles{
+			CAPath:   cmd.String("ca"),
+			CertPath: cmd.String("cert"),
+			KeyPath:  cmd.String("key-file"),
+		},
+		ShowTypes:       cmd.Bool("show-types"),
+		ColumnTypeNames: codec.ColumnTypeHintNames(cmd.StringSlice("col-types")),
+		Codec:           codecOptions(cmd),
+	}
+}
+
+// codecOptions returns the codec.Options of the global flags, e.g., --collation and --redact.
+// Before has checked them with checkCodecOptions.
+func codecOptions(cmd *cli.Command) codec.Options {
+	hints, _ := codec.ParseColumnTypeHints(cmd.StringSlice("col-types"))
+	return codec.Options{
+		Collation:           cmd.String("collation"),
+		UnsignedHandles:     cmd.Bool("unsigned"),
+		AutoRandomShardBits: cmd.Int("auto-random"),
+		FullHex:             cmd.Bool("full-hex"),
+		MaxHexBytes:         cmd.Int("max-hex-bytes"),
+		MaxValueBytes:       cmd.Int("max-value-bytes"),
+		Redact:              cmd.Bool("redact"),
+		ColumnTypeHints:     hints,
+		Charset:             cmd.String("charset"),
+	}
+}
+
+// checkCodecOptions reports the first codec flag which isn't valid.
+func checkCodecOptions(cmd *cli.Command) error {
+	if _, err := codec.ParseColumnTypeHints(cmd.StringSlice("col-types")); err != nil {
+		return fmt.Errorf("invalid --col-types: %w", err)
+	}
+	if cmd.Int("max-hex-bytes") <= 0 {
+		return fmt.Errorf("invalid --max-hex-bytes: must be greater than 0")
 	}
+
+	opts := codecOptions(cmd)
+	for _, c := range []struct {
+		flag string
+		opts codec.Options
+	}{
+		{"collation", codec.Options{Collation: opts.Collation}},
+		{"auto-random", codec.Options{AutoRandomShardBits: opts.AutoRandomShardBits}},
+		{"max-value-bytes", codec.Options{MaxValueBytes: opts.MaxValueBytes}},
+		{"charset", codec.Options{Charset: opts.Charset}},
+	} {
+		if err := c.opts.Validate(); err != nil {
+			return fmt.Errorf("invalid --%s: %w", c.flag, err)
+		}
+	}
+
+	return nil
 }
 
 // Validate validates global flags for the TiKVReaderFlags.
@@ -157,40 +878,292 @@ func (f *TiKVReaderFlags) Validate() error {
 		return fmt.Errorf("PD endpoints are required")
 	}
 
-	return nil
-}
+	if f.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
 
-func runGet(ctx context.Context, cmd *cli.Command) error {
-	f := parseFlags(cmd)
-	if err := f.Validate(); err != nil {
-		return err
+	if f.Retry.MaxRetries < 0 || f.Retry.Backoff < 0 {
+		return fmt.Errorf("max-retries and retry-backoff must not be negative")
 	}
 
-	key := f.TargetKey
-	if key == "" {
-		return fmt.Errorf("key is required")
+	switch f.Output {
+	case outputText, outputJSON, outputBinary, outputCSV, outputTable, outputValue, outputSQL, outputMsgpack, outputProto:
+	default:
+		return fmt.Errorf("unknown output format %q: must be %s, %s, %s, %s, %s, %s, %s, %s or %s",
+			f.Output, outputText, outputJSON, outputBinary, outputCSV, outputTable, outputValue, outputSQL, outputMsgpack, outputProto)
 	}
-	if !strings.HasPrefix(key, "t") {
-		return fmt.Errorf("currently only table keys (starting with 't') are supported")
+
+	switch f.Compress {
+	case "":
+		f.compress = strings.HasSuffix(f.OutputFile, ".gz")
+	case compressGzip, compressNone:
+		f.compress = f.Compress == compressGzip
+	default:
+		return fmt.Errorf("unknown --compress %q: must be %s or %s", f.Compress, compressGzip, compressNone)
 	}
 
+	if err := f.validateRedact(); err != nil {
+		return err
+	}
+
+	if f.ExportDir != "" {
+		if f.Output != outputText || f.FormatTemplate != "" {
+			return fmt.Errorf("--export-dir writes files of its own and cannot be used with --output %s or --format-template", f.Output)
+		}
+		if f.ExportDB == "" {
+			return fmt.Errorf("--export-dir needs the database name of the files in --export-db")
+		}
+		if f.OutputFile != "" {
+			return fmt.Errorf("--export-dir writes files of its own and cannot be used with --output-file")
+		}
+		if f.ExportFormat != exportCSV && f.ExportFormat != exportParquet {
+			return fmt.Errorf("unknown --export-format %q: must be %s or %s", f.ExportFormat, exportCSV, exportParquet)
+		}
+		f.Output = outputExport
+	} else if f.ExportDB != "" {
+		return fmt.Errorf("--export-db is the database of --export-dir and needs it")
+	}
+
+	if f.FormatTemplate != "" && f.Output != outputText {
+		return fmt.Errorf("--format-template replaces --output and cannot be used with --output %s", f.Output)
+	}
+	if f.Output == outputValue {
+		f.FormatTemplate = valueTemplate
+	}
+	if f.FormatTemplate != "" {
+		tmpl, err := output.ParseTemplate(f.FormatTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid --format-template: %w", err)
+		}
+		f.Output, f.formatTemplate = outputTemplate, tmpl
+	}
+
+	if f.SnapshotTSInput != "" {
+		ts, err := client.ParseTSO(f.SnapshotTSInput)
+		if err != nil {
+			return fmt.Errorf("invalid --snapshot-ts: %w", err)
+		}
+		f.SnapshotTS = ts
+	}
+
+	if f.SystemTable != "" {
+		schema, err := codec.SystemTable(f.SystemTable)
+		if err != nil {
+			return fmt.Errorf("invalid --system-table: %w", err)
+		}
+		f.Schema = &schema
+	}
+
+	if err := f.loadSchemas(); err != nil {
+		return err
+	}
+	if f.Output == outputSQL && f.Schema == nil && f.Schemas == nil {
+		return fmt.Errorf("--output sql needs the table schemas of --schema or --system-table")
+	}
+	if f.Output == outputExport && f.Schema == nil && f.Schemas == nil {
+		return fmt.Errorf("--export-dir needs the table schemas of --schema or --system-table")
+	}
+
+	return nil
+}
+
+// validateRedact rejects the outputs and the flags which would show the bytes --redact masks.
+func (f *TiKVReaderFlags) validateRedact() error {
+	if !f.Redact {
+		return nil
+	}
+
+	switch {
+	case f.Output == outputBinary || f.Output == outputMsgpack || f.Output == outputProto || f.Output == outputSQL:
+		return fmt.Errorf("--output %s writes the values unmasked and cannot be used with --redact", f.Output)
+	case f.ExportDir != "" || f.RawOut != "":
+		return fmt.Errorf("--export-dir and --raw-out write the values unmasked and cannot be used with --redact")
+	case f.Hexdump || len(f.ShowRawCols) > 0:
+		return fmt.Errorf("--hexdump and --show-raw-cols print the bytes unmasked and cannot be used with --redact")
+	}
+
+	return nil
+}
+
+// loadSchemas loads the table schemas of --schema.
+func (f *TiKVReaderFlags) loadSchemas() error {
+	if f.SchemaFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(f.SchemaFile)
+	if err != nil {
+		return fmt.Errorf("failed to read --schema: %w", err)
+	}
+	if f.Schemas, err = codec.ParseSchemas(data); err != nil {
+		return fmt.Errorf("invalid --schema %s: %w", f.SchemaFile, err)
+	}
+
+	return nil
+}
+
+// schemaFor returns the schema to decode the value of the key with: the table of the key in
+// --schema, or else the --system-table schema. It returns nil without either.
+func (f *TiKVReaderFlags) schemaFor(key []byte) *codec.TableSchema {
+	if parts, err := codec.ParseKeyParts(key); err == nil {
+		if schema, ok := f.Schemas[parts.TableID]; ok {
+			return &schema
+		}
+	}
+
+	return f.Schema
+}
+
+// typeNames returns the MySQL type names to print with the RowV2 columns of these IDs when the
+// value is decoded without a schema: those of --col-types with --show-types, or nil.
+func (f *TiKVReaderFlags) typeNames() map[int64]string {
+	if !f.ShowTypes {
+		return nil
+	}
+	return f.ColumnTypeNames
+}
+
+// clientOptions returns the options of the TiKV client given by the global flags.
+func (f *TiKVReaderFlags) clientOptions() []client.Option {
+	opts := []client.Option{client.WithRetry(f.Retry)}
+	if f.KeyspaceName != "" {
+		opts = append(opts, client.WithKeyspaceName(f.KeyspaceName))
+	}
+	if f.TLS != (client.TLSFiles{}) {
+		opts = append(opts, client.WithTLS(f.TLS))
+	}
+
+	return opts
+}
+
+// withTimeout bounds the context with --timeout. Without it, the context is returned as is.
+func (f *TiKVReaderFlags) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if f.Timeout == 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, f.Timeout)
+}
+
+// runSelfCheck verifies the codec with known samples when --self-check is given.
+func runSelfCheck(cmd *cli.Command) error {
+	if !cmd.Bool("self-check") {
+		return nil
+	}
+
+	if err := codec.SelfCheck(); err != nil {
+		return fmt.Errorf("codec self-check failed, decoded output may be wrong: %w", err)
+	}
+	slog.Info("codec self-check passed")
+
+	return nil
+}
+
+func runGet(ctx context.Context, cmd *cli.Command) (err error) {
+	f := parseFlags(cmd)
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	keyHex, keyBase64 := cmd.String("key-hex"), cmd.String("key-base64")
+	if countSet(f.TargetKey, keyHex, keyBase64) > 1 {
+		return fmt.Errorf("only one of --key, --key-hex and --key-base64 can be used")
+	}
+	if keyBase64 != "" {
+		b, err := decodeBase64Input(keyBase64)
+		if err != nil {
+			return fmt.Errorf("invalid --key-base64: %w", err)
+		}
+		keyHex = fmt.Sprintf("%X", b)
+	}
+	if keyHex != "" {
+		f.TargetKey, f.HexKey = keyHex, true
+	}
+	key := f.TargetKey
+	if key == "" {
+		return fmt.Errorf("--key, --key-hex or --key-base64 is required")
+	}
+	if !f.HexKey && !isSupportedKey(key) {
+		return fmt.Errorf("currently only table keys (starting with 't') and meta keys (starting with 'm') are supported, or hex keys with --hex")
+	}
+
+	closeOutput, err := f.redirectOutput()
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, closeOutput(err != nil)) }()
+
 	slog.Info("Starting get operation", slog.String("key", key), slog.String("pd_endpoints", fmt.Sprintf("%v", f.PDEndpoints)))
 
-	return getKey(ctx, f.PDEndpoints, key)
+	return getKey(ctx, f)
+}
+
+// countSet returns the number of the values which are set.
+func countSet(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+
+	return n
+}
+
+// isSupportedKey reports whether the key is a table key or a meta key, the keys ParseKey knows.
+func isSupportedKey(key string) bool {
+	return strings.HasPrefix(key, "t") || strings.HasPrefix(key, "m")
 }
 
 func runScan(ctx context.Context, cmd *cli.Command) error {
-	f := parseFlags(cmd)
+	return scan(ctx, cmd, parseFlags(cmd))
+}
+
+// scan runs scan with the flags, which the export command sets for an export of every row.
+func scan(ctx context.Context, cmd *cli.Command, f *TiKVReaderFlags) (err error) {
 	if err := f.Validate(); err != nil {
 		return err
 	}
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	// a base64 prefix is scanned as its hex bytes
+	if prefixBase64 := cmd.String("prefix-base64"); prefixBase64 != "" {
+		if f.TargetPrefix != "" {
+			return fmt.Errorf("--prefix and --prefix-base64 cannot be used together")
+		}
+		b, err := decodeBase64Input(prefixBase64)
+		if err != nil {
+			return fmt.Errorf("invalid --prefix-base64: %w", err)
+		}
+		f.TargetPrefix = fmt.Sprintf("%X", b)
+	}
+
+	isRange := f.RangeStart != "" || f.RangeEnd != ""
+	if isRange && f.TargetPrefix != "" {
+		return fmt.Errorf("--start and --end cannot be used with --prefix")
+	}
+	if isRange && (f.RangeStart == "" || f.RangeEnd == "") {
+		return fmt.Errorf("--start and --end must be given together")
+	}
+
+	// a system table scans its records by default
+	if !isRange && f.TargetPrefix == "" && f.Schema != nil {
+		f.TargetPrefix = fmt.Sprintf("t%d_r", f.Schema.TableID)
+	}
 
-	prefix := f.TargetPrefix
-	if prefix == "" {
+	if !isRange && f.TargetPrefix == "" {
 		return fmt.Errorf("prefix is required")
 	}
-	if !strings.HasPrefix(prefix, "t") {
-		return fmt.Errorf("currently only table key prefixes (starting with 't') are supported")
+	for _, k := range []string{f.TargetPrefix, f.RangeStart, f.RangeEnd} {
+		if k == "" || isSupportedKey(k) {
+			continue
+		}
+		if _, err := decodeHexInput(k); err != nil {
+			return fmt.Errorf("currently only table key prefixes (starting with 't') and meta key prefixes (starting with 'm') are supported, or hex keys: %w", err)
+		}
 	}
 
 	limit := f.Limit
@@ -198,54 +1171,281 @@ func runScan(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("limit must be greater than 0")
 	}
 
-	if limit > 1000 {
-		return fmt.Errorf("limit exceeds maximum of 1000")
+	// the result is kept in memory to print it, except for --all which prints while scanning
+	if limit > maxScanLimit && !f.ScanAll {
+		return fmt.Errorf("limit exceeds maximum of %d, use --all to scan every key", maxScanLimit)
+	}
+
+	if f.ScanAll && (f.ResolveHandles || f.GroupByTable) {
+		return fmt.Errorf("--all cannot be used with --resolve-handles or --group-by-table")
+	}
+
+	if err := f.compileFilter(); err != nil {
+		return err
+	}
+
+	if f.StartAfter != "" && f.SinceFile != "" {
+		return fmt.Errorf("--start-after cannot be used with --since-file, which resumes from its watermark")
+	}
+
+	if f.MaxScanDuration < 0 {
+		return fmt.Errorf("max-scan-duration must not be negative")
+	}
+
+	if f.Parallel < 1 || f.Parallel > maxScanParallel {
+		return fmt.Errorf("parallel must be between 1 and %d", maxScanParallel)
+	}
+
+	if f.ResolveHandles && f.OutSocket != "" {
+		return fmt.Errorf("--resolve-handles cannot be used with --out-socket")
+	}
+
+	if f.KeysOnly && f.Output == outputSQL {
+		return fmt.Errorf("--output sql needs the values and cannot be used with --keys-only")
+	}
+
+	if f.Output == outputExport && (f.KeysOnly || f.OutSocket != "") {
+		return fmt.Errorf("--export-dir cannot be used with --keys-only or --out-socket")
+	}
+
+	if f.KeysOnly && f.ResolveHandles {
+		return fmt.Errorf("--resolve-handles needs the values and cannot be used with --keys-only")
+	}
+
+	if f.GroupByTable && (f.OutSocket != "" || f.Output != outputText) {
+		return fmt.Errorf("--group-by-table is for the text output and cannot be used with --out-socket or --output %s", f.Output)
+	}
+
+	if f.Output != outputText && (f.OutSocket != "" || f.ResolveHandles) {
+		return fmt.Errorf("--output %s cannot be used with --out-socket or --resolve-handles", f.Output)
+	}
+
+	if f.OutputFile != "" && f.OutSocket != "" {
+		return fmt.Errorf("--output-file cannot be used with --out-socket, which streams the result to the socket")
+	}
+
+	if err := f.validatePager(); err != nil {
+		return err
+	}
+
+	if f.SortBy != "" {
+		if f.ScanAll || f.OutSocket != "" || f.Output == outputBinary || f.GroupByTable {
+			return fmt.Errorf("--sort-by needs the whole result and cannot be used with --all, --out-socket, --output binary or --group-by-table")
+		}
+		if f.sortBy, err = parseSortBy(f.SortBy); err != nil {
+			return fmt.Errorf("invalid --sort-by: %w", err)
+		}
+		if f.sortBy.by == "col" && f.KeysOnly {
+			return fmt.Errorf("--sort-by %s needs the values and cannot be used with --keys-only", f.SortBy)
+		}
+	}
+
+	start, end, err := f.scanBounds()
+	if err != nil {
+		return err
+	}
+
+	if f.StartAfter != "" {
+		if f.StartAfterKey, err = parseStartAfter(f.Codec, f.StartAfter); err != nil {
+			return err
+		}
+	}
+
+	closeOutput, err := f.redirectOutput()
+	if err != nil {
+		return err
 	}
+	defer func() { err = errors.Join(err, closeOutput(err != nil)) }()
+
+	if f.DryRun {
+		printScanBounds(f.Codec, f.scanTarget(), start, end)
+		return nil
+	}
+
+	// quitting the pager stops the scan, which isn't a failure
+	ctx, stopScan := context.WithCancel(ctx)
+	defer stopScan()
+	closePager := f.startPager(stopScan)
+	defer func() { err = closePager(err) }()
 
 	slog.Info("Starting scan operation",
-		slog.String("prefix", prefix), slog.String("pd_endpoints", fmt.Sprintf("%v", f.PDEndpoints)), slog.Int("limit", limit))
+		slog.String("target", f.scanTarget()), slog.String("pd_endpoints", fmt.Sprintf("%v", f.PDEndpoints)), slog.Int("limit", limit))
 
-	return scanKeys(ctx, f.PDEndpoints, prefix, limit)
+	return scanKeys(ctx, f, start, end)
 }
 
-func getKey(ctx context.Context, pdAddr []string, key string) error {
-	rawkey, err := codec.ParseKey(key)
+// scanBounds parses the prefix, or the start and end keys of a range scan, into the start and
+// the exclusive end of the scan. The end of a prefix is the successor of the prefix.
+func (f *TiKVReaderFlags) scanBounds() (start, end []byte, err error) {
+	if f.RangeStart == "" {
+		if start, err = parseScanKey(f.Codec, f.TargetPrefix); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse prefix %s: %w", f.TargetPrefix, err)
+		}
+		return start, codec.PrefixEnd(start), nil
+	}
+
+	if start, err = parseScanKey(f.Codec, f.RangeStart); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse start key %s: %w", f.RangeStart, err)
+	}
+	if end, err = parseScanKey(f.Codec, f.RangeEnd); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse end key %s: %w", f.RangeEnd, err)
+	}
+	if err := client.ValidateRange(start, end); err != nil {
+		return nil, nil, fmt.Errorf("invalid range %s - %s: %w", f.RangeStart, f.RangeEnd, err)
+	}
+
+	return start, end, nil
+}
+
+// parseScanKey parses a prefix or a range bound of scan: a key like t1_r, or hex bytes as is.
+func parseScanKey(opts codec.Options, input string) ([]byte, error) {
+	if isSupportedKey(input) {
+		return opts.ParsePrefix(input)
+	}
+
+	key, err := decodeHexInput(input)
 	if err != nil {
-		return fmt.Errorf("failed to parse key %s: %w", key, err)
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("the key is empty")
+	}
+
+	return key, nil
+}
+
+// scanTarget describes what the scan reads, e.g., "prefix t1_r" or "range t1_r100 - t1_r500".
+func (f *TiKVReaderFlags) scanTarget() string {
+	if f.RangeStart == "" {
+		return "prefix " + f.TargetPrefix
+	}
+
+	return fmt.Sprintf("range %s - %s", f.RangeStart, f.RangeEnd)
+}
+
+// parseGetKey returns the bytes of --key: the hex bytes as is with --hex, or else the encoded
+// logical key.
+func parseGetKey(f *TiKVReaderFlags) ([]byte, error) {
+	if f.HexKey {
+		rawkey, err := decodeHexInput(f.TargetKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --key: %w", err)
+		}
+		if len(rawkey) == 0 {
+			return nil, fmt.Errorf("invalid --key: the key is empty")
+		}
+		return rawkey, nil
+	}
+
+	rawkey, err := f.Codec.ParseKey(f.TargetKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", f.TargetKey, err)
+	}
+
+	return rawkey, nil
+}
+
+func getKey(ctx context.Context, f *TiKVReaderFlags) error {
+	pdAddr, key := f.PDEndpoints, f.TargetKey
+
+	rawkey, err := parseGetKey(f)
+	if err != nil {
+		return err
+	}
+	if f.HexKey {
+		key = f.Codec.DecodeKey(rawkey)
 	}
 	slog.Info("Processing the request", slog.String("key", key), slog.String("parsed_key", fmt.Sprintf("%X", rawkey)))
 
-	cli, err := client.NewTiKVClient(pdAddr)
+	if f.RawOut != "" && (f.AllCFs || f.Versions || f.AssertValueHex != "") {
+		return fmt.Errorf("--raw-out writes the value of the key and cannot be used with --all-cfs, --versions, or --assert-value-hex")
+	}
+
+	if f.AllCFs {
+		if f.AssertValueHex != "" || f.Output != outputText || f.KeyspaceName != "" || f.SnapshotTS != 0 {
+			return fmt.Errorf("--all-cfs reads raw column families and cannot be used with --assert-value-hex, --output, --keyspace-name, or --snapshot-ts")
+		}
+		return getKeyAllCFs(ctx, f, rawkey)
+	}
+
+	if f.MaxVersions < 0 {
+		return fmt.Errorf("max-versions must not be negative")
+	}
+	if f.MaxVersions > 0 && !f.Versions {
+		return fmt.Errorf("--max-versions caps the list of --versions and needs it")
+	}
+	if f.Versions {
+		if f.AllCFs || f.AssertValueHex != "" || f.SnapshotTS != 0 || (f.Output != outputText && f.Output != outputJSON) {
+			return fmt.Errorf("--versions reads every version and cannot be used with --all-cfs, --assert-value-hex, --snapshot-ts, or --output %s", f.Output)
+		}
+		return getKeyVersions(ctx, f, key, rawkey)
+	}
+
+	expected, err := parseAssertValueHex(f.AssertValueHex)
 	if err != nil {
-		return fmt.Errorf("failed to connect to PD server(%v): %w", pdAddr, err)
+		return err
+	}
+
+	cli, err := client.NewTiKVClientContext(ctx, pdAddr, f.clientOptions()...)
+	if err != nil {
+		return exitOnReadFailure(f, fmt.Errorf("failed to connect to PD server(%v): %w", pdAddr, err))
 	}
 	slog.Info("connected to PD servers", slog.String("pd_addr", fmt.Sprintf("%v", pdAddr)))
 	defer cli.Close()
 
-	value, err := cli.Get(ctx, rawkey)
+	logSnapshotTS(f.SnapshotTS)
+	value, err := cli.GetWithTS(ctx, rawkey, f.SnapshotTS)
+	if err != nil && expected != nil && client.IsKeyNotFound(err) {
+		return assertMissingKey(key)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get key %s: %w", key, err)
+		return exitOnReadFailure(f, fmt.Errorf("failed to get key %s: %w", key, err))
+	}
+
+	if expected != nil {
+		return assertValue(f.Codec, key, expected, value)
+	}
+
+	if f.RawOut != "" {
+		if err := os.WriteFile(f.RawOut, value, 0o644); err != nil {
+			return fmt.Errorf("failed to write --raw-out: %w", err)
+		}
+		slog.Info("wrote the raw value", slog.String("raw_out", f.RawOut), slog.Int("bytes", len(value)))
+	}
+
+	if f.Output == outputBinary {
+		w := output.NewBinaryWriter(os.Stdout)
+		if err := w.Write(rawkey, value); err != nil {
+			return fmt.Errorf("failed to write key %s: %w", key, err)
+		}
+		return w.Flush()
+	}
+
+	if f.Output == outputJSON {
+		return printJSON(output.NewRecord(f.Codec, rawkey, value))
+	}
+
+	if f.printsRecords() {
+		return printRecords(f, [][]byte{rawkey}, [][]byte{value})
 	}
 
-	decodedValue := codec.DecodeValue(value)
 	PrintSeparatorLine(60)
 	fmt.Printf("Key: %s\n", key)
-	fmt.Printf("  Hex: %s\n", codec.PrettyPrintKey(rawkey))
+	fmt.Printf("  Hex: %s\n", f.Codec.PrettyPrintKey(rawkey))
+	printPrimaryKey(f, rawkey, "  ")
 	fmt.Printf("Value:\n")
-	PrintDecodedValue(decodedValue, "    ")
+	printValue(rawkey, value, f, "    ")
 	PrintSeparatorLine(60)
 
 	return nil
 }
 
-func scanKeys(ctx context.Context, pdAddr []string, prefix string, limit int) error {
-	rawPrefix, err := codec.ParsePrefix(prefix)
-	if err != nil {
-		return fmt.Errorf("failed to parse prefix %s: %w", prefix, err)
-	}
-	slog.Info("Processing the request", slog.String("prefix", prefix), slog.String("parsed_prefix", fmt.Sprintf("%X", rawPrefix)))
+func scanKeys(ctx context.Context, f *TiKVReaderFlags, start, end []byte) error {
+	pdAddr, limit := f.PDEndpoints, f.Limit
+	slog.Info("Processing the request", slog.String("target", f.scanTarget()),
+		slog.String("parsed_start", fmt.Sprintf("%X", start)), slog.String("parsed_end", fmt.Sprintf("%X", end)))
 
-	cli, err := client.NewTiKVClient(pdAddr)
+	cli, err := client.NewTiKVClientContext(ctx, pdAddr, f.clientOptions()...)
 	if err != nil {
 		return fmt.Errorf("failed to connect to PD server(%v): %w", pdAddr, err)
 	}
@@ -253,34 +1453,689 @@ func scanKeys(ctx context.Context, pdAddr []string, prefix string, limit int) er
 	slog.Info("connected to PD servers", slog.String("pd_addr", fmt.Sprintf("%v", pdAddr)))
 
 	// Example scan logic (this would be more complex in a real application)
-	keys, values, err := cli.Scan(ctx, rawPrefix, limit)
+	logSnapshotTS(f.SnapshotTS)
+	opts := client.ScanOptions{Limit: limit, TS: f.SnapshotTS, MaxDuration: f.MaxScanDuration, KeysOnly: f.KeysOnly, Parallel: f.Parallel, Filter: f.scanFilter()}
+	if f.ScanAll {
+		opts.Limit = math.MaxInt
+	}
+	if f.StartAfterKey != nil {
+		opts.StartAfter = f.StartAfterKey
+	}
+	if f.SinceFile != "" {
+		if opts.StartAfter, err = client.ReadWatermark(f.SinceFile); err != nil {
+			return err
+		}
+		if opts.StartAfter == nil {
+			slog.Info("no watermark yet, scanning from the beginning", slog.String("since_file", f.SinceFile))
+		} else {
+			slog.Info("scanning after the watermark", slog.String("since_file", f.SinceFile), slog.String("watermark", f.Codec.DecodeKey(opts.StartAfter)))
+		}
+	}
+	if f.Output == outputBinary {
+		last, summary, err := streamBinary(ctx, cli, f, start, end, opts)
+		if err != nil {
+			return err
+		}
+		logScanStats(newScanStats(ctx, cli, f, start, end, opts, summary, last))
+		printResumeKey(f, opts, summary, last)
+		return saveWatermark(f.Codec, f.SinceFile, last)
+	}
+
+	if f.OutSocket != "" {
+		last, summary, err := streamToSocket(ctx, cli, f, start, end, opts)
+		if err != nil {
+			return err
+		}
+		logScanStats(newScanStats(ctx, cli, f, start, end, opts, summary, last))
+		printResumeKey(f, opts, summary, last)
+		return saveWatermark(f.Codec, f.SinceFile, last)
+	}
+
+	if f.ScanAll {
+		last, err := streamScan(ctx, cli, f, start, end, opts)
+		if err != nil {
+			return err
+		}
+		return saveWatermark(f.Codec, f.SinceFile, last)
+	}
+
+	// the key and value passed by ScanFunc are reused by the iterator, so keep copies
+	var keys, values [][]byte
+	summary, err := cli.ScanRangeFunc(ctx, start, end, opts, func(k, v []byte) error {
+		keys = append(keys, slices.Clone(k))
+		if f.KeysOnly {
+			values = append(values, nil)
+		} else {
+			values = append(values, slices.Clone(v))
+		}
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to scan keys: %w", err)
 	}
+	if summary.TimeBudgetReached {
+		slog.Warn("scan stopped at the time budget, the result is partial",
+			slog.Duration("max_scan_duration", f.MaxScanDuration), slog.Int("rows", summary.Rows))
+	}
+	logFilterSummary(f, summary)
+
+	var last []byte
+	if len(keys) > 0 {
+		last = keys[len(keys)-1]
+	}
+	stats := newScanStats(ctx, cli, f, start, end, opts, summary, last)
+	sortPairs(f, keys, values)
+
+	if f.Output == outputJSON {
+		records := make([]output.Record, 0, len(keys))
+		for i := range keys {
+			records = append(records, output.NewRecord(f.Codec, keys[i], values[i]))
+		}
+		var result any = records
+		if stats != nil {
+			result = scanResult{Records: records, Stats: stats}
+		}
+		if err := printJSON(result); err != nil {
+			return err
+		}
+		printResumeKey(f, opts, summary, last)
+		return saveWatermark(f.Codec, f.SinceFile, last)
+	}
+
+	if f.printsRecords() {
+		if err := printRecords(f, keys, values); err != nil {
+			return err
+		}
+		logScanStats(stats)
+		printResumeKey(f, opts, summary, last)
+		return saveWatermark(f.Codec, f.SinceFile, last)
+	}
+
+	var rows []resolvedRow
+	if f.ResolveHandles {
+		if rows, err = resolveHandles(ctx, cli, f.Codec, keys, values, f.SnapshotTS); err != nil {
+			return err
+		}
+	}
+
+	var groups []codec.TableGroup
+	if f.GroupByTable {
+		groups = codec.GroupByTable(keys)
+	}
 
 	fmt.Printf("Scan completed successfully. Retrieved %d key-value pairs:\n", len(keys))
 	for i := range keys {
-		decodedKey := codec.DecodeKey(keys[i])
-		hexKey := codec.PrettyPrintKey(keys[i])
-		decodedValue := codec.DecodeValue(values[i])
+		if len(groups) > 0 && groups[0].Start == i {
+			printTableGroupHeader(groups[0])
+			groups = groups[1:]
+		}
+		printScanRow(i+1, keys[i], values[i], f)
+		if rows != nil && !f.KeysOnly {
+			printResolvedRow(f.Codec, rows[i], f.typeNames(), "")
+		}
+	}
+	PrintSeparatorLine(60)
+	printTimeBudgetReached(f, summary)
+	printScanStats(stats)
+	printResumeKey(f, opts, summary, last)
+
+	return saveWatermark(f.Codec, f.SinceFile, last)
+}
+
+const (
+	filterOnValue = "value"
+	filterOnKey   = "key"
+)
+
+// compileFilter compiles --filter, so that an invalid regexp fails before connecting to TiKV.
+func (f *TiKVReaderFlags) compileFilter() error {
+	if f.Filter == "" {
+		return nil
+	}
+
+	switch f.FilterOn {
+	case filterOnValue:
+		if f.KeysOnly {
+			return fmt.Errorf("--filter on the values cannot be used with --keys-only, use --filter-on key")
+		}
+	case filterOnKey:
+	default:
+		return fmt.Errorf("unknown --filter-on %q: must be %s or %s", f.FilterOn, filterOnValue, filterOnKey)
+	}
 
-		PrintSeparatorLine(60)
-		fmt.Printf("[%d]\n", i+1)
-		fmt.Printf("Key: %s\n", decodedKey)
-		fmt.Printf("  Hex: %s\n", hexKey)
-		fmt.Printf("Value:\n")
-		PrintDecodedValue(decodedValue, "  ")
+	re, err := regexp.Compile(f.Filter)
+	if err != nil {
+		return fmt.Errorf("invalid --filter: %w", err)
 	}
+	f.filterRegexp = re
+
+	return nil
+}
+
+// scanFilter returns the filter of the scan options for --filter: it matches the regexp
+// against the decoded value, flattened by DecodedValue.String, or against the decoded key.
+func (f *TiKVReaderFlags) scanFilter() func(key, value []byte) bool {
+	re := f.filterRegexp
+	if re == nil {
+		return nil
+	}
+
+	if f.FilterOn == filterOnKey {
+		return func(key, _ []byte) bool { return re.MatchString(f.Codec.DecodeKey(key)) }
+	}
+	return func(key, value []byte) bool { return re.MatchString(f.Codec.DecodeValueForKey(key, value).String()) }
+}
+
+// logFilterSummary logs how many of the scanned pairs matched --filter.
+func logFilterSummary(f *TiKVReaderFlags, summary client.ScanSummary) {
+	if f.filterRegexp != nil {
+		slog.Info("filtered the scan", slog.String("filter", f.Filter), slog.String("filter_on", f.FilterOn),
+			slog.Int("matched", summary.Rows), slog.Int("scanned", summary.Scanned))
+	}
+}
+
+// printScanRow prints the index-th key-value pair of a scan result.
+func printScanRow(index int, key, value []byte, f *TiKVReaderFlags) {
 	PrintSeparatorLine(60)
+	fmt.Printf("[%d]\n", index)
+	fmt.Printf("Key: %s\n", f.Codec.DecodeKey(key))
+	fmt.Printf("  Hex: %s\n", f.Codec.PrettyPrintKey(key))
+	printPrimaryKey(f, key, "  ")
+	if f.KeysOnly {
+		return
+	}
+	fmt.Printf("Value:\n")
+	printValue(key, value, f, "  ")
+}
+
+func printTimeBudgetReached(f *TiKVReaderFlags, summary client.ScanSummary) {
+	if summary.TimeBudgetReached {
+		fmt.Printf("Scan stopped after hitting the time budget (%s): processed %d rows in %s.\n",
+			f.MaxScanDuration, summary.Rows, summary.Elapsed.Round(time.Millisecond))
+	}
+}
+
+// printResumeKey tells how to go on with --start-after when the scan stopped before the end of
+// the range, at the limit or at the time budget. Only the text output prints it to stdout, the
+// others log it. With --since-file, the watermark already keeps the key.
+func printResumeKey(f *TiKVReaderFlags, opts client.ScanOptions, summary client.ScanSummary, last []byte) {
+	if last == nil || f.SinceFile != "" || (summary.Rows < opts.Limit && !summary.TimeBudgetReached) {
+		return
+	}
+
+	if f.Output == outputText {
+		fmt.Printf("Stopped at %s. To go on, scan with --start-after %X\n", f.Codec.DecodeKey(last), last)
+		return
+	}
+	slog.Info("scan stopped before the end of the range, go on with --start-after",
+		slog.String("last_key", f.Codec.DecodeKey(last)), slog.String("start_after", fmt.Sprintf("%X", last)))
+}
+
+func printTableGroupHeader(g codec.TableGroup) {
+	fmt.Println(strings.Repeat("=", 60))
+	if g.TableID == 0 {
+		fmt.Printf("Non-table keys: %d keys\n", g.Count)
+	} else {
+		fmt.Printf("Table %d: %d keys\n", g.TableID, g.Count)
+	}
+	fmt.Println(strings.Repeat("=", 60))
+}
+
+// saveWatermark moves the --since-file watermark to the last key of the scan. An empty scan
+// keeps the previous watermark.
+func saveWatermark(opts codec.Options, path string, last []byte) error {
+	if path == "" || last == nil {
+		return nil
+	}
+	if err := client.WriteWatermark(path, last); err != nil {
+		return err
+	}
+	slog.Info("updated the watermark", slog.String("since_file", path), slog.String("watermark", opts.DecodeKey(last)))
 
 	return nil
 }
 
+// exitOnReadFailure gives a failed read its own exit code when the value is asserted.
+func exitOnReadFailure(f *TiKVReaderFlags, err error) error {
+	if f.AssertValueHex == "" {
+		return err
+	}
+
+	return cli.Exit(err, exitCodeReadFailed)
+}
+
+// parseAssertValueHex parses the value of --assert-value-hex, or returns nil without one.
+func parseAssertValueHex(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	expected, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --assert-value-hex: %w", err)
+	}
+
+	return expected, nil
+}
+
+// assertValue compares the stored value with the expected bytes, and prints the differences of
+// the decoded values on a mismatch.
+func assertValue(opts codec.Options, key string, expected, actual []byte) error {
+	if bytes.Equal(expected, actual) {
+		fmt.Printf("OK: value of %s matches (%d bytes)\n", key, len(actual))
+		return nil
+	}
+
+	fmt.Printf("MISMATCH: value of %s\n", key)
+	fmt.Printf("  Expected(Hex): %X\n", expected)
+	fmt.Printf("  Actual(Hex):   %X\n", actual)
+	diffs := codec.DiffDecodedValues(opts.DecodeValue(expected), opts.DecodeValue(actual))
+	if len(diffs) == 0 {
+		diffs = []string{"the decoded values are the same, only the raw bytes differ"}
+	}
+	for _, d := range diffs {
+		fmt.Printf("  %s\n", d)
+	}
+
+	return cli.Exit(fmt.Sprintf("value of %s doesn't match --assert-value-hex", key), exitCodeAssertionFailed)
+}
+
+func assertMissingKey(key string) error {
+	fmt.Printf("MISMATCH: key %s not found\n", key)
+	return cli.Exit(fmt.Sprintf("key %s doesn't exist, expected the --assert-value-hex value", key), exitCodeAssertionFailed)
+}
+
+// printScanBounds prints the keys a scan starts from and stops before.
+func printScanBounds(opts codec.Options, target string, start, end []byte) {
+	PrintSeparatorLine(60)
+	fmt.Printf("Scan: %s\n", target)
+	fmt.Printf("Start (inclusive): %s\n", opts.DecodeKey(start))
+	fmt.Printf("  Hex: %s\n", opts.PrettyPrintKey(start))
+	if end == nil {
+		fmt.Printf("End (exclusive): <none, scans to the end of the keyspace>\n")
+	} else {
+		fmt.Printf("End (exclusive): %s\n", opts.DecodeKey(end))
+		fmt.Printf("  Hex: %s\n", opts.PrettyPrintKey(end))
+	}
+	PrintSeparatorLine(60)
+}
+
+func logSnapshotTS(ts uint64) {
+	if ts == 0 {
+		return
+	}
+	slog.Info("reading at snapshot ts", slog.String("snapshot_ts", client.FormatTSO(ts)))
+}
+
+// streamToSocket writes each key-value pair as a JSON line to the Unix socket or named pipe of
+// --out-socket while scanning, like streamBinary, so a scan --all doesn't keep the result in
+// memory. It returns the last key written.
+func streamToSocket(ctx context.Context, cli *client.TiKVClient, f *TiKVReaderFlags, start, end []byte, opts client.ScanOptions) ([]byte, client.ScanSummary, error) {
+	sink, err := output.DialSocket(ctx, f.OutSocket)
+	if err != nil {
+		return nil, client.ScanSummary{}, err
+	}
+	defer sink.Close()
+	slog.Info("connected to output socket", slog.String("path", f.OutSocket))
+
+	w := output.NewJSONLinesWriter(sink)
+	var last []byte
+	rows := 0
+	summary, err := cli.ScanRangeFunc(ctx, start, end, opts, func(k, v []byte) error {
+		rows++
+		last = append(last[:0], k...)
+		if f.KeysOnly {
+			v = nil
+		}
+		if err := w.Write(output.NewRecord(f.Codec, k, v)); err != nil {
+			return fmt.Errorf("failed to write record %d to %s: %w", rows, f.OutSocket, err)
+		}
+		return nil
+	})
+	if err != nil {
+		if last != nil {
+			slog.Warn("scan failed, go on with --start-after",
+				slog.String("last_key", f.Codec.DecodeKey(last)), slog.String("start_after", fmt.Sprintf("%X", last)))
+		}
+		return nil, summary, fmt.Errorf("failed to scan keys: %w", err)
+	}
+
+	if summary.TimeBudgetReached {
+		slog.Warn("scan stopped at the time budget, the result is partial",
+			slog.Duration("max_scan_duration", opts.MaxDuration), slog.Int("rows", summary.Rows))
+	}
+	logFilterSummary(f, summary)
+	slog.Info("streamed scan result", slog.String("path", f.OutSocket), slog.Int("records", rows))
+
+	return last, summary, nil
+}
+
+// printValue prints the value of the key decoded, or as a hexdump with --hexdump, followed by
+// the row checksum of a RowV2 value having one, the partition of a global index entry and the
+// hexdumps of the columns given by --show-raw-cols.
+func printValue(key, value []byte, f *TiKVReaderFlags, indent string) {
+	if f.Hexdump {
+		fmt.Print(codec.Hexdump(value, indent))
+		return
+	}
+
+	if schema := f.schemaFor(key); schema == nil || !printSchemaRow(f.Codec, value, *schema, f.ShowTypes, indent) {
+		PrintDecodedValue(f.Codec.DecodeValueForKey(key, value), f.typeNames(), indent)
+	}
+	if checksum, ok := codec.VerifyRowChecksum(key, value); ok {
+		fmt.Printf("%sChecksum: %s\n", indent, checksum)
+	}
+	if id, ok := codec.IndexPartitionID(key, value); ok {
+		fmt.Printf("%sPartitionID: %d (physical table t%d)\n", indent, id, id)
+	}
+	if len(f.ShowRawCols) == 0 {
+		return
+	}
+
+	cols, err := codec.RowV2RawColumns(value)
+	if err != nil {
+		fmt.Printf("%s(Raw columns unavailable: %v)\n", indent, err)
+		return
+	}
+	for _, id := range f.ShowRawCols {
+		raw, ok := cols[id]
+		if !ok {
+			fmt.Printf("%sColID %d raw: <not in the row, NULL/Default>\n", indent, id)
+			continue
+		}
+		if raw == nil {
+			fmt.Printf("%sColID %d raw: <NULL>\n", indent, id)
+			continue
+		}
+		fmt.Printf("%sColID %d raw:\n", indent, id)
+		fmt.Print(codec.Hexdump(raw, indent+"  "))
+	}
+}
+
+// printPrimaryKey prints the clustered primary key of a record key with the column names of the
+// schema, e.g., PK: id=5, region="us", when the schema gives the primary key columns.
+func printPrimaryKey(f *TiKVReaderFlags, key []byte, indent string) {
+	if schema := f.schemaFor(key); schema != nil {
+		if pk, ok := f.Codec.PrimaryKeyString(key, *schema); ok {
+			fmt.Printf("%sPK: %s\n", indent, pk)
+		}
+	}
+}
+
+// printSchemaRow prints a RowV2 value column by column with the names and types of the schema,
+// or the names and the MySQL types with showTypes. It returns false when the value isn't a
+// RowV2 value, e.g., an index value.
+func printSchemaRow(opts codec.Options, value []byte, schema codec.TableSchema, showTypes bool, indent string) bool {
+	cols, err := opts.DecodeRowWithSchema(value, schema)
+	if err != nil {
+		return false
+	}
+
+	fmt.Printf("%sRow Format V2 (%s):\n", indent, schema.Name)
+	for _, c := range cols {
+		fmt.Printf("%s  %s\n", indent, schemaColumnLine(c, showTypes))
+	}
+
+	return true
+}
+
+// schemaColumnLine returns a column of a row decoded with its schema, e.g., name (ColID 2,
+// string): "foo", or name (VARCHAR): "foo" with showTypes.
+func schemaColumnLine(c codec.DecodedColumn, showTypes bool) string {
+	if showTypes {
+		return fmt.Sprintf("%s (%s): %s", c.Name, c.TypeName(), c.Value)
+	}
+	return fmt.Sprintf("%s (ColID %d, %s): %s", c.Name, c.ID, c.Type, c.Value)
+}
+
+// rowColumnLabel returns the label of a RowV2 column decoded without a schema, e.g., ColID 4,
+// or ColID 4 (DATETIME) when typeNames has a type for it.
+func rowColumnLabel(id int64, typeNames map[int64]string) string {
+	if name, ok := typeNames[id]; ok {
+		return fmt.Sprintf("ColID %d (%s)", id, name)
+	}
+	return fmt.Sprintf("ColID %d", id)
+}
+
+// streamBinary writes each key-value pair to stdout as a frame of the length-prefixed binary
+// output while scanning, without keeping the result in memory. It returns the last key written.
+func streamBinary(ctx context.Context, cli *client.TiKVClient, f *TiKVReaderFlags, start, end []byte, opts client.ScanOptions) ([]byte, client.ScanSummary, error) {
+	w := output.NewBinaryWriter(os.Stdout)
+	var last []byte
+	summary, err := cli.ScanRangeFunc(ctx, start, end, opts, func(k, v []byte) error {
+		last = append(last[:0], k...)
+		return w.Write(k, v)
+	})
+	if err != nil {
+		return nil, summary, fmt.Errorf("failed to scan keys: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, summary, fmt.Errorf("failed to write the scan result: %w", err)
+	}
+
+	if summary.TimeBudgetReached {
+		slog.Warn("scan stopped at the time budget, the result is partial",
+			slog.Duration("max_scan_duration", opts.MaxDuration), slog.Int("rows", summary.Rows))
+	}
+	logFilterSummary(f, summary)
+	slog.Info("streamed scan result", slog.String("output", outputBinary), slog.Int("records", summary.Rows))
+
+	return last, summary, nil
+}
+
+// redirectOutput points stdout to --output-file and compresses it with --compress. The returned
+// func ends the output, and is told whether the command failed.
+func (f *TiKVReaderFlags) redirectOutput() (func(failed bool) error, error) {
+	closeFile, err := f.openOutputFile()
+	if err != nil {
+		return nil, err
+	}
+	if !f.compress {
+		return closeFile, nil
+	}
+
+	closeGzip, err := gzipStdout()
+	if err != nil {
+		return nil, errors.Join(err, closeFile(true))
+	}
+
+	return func(failed bool) error {
+		if err := closeGzip(); err != nil {
+			return errors.Join(err, closeFile(true))
+		}
+		return closeFile(failed)
+	}, nil
+}
+
+// gzipStdout compresses what is written to stdout from now on with gzip, so the writers of the
+// outputs stay unaware of it. The returned func ends the stream and restores stdout.
+func gzipStdout() (func() error, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress the output: %w", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+
+	done := make(chan error, 1)
+	go func() {
+		zw := gzip.NewWriter(stdout)
+		_, err := io.Copy(zw, r)
+		if err = errors.Join(err, zw.Close()); err != nil {
+			_, _ = io.Copy(io.Discard, r) // keep the writers from blocking on the pipe
+		}
+		r.Close()
+		done <- err
+	}()
+
+	return func() error {
+		os.Stdout = stdout
+		w.Close()
+		if err := <-done; err != nil {
+			return fmt.Errorf("failed to compress the output: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// openOutputFile points stdout to a temporary file next to --output-file, so the result of get
+// and scan goes to the file while the logs stay on stderr. The returned func closes the file and
+// restores stdout, and renames the file to --output-file unless the command failed, so a failed
+// command never leaves a partial result there. The partial result of a failed command, e.g., a
+// scan --all to go on with --start-after, is kept in the temporary file.
+func (f *TiKVReaderFlags) openOutputFile() (func(failed bool) error, error) {
+	if f.OutputFile == "" {
+		return func(bool) error { return nil }, nil
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(f.OutputFile); err == nil {
+		if info.IsDir() {
+			return nil, fmt.Errorf("--output-file %s is a directory", f.OutputFile)
+		}
+		mode = info.Mode().Perm()
+	}
+	file, err := os.CreateTemp(filepath.Dir(f.OutputFile), "."+filepath.Base(f.OutputFile)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open --output-file: %w", err)
+	}
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to open --output-file: %w", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = file
+
+	return func(failed bool) error {
+		os.Stdout = stdout
+		err := file.Close()
+		if err == nil && !failed {
+			err = os.Rename(file.Name(), f.OutputFile)
+		}
+		if err != nil {
+			os.Remove(file.Name())
+			return fmt.Errorf("failed to write --output-file %s: %w", f.OutputFile, err)
+		}
+		if failed {
+			if info, err := os.Stat(file.Name()); err == nil && info.Size() > 0 {
+				slog.Warn("the command failed, --output-file is left as it was and the partial result is kept",
+					slog.String("output_file", f.OutputFile), slog.String("partial_result", file.Name()))
+			} else {
+				os.Remove(file.Name())
+			}
+			return nil
+		}
+
+		slog.Info("wrote the result", slog.String("output_file", f.OutputFile))
+		return nil
+	}, nil
+}
+
+// printJSON writes v to stdout as indented JSON. The logs go to stderr, so stdout stays pure JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printsRecords reports whether the output is written by printRecords.
+func (f *TiKVReaderFlags) printsRecords() bool {
+	switch f.Output {
+	case outputCSV, outputTemplate, outputTable, outputSQL, outputMsgpack, outputProto, outputExport:
+		return true
+	default:
+		return false
+	}
+}
+
+// printRecords writes the key-value pairs to stdout in the CSV, the template, the table, the
+// SQL, the msgpack or the proto output, or exports them into the files of --export-dir.
+func printRecords(f *TiKVReaderFlags, keys, values [][]byte) error {
+	switch f.Output {
+	case outputMsgpack:
+		w := output.NewMsgpackWriter(os.Stdout)
+		for i := range keys {
+			if err := w.Write(output.NewResultRecord(f.Codec, i+1, keys[i], values[i])); err != nil {
+				return fmt.Errorf("failed to write msgpack: %w", err)
+			}
+		}
+		return w.Flush()
+	case outputProto:
+		w := output.NewProtoWriter(os.Stdout)
+		for i := range keys {
+			if err := w.Write(output.NewResultRecord(f.Codec, i+1, keys[i], values[i])); err != nil {
+				return fmt.Errorf("failed to write proto: %w", err)
+			}
+		}
+		return w.Flush()
+	case outputExport:
+		e := newTableExporter(f)
+		for i := range keys {
+			if err := e.Write(keys[i], values[i]); err != nil {
+				return errors.Join(err, e.Close())
+			}
+		}
+		return e.Close()
+	case outputSQL:
+		w := newSQLWriter(os.Stdout, f)
+		for i := range keys {
+			if err := w.Write(keys[i], values[i]); err != nil {
+				return fmt.Errorf("failed to write the SQL: %w", err)
+			}
+		}
+		return w.Flush()
+	case outputTemplate:
+		return printTemplate(f.Codec, f.formatTemplate, keys, values)
+	case outputTable:
+		return printTable(f.Codec, keys, values)
+	default:
+		return printCSV(f.Codec, keys, values)
+	}
+}
+
+// printCSV writes the key-value pairs to stdout as CSV rows, numbered from 1.
+func printCSV(opts codec.Options, keys, values [][]byte) error {
+	w := output.NewCSVWriter(os.Stdout)
+	for i := range keys {
+		if err := w.Write(i+1, output.NewRecord(opts, keys[i], values[i])); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+
+	return w.Flush()
+}
+
+// printTemplate writes the key-value pairs to stdout with the --format-template, numbered from 1.
+func printTemplate(opts codec.Options, tmpl *template.Template, keys, values [][]byte) error {
+	w := output.NewTemplateWriter(os.Stdout, tmpl)
+	for i := range keys {
+		if err := w.Write(i+1, output.NewRecord(opts, keys[i], values[i])); err != nil {
+			return fmt.Errorf("failed to execute --format-template for key %s: %w", opts.DecodeKey(keys[i]), err)
+		}
+	}
+
+	return w.Flush()
+}
+
+// printTable writes the key-value pairs to stdout as an aligned table, numbered from 1.
+func printTable(opts codec.Options, keys, values [][]byte) error {
+	w := output.NewTableWriter(os.Stdout)
+	for i := range keys {
+		if err := w.Write(i+1, output.NewRecord(opts, keys[i], values[i])); err != nil {
+			return fmt.Errorf("failed to write the table: %w", err)
+		}
+	}
+
+	return w.Flush()
+}
+
 func PrintSeparatorLine(n int) {
 	fmt.Println(strings.Repeat("-", n))
 }
 
-func PrintDecodedValue(v codec.DecodedValue, indent string) {
+// PrintDecodedValue prints a decoded value, with the MySQL types of typeNames after the IDs of
+// the RowV2 columns which have one.
+func PrintDecodedValue(v codec.DecodedValue, typeNames map[int64]string, indent string) {
 	switch v.Type {
 	case codec.TypeNull:
 		fmt.Printf("%s<Null>\n", indent)
@@ -293,9 +2148,62 @@ func PrintDecodedValue(v codec.DecodedValue, indent string) {
 		vals := v.Payload.([]string)
 		fmt.Printf("%sIndexValues: %s\n", indent, strings.Join(vals, ", "))
 
-	case codec.TypeRowV2:
+	case codec.TypeIndexValue:
+		fmt.Printf("%sIndexValue: %s\n", indent, v.Payload.(codec.IndexValueData))
+
+	case codec.TypeTempIndex:
+		fmt.Printf("%sTemp Index (ADD INDEX in progress):\n", indent)
+		for i, op := range v.Payload.([]codec.TempIndexOp) {
+			if op.Value == nil {
+				fmt.Printf("%s  #%d %s\n", indent, i+1, op)
+				continue
+			}
+			value := *op.Value
+			op.Value = nil // printed below the operation
+			fmt.Printf("%s  #%d %s:\n", indent, i+1, op)
+			PrintDecodedValue(value, typeNames, indent+"    ")
+		}
+
+	case codec.TypeMeta:
+		meta := v.Payload.(codec.MetaValue)
+		fmt.Printf("%sMeta: %s\n", indent, meta)
+		for _, col := range meta.Columns {
+			handle := ""
+			if col.Handle {
+				handle = " (handle)"
+			} else if col.PK > 0 {
+				handle = fmt.Sprintf(" (pk %d)", col.PK)
+			}
+			fmt.Printf("%s  ColID %d: %s %s%s\n", indent, col.ID, col.Name, col.Type, handle)
+		}
+		for _, idx := range meta.Indexes {
+			fmt.Printf("%s  Index %s\n", indent, idx)
+		}
+		if len(meta.Partitions) > 0 {
+			fmt.Printf("%s  Partitions: %s\n", indent, strings.Trim(fmt.Sprint(meta.Partitions), "[]"))
+		}
+		if e := meta.Change; e != nil {
+			if e.RowCount != 0 {
+				fmt.Printf("%s  Rows: %d\n", indent, e.RowCount)
+			}
+			if e.Error != "" {
+				fmt.Printf("%s  Error: %s\n", indent, e.Error)
+			}
+			if e.FinishedTS != 0 {
+				fmt.Printf("%s  Finished at: %s\n", indent, client.FormatTSO(e.FinishedTS))
+			}
+			if e.Query != "" {
+				fmt.Printf("%s  Query: %s\n", indent, e.Query)
+			}
+		}
+
+	case codec.TypeRowV2, codec.TypeRowV1:
 		row := v.Payload.(codec.RowV2Data)
-		fmt.Printf("%sRow Format V2:\n", indent)
+		if v.Type == codec.TypeRowV1 {
+			fmt.Printf("%sRow Format V1:\n", indent)
+		} else {
+			fmt.Printf("%sRow Format V2:\n", indent)
+		}
 
 		// Mapは順序がないので、ColIDでソートして表示する
 		var ids []int64
@@ -310,10 +2218,13 @@ func PrintDecodedValue(v codec.DecodedValue, indent string) {
 			if id == -1 {
 				fmt.Printf("%s  Raw(Hex): %s\n", indent, val)
 			} else {
-				fmt.Printf("%s  ColID %d: %s\n", indent, id, val)
+				fmt.Printf("%s  %s: %s\n", indent, rowColumnLabel(id, typeNames), val)
 			}
 		}
 		fmt.Printf("%s  (Note: Missing columns are NULL/Default)\n", indent)
+		if row.Untouched {
+			fmt.Printf("%s  (Untouched: the index entry was only locked, not changed)\n", indent)
+		}
 
 	default:
 		fmt.Printf("%sUnknown Type: %v\n", indent, v.Payload)
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/urfave/cli/v3"
+)
+
+// captureStdout returns what fn prints to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+	fn()
+	w.Close()
+
+	return <-done
+}
+
+func TestSchemaColumnLine(t *testing.T) {
+	schemas, err := codec.ParseSchemas([]byte(`{"1": {"1": {"name": "id", "type": "bigint", "handle": true}, "2": {"name": "name", "type": "varchar(64)"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, name := schemas[1].Columns[0], schemas[1].Columns[1]
+
+	tests := []struct {
+		col       codec.DecodedColumn
+		showTypes bool
+		want      string
+	}{
+		{col: codec.DecodedColumn{ColumnSchema: name, Value: `"foo"`}, want: `name (ColID 2, string): "foo"`},
+		{col: codec.DecodedColumn{ColumnSchema: name, Value: `"foo"`}, showTypes: true, want: `name (VARCHAR): "foo"`},
+		{col: codec.DecodedColumn{ColumnSchema: name, Value: "NULL"}, showTypes: true, want: "name (VARCHAR): NULL"},
+		{col: codec.DecodedColumn{ColumnSchema: id, Value: "<handle, see the key>"}, showTypes: true, want: "id (BIGINT): <handle, see the key>"},
+	}
+	for _, tt := range tests {
+		if got := schemaColumnLine(tt.col, tt.showTypes); got != tt.want {
+			t.Errorf("schemaColumnLine(%s, %t) = %s, want %s", tt.col.Value, tt.showTypes, got, tt.want)
+		}
+	}
+}
+
+func TestRowColumnLabel(t *testing.T) {
+	f := &TiKVReaderFlags{ColumnTypeNames: codec.ColumnTypeHintNames([]string{"4=datetime"})}
+	if got := rowColumnLabel(4, f.typeNames()); got != "ColID 4" {
+		t.Errorf("rowColumnLabel(4) without --show-types = %s, want ColID 4", got)
+	}
+
+	f.ShowTypes = true
+	if got := rowColumnLabel(4, f.typeNames()); got != "ColID 4 (DATETIME)" {
+		t.Errorf("rowColumnLabel(4) = %s, want ColID 4 (DATETIME) for the hinted column", got)
+	}
+	if got := rowColumnLabel(5, f.typeNames()); got != "ColID 5" {
+		t.Errorf("rowColumnLabel(5) = %s, want ColID 5 without a hint", got)
+	}
+}
+
+func TestAssertValue(t *testing.T) {
+	// a RowV2 value of the string Alice as column 2 and the int 1 as column 3
+	expected, err := parseAssertValueHex("800002000000020305000600416c69636501")
+	if err != nil {
+		t.Fatalf("parseAssertValueHex() error = %v", err)
+	}
+
+	var got error
+	out := captureStdout(t, func() { got = assertValue(codec.Options{}, "t1_r1", expected, expected) })
+	if got != nil || !strings.HasPrefix(out, "OK: value of t1_r1 matches") {
+		t.Errorf("assertValue() of the same bytes = %v, %q, want OK", got, out)
+	}
+
+	actual, _ := hex.DecodeString("800002000000020305000600416c69636502")
+	out = captureStdout(t, func() { got = assertValue(codec.Options{}, "t1_r1", expected, actual) })
+	var exit cli.ExitCoder
+	if !errors.As(got, &exit) || exit.ExitCode() != exitCodeAssertionFailed {
+		t.Errorf("assertValue() of other bytes = %v, want the exit code %d", got, exitCodeAssertionFailed)
+	}
+	if !strings.HasPrefix(out, "MISMATCH: value of t1_r1") || !strings.Contains(out, "Actual(Hex):   800002000000020305000600416C69636502") {
+		t.Errorf("assertValue() printed %q, want the mismatch with both values", out)
+	}
+	if !strings.Contains(out, "ColID 3: expected") || strings.Contains(out, "ColID 2") {
+		t.Errorf("assertValue() printed %q, want the difference of column 3 alone", out)
+	}
+
+	if _, err := parseAssertValueHex("80zz"); err == nil || !strings.Contains(err.Error(), "--assert-value-hex") {
+		t.Errorf("parseAssertValueHex(80zz) error = %v, want an invalid --assert-value-hex", err)
+	}
+	if b, err := parseAssertValueHex(""); b != nil || err != nil {
+		t.Errorf("parseAssertValueHex(\"\") = %v, %v, want nothing to assert", b, err)
+	}
+}
+
+func TestOpenOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+
+	// the result goes to a temporary file next to the output, which is replaced on success
+	f := &TiKVReaderFlags{OutputFile: path}
+	closeFile, err := f.openOutputFile()
+	if err != nil {
+		t.Fatalf("openOutputFile() error = %v", err)
+	}
+	tmp := os.Stdout.Name()
+	if filepath.Dir(tmp) != dir || tmp == path {
+		t.Errorf("stdout = %s, want a temporary file in %s", tmp, dir)
+	}
+	fmt.Print("new")
+	if b, _ := os.ReadFile(path); string(b) != "old" {
+		t.Errorf("output file = %q while writing, want the old one", b)
+	}
+	if err := closeFile(false); err != nil {
+		t.Fatalf("close error = %v", err)
+	}
+	if os.Stdout != stdout {
+		t.Error("stdout isn't restored")
+	}
+	if b, _ := os.ReadFile(path); string(b) != "new" {
+		t.Errorf("output file = %q, want the new result", b)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("output file mode = %v, want the mode of the old file", info.Mode().Perm())
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("temporary file %s is left after the rename: %v", tmp, err)
+	}
+
+	// a failure before any output leaves the old file and no temporary file
+	closeFile, err = f.openOutputFile()
+	if err != nil {
+		t.Fatalf("openOutputFile() error = %v", err)
+	}
+	tmp = os.Stdout.Name()
+	if err := closeFile(true); err != nil {
+		t.Fatalf("close error = %v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "new" {
+		t.Errorf("output file = %q after a failure, want it as it was", b)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("empty temporary file %s is left after a failure: %v", tmp, err)
+	}
+
+	// a failure after a partial result keeps it aside
+	closeFile, err = f.openOutputFile()
+	if err != nil {
+		t.Fatalf("openOutputFile() error = %v", err)
+	}
+	tmp = os.Stdout.Name()
+	fmt.Print("partial")
+	if err := closeFile(true); err != nil {
+		t.Fatalf("close error = %v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "new" {
+		t.Errorf("output file = %q after a failure, want it as it was", b)
+	}
+	if b, _ := os.ReadFile(tmp); string(b) != "partial" {
+		t.Errorf("partial result = %q, want it kept in %s", b, tmp)
+	}
+
+	// a rename which fails removes the temporary file, e.g., when a directory took the name
+	blocked := filepath.Join(t.TempDir(), "out.txt")
+	closeFile, err = (&TiKVReaderFlags{OutputFile: blocked}).openOutputFile()
+	if err != nil {
+		t.Fatalf("openOutputFile() error = %v", err)
+	}
+	tmp = os.Stdout.Name()
+	fmt.Print("lost")
+	if err := os.MkdirAll(filepath.Join(blocked, "dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := closeFile(false); err == nil {
+		t.Error("close error = nil, want the failed rename")
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("temporary file %s is left after a failed rename: %v", tmp, err)
+	}
+
+	if _, err := (&TiKVReaderFlags{OutputFile: dir}).openOutputFile(); err == nil {
+		t.Error("openOutputFile() of a directory error = nil, want an error")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("directory has %d files, want the output and the partial result", len(entries))
+	}
+}
+
+// gunzip decompresses a whole gzip stream; a stream whose writer wasn't closed lacks the trailer
+// and fails with io.ErrUnexpectedEOF.
+func gunzip(t *testing.T, b []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading the gzip stream error = %v", err)
+	}
+
+	return string(out)
+}
+
+func TestGzipStdout(t *testing.T) {
+	var closeErr error
+	out := captureStdout(t, func() {
+		closeGzip, err := gzipStdout()
+		if err != nil {
+			t.Fatalf("gzipStdout() error = %v", err)
+		}
+		fmt.Print(strings.Repeat("t132_r1\n", 1000))
+		closeErr = closeGzip()
+	})
+	if closeErr != nil {
+		t.Fatalf("close error = %v", closeErr)
+	}
+	if got := gunzip(t, []byte(out)); got != strings.Repeat("t132_r1\n", 1000) {
+		t.Errorf("gunzip() = %d bytes, want the 8000 bytes printed", len(got))
+	}
+}
+
+func TestRedirectOutputCompress(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		file, compress string
+		gzipped        bool
+	}{
+		{file: "out.txt.gz", gzipped: true},
+		{file: "out.txt.gz", compress: compressNone},
+		{file: "out.txt", compress: compressGzip, gzipped: true},
+		{file: "out.txt"},
+	}
+	for _, tt := range tests {
+		f := &TiKVReaderFlags{PDEndpoints: []string{"127.0.0.1:2379"}, Output: outputText, OutputFile: filepath.Join(dir, tt.file), Compress: tt.compress, Pager: pagerNever}
+		if err := f.Validate(); err != nil {
+			t.Fatalf("Validate() error = %v", err)
+		}
+		closeOutput, err := f.redirectOutput()
+		if err != nil {
+			t.Fatalf("redirectOutput() error = %v", err)
+		}
+		fmt.Print("result\n")
+		if err := closeOutput(false); err != nil {
+			t.Fatalf("close error = %v", err)
+		}
+
+		// the gzip stream is complete in the file once renamed, so it was closed first
+		b, err := os.ReadFile(f.OutputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := string(b)
+		if tt.gzipped {
+			got = gunzip(t, b)
+		}
+		if got != "result\n" {
+			t.Errorf("%s with --compress %q = %q, want the result (gzipped %t)", tt.file, tt.compress, got, tt.gzipped)
+		}
+	}
+
+	// the partial result of a failed command is a complete gzip stream too
+	f := &TiKVReaderFlags{OutputFile: filepath.Join(dir, "failed.gz"), compress: true}
+	closeOutput, err := f.redirectOutput()
+	if err != nil {
+		t.Fatalf("redirectOutput() error = %v", err)
+	}
+	fmt.Print("partial\n")
+	if err := closeOutput(true); err != nil {
+		t.Fatalf("close error = %v", err)
+	}
+	if _, err := os.Stat(f.OutputFile); !os.IsNotExist(err) {
+		t.Errorf("%s exists after a failure: %v", f.OutputFile, err)
+	}
+	partial, _ := filepath.Glob(filepath.Join(dir, ".failed.gz.tmp-*"))
+	if len(partial) != 1 {
+		t.Fatalf("partial results = %v, want one", partial)
+	}
+	b, err := os.ReadFile(partial[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gunzip(t, b); got != "partial\n" {
+		t.Errorf("partial result = %q, want partial", got)
+	}
+}
+
+func TestPagerCommand(t *testing.T) {
+	auto := &TiKVReaderFlags{Pager: pagerAuto, Output: outputText}
+	tests := []struct {
+		name       string
+		f          *TiKVReaderFlags
+		isTerminal bool
+		pagerEnv   string
+		hasLess    bool
+		want       string
+	}{
+		{name: "terminal", f: auto, isTerminal: true, hasLess: true, want: defaultPager},
+		{name: "$PAGER", f: auto, isTerminal: true, pagerEnv: "more", hasLess: true, want: "more"},
+		{name: "$PAGER without less", f: auto, isTerminal: true, pagerEnv: "more", want: "more"},
+		{name: "$PAGER unset without less", f: auto, isTerminal: true},
+		{name: "not a terminal", f: auto, pagerEnv: "more", hasLess: true},
+		{name: "never", f: &TiKVReaderFlags{Pager: pagerNever, Output: outputText}, isTerminal: true, pagerEnv: "more", hasLess: true},
+		{name: "binary output", f: &TiKVReaderFlags{Pager: pagerAuto, Output: outputBinary}, isTerminal: true, hasLess: true},
+		{name: "socket", f: &TiKVReaderFlags{Pager: pagerAuto, Output: outputText, OutSocket: "/tmp/s"}, isTerminal: true, hasLess: true},
+	}
+	for _, tt := range tests {
+		if got := tt.f.pagerCommand(tt.isTerminal, tt.pagerEnv, tt.hasLess); got != tt.want {
+			t.Errorf("%s: pagerCommand() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if err := (&TiKVReaderFlags{Pager: "always"}).validatePager(); err == nil {
+		t.Error("validatePager(always) error = nil, want an error")
+	}
+}
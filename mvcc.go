@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+// mvccVersionRecord is a version of a key in the JSON output of get --versions.
+type mvccVersionRecord struct {
+	CommitTS uint64              `json:"commit_ts"`
+	StartTS  uint64              `json:"start_ts"`
+	Type     string              `json:"type"`
+	Value    *codec.DecodedValue `json:"value,omitempty"`
+}
+
+// mvccLockRecord is the lock of a key in the JSON output of get --versions.
+type mvccLockRecord struct {
+	StartTS uint64              `json:"start_ts"`
+	Type    string              `json:"type"`
+	Primary string              `json:"primary"`
+	TTL     uint64              `json:"ttl"`
+	Value   *codec.DecodedValue `json:"value,omitempty"`
+}
+
+type mvccRecord struct {
+	Key       string              `json:"key"`
+	Hex       string              `json:"hex"`
+	Versions  []mvccVersionRecord `json:"versions"`
+	Lock      *mvccLockRecord     `json:"lock,omitempty"`
+	Truncated bool                `json:"truncated"`                // older versions were left out by --max-versions
+	Older     int                 `json:"older_versions,omitempty"` // how many
+}
+
+// getKeyVersions prints every version of the key kept by TiKV, newest first, with the
+// commit ts and the decoded value of each, or the newest f.MaxVersions of them.
+func getKeyVersions(ctx context.Context, f *TiKVReaderFlags, key string, rawkey []byte) error {
+	cli, err := client.NewTiKVClientContext(ctx, f.PDEndpoints, f.clientOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PD server(%v): %w", f.PDEndpoints, err)
+	}
+	defer cli.Close()
+	slog.Info("connected to PD servers", slog.String("pd_addr", fmt.Sprintf("%v", f.PDEndpoints)))
+
+	info, err := cli.GetMVCC(ctx, rawkey, f.MaxVersions)
+	if err != nil {
+		return fmt.Errorf("failed to get the versions of key %s: %w", key, err)
+	}
+
+	if f.Output == outputJSON {
+		return printJSON(newMVCCRecord(f.Codec, rawkey, info))
+	}
+
+	PrintSeparatorLine(60)
+	fmt.Printf("Key: %s\n", key)
+	fmt.Printf("  Hex: %s\n", f.Codec.PrettyPrintKey(rawkey))
+	if l := info.Lock; l != nil {
+		fmt.Printf("Lock (uncommitted): start_ts %s, %s, primary %s, ttl %dms\n",
+			client.FormatTSO(l.StartTS), l.Type, f.Codec.DecodeKey(l.Primary), l.TTL)
+		if l.Value != nil {
+			printValue(rawkey, l.Value, f, "    ")
+		}
+	}
+	if len(info.Versions) == 0 {
+		fmt.Printf("No versions (the key was never written, or all versions were garbage collected)\n")
+	}
+	for i, v := range info.Versions {
+		PrintSeparatorLine(60)
+		fmt.Printf("[%d] %s\n", i+1, v.Type)
+		fmt.Printf("  Commit TS: %s\n", client.FormatTSO(v.CommitTS))
+		fmt.Printf("  Start TS:  %s\n", client.FormatTSO(v.StartTS))
+		if v.Type != "put" {
+			continue
+		}
+		fmt.Printf("  Value:\n")
+		printValue(rawkey, v.Value, f, "    ")
+	}
+	PrintSeparatorLine(60)
+	if info.Truncated > 0 {
+		fmt.Printf("Truncated: %d older versions not shown (--max-versions %d)\n", info.Truncated, f.MaxVersions)
+	}
+
+	return nil
+}
+
+func newMVCCRecord(opts codec.Options, key []byte, info client.MVCCInfo) mvccRecord {
+	r := mvccRecord{
+		Key:       opts.DecodeKey(key),
+		Hex:       opts.PrettyPrintKey(key),
+		Versions:  []mvccVersionRecord{},
+		Truncated: info.Truncated > 0,
+		Older:     info.Truncated,
+	}
+	for _, v := range info.Versions {
+		rec := mvccVersionRecord{CommitTS: v.CommitTS, StartTS: v.StartTS, Type: v.Type}
+		if v.Type == "put" {
+			value := opts.DecodeValueForKey(key, v.Value)
+			rec.Value = &value
+		}
+		r.Versions = append(r.Versions, rec)
+	}
+	if l := info.Lock; l != nil {
+		r.Lock = &mvccLockRecord{StartTS: l.StartTS, Type: l.Type, Primary: opts.PrettyPrintKey(l.Primary), TTL: l.TTL}
+		if l.Value != nil {
+			value := opts.DecodeValueForKey(key, l.Value)
+			r.Lock.Value = &value
+		}
+	}
+
+	return r
+}
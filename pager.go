@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"syscall"
+)
+
+// The modes of scan --pager.
+const (
+	pagerAuto  = "auto"
+	pagerNever = "never"
+)
+
+// defaultPager is run without $PAGER. -F quits at once when the output fits the screen, so only
+// the results longer than the terminal are paged, -R keeps the colors and -X leaves the screen
+// as it is on exit, as git does.
+const defaultPager = "less -FRX"
+
+// startPager pipes stdout through $PAGER, or less, with --pager auto when stdout is a terminal,
+// so a long scan doesn't run past the scrollback. stop is called when the pager is quit before
+// the end of the result, to stop the scan. The returned func waits for the pager and restores
+// stdout. It is given the error of the scan and drops it when the pager was quit, as the scan
+// then stops on the canceled context or the closed pipe.
+func (f *TiKVReaderFlags) startPager(stop func()) func(err error) error {
+	noPager := func(err error) error { return err }
+	_, err := exec.LookPath("less")
+	pager := f.pagerCommand(stdoutIsTerminal(), os.Getenv("PAGER"), err == nil)
+	if pager == "" {
+		return noPager
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		slog.Warn("failed to start the pager", slog.String("error", err.Error()))
+		return noPager
+	}
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = r, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		w.Close()
+		slog.Warn("failed to start the pager", slog.String("pager", pager), slog.String("error", err.Error()))
+		return noPager
+	}
+	r.Close() // the pager holds its own copy
+	stdout := os.Stdout
+	os.Stdout = w
+
+	var closing, quit atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			slog.Debug("the pager exited", slog.String("error", err.Error()))
+		}
+		if !closing.Load() {
+			quit.Store(true)
+			stop()
+		}
+		close(done)
+	}()
+
+	return func(err error) error {
+		closing.Store(true)
+		os.Stdout = stdout
+		w.Close()
+		<-done
+		if err != nil && (quit.Load() || errors.Is(err, syscall.EPIPE)) {
+			slog.Info("the pager was quit, stopped the scan", slog.String("error", err.Error()))
+			return nil
+		}
+		return err
+	}
+}
+
+// pagerCommand returns the command of the pager to page the result with, or "" to print it as
+// is: the result isn't paged with --pager never, when stdout isn't a terminal, or when it isn't
+// text. A pager is $PAGER, given as pagerEnv, or less when hasLess without $PAGER.
+func (f *TiKVReaderFlags) pagerCommand(isTerminal bool, pagerEnv string, hasLess bool) string {
+	if f.Pager != pagerAuto || f.OutSocket != "" || !isTerminal {
+		return ""
+	}
+	switch f.Output {
+	case outputBinary, outputMsgpack, outputProto, outputExport:
+		return ""
+	}
+
+	switch {
+	case pagerEnv != "":
+		return pagerEnv
+	case hasLess:
+		return defaultPager
+	default:
+		return ""
+	}
+}
+
+// stdoutIsTerminal reports whether stdout is a terminal, rather than a file or a pipe.
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// validatePager checks the mode of --pager.
+func (f *TiKVReaderFlags) validatePager() error {
+	if f.Pager != pagerAuto && f.Pager != pagerNever {
+		return fmt.Errorf("unknown --pager %q: must be %s or %s", f.Pager, pagerAuto, pagerNever)
+	}
+	return nil
+}
@@ -2,25 +2,120 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"slices"
 
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/tikv/client-go/v2/config"
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/kv"
+	"github.com/tikv/client-go/v2/tikv"
 	"github.com/tikv/client-go/v2/txnkv"
+	"github.com/tikv/client-go/v2/txnkv/transaction"
+	pdhttp "github.com/tikv/pd/client/http"
 )
 
 type TiKVClient struct {
-	client *txnkv.Client
+	client       *txnkv.Client
+	pdAddrs      []string
+	pdHTTP       pdhttp.Client // created on first use
+	keyspaceName string        // empty for API V1 clusters
+	keyspaceID   uint32
+	tlsFiles     TLSFiles
+	tlsConfig    *tls.Config // nil without TLS
+	retry        RetryPolicy
 }
 
-func NewTiKVClient(pdAddrs []string) (*TiKVClient, error) {
-	opts := []txnkv.ClientOpt{}
-	client, err := txnkv.NewClient(pdAddrs, opts...)
+// Option configures the TiKVClient.
+type Option func(*TiKVClient)
+
+// WithKeyspaceName reads the keyspace of an API V2 cluster. The name is resolved to the
+// keyspace ID through PD, and the keys are prefixed with the keyspace.
+func WithKeyspaceName(name string) Option {
+	return func(c *TiKVClient) {
+		c.keyspaceName = name
+	}
+}
+
+func NewTiKVClient(pdAddrs []string, opts ...Option) (*TiKVClient, error) {
+	return NewTiKVClientContext(context.Background(), pdAddrs, opts...)
+}
+
+// NewTiKVClientContext is NewTiKVClient giving up when the context is done, e.g., at the
+// deadline of --timeout when PD doesn't answer.
+func NewTiKVClientContext(ctx context.Context, pdAddrs []string, opts ...Option) (*TiKVClient, error) {
+	type result struct {
+		c   *TiKVClient
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		c, err := newTiKVClient(ctx, pdAddrs, opts...)
+		done <- result{c, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.c, r.err
+	case <-ctx.Done():
+		// client-go can't cancel the connection, so close the client whenever it is ready
+		go func() {
+			if r := <-done; r.c != nil {
+				r.c.Close()
+			}
+		}()
+		return nil, fmt.Errorf("timed out connecting to PD: %w", ctx.Err())
+	}
+}
+
+func newTiKVClient(ctx context.Context, pdAddrs []string, opts ...Option) (*TiKVClient, error) {
+	c := &TiKVClient{pdAddrs: pdAddrs, retry: RetryPolicy{MaxRetries: DefaultMaxRetries, Backoff: DefaultRetryBackoff}}
+	for _, o := range opts {
+		o(c)
+	}
+
+	sec, tlsConfig, err := c.tlsFiles.security()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		// txnkv.NewClient has no option for TLS, it takes the security of the global config
+		config.UpdateGlobal(func(conf *config.Config) {
+			conf.Security = sec
+		})
+		c.tlsConfig = tlsConfig
+	}
+
+	txnOpts := []txnkv.ClientOpt{}
+	if c.keyspaceName != "" {
+		// resolve the name first, client-go doesn't tell a missing keyspace clearly
+		pd, err := c.pdHTTPClient()
+		if err != nil {
+			return nil, err
+		}
+		if c.keyspaceID, err = resolveKeyspaceID(ctx, pd, c.keyspaceName); err != nil {
+			c.Close()
+			return nil, err
+		}
+		txnOpts = append(txnOpts, txnkv.WithAPIVersion(kvrpcpb.APIVersion_V2), txnkv.WithKeyspace(c.keyspaceName))
+	}
+
+	client, err := txnkv.NewClient(pdAddrs, txnOpts...)
 	if err != nil {
+		c.Close()
 		return nil, err
 	}
-	return &TiKVClient{client: client}, nil
+	c.client = client
+
+	return c, nil
 }
 
 func (c *TiKVClient) Close() error {
+	if c.pdHTTP != nil {
+		c.pdHTTP.Close()
+	}
+
 	if c.client == nil {
 		return nil
 	}
@@ -28,18 +123,44 @@ func (c *TiKVClient) Close() error {
 	return c.client.Close()
 }
 
+// begin starts a transaction reading at the given snapshot ts, or at the latest TSO when ts is 0.
+//
+// Reads go through the client-go snapshot, which resolves the locks it meets with the
+// LockResolver (ResolveLocksForRead). The resolver understands async-commit locks: it checks
+// the secondary locks of the transaction instead of waiting for the lock TTL, so a read doesn't
+// fail or block on a transaction which has already been committed. One-phase-commit (1PC)
+// transactions never leave locks behind. No extra option is required for such clusters.
+// The lock of a transaction which is still running blocks the read until it goes away or ctx
+// is done.
+func (c *TiKVClient) begin(ts uint64) (*transaction.KVTxn, error) {
+	if ts == 0 {
+		return c.client.Begin()
+	}
+
+	return c.client.Begin(tikv.WithStartTS(ts))
+}
+
 func (c *TiKVClient) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return c.GetWithTS(ctx, key, 0)
+}
+
+// GetWithTS reads the key at the snapshot ts. A ts of 0 reads the latest data.
+func (c *TiKVClient) GetWithTS(ctx context.Context, key []byte, ts uint64) ([]byte, error) {
 	if c.client == nil {
 		return nil, fmt.Errorf("TiKV client is not initialized")
 	}
 
-	tx, err := c.client.Begin()
+	tx, err := c.begin(ts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin the transaction with key %s :%w", string(key), err)
 	}
 	defer tx.Rollback()
 
 	val, err := tx.Get(ctx, key)
+	err = c.retry.retry(ctx, err, func() error {
+		val, err = tx.Get(ctx, key)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get key %s :%w", string(key), err)
 	}
@@ -48,57 +169,87 @@ func (c *TiKVClient) Get(ctx context.Context, key []byte) ([]byte, error) {
 }
 
 func (c *TiKVClient) Scan(ctx context.Context, prefix []byte, limit int) ([]([]byte), []([]byte), error) {
+	return c.ScanWithTS(ctx, prefix, limit, 0)
+}
+
+// ScanWithTS scans keys with the prefix at the snapshot ts. A ts of 0 reads the latest data.
+func (c *TiKVClient) ScanWithTS(ctx context.Context, prefix []byte, limit int, ts uint64) ([]([]byte), []([]byte), error) {
+	var keys [][]byte
+	var values [][]byte
+	_, err := c.ScanFunc(ctx, prefix, ScanOptions{Limit: limit, TS: ts}, func(k, v []byte) error {
+		keys = append(keys, slices.Clone(k))
+		values = append(values, slices.Clone(v))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keys, values, nil
+}
+
+// BatchGetWithTS reads several keys at the snapshot ts. Keys that don't exist are absent from the result.
+func (c *TiKVClient) BatchGetWithTS(ctx context.Context, keys [][]byte, ts uint64) (map[string][]byte, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("TiKV client is not initialized")
+	}
+
+	tx, err := c.begin(ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin the transaction for %d keys :%w", len(keys), err)
+	}
+	defer tx.Rollback()
+
+	values, err := tx.BatchGet(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %d keys :%w", len(keys), err)
+	}
+
+	return values, nil
+}
+
+// ScanReverseWithTS scans keys with the prefix from the largest one (newest-first for
+// keys which grow over time) at the snapshot ts. It retries like the forward scans, and an
+// error in the middle fails the scan instead of returning the keys read so far.
+func (c *TiKVClient) ScanReverseWithTS(ctx context.Context, prefix []byte, limit int, ts uint64) ([]([]byte), []([]byte), error) {
 	if c.client == nil {
 		return nil, nil, fmt.Errorf("TiKV client is not initialized")
 	}
 
-	tx, err := c.client.Begin()
+	tx, err := c.begin(ts)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to begin the transaction with prefix %s :%w", string(prefix), err)
 	}
 	defer tx.Rollback()
 
-	iter, err := tx.Iter(prefix, nil)
+	// a retried iterator reads the same snapshot of the transaction
+	iter, err := newReverseRetryIterator(ctx, c.retry, kv.PrefixNextKey(prefix), func(upper []byte) (iterator, error) {
+		return tx.IterReverse(upper, prefix)
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create iterator with prefix %s :%w", string(prefix), err)
+		return nil, nil, fmt.Errorf("failed to create reverse iterator with prefix %s :%w", string(prefix), err)
 	}
 	defer iter.Close()
 
 	var keys [][]byte
 	var values [][]byte
-	for i := 0; i < limit && iter.Valid(); i, err = i+1, iter.Next() {
-		if err != nil {
-			return nil, nil, fmt.Errorf("iterator error at prefix %s :%w", string(prefix), err)
+	// the lower bound of the iterator is the prefix, so it needs no end
+	_, err = scanIterator(iter, nil, ScanOptions{Limit: limit}, func(k, v []byte) error {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-
-		k := iter.Key()
-		if !hasPrefix(k, prefix) {
-			break
-		}
-
-		kCopy := make([]byte, len(k))
-		copy(kCopy, k)
-
-		vCopy := make([]byte, len(iter.Value()))
-		copy(vCopy, iter.Value())
-
-		keys = append(keys, kCopy)
-		values = append(values, vCopy)
+		keys = append(keys, slices.Clone(k))
+		values = append(values, slices.Clone(v))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan prefix %s in reverse :%w", string(prefix), err)
 	}
 
 	return keys, values, nil
 }
 
-func hasPrefix(s, prefix []byte) bool {
-	if len(s) < len(prefix) {
-		return false
-	}
-
-	for i := range prefix {
-		if s[i] != prefix[i] {
-			return false
-		}
-	}
-
-	return true
+// IsKeyNotFound reports whether err means the key doesn't exist at the read snapshot.
+func IsKeyNotFound(err error) bool {
+	return tikverr.IsErrNotFound(err)
 }
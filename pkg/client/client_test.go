@@ -0,0 +1,279 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/tikv/client-go/v2/oracle"
+	"github.com/tikv/client-go/v2/testutils"
+	"github.com/tikv/client-go/v2/tikv"
+	"github.com/tikv/client-go/v2/tikvrpc"
+	"github.com/tikv/client-go/v2/txnkv"
+)
+
+func TestNewTiKVClientContextTimeout(t *testing.T) {
+	// a PD which accepts connections but never answers
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = NewTiKVClientContext(ctx, []string{l.Addr().String()})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out connecting to PD") {
+		t.Errorf("NewTiKVClientContext() error = %v, want the timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("NewTiKVClientContext() took %s, want it to give up at the deadline", elapsed)
+	}
+}
+
+// asyncCommitTiKV plays the part of TiKV which mocktikv doesn't know: the locks of the
+// transaction started at startTS are async-commit locks. It reports them as such and answers
+// CheckTxnStatus and CheckSecondaryLocks for them until they are resolved, while mocktikv
+// keeps the data and resolves the locks.
+type asyncCommitTiKV struct {
+	tikv.Client
+	primary     []byte
+	secondaries [][]byte
+	missing     string // a secondary key the transaction never locked
+
+	mu       sync.Mutex
+	startTS  uint64
+	resolved bool
+}
+
+func (c *asyncCommitTiKV) lockInfo(key []byte) *kvrpcpb.LockInfo {
+	return &kvrpcpb.LockInfo{
+		Key:            key,
+		PrimaryLock:    c.primary,
+		LockVersion:    c.startTS,
+		LockTtl:        1,
+		LockType:       kvrpcpb.Op_Put,
+		UseAsyncCommit: true,
+		Secondaries:    c.secondaries,
+		MinCommitTs:    c.startTS + 1,
+	}
+}
+
+func (c *asyncCommitTiKV) SendRequest(ctx context.Context, addr string, req *tikvrpc.Request, timeout time.Duration) (*tikvrpc.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch req.Type {
+	case tikvrpc.CmdCheckTxnStatus:
+		if r := req.CheckTxnStatus(); r.LockTs == c.startTS && !c.resolved {
+			return &tikvrpc.Response{Resp: &kvrpcpb.CheckTxnStatusResponse{LockTtl: 1, LockInfo: c.lockInfo(c.primary)}}, nil
+		}
+	case tikvrpc.CmdCheckSecondaryLocks:
+		var locks []*kvrpcpb.LockInfo
+		for _, key := range req.CheckSecondaryLocks().Keys {
+			if string(key) != c.missing {
+				locks = append(locks, c.lockInfo(key))
+			}
+		}
+		return &tikvrpc.Response{Resp: &kvrpcpb.CheckSecondaryLocksResponse{Locks: locks}}, nil
+	case tikvrpc.CmdResolveLock:
+		if req.ResolveLock().StartVersion == c.startTS {
+			c.resolved = true
+		}
+	}
+
+	resp, err := c.Client.SendRequest(ctx, addr, req, timeout)
+	if err != nil || req.Type != tikvrpc.CmdGet {
+		return resp, err
+	}
+	if locked := resp.Resp.(*kvrpcpb.GetResponse).GetError().GetLocked(); locked != nil && locked.LockVersion == c.startTS {
+		resp.Resp.(*kvrpcpb.GetResponse).Error.Locked = c.lockInfo(locked.Key)
+	}
+	return resp, nil
+}
+
+// newMockClient returns a TiKVClient reading from a mocktikv cluster. hijack, if not nil, wraps
+// the RPC client of the store.
+func newMockClient(t *testing.T, hijack func(tikv.Client) tikv.Client) (*TiKVClient, *tikv.KVStore) {
+	t.Helper()
+	rpcClient, cluster, pdClient, err := testutils.NewMockTiKV("", nil)
+	if err != nil {
+		t.Fatalf("NewMockTiKV() error = %v", err)
+	}
+	testutils.BootstrapWithSingleStore(cluster)
+	store, err := tikv.NewTestTiKVStore(rpcClient, pdClient, hijack, nil, 0)
+	if err != nil {
+		t.Fatalf("NewTestTiKVStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return &TiKVClient{client: &txnkv.Client{KVStore: store}}, store
+}
+
+// prewrite leaves the locks of a transaction which never gets to its commit phase, as a
+// crashed TiDB does, and returns its start ts.
+func prewrite(t *testing.T, store *tikv.KVStore, primary string, ttl uint64, keys ...string) uint64 {
+	t.Helper()
+	startTS, err := store.CurrentTimestamp(oracle.GlobalTxnScope)
+	if err != nil {
+		t.Fatalf("CurrentTimestamp() error = %v", err)
+	}
+
+	req := &kvrpcpb.PrewriteRequest{PrimaryLock: []byte(primary), StartVersion: startTS, LockTtl: ttl}
+	for _, key := range keys {
+		req.Mutations = append(req.Mutations, &kvrpcpb.Mutation{Op: kvrpcpb.Op_Put, Key: []byte(key), Value: []byte("v" + key)})
+	}
+	bo := tikv.NewBackofferWithVars(context.Background(), 5000, nil)
+	loc, err := store.GetRegionCache().LocateKey(bo, []byte(primary))
+	if err != nil {
+		t.Fatalf("LocateKey() error = %v", err)
+	}
+	resp, err := store.SendReq(bo, tikvrpc.NewRequest(tikvrpc.CmdPrewrite, req), loc.Region, 5*time.Second)
+	if err != nil {
+		t.Fatalf("prewrite error = %v", err)
+	}
+	if errs := resp.Resp.(*kvrpcpb.PrewriteResponse).Errors; len(errs) > 0 {
+		t.Fatalf("prewrite failed: %v", errs)
+	}
+
+	return startTS
+}
+
+func TestGetResolvesAsyncCommitLocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		missing string
+		key     string
+		want    string // empty when the transaction is rolled back
+	}{
+		// every key of the transaction is locked, so it is committed at the largest min commit ts
+		{name: "all secondaries locked", key: "b", want: "vb"},
+		// a secondary key never got its lock, so the transaction is rolled back
+		{name: "a secondary missing", missing: "c", key: "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			async := &asyncCommitTiKV{primary: []byte("a"), secondaries: [][]byte{[]byte("b"), []byte("c")}, missing: tt.missing}
+			c, store := newMockClient(t, func(inner tikv.Client) tikv.Client {
+				async.Client = inner
+				return async
+			})
+			keys := []string{"a", "b", "c"}
+			if tt.missing != "" {
+				keys = slices.DeleteFunc(keys, func(k string) bool { return k == tt.missing })
+			}
+			startTS := prewrite(t, store, "a", 1, keys...)
+			async.mu.Lock()
+			async.startTS = startTS
+			async.mu.Unlock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			got, err := c.Get(ctx, []byte(tt.key))
+			if tt.want == "" {
+				if !IsKeyNotFound(err) {
+					t.Errorf("Get(%q) = %q, %v, want the key not found", tt.key, got, err)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Errorf("Get(%q) = %q, %v, want %q", tt.key, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFailsOnALiveLock(t *testing.T) {
+	c, store := newMockClient(t, nil)
+	prewrite(t, store, "a", uint64(time.Hour.Milliseconds()), "a")
+
+	// the lock doesn't expire, so the read waits for it until the deadline of --timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if got, err := c.Get(ctx, []byte("a")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() = %q, %v, want %v", got, err, context.DeadlineExceeded)
+	}
+}
+
+// commit writes the pairs in one transaction.
+func commit(t *testing.T, c *TiKVClient, keys ...string) {
+	t.Helper()
+	tx, err := c.client.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	for _, key := range keys {
+		if err := tx.Set([]byte(key), []byte("v"+key)); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+}
+
+// failingScanTiKV fails the scan request number failAt with a key error.
+type failingScanTiKV struct {
+	tikv.Client
+	failAt int
+
+	mu    sync.Mutex
+	scans int
+}
+
+func (c *failingScanTiKV) SendRequest(ctx context.Context, addr string, req *tikvrpc.Request, timeout time.Duration) (*tikvrpc.Response, error) {
+	if req.Type == tikvrpc.CmdScan {
+		c.mu.Lock()
+		c.scans++
+		fail := c.scans == c.failAt
+		c.mu.Unlock()
+		if fail {
+			return &tikvrpc.Response{Resp: &kvrpcpb.ScanResponse{Error: &kvrpcpb.KeyError{Abort: "injected"}}}, nil
+		}
+	}
+
+	return c.Client.SendRequest(ctx, addr, req, timeout)
+}
+
+func TestScanReverseWithTS(t *testing.T) {
+	scans := &failingScanTiKV{}
+	c, _ := newMockClient(t, func(inner tikv.Client) tikv.Client {
+		scans.Client = inner
+		return scans
+	})
+	var history []string
+	for i := range 300 {
+		history = append(history, fmt.Sprintf("h%03d", i))
+	}
+	commit(t, c, append(history, "g", "i")...)
+
+	keys, values, err := c.ScanReverseWithTS(context.Background(), []byte("h"), 3, 0)
+	if err != nil {
+		t.Fatalf("ScanReverseWithTS() error = %v", err)
+	}
+	var got []string
+	for i := range keys {
+		got = append(got, string(keys[i])+"="+string(values[i]))
+	}
+	if want := []string{"h299=vh299", "h298=vh298", "h297=vh297"}; !slices.Equal(got, want) {
+		t.Errorf("ScanReverseWithTS() = %v, want %v", got, want)
+	}
+
+	// an error past the first batch of the scanner fails the scan instead of cutting it short
+	scans.mu.Lock()
+	scans.scans, scans.failAt = 0, 2
+	scans.mu.Unlock()
+	if keys, _, err := c.ScanReverseWithTS(context.Background(), []byte("h"), len(history), 0); err == nil || !strings.Contains(err.Error(), "injected") {
+		t.Errorf("ScanReverseWithTS() = %d keys, %v, want the injected error", len(keys), err)
+	}
+}
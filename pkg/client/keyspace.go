@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pingcap/kvproto/pkg/keyspacepb"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+// keyspaceGetter is the part of the PD HTTP API used to resolve keyspace names.
+type keyspaceGetter interface {
+	GetKeyspaceMetaByName(context.Context, string) (*keyspacepb.KeyspaceMeta, error)
+}
+
+// resolveKeyspaceID returns the ID of the keyspace name, which must be enabled.
+func resolveKeyspaceID(ctx context.Context, pd keyspaceGetter, name string) (uint32, error) {
+	meta, err := pd.GetKeyspaceMetaByName(ctx, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve keyspace %q from PD, check that it exists and the cluster uses API V2: %w", name, err)
+	}
+
+	if meta.State != keyspacepb.KeyspaceState_ENABLED {
+		return 0, fmt.Errorf("keyspace %q (ID %d) is %s", name, meta.Id, meta.State)
+	}
+
+	return meta.Id, nil
+}
+
+// keyspaceKey returns the key as stored in TiKV, that is with the keyspace prefix when
+// the client reads a keyspace. The transaction API adds the prefix by itself, so this is
+// only for the raw key ranges sent to PD.
+func (c *TiKVClient) keyspaceKey(key []byte) []byte {
+	if c.keyspaceName == "" {
+		return key
+	}
+
+	return codec.EncodeKeyspaceKey(c.keyspaceID, key)
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pingcap/kvproto/pkg/keyspacepb"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+type fakeKeyspaces map[string]*keyspacepb.KeyspaceMeta
+
+func (f fakeKeyspaces) GetKeyspaceMetaByName(_ context.Context, name string) (*keyspacepb.KeyspaceMeta, error) {
+	meta, ok := f[name]
+	if !ok {
+		return nil, fmt.Errorf("request pd http api failed with status: '404 Not Found'")
+	}
+
+	return meta, nil
+}
+
+func TestResolveKeyspace(t *testing.T) {
+	fake := fakeKeyspaces{
+		"tenant_a": {Id: 0x010203, Name: "tenant_a", State: keyspacepb.KeyspaceState_ENABLED},
+		"archived": {Id: 7, Name: "archived", State: keyspacepb.KeyspaceState_ARCHIVED},
+	}
+
+	id, err := resolveKeyspaceID(context.Background(), fake, "tenant_a")
+	if err != nil {
+		t.Fatalf("resolveKeyspaceID() error = %v", err)
+	}
+	if id != 0x010203 {
+		t.Errorf("resolveKeyspaceID() = %d, want %d", id, 0x010203)
+	}
+
+	// the keyspace prefix goes in front of the parsed key
+	c := &TiKVClient{keyspaceName: "tenant_a", keyspaceID: id}
+	key, _ := codec.ParseKey("t1_r1")
+	expected := append([]byte{'x', 0x01, 0x02, 0x03}, key...)
+	if got := c.keyspaceKey(key); !bytes.Equal(got, expected) {
+		t.Errorf("keyspaceKey() = %X, want %X", got, expected)
+	}
+
+	if _, err := resolveKeyspaceID(context.Background(), fake, "missing"); err == nil {
+		t.Error("resolveKeyspaceID() should fail for a missing keyspace")
+	}
+	if _, err := resolveKeyspaceID(context.Background(), fake, "archived"); err == nil {
+		t.Error("resolveKeyspaceID() should fail for an archived keyspace")
+	}
+}
+
+func TestKeyspaceKeyWithoutKeyspace(t *testing.T) {
+	c := &TiKVClient{}
+	key := []byte("t\x80")
+	if got := c.keyspaceKey(key); !bytes.Equal(got, key) {
+		t.Errorf("keyspaceKey() = %X, want the key as is", got)
+	}
+}
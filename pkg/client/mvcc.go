@@ -0,0 +1,146 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+	"github.com/tikv/client-go/v2/tikv"
+	"github.com/tikv/client-go/v2/tikvrpc"
+)
+
+// mvccMaxBackoff is the backoff budget in milliseconds of an MVCC request over region errors.
+const mvccMaxBackoff = 20000
+
+// MVCCVersion is a version of a key in the write column family: a committed put or delete, or
+// a lock or rollback record of a transaction.
+type MVCCVersion struct {
+	CommitTS uint64
+	StartTS  uint64
+	Type     string // put, del, lock or rollback
+	Value    []byte // the value of a put, nil otherwise
+}
+
+// MVCCLock is the lock of a transaction on a key which is neither committed nor rolled back.
+type MVCCLock struct {
+	StartTS uint64
+	Type    string
+	Primary []byte
+	TTL     uint64
+	Value   []byte
+}
+
+// MVCCInfo is every version of a key still kept by TiKV, newest first, and its lock.
+type MVCCInfo struct {
+	Versions  []MVCCVersion
+	Lock      *MVCCLock // nil without a lock
+	Truncated int       // the older versions left out by the cap of GetMVCC
+}
+
+// GetMVCC reads the versions of the key with the MVCC debug API of TiKV, which bypasses the
+// snapshot: versions older than the GC safe point are included until they are compacted away.
+// With maxVersions > 0 it keeps the newest maxVersions versions and counts the older ones in
+// Truncated; their values are never paired or copied, so a key of thousands of versions takes
+// the memory of the cap. 0 keeps every version.
+func (c *TiKVClient) GetMVCC(ctx context.Context, key []byte, maxVersions int) (MVCCInfo, error) {
+	if c.client == nil {
+		return MVCCInfo{}, fmt.Errorf("TiKV client is not initialized")
+	}
+
+	bo := tikv.NewBackofferWithVars(ctx, mvccMaxBackoff, nil)
+	req := tikvrpc.NewRequest(tikvrpc.CmdMvccGetByKey, &kvrpcpb.MvccGetByKeyRequest{Key: key})
+	for {
+		loc, err := c.client.GetRegionCache().LocateKey(bo, key)
+		if err != nil {
+			return MVCCInfo{}, fmt.Errorf("failed to locate the region of key %X: %w", key, err)
+		}
+		resp, err := c.client.SendReq(bo, req, loc.Region, time.Minute)
+		if err != nil {
+			return MVCCInfo{}, fmt.Errorf("failed to get the MVCC of key %X: %w", key, err)
+		}
+
+		regionErr, err := resp.GetRegionError()
+		if err != nil {
+			return MVCCInfo{}, err
+		}
+		if regionErr != nil {
+			// the region moved or split, locate the key again
+			if err := bo.Backoff(tikv.BoRegionMiss(), errors.New(regionErr.String())); err != nil {
+				return MVCCInfo{}, fmt.Errorf("failed to get the MVCC of key %X: %w", key, err)
+			}
+			continue
+		}
+
+		mvcc, ok := resp.Resp.(*kvrpcpb.MvccGetByKeyResponse)
+		if !ok {
+			return MVCCInfo{}, fmt.Errorf("unexpected MVCC response %T", resp.Resp)
+		}
+		if mvcc.Error != "" {
+			return MVCCInfo{}, fmt.Errorf("failed to get the MVCC of key %X: %s", key, mvcc.Error)
+		}
+
+		return newMVCCInfo(mvcc.Info, maxVersions), nil
+	}
+}
+
+// newMVCCInfo pairs each write record with its value, which is either kept in the record as
+// a short value or stored in the default column family under the start ts. The writes are
+// sorted newest first and cut to maxVersions before pairing, when maxVersions > 0.
+func newMVCCInfo(info *kvrpcpb.MvccInfo, maxVersions int) MVCCInfo {
+	if info == nil {
+		return MVCCInfo{}
+	}
+
+	var m MVCCInfo
+	writes := slices.Clone(info.Writes)
+	slices.SortStableFunc(writes, func(a, b *kvrpcpb.MvccWrite) int { return cmp.Compare(b.CommitTs, a.CommitTs) })
+	if maxVersions > 0 && len(writes) > maxVersions {
+		m.Truncated = len(writes) - maxVersions
+		writes = writes[:maxVersions]
+	}
+
+	needed := make(map[uint64]bool, len(writes)+1)
+	for _, w := range writes {
+		if w.Type == kvrpcpb.Op_Put && w.ShortValue == nil {
+			needed[w.StartTs] = true
+		}
+	}
+	if l := info.Lock; l != nil && l.ShortValue == nil {
+		needed[l.StartTs] = true
+	}
+	values := make(map[uint64][]byte, len(needed))
+	for _, v := range info.Values {
+		if needed[v.StartTs] {
+			values[v.StartTs] = v.Value
+		}
+	}
+
+	for _, w := range writes {
+		v := MVCCVersion{CommitTS: w.CommitTs, StartTS: w.StartTs, Type: opName(w.Type)}
+		if w.Type == kvrpcpb.Op_Put {
+			v.Value = w.ShortValue
+			if v.Value == nil {
+				v.Value = values[w.StartTs]
+			}
+		}
+		m.Versions = append(m.Versions, v)
+	}
+
+	if l := info.Lock; l != nil {
+		m.Lock = &MVCCLock{StartTS: l.StartTs, Type: opName(l.Type), Primary: l.Primary, TTL: l.Ttl, Value: l.ShortValue}
+		if m.Lock.Value == nil {
+			m.Lock.Value = values[l.StartTs]
+		}
+	}
+
+	return m
+}
+
+func opName(op kvrpcpb.Op) string {
+	return strings.ToLower(op.String())
+}
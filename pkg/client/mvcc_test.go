@@ -0,0 +1,75 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pingcap/kvproto/pkg/kvrpcpb"
+)
+
+func TestNewMVCCInfo(t *testing.T) {
+	info := newMVCCInfo(&kvrpcpb.MvccInfo{
+		Writes: []*kvrpcpb.MvccWrite{
+			{Type: kvrpcpb.Op_Put, StartTs: 100, CommitTs: 101, ShortValue: []byte("short")},
+			{Type: kvrpcpb.Op_Del, StartTs: 300, CommitTs: 301},
+			{Type: kvrpcpb.Op_Put, StartTs: 200, CommitTs: 201},
+		},
+		Values: []*kvrpcpb.MvccValue{{StartTs: 200, Value: []byte("long value")}},
+		Lock:   &kvrpcpb.MvccLock{Type: kvrpcpb.Op_Put, StartTs: 400, Primary: []byte("pk"), ShortValue: []byte("pending")},
+	}, 0)
+
+	if len(info.Versions) != 3 {
+		t.Fatalf("Versions = %+v, want 3 versions", info.Versions)
+	}
+	// newest first, each put with its value
+	expected := []MVCCVersion{
+		{CommitTS: 301, StartTS: 300, Type: "del"},
+		{CommitTS: 201, StartTS: 200, Type: "put", Value: []byte("long value")},
+		{CommitTS: 101, StartTS: 100, Type: "put", Value: []byte("short")},
+	}
+	for i, v := range info.Versions {
+		e := expected[i]
+		if v.CommitTS != e.CommitTS || v.StartTS != e.StartTS || v.Type != e.Type || !bytes.Equal(v.Value, e.Value) {
+			t.Errorf("Versions[%d] = %+v, want %+v", i, v, e)
+		}
+	}
+	if info.Truncated != 0 {
+		t.Errorf("Truncated = %d, want 0 without a cap", info.Truncated)
+	}
+	if info.Lock == nil || info.Lock.StartTS != 400 || string(info.Lock.Value) != "pending" || info.Lock.Type != "put" {
+		t.Errorf("Lock = %+v, want the pending put at 400", info.Lock)
+	}
+
+	if got := newMVCCInfo(nil, 0); got.Versions != nil || got.Lock != nil {
+		t.Errorf("newMVCCInfo(nil, 0) = %+v, want no versions", got)
+	}
+}
+
+func TestNewMVCCInfoMaxVersions(t *testing.T) {
+	info := &kvrpcpb.MvccInfo{Lock: &kvrpcpb.MvccLock{Type: kvrpcpb.Op_Put, StartTs: 900}}
+	for ts := uint64(100); ts < 600; ts += 100 {
+		info.Writes = append(info.Writes, &kvrpcpb.MvccWrite{Type: kvrpcpb.Op_Put, StartTs: ts, CommitTs: ts + 1})
+		info.Values = append(info.Values, &kvrpcpb.MvccValue{StartTs: ts, Value: []byte{byte(ts / 100)}})
+	}
+	info.Values = append(info.Values, &kvrpcpb.MvccValue{StartTs: 900, Value: []byte("pending")})
+
+	m := newMVCCInfo(info, 2)
+	if m.Truncated != 3 {
+		t.Errorf("Truncated = %d, want 3 of the 5 versions left out", m.Truncated)
+	}
+	// the newest two, with their values
+	if len(m.Versions) != 2 || m.Versions[0].CommitTS != 501 || m.Versions[1].CommitTS != 401 {
+		t.Fatalf("Versions = %+v, want the versions committed at 501 and 401", m.Versions)
+	}
+	if !bytes.Equal(m.Versions[0].Value, []byte{5}) || !bytes.Equal(m.Versions[1].Value, []byte{4}) {
+		t.Errorf("Values = %v, %v, want 5 and 4", m.Versions[0].Value, m.Versions[1].Value)
+	}
+	if m.Lock == nil || string(m.Lock.Value) != "pending" {
+		t.Errorf("Lock = %+v, want the pending put kept under a cap", m.Lock)
+	}
+
+	// a cap above the versions leaves nothing out
+	if m := newMVCCInfo(info, 5); m.Truncated != 0 || len(m.Versions) != 5 {
+		t.Errorf("newMVCCInfo(5) = %d versions, %d truncated, want 5 and 0", len(m.Versions), m.Truncated)
+	}
+}
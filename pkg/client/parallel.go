@@ -0,0 +1,179 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// maxParallelRanges is the number of regions a parallel scan is split into at most. The
+	// last range covers the rest of the scan when the range spans more regions.
+	maxParallelRanges = 1024
+
+	// parallelBuffer is the number of pairs a worker reads ahead of the output.
+	parallelBuffer = 256
+)
+
+// keyRange is a sub-range [start, end) of a parallel scan. A nil end has no bound.
+type keyRange struct {
+	start, end []byte
+}
+
+// rangeScanner scans a sub-range like ScanRangeFunc.
+type rangeScanner func(ctx context.Context, r keyRange, opts ScanOptions, fn func(key, value []byte) error) (ScanSummary, error)
+
+// scanParallel splits [start, end) at the region bounds and scans the regions with
+// opts.Parallel workers. All of them read the same snapshot.
+func (c *TiKVClient) scanParallel(ctx context.Context, start, end []byte, opts ScanOptions, fn func(key, value []byte) error) (ScanSummary, error) {
+	if opts.TS == 0 {
+		tx, err := c.begin(0)
+		if err != nil {
+			return ScanSummary{}, fmt.Errorf("failed to get the snapshot ts of the scan: %w", err)
+		}
+		opts.TS = tx.StartTS()
+		tx.Rollback()
+	}
+
+	regions, err := scanRegions(ctx, c.client.GetPDClient(), start, end, maxParallelRanges)
+	if err != nil {
+		return ScanSummary{}, err
+	}
+
+	return scanRangesParallel(ctx, splitRange(start, end, regions), opts, func(ctx context.Context, r keyRange, opts ScanOptions, fn func(key, value []byte) error) (ScanSummary, error) {
+		return c.scanRange(ctx, r.start, r.end, opts, fn)
+	}, fn)
+}
+
+// splitRange splits [start, end) at the end keys of the regions, which are in key order.
+func splitRange(start, end []byte, regions []Region) []keyRange {
+	var ranges []keyRange
+	for _, r := range regions {
+		if len(r.EndKey) == 0 || !beforeEnd(r.EndKey, end) {
+			break
+		}
+		if bytes.Compare(r.EndKey, start) <= 0 {
+			continue
+		}
+		ranges = append(ranges, keyRange{start: start, end: r.EndKey})
+		start = r.EndKey
+	}
+
+	return append(ranges, keyRange{start: start, end: end})
+}
+
+// scanRangesParallel scans the ranges, which are in key order, with up to opts.Parallel
+// workers and calls fn with the pairs in key order from the calling goroutine. The workers
+// take the ranges in order and read ahead of the output, so the range being passed to fn is
+// always being scanned. Once opts.Limit pairs are passed, the other workers are canceled. The
+// summary counts the pairs read by the ranges passed to fn, up to the one the limit stops in.
+//
+// The time budget is shared by all the ranges. When a range runs out of it, the later ranges
+// are dropped, so the result stays a prefix of the scan.
+func scanRangesParallel(ctx context.Context, ranges []keyRange, opts ScanOptions, scan rangeScanner, fn func(key, value []byte) error) (summary ScanSummary, err error) {
+	begin := time.Now()
+	defer func() { summary.Elapsed = time.Since(begin) }()
+
+	type part struct {
+		pairs   chan [2][]byte // closed when the range is done
+		summary ScanSummary    // set before pairs is closed
+		err     error
+	}
+	parts := make([]*part, len(ranges))
+	for i := range parts {
+		parts[i] = &part{pairs: make(chan [2][]byte, parallelBuffer)}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range ranges {
+			select {
+			case next <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for range min(opts.Parallel, len(ranges)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				p, rangeOpts := parts[i], opts
+				if opts.MaxDuration > 0 {
+					if rangeOpts.MaxDuration = opts.MaxDuration - time.Since(begin); rangeOpts.MaxDuration <= 0 {
+						p.summary.TimeBudgetReached = true
+						close(p.pairs)
+						continue
+					}
+				}
+
+				// the key and the value are the buffers of the iterator, keep copies
+				p.summary, p.err = scan(ctx, ranges[i], rangeOpts, func(k, v []byte) error {
+					select {
+					case p.pairs <- [2][]byte{slices.Clone(k), slices.Clone(v)}:
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				})
+				close(p.pairs)
+			}
+		}()
+	}
+
+	for _, p := range parts {
+		if summary.Rows >= opts.Limit {
+			return summary, nil
+		}
+
+		for done := false; !done; {
+			select {
+			case pair, ok := <-p.pairs:
+				if !ok {
+					done = true
+					break
+				}
+				if err := fn(pair[0], pair[1]); err != nil {
+					return summary, err
+				}
+				summary.Rows++
+				if summary.Rows >= opts.Limit {
+					// stop the range and count what it has read, including the pairs read ahead
+					cancel()
+					for range p.pairs {
+					}
+					summary.Scanned += p.summary.Scanned
+					summary.Bytes += p.summary.Bytes
+					return summary, nil
+				}
+			case <-ctx.Done():
+				return summary, ctx.Err()
+			}
+		}
+
+		summary.Scanned += p.summary.Scanned
+		summary.Bytes += p.summary.Bytes
+		if p.err != nil {
+			return summary, p.err
+		}
+		if p.summary.TimeBudgetReached {
+			summary.TimeBudgetReached = true
+			return summary, nil
+		}
+	}
+
+	return summary, nil
+}
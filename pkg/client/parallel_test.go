@@ -0,0 +1,141 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSplitRange(t *testing.T) {
+	regions := []Region{
+		{EndKey: []byte("a")},
+		{StartKey: []byte("a"), EndKey: []byte("c")},
+		{StartKey: []byte("c"), EndKey: []byte("e")},
+		{StartKey: []byte("e")},
+	}
+
+	tests := []struct {
+		start, end string
+		want       string
+	}{
+		{"b", "d", "[b c) [c d)"},
+		{"b", "", "[b c) [c e) [e )"},
+		{"c", "e", "[c e)"},
+		{"a0", "b", "[a0 b)"},
+	}
+
+	for _, tt := range tests {
+		var end []byte
+		if tt.end != "" {
+			end = []byte(tt.end)
+		}
+
+		var got []string
+		for _, r := range splitRange([]byte(tt.start), end, regions) {
+			got = append(got, fmt.Sprintf("[%s %s)", r.start, r.end))
+		}
+		if fmt.Sprint(got) != "["+tt.want+"]" {
+			t.Errorf("splitRange(%s, %s) = %v, want [%s]", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+// fakeRangeScanner scans the keys within each range with fake iterators. The earlier ranges
+// (by the tens digit of the start) are paced slower, so the later ones finish first.
+func fakeRangeScanner(keys [][]byte, failAt string) rangeScanner {
+	return func(ctx context.Context, r keyRange, opts ScanOptions, fn func(key, value []byte) error) (ScanSummary, error) {
+		if string(r.start) == failAt {
+			return ScanSummary{}, errors.New("region unavailable")
+		}
+
+		pos, _ := slices.BinarySearchFunc(keys, r.start, bytes.Compare)
+		pace := time.Duration(5-int(r.start[2]-'0')) * time.Millisecond
+		return scanIterator(&fakeIterator{keys: keys, values: keys, pos: pos, pace: pace}, r.end, opts, func(k, v []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fn(k, v)
+		})
+	}
+}
+
+func TestScanRangesParallel(t *testing.T) {
+	keys := fakeKeys("k", 50) // k000 to k049
+	var ranges []keyRange
+	for i := range 5 {
+		ranges = append(ranges, keyRange{start: fmt.Appendf(nil, "k%03d", i*10), end: fmt.Appendf(nil, "k%03d", i*10+10)})
+	}
+
+	scan := func(opts ScanOptions, failAt string) ([]string, ScanSummary, error) {
+		t.Helper()
+		var got []string
+		summary, err := scanRangesParallel(context.Background(), ranges, opts, fakeRangeScanner(keys, failAt), func(k, v []byte) error {
+			if !bytes.Equal(k, v) {
+				t.Fatalf("value of %s = %s, want the pair kept together", k, v)
+			}
+			got = append(got, string(k))
+			return nil
+		})
+		return got, summary, err
+	}
+
+	// the pairs come in key order although the later ranges are done first
+	got, summary, err := scan(ScanOptions{Limit: 100, Parallel: 3}, "")
+	if err != nil {
+		t.Fatalf("scanRangesParallel() error = %v", err)
+	}
+	var want []string
+	for _, k := range keys {
+		want = append(want, string(k))
+	}
+	if !slices.Equal(got, want) || summary.Rows != 50 || summary.Scanned != 50 {
+		t.Errorf("scanRangesParallel() = %v, %+v, want all the keys in order", got, summary)
+	}
+
+	// the limit applies to the whole scan, and the range it stops in is counted as well
+	got, summary, err = scan(ScanOptions{Limit: 15, Parallel: 4}, "")
+	if err != nil || !slices.Equal(got, want[:15]) || summary.Rows != 15 {
+		t.Errorf("scanRangesParallel() = %v, %+v, %v, want the first 15 keys", got, summary, err)
+	}
+	if summary.Scanned < 15 || summary.Scanned > 20 || summary.Bytes != int64(4*summary.Scanned) {
+		t.Errorf("scanRangesParallel() summary = %+v, want 15 to 20 pairs of 4 bytes scanned", summary)
+	}
+
+	// a failed range fails the scan after the pairs before it
+	got, _, err = scan(ScanOptions{Limit: 100, Parallel: 2}, "k020")
+	if err == nil || !slices.Equal(got, want[:20]) {
+		t.Errorf("scanRangesParallel() = %v, %v, want the error after the first 20 keys", got, err)
+	}
+
+	// the filter is applied by each range
+	got, summary, err = scan(ScanOptions{Limit: 3, Parallel: 5, Filter: func(k, _ []byte) bool { return k[3] == '7' }}, "")
+	if err != nil || !slices.Equal(got, []string{"k007", "k017", "k027"}) || summary.Rows != 3 {
+		t.Errorf("scanRangesParallel() = %v, %+v, %v, want the first 3 matches", got, summary, err)
+	}
+	if summary.Scanned < 28 || summary.Scanned > 30 {
+		t.Errorf("scanRangesParallel() scanned %d pairs, want the 28 up to k027 and at most the rest of its range", summary.Scanned)
+	}
+}
+
+func TestScanRangesParallelTimeBudget(t *testing.T) {
+	keys := fakeKeys("k", 50)
+	ranges := []keyRange{{start: []byte("k000"), end: []byte("k010")}, {start: []byte("k010"), end: []byte("k020")}}
+
+	// the first range is paced at 5ms a key, so it runs out of the budget
+	var got []string
+	summary, err := scanRangesParallel(context.Background(), ranges, ScanOptions{Limit: 100, Parallel: 2, MaxDuration: 20 * time.Millisecond},
+		fakeRangeScanner(keys, ""), func(k, _ []byte) error {
+			got = append(got, string(k))
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("scanRangesParallel() error = %v", err)
+	}
+	if !summary.TimeBudgetReached || len(got) == 0 || len(got) >= 10 || got[len(got)-1] >= "k010" {
+		t.Errorf("scanRangesParallel() = %v, %+v, want a part of the first range only", got, summary)
+	}
+}
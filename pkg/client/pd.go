@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	pdhttp "github.com/tikv/pd/client/http"
+)
+
+const pdHTTPSource = "tikv-reader"
+
+// regionStatsGetter is the part of the PD HTTP API used to read region statistics.
+type regionStatsGetter interface {
+	GetRegionsByKeyRange(context.Context, *pdhttp.KeyRange, int) (*pdhttp.RegionsInfo, error)
+}
+
+// storeGetter is the part of the PD HTTP API used to read store metadata.
+type storeGetter interface {
+	GetStore(context.Context, uint64) (*pdhttp.StoreInfo, error)
+	GetStores(context.Context) (*pdhttp.StoresInfo, error)
+}
+
+// ErrStoreNotFound is returned when PD doesn't know the store ID.
+var ErrStoreNotFound = errors.New("store not found")
+
+// pdHTTPClient returns the PD HTTP client, creating it on first use.
+func (c *TiKVClient) pdHTTPClient() (pdhttp.Client, error) {
+	if c.pdHTTP != nil {
+		return c.pdHTTP, nil
+	}
+
+	var opts []pdhttp.ClientOption
+	if c.tlsConfig != nil {
+		opts = append(opts, pdhttp.WithTLSConfig(c.tlsConfig))
+	}
+
+	cli := pdhttp.NewClient(pdHTTPSource, c.pdAddrs, opts...)
+	if cli == nil { // NewClient returns nil when it fails to discover PD members
+		return nil, fmt.Errorf("failed to create PD HTTP client for %v", c.pdAddrs)
+	}
+	c.pdHTTP = cli
+
+	return cli, nil
+}
+
+// RegionKeyEstimate is the sum of the approximate key counts of regions overlapping a key range.
+type RegionKeyEstimate struct {
+	Regions         int   `json:"regions"`
+	ApproximateKeys int64 `json:"approximate_keys"`
+	ApproximateSize int64 `json:"approximate_size_mb"`
+}
+
+// EstimateKeys sums the approximate key counts PD tracks for the regions overlapping [start, end).
+// Regions on the boundaries are counted as a whole, so the result is an approximation which can
+// include keys outside the range.
+func (c *TiKVClient) EstimateKeys(ctx context.Context, start, end []byte) (RegionKeyEstimate, error) {
+	pd, err := c.pdHTTPClient()
+	if err != nil {
+		return RegionKeyEstimate{}, err
+	}
+
+	if c.keyspaceName != "" && len(end) == 0 { // the end of the keyspace, not of the whole key space
+		end = codec.PrefixEnd(codec.KeyspacePrefix(c.keyspaceID))
+	} else {
+		end = c.keyspaceKey(end)
+	}
+
+	return estimateKeys(ctx, pd, c.keyspaceKey(start), end)
+}
+
+func estimateKeys(ctx context.Context, pd regionStatsGetter, start, end []byte) (RegionKeyEstimate, error) {
+	// PD keeps region boundaries in the memcomparable format used by the TiKV data layer.
+	keyRange := pdhttp.NewKeyRange(encodeRegionKey(start), encodeRegionKey(end))
+
+	regions, err := pd.GetRegionsByKeyRange(ctx, keyRange, -1)
+	if err != nil {
+		return RegionKeyEstimate{}, fmt.Errorf("failed to get regions from PD: %w", err)
+	}
+
+	var est RegionKeyEstimate
+	for _, r := range regions.Regions {
+		est.Regions++
+		est.ApproximateKeys += r.ApproximateKeys
+		est.ApproximateSize += r.ApproximateSize
+	}
+
+	return est, nil
+}
+
+// encodeRegionKey converts a transactional key into the form PD uses for region boundaries.
+// An empty key means the start or the end of the whole key space and is kept as is.
+func encodeRegionKey(key []byte) []byte {
+	if len(key) == 0 {
+		return key
+	}
+
+	return tidbcodec.EncodeBytes(nil, key)
+}
+
+// StoreInfo is the metadata and the heartbeat status PD keeps for a store.
+type StoreInfo = pdhttp.StoreInfo
+
+// GetStore reads the metadata of the store from PD.
+func (c *TiKVClient) GetStore(ctx context.Context, storeID uint64) (*StoreInfo, error) {
+	pd, err := c.pdHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return getStore(ctx, pd, storeID)
+}
+
+func getStore(ctx context.Context, pd storeGetter, storeID uint64) (*StoreInfo, error) {
+	store, err := pd.GetStore(ctx, storeID)
+	if err == nil {
+		return store, nil
+	}
+
+	// PD answers an unknown store with a generic HTTP error, so tell it apart with the store list
+	stores, listErr := pd.GetStores(ctx)
+	if listErr != nil {
+		return nil, fmt.Errorf("failed to get store %d from PD: %w", storeID, err)
+	}
+
+	ids := make([]int64, 0, len(stores.Stores))
+	for _, s := range stores.Stores {
+		if s.Store.ID == int64(storeID) {
+			return nil, fmt.Errorf("failed to get store %d from PD: %w", storeID, err)
+		}
+		ids = append(ids, s.Store.ID)
+	}
+
+	return nil, fmt.Errorf("%w: store %d (known stores: %v)", ErrStoreNotFound, storeID, ids)
+}
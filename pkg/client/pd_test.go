@@ -0,0 +1,106 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+	pdhttp "github.com/tikv/pd/client/http"
+)
+
+type fakeRegionStats struct {
+	regions []pdhttp.RegionInfo
+	err     error
+	got     *pdhttp.KeyRange
+}
+
+func (f *fakeRegionStats) GetRegionsByKeyRange(_ context.Context, keyRange *pdhttp.KeyRange, _ int) (*pdhttp.RegionsInfo, error) {
+	f.got = keyRange
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &pdhttp.RegionsInfo{Count: int64(len(f.regions)), Regions: f.regions}, nil
+}
+
+func TestEstimateKeys(t *testing.T) {
+	fake := &fakeRegionStats{
+		regions: []pdhttp.RegionInfo{
+			{ID: 2, ApproximateKeys: 1000, ApproximateSize: 10},
+			{ID: 3, ApproximateKeys: 2500, ApproximateSize: 24},
+			{ID: 7, ApproximateKeys: 0, ApproximateSize: 1},
+		},
+	}
+
+	start := []byte("t\x80_r")
+	end := []byte("t\x80_s")
+	est, err := estimateKeys(context.Background(), fake, start, end)
+	if err != nil {
+		t.Fatalf("estimateKeys() error = %v", err)
+	}
+
+	expected := RegionKeyEstimate{Regions: 3, ApproximateKeys: 3500, ApproximateSize: 35}
+	if est != expected {
+		t.Errorf("estimateKeys() = %+v, want %+v", est, expected)
+	}
+
+	// the range must be sent in the memcomparable form used for region boundaries
+	if !bytes.Equal(fake.got.StartKey, tidbcodec.EncodeBytes(nil, start)) ||
+		!bytes.Equal(fake.got.EndKey, tidbcodec.EncodeBytes(nil, end)) {
+		t.Errorf("unexpected key range sent to PD: %X - %X", fake.got.StartKey, fake.got.EndKey)
+	}
+}
+
+func TestEstimateKeysError(t *testing.T) {
+	fake := &fakeRegionStats{err: fmt.Errorf("PD is unavailable")}
+	if _, err := estimateKeys(context.Background(), fake, []byte("a"), []byte("b")); err == nil {
+		t.Error("estimateKeys() should return the PD error")
+	}
+}
+
+func TestEncodeRegionKey(t *testing.T) {
+	if got := encodeRegionKey(nil); len(got) != 0 {
+		t.Errorf("encodeRegionKey(nil) = %X, want empty", got)
+	}
+}
+
+type fakeStores struct {
+	stores []pdhttp.StoreInfo
+}
+
+func (f *fakeStores) GetStore(_ context.Context, storeID uint64) (*pdhttp.StoreInfo, error) {
+	for _, s := range f.stores {
+		if s.Store.ID == int64(storeID) {
+			return &s, nil
+		}
+	}
+
+	return nil, fmt.Errorf("request pd http api failed with status: '500 Internal Server Error'")
+}
+
+func (f *fakeStores) GetStores(context.Context) (*pdhttp.StoresInfo, error) {
+	return &pdhttp.StoresInfo{Count: len(f.stores), Stores: f.stores}, nil
+}
+
+func TestGetStore(t *testing.T) {
+	fake := &fakeStores{stores: []pdhttp.StoreInfo{
+		{Store: pdhttp.MetaStore{ID: 1, Address: "tikv-0:20160", StateName: "Up"}},
+		{Store: pdhttp.MetaStore{ID: 4, Address: "tikv-1:20160", StateName: "Offline"}},
+	}}
+
+	store, err := getStore(context.Background(), fake, 4)
+	if err != nil {
+		t.Fatalf("getStore() error = %v", err)
+	}
+	if store.Store.Address != "tikv-1:20160" || store.Store.StateName != "Offline" {
+		t.Errorf("getStore() = %+v", store.Store)
+	}
+
+	_, err = getStore(context.Background(), fake, 99)
+	if !errors.Is(err, ErrStoreNotFound) {
+		t.Errorf("getStore(99) error = %v, want ErrStoreNotFound", err)
+	}
+}
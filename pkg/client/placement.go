@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	pdhttp "github.com/tikv/pd/client/http"
+)
+
+// defaultPlacementGroup is the rule group of the PD default rules, used when a table has no rules.
+const defaultPlacementGroup = "pd"
+
+// placementGetter is the part of the PD HTTP API used to read placement rules.
+type placementGetter interface {
+	GetPlacementRuleBundleByGroup(context.Context, string) (*pdhttp.GroupBundle, error)
+}
+
+// PlacementRule is a placement rule with its key range decoded.
+type PlacementRule struct {
+	ID             string   `json:"id"`
+	Role           string   `json:"role"`
+	Count          int      `json:"count"`
+	Constraints    []string `json:"constraints,omitempty"` // e.g., region in [us-east-1]
+	LocationLabels []string `json:"location_labels,omitempty"`
+	IsolationLevel string   `json:"isolation_level,omitempty"`
+	StartKey       string   `json:"start_key"` // decoded, empty for the start of the key space
+	EndKey         string   `json:"end_key"`   // decoded, empty for the end of the key space
+}
+
+// TablePlacement is the placement policy of a table: the rules TiDB put into PD for it, or the
+// PD default rules when it has none.
+type TablePlacement struct {
+	TableID int64           `json:"table_id"`
+	GroupID string          `json:"group_id"`
+	Default bool            `json:"default"` // the table has no rules of its own
+	Leader  string          `json:"leader"`  // where the leaders are placed
+	Rules   []PlacementRule `json:"rules"`
+}
+
+// placementGroupID is the rule group TiDB uses for the placement of a table or a partition.
+func placementGroupID(tableID int64) string {
+	return fmt.Sprintf("TiDB_DDL_%d", tableID)
+}
+
+// GetTablePlacement reads the placement rules of the table (or partition) from PD.
+func (c *TiKVClient) GetTablePlacement(ctx context.Context, tableID int64) (*TablePlacement, error) {
+	pd, err := c.pdHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return getTablePlacement(ctx, pd, tableID)
+}
+
+func getTablePlacement(ctx context.Context, pd placementGetter, tableID int64) (*TablePlacement, error) {
+	p := &TablePlacement{TableID: tableID, GroupID: placementGroupID(tableID)}
+
+	bundle, err := pd.GetPlacementRuleBundleByGroup(ctx, p.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get placement rules of table %d from PD: %w", tableID, err)
+	}
+
+	// PD answers a group without rules with an empty bundle
+	if len(bundle.Rules) == 0 {
+		p.Default = true
+		p.GroupID = defaultPlacementGroup
+		if bundle, err = pd.GetPlacementRuleBundleByGroup(ctx, defaultPlacementGroup); err != nil {
+			return nil, fmt.Errorf("failed to get the default placement rules from PD: %w", err)
+		}
+	}
+
+	for _, r := range bundle.Rules {
+		p.Rules = append(p.Rules, summarizeRule(r))
+	}
+	p.Leader = leaderPlacement(p.Rules)
+
+	return p, nil
+}
+
+func summarizeRule(r *pdhttp.Rule) PlacementRule {
+	rule := PlacementRule{
+		ID:             r.ID,
+		Role:           string(r.Role),
+		Count:          r.Count,
+		LocationLabels: r.LocationLabels,
+		IsolationLevel: r.IsolationLevel,
+		StartKey:       decodeRuleKey(r.StartKeyHex),
+		EndKey:         decodeRuleKey(r.EndKeyHex),
+	}
+	for _, c := range r.LabelConstraints {
+		rule.Constraints = append(rule.Constraints, fmt.Sprintf("%s %s [%s]", c.Key, c.Op, strings.Join(c.Values, ", ")))
+	}
+
+	return rule
+}
+
+// decodeRuleKey decodes a rule boundary, which is hex of the memcomparable form of region keys.
+func decodeRuleKey(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return s
+	}
+	_, key, err := tidbcodec.DecodeBytes(b, nil)
+	if err != nil {
+		return s
+	}
+
+	return codec.DecodeKey(key)
+}
+
+// leaderPlacement tells where the leaders go: a leader rule places them explicitly, otherwise
+// any voter can be the leader.
+func leaderPlacement(rules []PlacementRule) string {
+	var voters []string
+	for _, r := range rules {
+		switch r.Role {
+		case string(pdhttp.Leader):
+			return constraintsString(r.Constraints)
+		case string(pdhttp.Voter):
+			voters = append(voters, constraintsString(r.Constraints))
+		}
+	}
+
+	if len(voters) == 0 {
+		return "no voter rules"
+	}
+
+	return "any voter: " + strings.Join(voters, "; ")
+}
+
+func constraintsString(constraints []string) string {
+	if len(constraints) == 0 {
+		return "any store"
+	}
+
+	return strings.Join(constraints, ", ")
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"testing"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	pdhttp "github.com/tikv/pd/client/http"
+)
+
+type fakePlacement struct {
+	bundles map[string]*pdhttp.GroupBundle
+	err     error
+}
+
+func (f *fakePlacement) GetPlacementRuleBundleByGroup(_ context.Context, group string) (*pdhttp.GroupBundle, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if b, ok := f.bundles[group]; ok {
+		return b, nil
+	}
+
+	return &pdhttp.GroupBundle{ID: group}, nil
+}
+
+func ruleKeyHex(t *testing.T, key string) string {
+	t.Helper()
+	raw, err := codec.ParsePrefix(key)
+	if err != nil {
+		t.Fatalf("ParsePrefix(%s) error = %v", key, err)
+	}
+
+	return hex.EncodeToString(tidbcodec.EncodeBytes(nil, raw))
+}
+
+func TestGetTablePlacement(t *testing.T) {
+	fake := &fakePlacement{bundles: map[string]*pdhttp.GroupBundle{
+		"TiDB_DDL_132": {ID: "TiDB_DDL_132", Index: 40, Rules: []*pdhttp.Rule{
+			{
+				GroupID: "TiDB_DDL_132", ID: "table_rule_132_0", Role: pdhttp.Leader, Count: 1,
+				StartKeyHex: ruleKeyHex(t, "t132"), EndKeyHex: ruleKeyHex(t, "t133"),
+				LabelConstraints: []pdhttp.LabelConstraint{{Key: "region", Op: pdhttp.In, Values: []string{"us-east-1"}}},
+			},
+			{
+				GroupID: "TiDB_DDL_132", ID: "table_rule_132_1", Role: pdhttp.Follower, Count: 2,
+				StartKeyHex: ruleKeyHex(t, "t132"), EndKeyHex: ruleKeyHex(t, "t133"),
+				LabelConstraints: []pdhttp.LabelConstraint{{Key: "region", Op: pdhttp.In, Values: []string{"us-west-1", "us-west-2"}}},
+				LocationLabels:   []string{"region", "zone"},
+			},
+		}},
+		"pd": {ID: "pd", Rules: []*pdhttp.Rule{
+			{GroupID: "pd", ID: "default", Role: pdhttp.Voter, Count: 3, LocationLabels: []string{"zone", "host"}},
+		}},
+	}}
+
+	p, err := getTablePlacement(context.Background(), fake, 132)
+	if err != nil {
+		t.Fatalf("getTablePlacement() error = %v", err)
+	}
+	if p.Default || p.GroupID != "TiDB_DDL_132" || len(p.Rules) != 2 {
+		t.Fatalf("getTablePlacement() = %+v, want the 2 rules of TiDB_DDL_132", p)
+	}
+	if p.Leader != "region in [us-east-1]" {
+		t.Errorf("Leader = %s, want region in [us-east-1]", p.Leader)
+	}
+	follower := p.Rules[1]
+	if follower.Role != "follower" || follower.Count != 2 ||
+		!slices.Equal(follower.Constraints, []string{"region in [us-west-1, us-west-2]"}) {
+		t.Errorf("Rules[1] = %+v", follower)
+	}
+	if follower.StartKey != "t132" || follower.EndKey != "t133" {
+		t.Errorf("Rules[1] range = %s - %s, want t132 - t133", follower.StartKey, follower.EndKey)
+	}
+
+	// a table without rules of its own follows the PD default rules
+	p, err = getTablePlacement(context.Background(), fake, 200)
+	if err != nil {
+		t.Fatalf("getTablePlacement() error = %v", err)
+	}
+	if !p.Default || p.GroupID != "pd" || len(p.Rules) != 1 || p.Rules[0].StartKey != "" {
+		t.Errorf("getTablePlacement() = %+v, want the default rule", p)
+	}
+	if p.Leader != "any voter: any store" {
+		t.Errorf("Leader = %s, want any voter: any store", p.Leader)
+	}
+
+	fake.err = errors.New("PD is unavailable")
+	if _, err := getTablePlacement(context.Background(), fake, 132); !errors.Is(err, fake.err) {
+		t.Errorf("getTablePlacement() error = %v, want the PD error", err)
+	}
+}
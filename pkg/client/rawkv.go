@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+// ColumnFamilies are the column families of TiKV in the order they are read.
+var ColumnFamilies = []string{"default", "lock", "write"}
+
+// cfGetter reads a key from a column family through the RawKV API.
+// A missing key is returned as nil without an error.
+type cfGetter interface {
+	getCF(ctx context.Context, key []byte, cf string) ([]byte, error)
+}
+
+// CFValue is what a column family holds for a key.
+type CFValue struct {
+	CF    string
+	Value []byte
+	Found bool
+	Err   error
+}
+
+// RawKVClient reads keys with the RawKV API, which addresses the column families directly.
+type RawKVClient struct {
+	client *rawkv.Client
+}
+
+// NewRawKVClient connects to the cluster, with mutual TLS when the files are given.
+func NewRawKVClient(ctx context.Context, pdAddrs []string, tlsFiles TLSFiles) (*RawKVClient, error) {
+	sec, _, err := tlsFiles.security()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := rawkv.NewClient(ctx, pdAddrs, sec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RawKVClient{client: client}, nil
+}
+
+func (c *RawKVClient) Close() error {
+	if c.client == nil {
+		return nil
+	}
+
+	return c.client.Close()
+}
+
+func (c *RawKVClient) getCF(ctx context.Context, key []byte, cf string) ([]byte, error) {
+	return c.client.Get(ctx, key, rawkv.SetColumnFamily(cf))
+}
+
+// GetAllCFs reads the key from each column family. A failure of one column family is kept in
+// its CFValue, so the others are still shown.
+func (c *RawKVClient) GetAllCFs(ctx context.Context, key []byte) []CFValue {
+	return getAllCFs(ctx, c, key)
+}
+
+func getAllCFs(ctx context.Context, raw cfGetter, key []byte) []CFValue {
+	values := make([]CFValue, 0, len(ColumnFamilies))
+	for _, cf := range ColumnFamilies {
+		v, err := raw.getCF(ctx, key, cf)
+		if err != nil {
+			values = append(values, CFValue{CF: cf, Err: fmt.Errorf("failed to get key from %s CF: %w", cf, err)})
+			continue
+		}
+		values = append(values, CFValue{CF: cf, Value: v, Found: v != nil})
+	}
+
+	return values
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+// fakeCFs holds the value of each column family, or the error to return.
+type fakeCFs map[string]any
+
+func (f fakeCFs) getCF(_ context.Context, _ []byte, cf string) ([]byte, error) {
+	switch v := f[cf].(type) {
+	case []byte:
+		return v, nil
+	case error:
+		return nil, v
+	default:
+		return nil, nil
+	}
+}
+
+func TestGetAllCFs(t *testing.T) {
+	fake := fakeCFs{
+		"default": []byte("row data"),
+		"write":   fmt.Errorf("region unavailable"),
+	}
+
+	values := getAllCFs(context.Background(), fake, []byte("t1_r1"))
+	if len(values) != len(ColumnFamilies) {
+		t.Fatalf("getAllCFs() returned %d CFs, want %d", len(values), len(ColumnFamilies))
+	}
+
+	byCF := make(map[string]CFValue)
+	for _, v := range values {
+		byCF[v.CF] = v
+	}
+
+	if v := byCF["default"]; !v.Found || !bytes.Equal(v.Value, []byte("row data")) || v.Err != nil {
+		t.Errorf("default CF = %+v, want the row data", v)
+	}
+	if v := byCF["lock"]; v.Found || v.Err != nil {
+		t.Errorf("lock CF = %+v, want absent without an error", v)
+	}
+	if v := byCF["write"]; v.Found || v.Err == nil {
+		t.Errorf("write CF = %+v, want the error", v)
+	}
+}
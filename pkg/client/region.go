@@ -0,0 +1,137 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	pdclient "github.com/tikv/pd/client"
+)
+
+// regionScanBatch is the number of regions asked from PD at once while listing a range.
+const regionScanBatch = 128
+
+// regionGetter is the part of the PD client used to locate regions. The client of client-go
+// encodes the keys into the region key space (and the keyspace) and decodes the region bounds.
+type regionGetter interface {
+	GetRegion(ctx context.Context, key []byte, opts ...pdclient.GetRegionOption) (*pdclient.Region, error)
+	BatchScanRegions(ctx context.Context, keyRanges []pdclient.KeyRange, limit int, opts ...pdclient.GetRegionOption) ([]*pdclient.Region, error)
+}
+
+// Region is a TiKV region and the layout of its peers as PD reports it.
+type Region struct {
+	ID       uint64
+	StartKey []byte // empty for the start of the key space
+	EndKey   []byte // empty for the end of the key space
+	ConfVer  uint64
+	Version  uint64
+	Leader   uint64 // the store ID of the leader, 0 while PD doesn't know it
+	Peers    []RegionPeer
+}
+
+// RegionPeer is a replica of a region on a store.
+type RegionPeer struct {
+	ID      uint64
+	StoreID uint64
+	Role    string // voter, learner, incomingvoter or demotingvoter
+	Down    bool
+	Pending bool
+}
+
+// LocateRegion returns the region which holds the key.
+func (c *TiKVClient) LocateRegion(ctx context.Context, key []byte) (Region, error) {
+	if c.client == nil {
+		return Region{}, fmt.Errorf("TiKV client is not initialized")
+	}
+
+	return locateRegion(ctx, c.client.GetPDClient(), key)
+}
+
+func locateRegion(ctx context.Context, pd regionGetter, key []byte) (Region, error) {
+	r, err := pd.GetRegion(ctx, key)
+	if err != nil {
+		return Region{}, fmt.Errorf("failed to get the region of key %X from PD: %w", key, err)
+	}
+	if r == nil || r.Meta == nil { // PD may not know the region for a while, e.g., right after a split
+		return Region{}, fmt.Errorf("PD has no region for key %X yet, try again later", key)
+	}
+
+	return newRegion(r), nil
+}
+
+// ScanRegions returns the regions overlapping [start, end), up to limit regions. An empty end
+// scans to the end of the key space.
+func (c *TiKVClient) ScanRegions(ctx context.Context, start, end []byte, limit int) ([]Region, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("TiKV client is not initialized")
+	}
+
+	return scanRegions(ctx, c.client.GetPDClient(), start, end, limit)
+}
+
+func scanRegions(ctx context.Context, pd regionGetter, start, end []byte, limit int) ([]Region, error) {
+	var regions []Region
+	for len(regions) < limit {
+		batch, err := pd.BatchScanRegions(ctx, []pdclient.KeyRange{{StartKey: start, EndKey: end}}, min(limit-len(regions), regionScanBatch))
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan the regions of %X - %X from PD: %w", start, end, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, r := range batch {
+			if r == nil || r.Meta == nil {
+				continue
+			}
+			regions = append(regions, newRegion(r))
+		}
+
+		// go on from the end of the last region until it passes the end of the range
+		last := batch[len(batch)-1]
+		if last == nil || last.Meta == nil || len(last.Meta.EndKey) == 0 ||
+			(len(end) > 0 && bytes.Compare(last.Meta.EndKey, end) >= 0) {
+			break
+		}
+		start = last.Meta.EndKey
+	}
+
+	return regions, nil
+}
+
+func newRegion(r *pdclient.Region) Region {
+	region := Region{
+		ID:       r.Meta.GetId(),
+		StartKey: r.Meta.GetStartKey(),
+		EndKey:   r.Meta.GetEndKey(),
+		ConfVer:  r.Meta.GetRegionEpoch().GetConfVer(),
+		Version:  r.Meta.GetRegionEpoch().GetVersion(),
+		Leader:   r.Leader.GetStoreId(),
+	}
+
+	down := make(map[uint64]bool, len(r.DownPeers))
+	for _, p := range r.DownPeers {
+		down[p.GetId()] = true
+	}
+	pending := make(map[uint64]bool, len(r.PendingPeers))
+	for _, p := range r.PendingPeers {
+		pending[p.GetId()] = true
+	}
+	for _, p := range r.Meta.GetPeers() {
+		region.Peers = append(region.Peers, RegionPeer{
+			ID:      p.GetId(),
+			StoreID: p.GetStoreId(),
+			Role:    peerRoleName(p.GetRole()),
+			Down:    down[p.GetId()],
+			Pending: pending[p.GetId()],
+		})
+	}
+
+	return region
+}
+
+func peerRoleName(role metapb.PeerRole) string {
+	return strings.ToLower(role.String())
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	pdclient "github.com/tikv/pd/client"
+)
+
+// fakeRegions splits the key space at the keys. It records the limits of the scans.
+type fakeRegions struct {
+	splits [][]byte
+	limits []int
+}
+
+func (f *fakeRegions) region(i int) *pdclient.Region {
+	meta := &metapb.Region{
+		Id:          uint64(i + 1),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 5, Version: uint64(i + 1)},
+		Peers: []*metapb.Peer{
+			{Id: 10*uint64(i) + 1, StoreId: 1},
+			{Id: 10*uint64(i) + 2, StoreId: 2},
+			{Id: 10*uint64(i) + 3, StoreId: 3, Role: metapb.PeerRole_Learner},
+		},
+	}
+	if i > 0 {
+		meta.StartKey = f.splits[i-1]
+	}
+	if i < len(f.splits) {
+		meta.EndKey = f.splits[i]
+	}
+
+	return &pdclient.Region{Meta: meta, Leader: meta.Peers[1], DownPeers: []*metapb.Peer{meta.Peers[0]}}
+}
+
+// find returns the index of the region holding the key.
+func (f *fakeRegions) find(key []byte) int {
+	i := 0
+	for i < len(f.splits) && bytes.Compare(key, f.splits[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+func (f *fakeRegions) GetRegion(_ context.Context, key []byte, _ ...pdclient.GetRegionOption) (*pdclient.Region, error) {
+	return f.region(f.find(key)), nil
+}
+
+func (f *fakeRegions) BatchScanRegions(_ context.Context, ranges []pdclient.KeyRange, limit int, _ ...pdclient.GetRegionOption) ([]*pdclient.Region, error) {
+	f.limits = append(f.limits, limit)
+
+	var regions []*pdclient.Region
+	for i := f.find(ranges[0].StartKey); i <= len(f.splits) && len(regions) < limit; i++ {
+		r := f.region(i)
+		if len(ranges[0].EndKey) > 0 && bytes.Compare(r.Meta.StartKey, ranges[0].EndKey) >= 0 {
+			break
+		}
+		regions = append(regions, r)
+	}
+	return regions, nil
+}
+
+func TestLocateRegion(t *testing.T) {
+	fake := &fakeRegions{splits: [][]byte{[]byte("b"), []byte("d")}}
+
+	got, err := locateRegion(context.Background(), fake, []byte("c"))
+	if err != nil {
+		t.Fatalf("locateRegion() error = %v", err)
+	}
+	if got.ID != 2 || string(got.StartKey) != "b" || string(got.EndKey) != "d" || got.Version != 2 || got.Leader != 2 {
+		t.Errorf("locateRegion() = %+v, want region 2 of [b, d) led by store 2", got)
+	}
+
+	want := []RegionPeer{
+		{ID: 11, StoreID: 1, Role: "voter", Down: true},
+		{ID: 12, StoreID: 2, Role: "voter"},
+		{ID: 13, StoreID: 3, Role: "learner"},
+	}
+	if fmt.Sprint(got.Peers) != fmt.Sprint(want) {
+		t.Errorf("locateRegion() peers = %+v, want %+v", got.Peers, want)
+	}
+}
+
+func TestScanRegions(t *testing.T) {
+	var splits [][]byte
+	for i := range 300 {
+		splits = append(splits, fmt.Appendf(nil, "k%03d", i))
+	}
+
+	tests := []struct {
+		name       string
+		start, end string
+		limit      int
+		regions    int
+		limits     []int
+	}{
+		{"one region", "k010", "k010\x00", 100, 1, []int{100}},
+		{"pages of a long range", "k000", "k200", 1000, 200, []int{128, 128}},
+		{"up to the limit", "k000", "", 130, 130, []int{128, 2}},
+		{"to the end of the key space", "k290", "", 1000, 10, []int{128}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeRegions{splits: splits}
+			got, err := scanRegions(context.Background(), fake, []byte(tt.start), []byte(tt.end), tt.limit)
+			if err != nil {
+				t.Fatalf("scanRegions() error = %v", err)
+			}
+			if len(got) != tt.regions {
+				t.Errorf("scanRegions() returned %d regions, want %d", len(got), tt.regions)
+			}
+			if fmt.Sprint(fake.limits) != fmt.Sprint(tt.limits) {
+				t.Errorf("scanRegions() scanned with limits %v, want %v", fake.limits, tt.limits)
+			}
+			for i := 1; i < len(got); i++ {
+				if !bytes.Equal(got[i].StartKey, got[i-1].EndKey) {
+					t.Fatalf("region %d starts at %q after the end %q, want adjacent regions", got[i].ID, got[i].StartKey, got[i-1].EndKey)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	tikverr "github.com/tikv/client-go/v2/error"
+)
+
+const (
+	DefaultMaxRetries   = 3
+	DefaultRetryBackoff = 500 * time.Millisecond
+)
+
+// RetryPolicy is how often a read is retried on a transient TiKV error. The backoff doubles
+// with each retry.
+type RetryPolicy struct {
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+// WithRetry sets the retry policy of Get and the scans. Without it, the defaults are used.
+func WithRetry(p RetryPolicy) Option {
+	return func(c *TiKVClient) {
+		c.retry = p
+	}
+}
+
+// retryableErrors are the errors of regions or stores which go away after a while, e.g., while
+// a region is split or its leader moves. client-go already retries region errors such as
+// not-leader internally and reports them as one of these once its own backoff runs out.
+var retryableErrors = []error{
+	tikverr.ErrRegionUnavailable,
+	tikverr.ErrRegionNotInitialized,
+	tikverr.ErrRegionDataNotReady,
+	tikverr.ErrTiKVServerBusy,
+	tikverr.ErrTiKVServerTimeout,
+	tikverr.ErrTiKVStaleCommand,
+	tikverr.ErrTiKVMaxTimestampNotSynced,
+	tikverr.ErrIsWitness,
+}
+
+// isRetryable reports whether the read may succeed when retried. Errors such as a missing key
+// or a canceled context are final.
+func isRetryable(err error) bool {
+	for _, e := range retryableErrors {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+
+	var pdTimeout *tikverr.ErrPDServerTimeout
+	return errors.As(err, &pdTimeout)
+}
+
+// retry calls fn again while the previous attempt failed with a retryable error, up to
+// MaxRetries times. err is the error of the first attempt.
+func (p RetryPolicy) retry(ctx context.Context, err error, fn func() error) error {
+	for attempt := 0; err != nil && isRetryable(err) && attempt < p.MaxRetries; attempt++ {
+		wait := p.Backoff << attempt
+		slog.Warn("transient TiKV error, retrying",
+			slog.Int("attempt", attempt+1), slog.Duration("backoff", wait), slog.String("error", err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		err = fn()
+	}
+
+	return err
+}
+
+// retryIterator reopens the iterator right after the last key it returned when moving forward
+// fails with a retryable error, so a scan goes on without returning a key twice.
+type retryIterator struct {
+	ctx     context.Context
+	policy  RetryPolicy
+	open    func(start []byte) (iterator, error)
+	iter    iterator
+	key     []byte // the current key, kept since the iterator buffer is gone after an error
+	reverse bool   // open takes the exclusive upper bound of a reverse iterator
+}
+
+func newRetryIterator(ctx context.Context, policy RetryPolicy, start []byte, open func(start []byte) (iterator, error)) (*retryIterator, error) {
+	r := &retryIterator{ctx: ctx, policy: policy, open: open}
+	if err := policy.retry(ctx, r.openAt(start), func() error { return r.openAt(start) }); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// newReverseRetryIterator is newRetryIterator for a reverse iterator, which open creates
+// below the exclusive upper bound. It reopens the iterator below the last key it returned.
+func newReverseRetryIterator(ctx context.Context, policy RetryPolicy, upper []byte, open func(upper []byte) (iterator, error)) (*retryIterator, error) {
+	r, err := newRetryIterator(ctx, policy, upper, open)
+	if err != nil {
+		return nil, err
+	}
+	r.reverse = true
+
+	return r, nil
+}
+
+func (r *retryIterator) openAt(start []byte) error {
+	if r.iter != nil {
+		r.iter.Close()
+	}
+
+	var err error
+	if r.iter, err = r.open(start); err != nil {
+		r.iter = nil
+	}
+	return err
+}
+
+func (r *retryIterator) Valid() bool   { return r.iter != nil && r.iter.Valid() }
+func (r *retryIterator) Key() []byte   { return r.iter.Key() }
+func (r *retryIterator) Value() []byte { return r.iter.Value() }
+
+func (r *retryIterator) Close() {
+	if r.iter != nil {
+		r.iter.Close()
+	}
+}
+
+func (r *retryIterator) Next() error {
+	r.key = append(r.key[:0], r.iter.Key()...)
+	err := r.iter.Next()
+	if err == nil || !isRetryable(err) {
+		return err
+	}
+
+	// go on from the smallest key greater than the current one, or below it in reverse
+	next := append(slices.Clone(r.key), 0)
+	if r.reverse {
+		next = slices.Clone(r.key)
+	}
+	return r.policy.retry(r.ctx, err, func() error { return r.openAt(next) })
+}
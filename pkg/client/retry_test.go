@@ -0,0 +1,159 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	tikverr "github.com/tikv/client-go/v2/error"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{fmt.Errorf("backoff exceeded: %w", tikverr.ErrRegionUnavailable), true},
+		{tikverr.ErrTiKVServerBusy, true},
+		{&tikverr.ErrPDServerTimeout{}, true},
+		{tikverr.ErrNotExist, false}, // key not found
+		{context.DeadlineExceeded, false},
+		{errors.New("unknown"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.expected {
+			t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.expected)
+		}
+	}
+}
+
+// flakyOpener opens fake iterators over the keys. The first one fails with err instead of
+// moving to the key at failAt. It records the start keys it was opened at. With reverse, the
+// iterators go down from below the key they are opened at.
+type flakyOpener struct {
+	keys    [][]byte
+	err     error
+	failAt  int
+	starts  []string
+	reverse bool
+}
+
+func (o *flakyOpener) open(start []byte) (iterator, error) {
+	o.starts = append(o.starts, string(start))
+	keys, pos := o.keys, 0
+	if i, _ := slices.BinarySearchFunc(o.keys, start, bytes.Compare); o.reverse {
+		keys = slices.Clone(o.keys[:i])
+		slices.Reverse(keys)
+	} else {
+		pos = i
+	}
+	if len(o.starts) == 1 {
+		return &failingIterator{fakeIterator: &fakeIterator{keys: keys[:o.failAt], pos: pos}, err: o.err}, nil
+	}
+	return &fakeIterator{keys: keys, pos: pos}, nil
+}
+
+// failingIterator fails when moving past its last key instead of becoming invalid.
+type failingIterator struct {
+	*fakeIterator
+	err error
+}
+
+func (f *failingIterator) Next() error {
+	if f.pos == len(f.keys)-1 {
+		return f.err
+	}
+	return f.fakeIterator.Next()
+}
+
+func TestRetryIterator(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}
+	scan := func(o *flakyOpener) ([]string, error) {
+		t.Helper()
+		iter, err := newRetryIterator(context.Background(), policy, []byte("a000"), o.open)
+		if err != nil {
+			t.Fatalf("newRetryIterator() error = %v", err)
+		}
+		defer iter.Close()
+
+		var got []string
+		_, err = scanIterator(iter, []byte("b"), ScanOptions{Limit: 10}, func(k, _ []byte) error {
+			got = append(got, string(k))
+			return nil
+		})
+		return got, err
+	}
+
+	// a region error in the middle resumes right after the last key
+	o := &flakyOpener{keys: fakeKeys("a", 5), err: tikverr.ErrRegionUnavailable, failAt: 3}
+	got, err := scan(o)
+	if err != nil {
+		t.Fatalf("scan error = %v, want the retry to succeed", err)
+	}
+	if !slices.Equal(got, []string{"a000", "a001", "a002", "a003", "a004"}) {
+		t.Errorf("scan keys = %v, want each key once", got)
+	}
+	if !slices.Equal(o.starts, []string{"a000", "a002\x00"}) {
+		t.Errorf("opened at %q, want a000 and right after a002", o.starts)
+	}
+
+	// other errors are returned at once
+	o = &flakyOpener{keys: fakeKeys("a", 5), err: errors.New("corrupted"), failAt: 3}
+	if _, err := scan(o); err == nil || len(o.starts) != 1 {
+		t.Errorf("scan error = %v after %d opens, want the error without a retry", err, len(o.starts))
+	}
+}
+
+func TestReverseRetryIterator(t *testing.T) {
+	// a region error in the middle resumes right below the last key
+	o := &flakyOpener{keys: fakeKeys("a", 5), err: tikverr.ErrRegionUnavailable, failAt: 3, reverse: true}
+	iter, err := newReverseRetryIterator(context.Background(), RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}, []byte("b"), o.open)
+	if err != nil {
+		t.Fatalf("newReverseRetryIterator() error = %v", err)
+	}
+	defer iter.Close()
+
+	var got []string
+	if _, err := scanIterator(iter, nil, ScanOptions{Limit: 10}, func(k, _ []byte) error {
+		got = append(got, string(k))
+		return nil
+	}); err != nil {
+		t.Fatalf("scan error = %v, want the retry to succeed", err)
+	}
+	if !slices.Equal(got, []string{"a004", "a003", "a002", "a001", "a000"}) {
+		t.Errorf("scan keys = %v, want each key once from the largest", got)
+	}
+	if !slices.Equal(o.starts, []string{"b", "a002"}) {
+		t.Errorf("opened at %q, want b and below a002", o.starts)
+	}
+}
+
+func TestRetryPolicyGivesUp(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}
+
+	calls := 0
+	err := policy.retry(context.Background(), tikverr.ErrTiKVServerBusy, func() error {
+		calls++
+		return tikverr.ErrTiKVServerBusy
+	})
+	if !errors.Is(err, tikverr.ErrTiKVServerBusy) || calls != 2 {
+		t.Errorf("retry() = %v after %d calls, want the busy error after 2 retries", err, calls)
+	}
+
+	// a retry which succeeds ends the loop
+	calls = 0
+	if err := policy.retry(context.Background(), tikverr.ErrTiKVServerBusy, func() error { calls++; return nil }); err != nil || calls != 1 {
+		t.Errorf("retry() = %v after %d calls, want success after 1 retry", err, calls)
+	}
+
+	// the key-not-found error isn't retried
+	calls = 0
+	if err := policy.retry(context.Background(), tikverr.ErrNotExist, func() error { calls++; return nil }); !errors.Is(err, tikverr.ErrNotExist) || calls != 0 {
+		t.Errorf("retry() = %v after %d calls, want the not-found error at once", err, calls)
+	}
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+// iterator is the part of the transaction iterator used by the scans.
+type iterator interface {
+	Valid() bool
+	Key() []byte
+	Value() []byte
+	Next() error
+	Close()
+}
+
+// ScanOptions controls how far ScanFunc goes.
+type ScanOptions struct {
+	Limit       int           // maximum number of key-value pairs
+	TS          uint64        // snapshot ts, 0 reads the latest data
+	MaxDuration time.Duration // wall-clock budget of the scan, 0 means no budget
+	KeysOnly    bool          // ask TiKV for the keys only, fn gets empty values
+	StartAfter  []byte        // scan only the keys after this one, nil scans the whole prefix
+	Parallel    int           // scan the regions of the range with this many workers, 0 or 1 scans serially
+
+	// Filter skips the pairs it returns false for: fn isn't called for them and they don't
+	// count toward Limit. Nil passes every pair.
+	Filter func(key, value []byte) bool
+}
+
+// ScanSummary describes how a scan finished.
+type ScanSummary struct {
+	Rows              int   // pairs passed to fn
+	Scanned           int   // pairs read, including the ones skipped by the filter
+	Bytes             int64 // value bytes of the pairs read
+	Elapsed           time.Duration
+	TimeBudgetReached bool // stopped by ScanOptions.MaxDuration, the result is partial
+}
+
+// ScanFunc calls fn for each key-value pair with the prefix in key order. Key and value are
+// the buffers of the iterator, passed without copying, and are only valid until the iterator
+// moves to the next pair after fn returns. fn must copy what it keeps; a keys-only or counting
+// caller saves copying the values that way.
+//
+// With StartAfter, the scan starts right after that key, which must be within the prefix.
+//
+// Unlike a context deadline, running out of MaxDuration isn't an error: the scan stops
+// gracefully and reports it in the summary, keeping the pairs processed so far.
+func (c *TiKVClient) ScanFunc(ctx context.Context, prefix []byte, opts ScanOptions, fn func(key, value []byte) error) (ScanSummary, error) {
+	return c.ScanRangeFunc(ctx, prefix, codec.PrefixEnd(prefix), opts, fn)
+}
+
+// ScanRange scans the keys from start (inclusive) to end (exclusive) like TiKV does. A nil end
+// scans to the end of the key space.
+func (c *TiKVClient) ScanRange(ctx context.Context, start, end []byte, limit int) ([]([]byte), []([]byte), error) {
+	var keys [][]byte
+	var values [][]byte
+	_, err := c.ScanRangeFunc(ctx, start, end, ScanOptions{Limit: limit}, func(k, v []byte) error {
+		keys = append(keys, slices.Clone(k))
+		values = append(values, slices.Clone(v))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keys, values, nil
+}
+
+// ScanRangeFunc is ScanFunc over the keys from start (inclusive) to end (exclusive). A nil end
+// scans to the end of the key space. With StartAfter, the scan starts right after that key,
+// which must be within the range.
+func (c *TiKVClient) ScanRangeFunc(ctx context.Context, start, end []byte, opts ScanOptions, fn func(key, value []byte) error) (ScanSummary, error) {
+	if c.client == nil {
+		return ScanSummary{}, fmt.Errorf("TiKV client is not initialized")
+	}
+
+	if err := ValidateRange(start, end); err != nil {
+		return ScanSummary{}, err
+	}
+
+	start, err := scanStart(start, end, opts.StartAfter)
+	if err != nil {
+		return ScanSummary{}, err
+	}
+	if opts.Parallel > 1 {
+		return c.scanParallel(ctx, start, end, opts, fn)
+	}
+
+	return c.scanRange(ctx, start, end, opts, fn)
+}
+
+// scanRange scans [start, end) in one iterator. The start is already moved past StartAfter.
+func (c *TiKVClient) scanRange(ctx context.Context, start, end []byte, opts ScanOptions, fn func(key, value []byte) error) (ScanSummary, error) {
+	tx, err := c.begin(opts.TS)
+	if err != nil {
+		return ScanSummary{}, fmt.Errorf("failed to begin the transaction with range %X - %X :%w", start, end, err)
+	}
+	defer tx.Rollback()
+	if opts.KeysOnly {
+		tx.GetSnapshot().SetKeyOnly(true)
+	}
+
+	// a retried iterator reads the same snapshot of the transaction
+	iter, err := newRetryIterator(ctx, c.retry, start, func(start []byte) (iterator, error) {
+		return tx.Iter(start, end)
+	})
+	if err != nil {
+		return ScanSummary{}, fmt.Errorf("failed to create iterator with range %X - %X :%w", start, end, err)
+	}
+	defer iter.Close()
+
+	// the iterator doesn't take the context, so stop between the pairs once it is done
+	return scanIterator(iter, end, opts, func(k, v []byte) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(k, v)
+	})
+}
+
+// ValidateRange checks that the end of the range is after its start. A nil end has no bound.
+func ValidateRange(start, end []byte) error {
+	if end != nil && bytes.Compare(end, start) <= 0 {
+		return fmt.Errorf("end key %X must be greater than start key %X", end, start)
+	}
+
+	return nil
+}
+
+// scanStart returns the first key to scan: the start itself, or the key right after startAfter.
+func scanStart(start, end, startAfter []byte) ([]byte, error) {
+	if startAfter == nil {
+		return start, nil
+	}
+	if bytes.Compare(startAfter, start) < 0 || !beforeEnd(startAfter, end) {
+		return nil, fmt.Errorf("start key %X is outside the range %X - %X", startAfter, start, end)
+	}
+
+	// the smallest key greater than startAfter
+	return append(slices.Clone(startAfter), 0), nil
+}
+
+// beforeEnd reports whether the key is before the exclusive end bound. A nil end has no bound.
+func beforeEnd(key, end []byte) bool {
+	return end == nil || bytes.Compare(key, end) < 0
+}
+
+func scanIterator(iter iterator, end []byte, opts ScanOptions, fn func(key, value []byte) error) (summary ScanSummary, err error) {
+	start := time.Now()
+	defer func() { summary.Elapsed = time.Since(start) }()
+
+	for summary.Rows < opts.Limit && iter.Valid() {
+		if opts.MaxDuration > 0 && time.Since(start) >= opts.MaxDuration {
+			summary.TimeBudgetReached = true
+			break
+		}
+
+		k := iter.Key()
+		if !beforeEnd(k, end) {
+			break
+		}
+
+		summary.Scanned++
+		v := iter.Value()
+		summary.Bytes += int64(len(v))
+		if opts.Filter == nil || opts.Filter(k, v) {
+			if err := fn(k, v); err != nil {
+				return summary, err
+			}
+			summary.Rows++
+		}
+
+		if err := iter.Next(); err != nil {
+			return summary, fmt.Errorf("iterator error at key %X :%w", k, err)
+		}
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,276 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeIterator iterates over fixed pairs and sleeps on each Next to pace the scan.
+// Like the TiKV iterator, it returns its own buffers without copying.
+type fakeIterator struct {
+	keys   [][]byte
+	values [][]byte // optional, nil values are empty
+	pos    int
+	pace   time.Duration
+	err    error // returned by Next instead of moving forward
+}
+
+func (f *fakeIterator) Valid() bool { return f.pos < len(f.keys) }
+func (f *fakeIterator) Key() []byte { return f.keys[f.pos] }
+func (f *fakeIterator) Close()      {}
+
+func (f *fakeIterator) Value() []byte {
+	if f.values == nil {
+		return nil
+	}
+	return f.values[f.pos]
+}
+
+func (f *fakeIterator) Next() error {
+	if f.err != nil {
+		return f.err
+	}
+	time.Sleep(f.pace)
+	f.pos++
+	return nil
+}
+
+func fakeKeys(prefix string, n int) [][]byte {
+	keys := make([][]byte, 0, n)
+	for i := range n {
+		keys = append(keys, fmt.Appendf(nil, "%s%03d", prefix, i))
+	}
+	return keys
+}
+
+func TestScanIteratorLimitAndPrefix(t *testing.T) {
+	iter := &fakeIterator{keys: append(fakeKeys("a", 3), fakeKeys("b", 3)...)}
+
+	var got []string
+	summary, err := scanIterator(iter, []byte("b"), ScanOptions{Limit: 10}, func(k, _ []byte) error {
+		got = append(got, string(k))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("scanIterator() error = %v", err)
+	}
+	if summary.Rows != 3 || len(got) != 3 || summary.TimeBudgetReached {
+		t.Errorf("scanIterator() = %+v, keys %v, want 3 rows with prefix a", summary, got)
+	}
+
+	iter = &fakeIterator{keys: fakeKeys("a", 5)}
+	summary, _ = scanIterator(iter, []byte("b"), ScanOptions{Limit: 2}, func(_, _ []byte) error { return nil })
+	if summary.Rows != 2 {
+		t.Errorf("scanIterator() rows = %d, want 2 (limit)", summary.Rows)
+	}
+}
+
+func TestScanIteratorRange(t *testing.T) {
+	keys := fakeKeys("a", 10)
+	start, end := []byte("a003"), []byte("a006")
+
+	// position the iterator like tx.Iter(start, end)
+	pos, _ := slices.BinarySearchFunc(keys, start, bytes.Compare)
+	var got []string
+	if _, err := scanIterator(&fakeIterator{keys: keys, pos: pos}, end, ScanOptions{Limit: 10}, func(k, _ []byte) error {
+		got = append(got, string(k))
+		return nil
+	}); err != nil {
+		t.Fatalf("scanIterator() error = %v", err)
+	}
+	if !slices.Equal(got, []string{"a003", "a004", "a005"}) {
+		t.Errorf("scanIterator() keys = %v, want a003-a005 (end is exclusive)", got)
+	}
+
+	// no end scans to the end of the key space
+	summary, _ := scanIterator(&fakeIterator{keys: keys, pos: pos}, nil, ScanOptions{Limit: 10}, func(_, _ []byte) error { return nil })
+	if summary.Rows != 7 {
+		t.Errorf("scanIterator() rows = %d, want 7 without an end", summary.Rows)
+	}
+}
+
+func TestScanIteratorFilter(t *testing.T) {
+	// keep the keys with an odd number, the limit counts the matches only
+	odd := func(k, _ []byte) bool { return (k[len(k)-1]-'0')%2 == 1 }
+
+	var got []string
+	keys := fakeKeys("a", 10)
+	summary, err := scanIterator(&fakeIterator{keys: keys, values: keys}, []byte("b"), ScanOptions{Limit: 3, Filter: odd}, func(k, _ []byte) error {
+		got = append(got, string(k))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("scanIterator() error = %v", err)
+	}
+	if !slices.Equal(got, []string{"a001", "a003", "a005"}) {
+		t.Errorf("scanIterator() keys = %v, want the first 3 odd keys", got)
+	}
+	// the bytes count the values of the skipped pairs too, 4 bytes each
+	if summary.Rows != 3 || summary.Scanned != 6 || summary.Bytes != 24 {
+		t.Errorf("scanIterator() = %+v, want 3 rows out of 6 scanned with 24 value bytes", summary)
+	}
+}
+
+func TestValidateRange(t *testing.T) {
+	tests := []struct {
+		start, end string
+		wantErr    bool
+	}{
+		{"a003", "a006", false},
+		{"a003", "", false}, // no end
+		{"a003", "a003", true},
+		{"a006", "a003", true},
+	}
+	for _, tt := range tests {
+		var end []byte
+		if tt.end != "" {
+			end = []byte(tt.end)
+		}
+		if err := ValidateRange([]byte(tt.start), end); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRange(%s, %s) error = %v, wantErr %v", tt.start, tt.end, err, tt.wantErr)
+		}
+	}
+}
+
+func TestScanIteratorMaxDuration(t *testing.T) {
+	iter := &fakeIterator{keys: fakeKeys("a", 100), pace: 20 * time.Millisecond}
+
+	summary, err := scanIterator(iter, []byte("b"), ScanOptions{Limit: 100, MaxDuration: 100 * time.Millisecond}, func(_, _ []byte) error { return nil })
+	if err != nil {
+		t.Fatalf("scanIterator() error = %v, want a graceful stop", err)
+	}
+	if !summary.TimeBudgetReached {
+		t.Errorf("scanIterator() TimeBudgetReached = false, want true")
+	}
+	if summary.Rows == 0 || summary.Rows >= 100 {
+		t.Errorf("scanIterator() rows = %d, want partial result", summary.Rows)
+	}
+	if summary.Elapsed < 100*time.Millisecond {
+		t.Errorf("scanIterator() elapsed = %s, want at least the budget", summary.Elapsed)
+	}
+}
+
+func TestScanIteratorErrors(t *testing.T) {
+	iterErr := errors.New("region unavailable")
+	iter := &fakeIterator{keys: fakeKeys("a", 3), err: iterErr}
+	summary, err := scanIterator(iter, []byte("b"), ScanOptions{Limit: 10}, func(_, _ []byte) error { return nil })
+	if !errors.Is(err, iterErr) || summary.Rows != 1 {
+		t.Errorf("scanIterator() = %+v, %v, want 1 row and the iterator error", summary, err)
+	}
+
+	fnErr := errors.New("write failed")
+	iter = &fakeIterator{keys: fakeKeys("a", 3)}
+	if _, err := scanIterator(iter, []byte("b"), ScanOptions{Limit: 10}, func(_, _ []byte) error { return fnErr }); !errors.Is(err, fnErr) {
+		t.Errorf("scanIterator() error = %v, want %v", err, fnErr)
+	}
+}
+
+func TestScanIncrementalWatermark(t *testing.T) {
+	prefix, end := []byte("a"), []byte("b")
+	path := filepath.Join(t.TempDir(), "watermark")
+
+	// run is one incremental export: resume after the watermark, then save the last key
+	run := func(keys [][]byte, limit int) []string {
+		t.Helper()
+		startAfter, err := ReadWatermark(path)
+		if err != nil {
+			t.Fatalf("ReadWatermark() error = %v", err)
+		}
+		start, err := scanStart(prefix, end, startAfter)
+		if err != nil {
+			t.Fatalf("scanStart() error = %v", err)
+		}
+		// position the iterator like tx.Iter(start, ...)
+		pos, _ := slices.BinarySearchFunc(keys, start, bytes.Compare)
+
+		var got []string
+		var last []byte
+		iter := &fakeIterator{keys: keys, pos: pos}
+		if _, err := scanIterator(iter, end, ScanOptions{Limit: limit}, func(k, _ []byte) error {
+			got = append(got, string(k))
+			last = slices.Clone(k)
+			return nil
+		}); err != nil {
+			t.Fatalf("scanIterator() error = %v", err)
+		}
+		if last != nil {
+			if err := WriteWatermark(path, last); err != nil {
+				t.Fatalf("WriteWatermark() error = %v", err)
+			}
+		}
+		return got
+	}
+
+	// the missing watermark file starts from the beginning
+	keys := fakeKeys("a", 5)
+	if got := run(keys, 3); !slices.Equal(got, []string{"a000", "a001", "a002"}) {
+		t.Errorf("first run = %v, want a000-a002", got)
+	}
+
+	// rows written between the runs are picked up after the rest of the previous ones
+	keys = fakeKeys("a", 7)
+	if got := run(keys, 10); !slices.Equal(got, []string{"a003", "a004", "a005", "a006"}) {
+		t.Errorf("second run = %v, want a003-a006", got)
+	}
+
+	// nothing new keeps the watermark
+	if got := run(keys, 10); len(got) != 0 {
+		t.Errorf("third run = %v, want no rows", got)
+	}
+	if wm, _ := ReadWatermark(path); string(wm) != "a006" {
+		t.Errorf("watermark = %q, want a006", wm)
+	}
+
+	if _, err := scanStart(prefix, end, []byte("b000")); err == nil {
+		t.Error("scanStart() error = nil, want error for a watermark outside the prefix")
+	}
+}
+
+func benchmarkScan(b *testing.B, fn func(k, v []byte) error) {
+	keys := fakeKeys("t", 1000)
+	values := make([][]byte, len(keys))
+	for i := range values {
+		values[i] = make([]byte, 256)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		iter := &fakeIterator{keys: keys, values: values}
+		if _, err := scanIterator(iter, []byte("u"), ScanOptions{Limit: len(keys)}, fn); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkScanCopy copies every key and value like the buffered Scan.
+func BenchmarkScanCopy(b *testing.B) {
+	var keys, values [][]byte
+	benchmarkScan(b, func(k, v []byte) error {
+		keys = append(keys[:0], slices.Clone(k))
+		values = append(values[:0], slices.Clone(v))
+		return nil
+	})
+}
+
+// BenchmarkScanKeysOnly copies the keys only, the values are never copied.
+func BenchmarkScanKeysOnly(b *testing.B) {
+	var keys [][]byte
+	benchmarkScan(b, func(k, _ []byte) error {
+		keys = append(keys[:0], slices.Clone(k))
+		return nil
+	})
+}
+
+// BenchmarkScanCount doesn't copy anything.
+func BenchmarkScanCount(b *testing.B) {
+	n := 0
+	benchmarkScan(b, func(_, _ []byte) error {
+		n++
+		return nil
+	})
+}
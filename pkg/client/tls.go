@@ -0,0 +1,50 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/tikv/client-go/v2/config"
+)
+
+// TLSFiles are the PEM files for mutual TLS with PD and TiKV. The zero value connects without TLS.
+type TLSFiles struct {
+	CAPath   string
+	CertPath string
+	KeyPath  string
+}
+
+// WithTLS connects to PD and TiKV with mutual TLS. The CA, the certificate and the key are all
+// required.
+func WithTLS(files TLSFiles) Option {
+	return func(c *TiKVClient) {
+		c.tlsFiles = files
+	}
+}
+
+// security validates the files and loads them into the security config of client-go and the
+// TLS config of the PD HTTP client. Both are zero without any file.
+func (t TLSFiles) security() (config.Security, *tls.Config, error) {
+	if t == (TLSFiles{}) {
+		return config.Security{}, nil, nil
+	}
+
+	var missing []string
+	for _, f := range []struct{ name, path string }{{"CA", t.CAPath}, {"certificate", t.CertPath}, {"key", t.KeyPath}} {
+		if f.path == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return config.Security{}, nil, fmt.Errorf("TLS requires the CA, the certificate and the key files, missing the %s", strings.Join(missing, " and the "))
+	}
+
+	sec := config.NewSecurity(t.CAPath, t.CertPath, t.KeyPath, nil)
+	tlsConfig, err := sec.ToTLSConfig()
+	if err != nil {
+		return config.Security{}, nil, fmt.Errorf("failed to load the TLS files: %w", err)
+	}
+
+	return sec, tlsConfig, nil
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate, used as the CA too, and its key.
+func writeTestCert(t *testing.T) TLSFiles {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "tikv-reader"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	files := TLSFiles{
+		CAPath:   filepath.Join(dir, "ca.pem"),
+		CertPath: filepath.Join(dir, "client.pem"),
+		KeyPath:  filepath.Join(dir, "client-key.pem"),
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	for path, b := range map[string][]byte{
+		files.CAPath:   certPEM,
+		files.CertPath: certPEM,
+		files.KeyPath:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	} {
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	return files
+}
+
+func TestTLSFilesSecurity(t *testing.T) {
+	// no files keeps the plaintext connection
+	sec, tlsConfig, err := TLSFiles{}.security()
+	if err != nil || tlsConfig != nil || sec.ClusterSSLCA != "" {
+		t.Errorf("security() = %+v, %v, %v, want no TLS", sec, tlsConfig, err)
+	}
+
+	files := writeTestCert(t)
+	sec, tlsConfig, err = files.security()
+	if err != nil {
+		t.Fatalf("security() error = %v", err)
+	}
+	// client-go loads the client certificate on each handshake instead of keeping it in Certificates
+	if tlsConfig == nil || tlsConfig.GetClientCertificate == nil || tlsConfig.RootCAs == nil || sec.ClusterSSLCert != files.CertPath {
+		t.Errorf("security() = %+v, %+v, want the client certificate loaded", sec, tlsConfig)
+	}
+
+	partial := TLSFiles{CAPath: files.CAPath}
+	if _, _, err := partial.security(); err == nil || !strings.Contains(err.Error(), "missing the certificate and the key") {
+		t.Errorf("security() error = %v, want the missing files", err)
+	}
+
+	broken := files
+	broken.KeyPath = filepath.Join(t.TempDir(), "missing.pem")
+	if _, _, err := broken.security(); err == nil {
+		t.Error("security() error = nil, want error for an unreadable key")
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+// A TSO is a 64-bit integer made of a 46-bit physical part (milliseconds since the Unix epoch)
+// and an 18-bit logical counter. TiDB reports it as a decimal string, e.g. `SELECT @@tidb_current_ts`.
+var (
+	// minTSOTime is older than any TiDB cluster could have been written.
+	minTSOTime = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	// maxTSOClockSkew tolerates a PD clock which is slightly ahead of the local clock.
+	maxTSOClockSkew = 24 * time.Hour
+)
+
+// ParseTSO parses the decimal TSO value reported by TiDB and validates that its physical
+// part points to a plausible wall-clock time.
+func ParseTSO(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	ts, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid TSO %q: must be a decimal integer such as the value of @@tidb_current_ts: %w", s, err)
+	}
+
+	if err := ValidateTSO(ts); err != nil {
+		return 0, err
+	}
+
+	return ts, nil
+}
+
+// ValidateTSO checks that ts is non-zero and its physical time is neither before
+// TiDB existed nor far in the future.
+func ValidateTSO(ts uint64) error {
+	if ts == 0 {
+		return fmt.Errorf("invalid TSO 0: timestamp must be greater than 0")
+	}
+
+	physical := oracle.GetTimeFromTS(ts)
+	if physical.Before(minTSOTime) || physical.After(time.Now().Add(maxTSOClockSkew)) {
+		return fmt.Errorf("invalid TSO %d: physical time %s (logical %d) is out of the plausible range; "+
+			"a TSO is a 46-bit physical timestamp in milliseconds followed by an 18-bit logical counter",
+			ts, physical.UTC().Format(time.RFC3339), oracle.ExtractLogical(ts))
+	}
+
+	return nil
+}
+
+// FormatTSO renders a TSO with its physical time for logging.
+func FormatTSO(ts uint64) string {
+	return fmt.Sprintf("%d (%s, logical %d)",
+		ts, oracle.GetTimeFromTS(ts).UTC().Format(time.RFC3339Nano), oracle.ExtractLogical(ts))
+}
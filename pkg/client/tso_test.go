@@ -0,0 +1,61 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+func TestParseTSO(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected uint64
+		hasError bool
+	}{
+		// valid cases
+		{"449460987526643717", 449460987526643717, false}, // 2024-05-01T10:00:00.123Z, logical 5
+		{" 449460987526643717\n", 449460987526643717, false},
+
+		// invalid cases
+		{"", 0, true},
+		{"0", 0, true},
+		{"-1", 0, true},
+		{"abc", 0, true},
+		{"12345", 0, true},                // physical time is in 1970
+		{"18446744073709551615", 0, true}, // physical time is far in the future
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTSO(tt.input)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("ParseTSO(%q) error = nil, want error", tt.input)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseTSO(%q) error = %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseTSO(%q) = %d, want %d", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestParseTSOComponents(t *testing.T) {
+	ts, err := ParseTSO("449460987526643717")
+	if err != nil {
+		t.Fatalf("ParseTSO() error = %v", err)
+	}
+
+	expectedTime := time.Date(2024, 5, 1, 10, 0, 0, 123000000, time.UTC)
+	if got := oracle.GetTimeFromTS(ts); !got.Equal(expectedTime) {
+		t.Errorf("physical time = %s, want %s", got.UTC(), expectedTime)
+	}
+	if got := oracle.ExtractLogical(ts); got != 5 {
+		t.Errorf("logical = %d, want 5", got)
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadWatermark reads the last scanned key kept by WriteWatermark. A missing or empty file
+// returns nil, which scans from the beginning of the prefix.
+func ReadWatermark(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watermark file %s: %w", path, err)
+	}
+
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		return nil, nil
+	}
+
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid watermark in %s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+// WriteWatermark saves the last scanned key as hex. The file is replaced by a rename, so an
+// interrupted write leaves the previous watermark in place.
+func WriteWatermark(path string, key []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write watermark file %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after the rename
+
+	if _, err := fmt.Fprintf(tmp, "%X\n", key); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write watermark file %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write watermark file %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write watermark file %s: %w", path, err)
+	}
+
+	return nil
+}
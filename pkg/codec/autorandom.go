@@ -0,0 +1,70 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The shard bits of an AUTO_RANDOM column, as TiDB takes them: AUTO_RANDOM alone is
+// AUTO_RANDOM(5).
+const (
+	autoRandomShardBitsDefault = 5
+	autoRandomShardBitsMax     = 15
+)
+
+// autoRandomIncrementBits returns the bits of the increment under the shard bits. The handle of
+// a signed primary key keeps its sign bit 0, see Options.UnsignedHandles.
+func (o Options) autoRandomIncrementBits(shardBits int) int {
+	if o.UnsignedHandles {
+		return 64 - shardBits
+	}
+	return 63 - shardBits
+}
+
+// formatAutoRandom prints an int handle as its shard and increment, e.g., {shard=5,inc=1}. It
+// reports false for a handle with the sign bit set, which AUTO_RANDOM doesn't allocate.
+func (o Options) formatAutoRandom(rowID int64) (string, bool) {
+	if rowID < 0 && !o.UnsignedHandles {
+		return "", false
+	}
+
+	incBits := o.autoRandomIncrementBits(o.AutoRandomShardBits)
+	shard := uint64(rowID) >> incBits
+	inc := uint64(rowID) & (1<<incBits - 1)
+
+	return fmt.Sprintf("{shard=%d,inc=%d}", shard, inc), true
+}
+
+// parseAutoRandomHandle parses the shard and increment of an int handle written as printed
+// with AutoRandomShardBits, e.g., shard=5,inc=1 (without the braces), by those shard bits or
+// else the default of AUTO_RANDOM. It reports false for other values, such as the ones of a
+// common handle.
+func (o Options) parseAutoRandomHandle(values string) (int64, bool, error) {
+	shardStr, incStr, found := strings.Cut(values, ",")
+	shardStr, ok := strings.CutPrefix(strings.TrimSpace(shardStr), "shard=")
+	if !ok {
+		return 0, false, nil
+	}
+	incStr, ok = strings.CutPrefix(strings.TrimSpace(incStr), "inc=")
+	if !found || !ok {
+		return 0, true, fmt.Errorf("the AUTO_RANDOM handle must be {shard=N,inc=N}")
+	}
+
+	shardBits := o.AutoRandomShardBits
+	if shardBits == 0 {
+		shardBits = autoRandomShardBitsDefault
+	}
+	incBits := o.autoRandomIncrementBits(shardBits)
+
+	shard, err := strconv.ParseUint(shardStr, 10, 64)
+	if err != nil || shard >= 1<<shardBits {
+		return 0, true, fmt.Errorf("invalid shard %q: must be less than %d for %d shard bits", shardStr, 1<<shardBits, shardBits)
+	}
+	inc, err := strconv.ParseUint(incStr, 10, 64)
+	if err != nil || inc >= 1<<incBits {
+		return 0, true, fmt.Errorf("invalid increment %q: must be less than 2^%d", incStr, incBits)
+	}
+
+	return int64(shard<<incBits | inc), true, nil
+}
@@ -0,0 +1,53 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+func TestAutoRandomHandle(t *testing.T) {
+	rowID := int64(5<<58 | 1) // AUTO_RANDOM(5): the sign bit, 5 shard bits and 58 bits of increment
+	key := tidbcodec.EncodeInt(append(tidbcodec.EncodeInt([]byte{'t'}, 1), '_', 'r'), rowID)
+
+	opts := Options{AutoRandomShardBits: 5}
+	if got, want := opts.DecodeKey(key), "t1_r{shard=5,inc=1}"; got != want {
+		t.Errorf("DecodeKey() = %s, want %s", got, want)
+	}
+	for _, input := range []string{"t1_r{shard=5,inc=1}", "t1_r{shard=5, inc=1}", opts.DecodeKey(key)} {
+		got, err := opts.ParseKey(input)
+		if err != nil {
+			t.Fatalf("ParseKey(%s) error = %v", input, err)
+		}
+		if !bytes.Equal(got, key) {
+			t.Errorf("ParseKey(%s) = %X, want %X", input, got, key)
+		}
+	}
+	// a negative handle isn't allocated by AUTO_RANDOM
+	if got := opts.DecodeKey(tidbcodec.EncodeInt(key[:11:11], -1)); got != "t1_r-1" {
+		t.Errorf("DecodeKey() = %s, want t1_r-1", got)
+	}
+
+	// the shard bits of AUTO_RANDOM(3) leave a longer increment
+	if got, want := (Options{AutoRandomShardBits: 3}).DecodeKey(key), "t1_r{shard=1,inc=288230376151711745}"; got != want {
+		t.Errorf("DecodeKey() = %s, want %s", got, want)
+	}
+
+	// without AutoRandomShardBits, the input takes the default shard bits
+	if got, err := ParseKey("t1_r{shard=5,inc=1}"); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("ParseKey() = %X, %v, want %X", got, err, key)
+	}
+	if got, want := DecodeKey(key), "t1_r1441151880758558721"; got != want {
+		t.Errorf("DecodeKey() = %s, want %s", got, want)
+	}
+
+	for _, input := range []string{"t1_r{shard=32,inc=1}", "t1_r{shard=1}", "t1_r{shard=1,inc=-1}", "t1_r{shard=1,inc=288230376151711744}"} {
+		if _, err := ParseKey(input); err == nil {
+			t.Errorf("ParseKey(%s) error = nil, want error", input)
+		}
+	}
+	if err := (Options{AutoRandomShardBits: 16}).Validate(); err == nil {
+		t.Error("Validate() with 16 shard bits error = nil, want error")
+	}
+}
@@ -0,0 +1,63 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxBitWidth is the largest N of a BIT(N) column.
+const maxBitWidth = 64
+
+// parseBitWidth returns the N of a BIT(N) column type, 1 for BIT alone as in MySQL.
+func parseBitWidth(mysqlType string) (int, error) {
+	s := strings.TrimSpace(mysqlType)
+	start := strings.IndexByte(s, '(')
+	if start < 0 {
+		return 1, nil
+	}
+	end := strings.LastIndexByte(s, ')')
+	if end < start {
+		return 0, fmt.Errorf("invalid column type %q: the width isn't closed by )", mysqlType)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(s[start+1 : end]))
+	if err != nil || n < 1 || n > maxBitWidth {
+		return 0, fmt.Errorf("invalid column type %q: the width must be from 1 to %d", mysqlType, maxBitWidth)
+	}
+
+	return n, nil
+}
+
+// bitLiteral writes the bits of a BIT value as a literal such as b'0101', with the leading
+// zeros up to the width. A width of 0 writes the bits from the highest one set.
+func bitLiteral(n uint64, width int) string {
+	return "b'" + fmt.Sprintf("%0*b", width, n) + "'"
+}
+
+// decodeRowV2Bit decodes a BIT value, stored as a RowV2 unsigned integer. It reports false when
+// the value has more bits than the width, unless the width is 0.
+func decodeRowV2Bit(b []byte, width int) (uint64, bool) {
+	n, ok := decodeRowV2Uint(b)
+	if !ok || (width > 0 && width < maxBitWidth && n>>width != 0) {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// bitBytes returns the bytes of a BIT value as TiDB holds them, big-endian in as many bytes as
+// the width takes, or in 8 without the width.
+func bitBytes(n uint64, width int) []byte {
+	size := (width + 7) / 8
+	if width == 0 {
+		size = 8
+	}
+
+	b := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		b[i], n = byte(n), n>>8
+	}
+
+	return b
+}
@@ -0,0 +1,74 @@
+package codec
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseBitWidth(t *testing.T) {
+	for input, want := range map[string]int{"bit": 1, "BIT(4)": 4, "bit( 64 )": 64} {
+		if got, err := parseBitWidth(input); err != nil || got != want {
+			t.Errorf("parseBitWidth(%s) = %d, %v, want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"bit(0)", "bit(65)", "bit(x)", "bit(4"} {
+		if _, err := parseBitWidth(input); err == nil {
+			t.Errorf("parseBitWidth(%s) error = nil, want error", input)
+		}
+	}
+}
+
+func TestDecodeBitWithSchema(t *testing.T) {
+	schemas, err := ParseSchemas([]byte(`{"132": {
+		"1": {"name": "id", "type": "bigint", "handle": true},
+		"2": {"name": "flags", "type": "bit(4)"},
+		"3": {"name": "mask", "type": "bit(12)"}
+	}}`))
+	if err != nil {
+		t.Fatalf("ParseSchemas() error = %v", err)
+	}
+	schema := schemas[132]
+
+	value := encodeSmallRowV2(map[int64][]byte{2: {0x0a}, 3: {0x01, 0x08}})
+	cols, err := DecodeRowWithSchema(value, schema)
+	if err != nil {
+		t.Fatalf("DecodeRowWithSchema() error = %v", err)
+	}
+	if got, want := []string{cols[1].Value, cols[2].Value}, []string{"b'1010'", "b'100000000001'"}; !slices.Equal(got, want) {
+		t.Errorf("DecodeRowWithSchema() = %v, want %v", got, want)
+	}
+
+	// more bits than the width fall back to hex
+	cols, err = DecodeRowWithSchema(encodeSmallRowV2(map[int64][]byte{2: {0x1f}}), schema)
+	if err != nil {
+		t.Fatalf("DecodeRowWithSchema() error = %v", err)
+	}
+	if got := cols[1].Value; !strings.HasPrefix(got, "Invalid bit") {
+		t.Errorf("DecodeRowWithSchema() = %s, want Invalid bit", got)
+	}
+
+	// without the width, e.g., by --col-types, the bits from the highest one set
+	if got := (Options{}).decodeColumn([]byte{0x05}, ColumnBit); got != "b'101'" {
+		t.Errorf("decodeColumn() = %s, want b'101'", got)
+	}
+
+	key, err := ParseKey("t132_r7")
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	stmt, err := SQLInsert(key, value, schema)
+	if err != nil {
+		t.Fatalf("SQLInsert() error = %v", err)
+	}
+	if want := "INSERT INTO `t132` (`id`, `flags`, `mask`) VALUES (7, b'1010', b'100000000001');"; stmt != want {
+		t.Errorf("SQLInsert() = %s, want %s", stmt, want)
+	}
+	values, err := DecodeRowValues(key, value, schema)
+	if err != nil {
+		t.Fatalf("DecodeRowValues() error = %v", err)
+	}
+	if got := values[2].Text; got != "\x08\x01" {
+		t.Errorf("DecodeRowValues() = %q, want the big-endian bytes \\x08\\x01", got)
+	}
+}
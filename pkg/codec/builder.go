@@ -0,0 +1,278 @@
+package codec
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// KeyKind is the kind of a key, for table keys the kind of data they point to.
+type KeyKind string
+
+const (
+	KindRecord KeyKind = "record"
+	KindIndex  KeyKind = "index"
+)
+
+// ComponentType is the datum type of an index value component.
+type ComponentType string
+
+const (
+	ComponentInt      ComponentType = "int"
+	ComponentUint     ComponentType = "uint"
+	ComponentString   ComponentType = "string"
+	ComponentDouble   ComponentType = "double"
+	ComponentDecimal  ComponentType = "decimal"
+	ComponentDatetime ComponentType = "datetime"
+)
+
+// mysqlTypes maps MySQL column types to the component type their datums are encoded as.
+var mysqlTypes = map[string]ComponentType{
+	"tinyint": ComponentInt, "smallint": ComponentInt, "mediumint": ComponentInt, "int": ComponentInt, "bigint": ComponentInt,
+	"uint": ComponentUint, "unsigned": ComponentUint, "bigint unsigned": ComponentUint,
+	"char": ComponentString, "varchar": ComponentString, "text": ComponentString, "string": ComponentString,
+	"binary": ComponentString, "varbinary": ComponentString, "blob": ComponentString,
+	"float": ComponentDouble, "double": ComponentDouble,
+	"decimal": ComponentDecimal, "numeric": ComponentDecimal,
+	"date": ComponentDatetime, "datetime": ComponentDatetime, "timestamp": ComponentDatetime,
+}
+
+// ParseComponentType returns the component type of a MySQL column type such as varchar or bigint.
+func ParseComponentType(mysqlType string) (ComponentType, error) {
+	t, ok := mysqlTypes[strings.ToLower(strings.TrimSpace(mysqlType))]
+	if !ok {
+		return "", fmt.Errorf("unsupported type %q", mysqlType)
+	}
+
+	return t, nil
+}
+
+// KeyComponent is a typed value of an index key, such as an indexed column value or the handle.
+type KeyComponent struct {
+	Type  ComponentType
+	Value string
+}
+
+// componentAliases are the short forms of the component types, e.g., "d:12.50".
+var componentAliases = map[string]ComponentType{"d": ComponentDecimal, "t": ComponentDatetime}
+
+// datetimePattern matches the dates and datetimes which are inferred as datetime components.
+var datetimePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([ T]\d{2}:\d{2}:\d{2}(\.\d{1,6})?)?$`)
+
+// componentType resolves a component type or its alias.
+func componentType(name string) (ComponentType, bool) {
+	if t, ok := componentAliases[name]; ok {
+		return t, true
+	}
+
+	switch t := ComponentType(name); t {
+	case ComponentInt, ComponentUint, ComponentString, ComponentDouble, ComponentDecimal, ComponentDatetime:
+		return t, true
+	}
+	return "", false
+}
+
+// inferComponentType guesses the type of an untyped value: integers, unsigned integers beyond
+// the signed range, floats, dates and datetimes, and strings for everything else.
+func inferComponentType(value string) ComponentType {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ComponentInt
+	}
+	if _, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return ComponentUint
+	}
+	// "Inf" and "NaN" are parsed as floats too, so a float must look like a number
+	if strings.ContainsAny(value, ".eE") && strings.ContainsAny(value, "0123456789") {
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return ComponentDouble
+		}
+	}
+	if datetimePattern.MatchString(value) {
+		if _, err := types.ParseDatetime(types.DefaultStmtNoWarningContext, value); err == nil {
+			return ComponentDatetime
+		}
+	}
+
+	return ComponentString
+}
+
+// ParseKeyComponent parses a component written as "type:value" (e.g., "int:5", "string:abc",
+// "d:12.50"). Without a type, the type is inferred in the same way as ParseKey.
+func ParseKeyComponent(input string) (KeyComponent, error) {
+	typ, value, found := strings.Cut(input, ":")
+	// the colons of a time such as "2024-01-01 10:20:30" don't follow a type name
+	if !found || strings.ContainsFunc(typ, func(r rune) bool { return r < 'a' || r > 'z' }) {
+		return KeyComponent{Type: inferComponentType(input), Value: input}, nil
+	}
+
+	c := KeyComponent{Type: ComponentType(typ), Value: value}
+	if t, ok := componentType(typ); ok {
+		c.Type = t
+	}
+	if _, err := c.datum(); err != nil {
+		return KeyComponent{}, err
+	}
+
+	return c, nil
+}
+
+// parseIndexValue returns the datum of an index value of a one-liner key. A value with a known
+// type in front, e.g., "d:12.50", takes that type, and a value in double quotes, e.g., "42", is
+// a string; other values, including strings which happen to contain ':', are inferred.
+func parseIndexValue(input string) (types.Datum, error) {
+	if len(input) >= 2 && input[0] == '"' && input[len(input)-1] == '"' {
+		return types.NewStringDatum(input[1 : len(input)-1]), nil
+	}
+	if typ, value, found := strings.Cut(input, ":"); found {
+		if t, ok := componentType(typ); ok {
+			return KeyComponent{Type: t, Value: value}.datum()
+		}
+	}
+
+	return KeyComponent{Type: inferComponentType(input), Value: input}.datum()
+}
+
+func (c KeyComponent) datum() (types.Datum, error) {
+	switch c.Type {
+	case ComponentInt:
+		n, err := strconv.ParseInt(c.Value, 10, 64)
+		if err != nil {
+			return types.Datum{}, fmt.Errorf("invalid int component %q: %v", c.Value, err)
+		}
+		return types.NewIntDatum(n), nil
+	case ComponentUint:
+		n, err := strconv.ParseUint(c.Value, 10, 64)
+		if err != nil {
+			return types.Datum{}, fmt.Errorf("invalid uint component %q: %v", c.Value, err)
+		}
+		return types.NewUintDatum(n), nil
+	case ComponentString:
+		return types.NewStringDatum(c.Value), nil
+	case ComponentDouble:
+		f, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return types.Datum{}, fmt.Errorf("invalid double component %q: %v", c.Value, err)
+		}
+		return types.NewFloat64Datum(f), nil
+	case ComponentDecimal:
+		dec := new(types.MyDecimal)
+		if err := dec.FromString([]byte(c.Value)); err != nil {
+			return types.Datum{}, fmt.Errorf("invalid decimal component %q: %v", c.Value, err)
+		}
+		// the encoding needs the precision and the scale, which are taken from the value itself
+		d := types.NewDecimalDatum(dec)
+		precision, frac := dec.PrecisionAndFrac()
+		d.SetLength(precision)
+		d.SetFrac(frac)
+		return d, nil
+	case ComponentDatetime:
+		t, err := types.ParseDatetime(types.DefaultStmtNoWarningContext, c.Value)
+		if err != nil {
+			return types.Datum{}, fmt.Errorf("invalid datetime component %q: %v", c.Value, err)
+		}
+		return types.NewTimeDatum(t), nil
+	default:
+		return types.Datum{}, fmt.Errorf("unknown component type %q: must be one of %s, %s, %s, %s, %s, %s",
+			c.Type, ComponentInt, ComponentUint, ComponentString, ComponentDouble, ComponentDecimal, ComponentDatetime)
+	}
+}
+
+// EncodeValue returns the memcomparable encoding of the component as it appears in an index key.
+func EncodeValue(c KeyComponent) ([]byte, error) {
+	d, err := c.datum()
+	if err != nil {
+		return nil, err
+	}
+
+	typeCtx := types.DefaultStmtNoWarningContext.WithLocation(time.Local)
+	b, err := tidbcodec.EncodeKey(typeCtx.Location(), nil, d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s value %q: %v", c.Type, c.Value, err)
+	}
+
+	return b, nil
+}
+
+// KeySpec describes a table key field by field.
+type KeySpec struct {
+	TableID int64
+	Kind    KeyKind
+	RowID   int64 // only for KindRecord
+	IndexID int64 // only for KindIndex
+	// Components are the indexed column values of KindIndex optionally followed by the handle,
+	// or the primary key values of the common handle of KindRecord instead of RowID
+	Components []KeyComponent
+}
+
+// String returns the one-liner form of the key accepted by ParseKey, e.g., t1_i2_abc_5.
+func (s KeySpec) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("t%d", s.TableID))
+
+	switch s.Kind {
+	case KindRecord:
+		if len(s.Components) > 0 {
+			sb.WriteString("_r")
+		} else {
+			sb.WriteString(fmt.Sprintf("_r%d", s.RowID))
+		}
+	case KindIndex:
+		sb.WriteString(fmt.Sprintf("_i%d", s.IndexID))
+	}
+	for _, c := range s.Components {
+		sb.WriteString(separator)
+		sb.WriteString(c.Value)
+	}
+
+	return sb.String()
+}
+
+// Encode encodes the key into its TiKV form using the component types as given,
+// not the type inference of ParseKey.
+func (s KeySpec) Encode() ([]byte, error) {
+	buf := make([]byte, 0, 32)
+	buf = append(buf, 't')
+	buf = tidbcodec.EncodeInt(buf, s.TableID)
+
+	switch s.Kind {
+	case KindRecord:
+		buf = append(buf, []byte(separator+"r")...)
+		if len(s.Components) == 0 {
+			return tidbcodec.EncodeInt(buf, s.RowID), nil
+		}
+		if s.RowID != 0 {
+			return nil, fmt.Errorf("record key has either a row ID or the values of a common handle")
+		}
+	case KindIndex:
+		buf = append(buf, []byte(separator+"i")...)
+		buf = tidbcodec.EncodeInt(buf, s.IndexID)
+	default:
+		return nil, fmt.Errorf("unknown key kind %q: must be %s or %s", s.Kind, KindRecord, KindIndex)
+	}
+
+	if len(s.Components) == 0 {
+		return buf, nil
+	}
+
+	datums := make([]types.Datum, 0, len(s.Components))
+	for _, c := range s.Components {
+		d, err := c.datum()
+		if err != nil {
+			return nil, err
+		}
+		datums = append(datums, d)
+	}
+
+	typeCtx := types.DefaultStmtNoWarningContext.WithLocation(time.Local)
+	buf, err := tidbcodec.EncodeKey(typeCtx.Location(), buf, datums...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %v", err)
+	}
+
+	return buf, nil
+}
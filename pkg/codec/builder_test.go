@@ -0,0 +1,207 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+func TestParseKeyComponent(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected KeyComponent
+		hasError bool
+	}{
+		{"5", KeyComponent{Type: ComponentInt, Value: "5"}, false},
+		{"abc", KeyComponent{Type: ComponentString, Value: "abc"}, false},
+		{"int:-7", KeyComponent{Type: ComponentInt, Value: "-7"}, false},
+		{"string:123", KeyComponent{Type: ComponentString, Value: "123"}, false},
+		{"string:a:b", KeyComponent{Type: ComponentString, Value: "a:b"}, false},
+		{"18446744073709551615", KeyComponent{Type: ComponentUint, Value: "18446744073709551615"}, false},
+		{"12.5", KeyComponent{Type: ComponentDouble, Value: "12.5"}, false},
+		{"2024-01-01 10:20:30", KeyComponent{Type: ComponentDatetime, Value: "2024-01-01 10:20:30"}, false},
+		{"d:12.50", KeyComponent{Type: ComponentDecimal, Value: "12.50"}, false},
+		{"t:2024-01-01", KeyComponent{Type: ComponentDatetime, Value: "2024-01-01"}, false},
+		{"NaN", KeyComponent{Type: ComponentString, Value: "NaN"}, false},
+
+		{"int:abc", KeyComponent{}, true},
+		{"float:1.5", KeyComponent{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseKeyComponent(tt.input)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("ParseKeyComponent(%s) error = nil, want error", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseKeyComponent(%s) error = %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseKeyComponent(%s) = %+v, want %+v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestKeySpecEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    KeySpec
+		oneLine string
+		decoded string
+	}{
+		{
+			name:    "Record key",
+			spec:    KeySpec{TableID: 132, Kind: KindRecord, RowID: 1772018},
+			oneLine: "t132_r1772018",
+			decoded: "t132_r1772018",
+		},
+		{
+			name: "Index key with int values",
+			spec: KeySpec{TableID: 128, Kind: KindIndex, IndexID: 2, Components: []KeyComponent{
+				{Type: ComponentInt, Value: "594692"},
+				{Type: ComponentInt, Value: "3400463811"},
+			}},
+			oneLine: "t128_i2_594692_3400463811",
+			decoded: "t128_i2_594692_3400463811",
+		},
+		{
+			name: "Index key with string and handle",
+			spec: KeySpec{TableID: 1, Kind: KindIndex, IndexID: 2, Components: []KeyComponent{
+				{Type: ComponentString, Value: "apple"},
+				{Type: ComponentInt, Value: "5"},
+			}},
+			oneLine: "t1_i2_apple_5",
+			decoded: "t1_i2_apple_5",
+		},
+		{
+			name: "Record key with a common handle",
+			spec: KeySpec{TableID: 55, Kind: KindRecord, Components: []KeyComponent{
+				{Type: ComponentString, Value: "abc"},
+				{Type: ComponentInt, Value: "42"},
+			}},
+			oneLine: "t55_r_abc_42",
+			decoded: "t55_r{h1: abc, h2: 42}",
+		},
+		{
+			name:    "Index prefix without values",
+			spec:    KeySpec{TableID: 1, Kind: KindIndex, IndexID: 2},
+			oneLine: "t1_i2",
+			decoded: "t1_i2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.spec.Encode()
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			if got := tt.spec.String(); got != tt.oneLine {
+				t.Errorf("String() = %s, want %s", got, tt.oneLine)
+			}
+			if got := DecodeKey(raw); got != tt.decoded {
+				t.Errorf("DecodeKey(Encode()) = %s, want %s", got, tt.decoded)
+			}
+
+			// the one-liner must produce the same bytes through ParsePrefix
+			parsed, err := ParsePrefix(tt.oneLine)
+			if err != nil {
+				t.Fatalf("ParsePrefix(%s) error = %v", tt.oneLine, err)
+			}
+			if !bytes.Equal(parsed, raw) {
+				t.Errorf("ParsePrefix(%s) = %X, want %X", tt.oneLine, parsed, raw)
+			}
+		})
+	}
+}
+
+func TestKeySpecEncodeTypedString(t *testing.T) {
+	// "123" typed as a string is encoded differently from the int inferred by ParseKey
+	spec := KeySpec{TableID: 1, Kind: KindIndex, IndexID: 2, Components: []KeyComponent{
+		{Type: ComponentString, Value: "123"},
+	}}
+
+	raw, err := spec.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	parsed, _ := ParseKey(spec.String())
+	if bytes.Equal(raw, parsed) {
+		t.Errorf("typed string component should not match the inferred int encoding")
+	}
+}
+
+func TestKeySpecEncodeInvalid(t *testing.T) {
+	invalid := []KeySpec{
+		{TableID: 1, Kind: "unknown"},
+		{TableID: 1, Kind: KindRecord, RowID: 1, Components: []KeyComponent{{Type: ComponentInt, Value: "1"}}},
+		{TableID: 1, Kind: KindIndex, IndexID: 1, Components: []KeyComponent{{Type: ComponentInt, Value: "x"}}},
+	}
+
+	for _, spec := range invalid {
+		if _, err := spec.Encode(); err == nil {
+			t.Errorf("Encode(%+v) error = nil, want error", spec)
+		}
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	dec := types.NewDecFromStringForTest("3.14")
+	decDatum := types.NewDecimalDatum(dec)
+	decDatum.SetLength(3)
+	decDatum.SetFrac(2)
+
+	dt, err := types.ParseDatetime(types.DefaultStmtNoWarningContext, "2024-01-02 03:04:05")
+	if err != nil {
+		t.Fatalf("ParseDatetime() error = %v", err)
+	}
+
+	tests := []struct {
+		mysqlType string
+		value     string
+		datum     types.Datum
+	}{
+		{"bigint", "-42", types.NewIntDatum(-42)},
+		{"BIGINT UNSIGNED", "18446744073709551615", types.NewUintDatum(18446744073709551615)},
+		{"varchar", "foo", types.NewStringDatum("foo")},
+		{"double", "1.5", types.NewFloat64Datum(1.5)},
+		{"decimal", "3.14", decDatum},
+		{"datetime", "2024-01-02 03:04:05", types.NewTimeDatum(dt)},
+	}
+
+	for _, tt := range tests {
+		typ, err := ParseComponentType(tt.mysqlType)
+		if err != nil {
+			t.Errorf("ParseComponentType(%s) error = %v", tt.mysqlType, err)
+			continue
+		}
+
+		got, err := EncodeValue(KeyComponent{Type: typ, Value: tt.value})
+		if err != nil {
+			t.Errorf("EncodeValue(%s %s) error = %v", tt.mysqlType, tt.value, err)
+			continue
+		}
+		expected, err := tidbcodec.EncodeKey(time.Local, nil, tt.datum)
+		if err != nil {
+			t.Fatalf("EncodeKey() error = %v", err)
+		}
+		if !bytes.Equal(got, expected) {
+			t.Errorf("EncodeValue(%s %s) = %X, want %X", tt.mysqlType, tt.value, got, expected)
+		}
+	}
+
+	if _, err := ParseComponentType("geometry"); err == nil {
+		t.Error("ParseComponentType(geometry) error = nil, want error")
+	}
+	if _, err := EncodeValue(KeyComponent{Type: ComponentDecimal, Value: "abc"}); err == nil {
+		t.Error("EncodeValue(decimal abc) error = nil, want error")
+	}
+}
@@ -0,0 +1,111 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	tidbcharset "github.com/pingcap/tidb/pkg/parser/charset"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// The charsets the strings of the values are transcoded from. MySQL's latin1 is cp1252.
+const (
+	charsetUTF8   = "utf8mb4"
+	charsetGBK    = "gbk"
+	charsetLatin1 = "latin1"
+)
+
+// charsets maps the charset names accepted by Options.Charset and schema files to the charsets.
+var charsets = map[string]string{
+	"utf8mb4": charsetUTF8, "utf8": charsetUTF8, "utf-8": charsetUTF8, "gbk": charsetGBK,
+	"latin1": charsetLatin1, "cp1252": charsetLatin1,
+}
+
+func parseCharset(name string) (string, error) {
+	cs, ok := charsets[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", fmt.Errorf("unsupported charset %q: must be one of utf8mb4, gbk or latin1", name)
+	}
+
+	return cs, nil
+}
+
+// parseColumnCharset returns the charset of a string column type such as
+// varchar(64) CHARACTER SET gbk, as in SHOW CREATE TABLE, or "" for a type without one.
+func parseColumnCharset(mysqlType string) (string, error) {
+	fields := strings.Fields(strings.ToLower(mysqlType))
+	for i, f := range fields {
+		var name string
+		switch {
+		case f == "charset" && i+1 < len(fields):
+			name = fields[i+1]
+		case f == "character" && i+2 < len(fields) && fields[i+1] == "set":
+			name = fields[i+2]
+		default:
+			continue
+		}
+
+		cs, err := parseCharset(strings.Trim(name, "'\"`"))
+		if err != nil {
+			return "", fmt.Errorf("column type %q: %w", mysqlType, err)
+		}
+		return cs, nil
+	}
+
+	return "", nil
+}
+
+// transcode decodes the bytes of a string of the charset into UTF-8. It reports false when the
+// bytes aren't of the charset.
+func transcode(b []byte, cs string) (string, bool) {
+	switch cs {
+	case charsetGBK:
+		s, err := tidbcharset.EncodingGBKImpl.Transform(nil, b, tidbcharset.OpDecode)
+		if err != nil {
+			return "", false
+		}
+		return string(s), true
+	case charsetLatin1:
+		s, err := charmap.Windows1252.NewDecoder().Bytes(b)
+		if err != nil {
+			return "", false
+		}
+		return string(s), true
+	default:
+		return string(b), utf8.Valid(b)
+	}
+}
+
+// charsetString returns the text of the bytes of a string transcoded from the charset, e.g., the
+// one a schema declares for the column. Without a charset, only the bytes which aren't UTF-8 are
+// transcoded, from the one of Options.Charset. It reports false when the bytes are UTF-8 already
+// or don't read as text in the charset either.
+func (o Options) charsetString(b []byte, cs string) (string, bool) {
+	if cs == "" {
+		if utf8.Valid(b) {
+			return "", false
+		}
+		cs = o.stringCharset()
+	}
+	if cs == charsetUTF8 {
+		return "", false
+	}
+
+	s, ok := transcode(b, cs)
+	if !ok || !isLooksLikeString([]byte(s)) {
+		return "", false
+	}
+
+	return s, true
+}
+
+// stringText returns the text of the bytes of a string: the bytes themselves when they read as
+// UTF-8 text, or else transcoded as charsetString does.
+func (o Options) stringText(b []byte, cs string) (string, bool) {
+	if isLooksLikeString(b) {
+		return string(b), true
+	}
+
+	return o.charsetString(b, cs)
+}
@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseColumnCharset(t *testing.T) {
+	tests := map[string]string{
+		"varchar(20) CHARACTER SET gbk COLLATE gbk_chinese_ci": charsetGBK,
+		"text charset latin1":          charsetLatin1,
+		"char(4) character set 'utf8'": charsetUTF8,
+		"varchar(64)":                  "",
+	}
+	for input, want := range tests {
+		if got, err := parseColumnCharset(input); err != nil || got != want {
+			t.Errorf("parseColumnCharset(%s) = %s, %v, want %s", input, got, err, want)
+		}
+	}
+	if _, err := parseColumnCharset("varchar(20) character set big5"); err == nil {
+		t.Error("parseColumnCharset(big5) error = nil, want error")
+	}
+	if err := (Options{Charset: "ujis"}).Validate(); err == nil {
+		t.Error("Validate() with charset ujis error = nil, want error")
+	}
+}
+
+func TestDecodeCharset(t *testing.T) {
+	gbk := []byte{0xd6, 0xd0, 0xce, 0xc4, 0xb2, 0xe2, 0xca, 0xd4} // 中文测试
+
+	if got := (Options{}).trySmartDecode(gbk); strings.Contains(got, "中文") {
+		t.Errorf("trySmartDecode() without --charset = %s, want the bytes", got)
+	}
+	opts := Options{Charset: "GBK"}
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got, want := opts.trySmartDecode(gbk[:6]), `"中文测"`; got != want {
+		t.Errorf("trySmartDecode() = %s, want %s", got, want)
+	}
+	if got, want := opts.decodeColumn(gbk, ColumnString), `"中文测试"`; got != want {
+		t.Errorf("decodeColumn() = %s, want %s", got, want)
+	}
+	// UTF-8 strings are printed as they are
+	if got, want := opts.decodeColumn([]byte("中文"), ColumnString), `"中文"`; got != want {
+		t.Errorf("decodeColumn() = %s, want %s", got, want)
+	}
+
+	// the charset of the column in the schema
+	schemas, err := ParseSchemas([]byte(`{"132": {
+		"1": {"name": "id", "type": "bigint", "handle": true},
+		"2": {"name": "name", "type": "varchar(20) CHARACTER SET gbk"},
+		"3": {"name": "city", "type": "varchar(20) CHARACTER SET latin1"}
+	}}`))
+	if err != nil {
+		t.Fatalf("ParseSchemas() error = %v", err)
+	}
+	schema := schemas[132]
+	value := encodeSmallRowV2(map[int64][]byte{2: gbk, 3: []byte("Z\xfcrich")})
+	cols, err := DecodeRowWithSchema(value, schema)
+	if err != nil {
+		t.Fatalf("DecodeRowWithSchema() error = %v", err)
+	}
+	if got, want := cols[1].Value+" "+cols[2].Value, `"中文测试" "Zürich"`; got != want {
+		t.Errorf("DecodeRowWithSchema() = %s, want %s", got, want)
+	}
+	// the latin1 bytes of "Ã©" are valid UTF-8 too, but the declared charset wins
+	cols, err = DecodeRowWithSchema(encodeSmallRowV2(map[int64][]byte{3: []byte("\xc3\xa9")}), schema)
+	if err != nil {
+		t.Fatalf("DecodeRowWithSchema() error = %v", err)
+	}
+	if got, want := cols[2].Value, `"Ã©"`; got != want {
+		t.Errorf("DecodeRowWithSchema() latin1 = %s, want %s", got, want)
+	}
+
+	key, err := ParseKey("t132_r1")
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	stmt, err := SQLInsert(key, value, schema)
+	if err != nil {
+		t.Fatalf("SQLInsert() error = %v", err)
+	}
+	if want := "INSERT INTO `t132` (`id`, `name`, `city`) VALUES (1, '中文测试', 'Zürich');"; stmt != want {
+		t.Errorf("SQLInsert() = %s, want %s", stmt, want)
+	}
+}
@@ -0,0 +1,75 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"testing"
+)
+
+// withChecksum flags the RowV2 value as having a checksum of the version and appends it,
+// computed the way TiDB does over the value and the suffix.
+func withChecksum(t *testing.T, rowHex string, version byte, suffix []byte) []byte {
+	t.Helper()
+	row, err := hex.DecodeString(rowHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row[1] |= rowV2FlagChecksum
+	row = append(row, version)
+	crc := crc32.Update(crc32.Checksum(row, crc32.IEEETable), crc32.IEEETable, suffix)
+
+	return binary.LittleEndian.AppendUint32(row, crc)
+}
+
+func TestVerifyRowChecksum(t *testing.T) {
+	key, err := ParseKey("t132_r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const rowHex = "80000200000002030f00100041616c69796168204d75656c6c657201"
+
+	// version 2 sums the value and the handle
+	value := withChecksum(t, rowHex, checksumVersionRawHandle, key[11:])
+	if c, ok := VerifyRowChecksum(key, value); !ok || c.Status != ChecksumOK {
+		t.Errorf("VerifyRowChecksum(v2) = %v, %v, want OK", c, ok)
+	}
+	// the columns are read as before
+	if cols, err := RowV2RawColumns(value); err != nil || string(cols[2]) != "Aaliyah Mueller" {
+		t.Errorf("RowV2RawColumns(with checksum) = %q, %v", cols, err)
+	}
+
+	corrupt := bytes.Clone(value)
+	corrupt[12] = 'B'
+	if c, ok := VerifyRowChecksum(key, corrupt); !ok || c.Status != ChecksumCorrupt {
+		t.Errorf("VerifyRowChecksum(corrupt v2) = %v, %v, want CORRUPT", c, ok)
+	}
+	other, _ := ParseKey("t132_r2")
+	if c, _ := VerifyRowChecksum(other, value); c.Status != ChecksumCorrupt {
+		t.Errorf("VerifyRowChecksum(v2 under another key) = %v, want CORRUPT", c)
+	}
+	if c, _ := VerifyRowChecksum(key, value[:len(value)-2]); c.Status != ChecksumCorrupt {
+		t.Errorf("VerifyRowChecksum(cut checksum) = %v, want CORRUPT", c)
+	}
+
+	// version 1 sums the value and the whole key
+	value = withChecksum(t, rowHex, checksumVersionRawKey, key)
+	if c, ok := VerifyRowChecksum(key, value); !ok || c.Status != ChecksumOK {
+		t.Errorf("VerifyRowChecksum(v1) = %v, %v, want OK", c, ok)
+	}
+	if c, _ := VerifyRowChecksum(nil, value); c.Status != ChecksumUnverified {
+		t.Errorf("VerifyRowChecksum(v1 without the key) = %v, want UNVERIFIED", c)
+	}
+
+	// version 0 takes the column types
+	value = withChecksum(t, rowHex, checksumVersionColumn, nil)
+	if c, _ := VerifyRowChecksum(key, value); c.Status != ChecksumUnverified {
+		t.Errorf("VerifyRowChecksum(v0) = %v, want UNVERIFIED", c)
+	}
+
+	plain, _ := hex.DecodeString(rowHex)
+	if c, ok := VerifyRowChecksum(key, plain); ok {
+		t.Errorf("VerifyRowChecksum(without checksum) = %v, want none", c)
+	}
+}
@@ -0,0 +1,99 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pingcap/tidb/pkg/types"
+)
+
+// With the new collation framework of TiDB, the string values in index keys and common handles
+// are stored as the sort key of their collation instead of the string itself. Binary
+// collations keep the string (without the trailing spaces for PAD SPACE collations), but the
+// case-insensitive ones store weights which can't be turned back into the original string.
+// See pkg/util/collate of TiDB.
+
+// collationKind is how the sort keys of a collation are decoded.
+type collationKind int
+
+const (
+	collationBinary    collationKind = iota // the string itself
+	collationGeneralCI                      // a 2-byte weight per character, case and accent folded
+	collationOpaque                         // weights which can't be decoded
+)
+
+// DefaultCollation is the collation assumed for the strings of index keys.
+const DefaultCollation = "utf8mb4_bin"
+
+var collations = map[string]collationKind{
+	"binary":             collationBinary,
+	"utf8mb4_bin":        collationBinary,
+	"utf8_bin":           collationBinary,
+	"ascii_bin":          collationBinary,
+	"latin1_bin":         collationBinary,
+	"utf8mb4_0900_bin":   collationBinary,
+	"utf8mb4_general_ci": collationGeneralCI,
+	"utf8_general_ci":    collationGeneralCI,
+	"utf8mb4_unicode_ci": collationOpaque,
+	"utf8_unicode_ci":    collationOpaque,
+	"utf8mb4_0900_ai_ci": collationOpaque,
+	"gbk_bin":            collationOpaque, // GBK bytes, not UTF-8
+	"gbk_chinese_ci":     collationOpaque,
+	"gb18030_bin":        collationOpaque,
+	"gb18030_chinese_ci": collationOpaque,
+}
+
+// normalizeCollation returns the collation name as the keys of collations are.
+func normalizeCollation(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// keyDatumString renders a datum of an index key or a common handle. Strings are decoded by
+// the key collation; a sort key which can't be turned back into the string is marked instead.
+// With Redact, the strings are masked.
+func (o Options) keyDatumString(d types.Datum) string {
+	if !isStringDatum(d) {
+		s, _ := d.ToString()
+		return s
+	}
+	if o.Redact {
+		return redacted(d.GetBytes())
+	}
+
+	return collatedString(d.GetBytes(), o.keyCollation())
+}
+
+func collatedString(b []byte, collation string) string {
+	switch collations[collation] {
+	case collationBinary:
+		return string(b)
+	case collationGeneralCI:
+		if s, ok := decodeGeneralCIKey(b); ok {
+			return fmt.Sprintf("%s (%s, case-folded)", s, collation)
+		}
+	}
+
+	return fmt.Sprintf("<%s sort key 0x%x, not reversible>", collation, b)
+}
+
+// decodeGeneralCIKey turns the weights of a general_ci sort key back into characters. The
+// weight of a character is the code point of its folded form, e.g., 'A' for 'a' and 'á'.
+func decodeGeneralCIKey(b []byte) (string, bool) {
+	if len(b)%2 != 0 {
+		return "", false
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(b); i += 2 {
+		r := rune(binary.BigEndian.Uint16(b[i:]))
+		if !utf8.ValidRune(r) || !unicode.IsPrint(r) {
+			return "", false
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String(), true
+}
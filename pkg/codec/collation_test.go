@@ -0,0 +1,57 @@
+package codec
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+	"github.com/pingcap/tidb/pkg/util/collate"
+)
+
+// collatedIndexKey builds t1_i2 with the sort key of the string in the collation, like TiDB
+// with the new collation framework.
+func collatedIndexKey(t *testing.T, s, collation string) []byte {
+	t.Helper()
+	collate.SetNewCollationEnabledForTest(true)
+	t.Cleanup(func() { collate.SetNewCollationEnabledForTest(false) })
+
+	b := []byte{'t'}
+	b = tidbcodec.EncodeInt(b, 1)
+	b = append(b, "_i"...)
+	b = tidbcodec.EncodeInt(b, 2)
+	b, err := tidbcodec.EncodeKey(time.UTC, b, types.NewBytesDatum(collate.GetCollator(collation).Key(s)), types.NewIntDatum(5))
+	if err != nil {
+		t.Fatalf("EncodeKey() error = %v", err)
+	}
+
+	return b
+}
+
+func TestDecodeKeyCollation(t *testing.T) {
+	key := collatedIndexKey(t, "Apple Pie", "utf8mb4_general_ci")
+	if got, want := (Options{Collation: "utf8mb4_general_ci"}).DecodeKey(key), "t1_i2_APPLE PIE (utf8mb4_general_ci, case-folded)_5"; got != want {
+		t.Errorf("DecodeKey() = %s, want %s", got, want)
+	}
+
+	// the weights of unicode_ci can't be turned back into the string
+	key = collatedIndexKey(t, "Apple", "utf8mb4_unicode_ci")
+	if got := (Options{Collation: "utf8mb4_unicode_ci"}).DecodeKey(key); !strings.Contains(got, "<utf8mb4_unicode_ci sort key 0x") || !strings.HasSuffix(got, "not reversible>_5") {
+		t.Errorf("DecodeKey() = %s, want the sort key marked as not reversible", got)
+	}
+
+	// the binary collations keep the string, without the trailing spaces
+	key = collatedIndexKey(t, "Apple  ", "utf8mb4_bin")
+	opts := Options{Collation: "UTF8MB4_BIN"}
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got, want := opts.DecodeKey(key), "t1_i2_Apple_5"; got != want {
+		t.Errorf("DecodeKey() = %s, want %s", got, want)
+	}
+
+	if err := (Options{Collation: "utf8mb4_klingon_ci"}).Validate(); err == nil {
+		t.Error("Validate() with utf8mb4_klingon_ci error = nil, want error")
+	}
+}
@@ -0,0 +1,53 @@
+package codec
+
+import (
+	"bytes"
+	"cmp"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// CompareRowIDs compares two int handles of record keys.
+func CompareRowIDs(a, b int64) int {
+	return Options{}.CompareRowIDs(a, b)
+}
+
+// CompareRowIDs compares two int handles like CompareRowIDs does, as unsigned integers with
+// UnsignedHandles.
+func (o Options) CompareRowIDs(a, b int64) int {
+	if o.UnsignedHandles {
+		return cmp.Compare(uint64(a), uint64(b))
+	}
+	return cmp.Compare(a, b)
+}
+
+// CompareColumnValues compares the raw bytes of two RowV2 column values of the type: numbers
+// by value, times in time order, and the strings, the blobs and the JSON byte by byte, the
+// order of a binary collation. Without a type (""), e.g., without a schema, the values are
+// compared as integers when both have the size of one, else byte by byte. Bytes which don't
+// fit the type are compared byte by byte too.
+func CompareColumnValues(a, b []byte, typ ColumnType) int {
+	switch typ {
+	case ColumnInt, ColumnTime, "":
+		x, okA := decodeRowV2Int(a)
+		y, okB := decodeRowV2Int(b)
+		if okA && okB {
+			return cmp.Compare(x, y)
+		}
+	case ColumnUint, ColumnBit, ColumnEnum, ColumnSet, ColumnDatetime, ColumnTimestamp: // the packed times are in time order
+		x, okA := decodeRowV2Uint(a)
+		y, okB := decodeRowV2Uint(b)
+		if okA && okB {
+			return cmp.Compare(x, y)
+		}
+	case ColumnDecimal:
+		restA, x, _, _, errA := tidbcodec.DecodeDecimal(a)
+		restB, y, _, _, errB := tidbcodec.DecodeDecimal(b)
+		if errA == nil && errB == nil && len(restA) == 0 && len(restB) == 0 {
+			return x.Compare(y)
+		}
+	}
+
+	// a double is stored in its memcomparable form, so its bytes are in value order
+	return bytes.Compare(a, b)
+}
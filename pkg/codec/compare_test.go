@@ -0,0 +1,50 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+func TestCompareColumnValues(t *testing.T) {
+	decimal := func(s string) []byte {
+		b, err := tidbcodec.EncodeDecimal(nil, types.NewDecFromStringForTest(s), 10, 2)
+		if err != nil {
+			t.Fatalf("EncodeDecimal(%s) error = %v", s, err)
+		}
+		return b
+	}
+	double := func(f float64) []byte { return tidbcodec.EncodeFloat(nil, f) }
+
+	tests := []struct {
+		name string
+		a, b []byte
+		typ  ColumnType
+		want int
+	}{
+		{"signed ints of different sizes", []byte{0xff}, []byte{0x02, 0x01}, ColumnInt, -1}, // -1 < 258
+		{"ints without a schema", []byte{0x0a}, []byte{0x09, 0x00}, "", 1},
+		{"strings without a schema", []byte("apple"), []byte("banana"), "", -1},
+		{"unsigned ints", []byte{0xff}, []byte{0x01}, ColumnUint, 1},
+		{"decimals by value", decimal("9.50"), decimal("10.00"), ColumnDecimal, -1},
+		{"doubles by value", double(-2.5), double(1), ColumnDouble, -1},
+		{"strings byte by byte", []byte("b"), []byte("ab"), ColumnString, 1},
+		{"equal", []byte{0x01}, []byte{0x01}, ColumnInt, 0},
+	}
+
+	for _, tt := range tests {
+		if got := CompareColumnValues(tt.a, tt.b, tt.typ); got != tt.want {
+			t.Errorf("%s: CompareColumnValues(%x, %x) = %d, want %d", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCompareRowIDs(t *testing.T) {
+	if CompareRowIDs(-1, 1) != -1 {
+		t.Error("CompareRowIDs(-1, 1) should be -1")
+	}
+	if (Options{UnsignedHandles: true}).CompareRowIDs(-1, 1) != 1 {
+		t.Error("CompareRowIDs(-1, 1) should be 1 with unsigned handles, -1 being the largest")
+	}
+}
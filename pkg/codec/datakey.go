@@ -0,0 +1,41 @@
+package codec
+
+import (
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// dataPrefix starts the data keys of TiKV, the keys as its storage layer keeps them.
+const dataPrefix = 'z'
+
+// DataKey is a data key of TiKV as printed by pd-ctl, region errors and the TiKV logs: z, the
+// memcomparable form of the key, and the MVCC ts for the keys of the default and write CFs.
+type DataKey struct {
+	Key []byte // the key as TiDB wrote it
+	TS  uint64 // the MVCC ts, 0 without it
+}
+
+// DecodeDataKey decodes a data key. It returns false for the other keys, including the ones
+// starting with z which aren't memcomparable or have a suffix other than a ts.
+func DecodeDataKey(key []byte) (DataKey, bool) {
+	if len(key) == 0 || key[0] != dataPrefix {
+		return DataKey{}, false
+	}
+	rest, inner, err := tidbcodec.DecodeBytes(key[1:], nil)
+	if err != nil {
+		return DataKey{}, false
+	}
+
+	switch len(rest) {
+	case 0:
+		return DataKey{Key: inner}, true
+	case 8:
+		// TiKV appends the ts in descending order, so the newest version sorts first
+		_, ts, err := tidbcodec.DecodeUintDesc(rest)
+		if err != nil {
+			return DataKey{}, false
+		}
+		return DataKey{Key: inner, TS: ts}, true
+	default:
+		return DataKey{}, false
+	}
+}
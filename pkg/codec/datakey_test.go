@@ -0,0 +1,34 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+func TestDecodeDataKey(t *testing.T) {
+	raw, err := ParseKey("t132_r1")
+	if err != nil {
+		t.Fatalf("ParseKey(t132_r1) error = %v", err)
+	}
+	dataKey := tidbcodec.EncodeBytes([]byte{'z'}, raw)
+
+	// the key of the lock CF has no ts
+	got, ok := DecodeDataKey(dataKey)
+	if !ok || !bytes.Equal(got.Key, raw) || got.TS != 0 {
+		t.Errorf("DecodeDataKey(%X) = %X, %d, %v, want %X without a ts", dataKey, got.Key, got.TS, ok, raw)
+	}
+
+	withTS := tidbcodec.EncodeUintDesc(bytes.Clone(dataKey), 449460987526643717)
+	got, ok = DecodeDataKey(withTS)
+	if !ok || !bytes.Equal(got.Key, raw) || got.TS != 449460987526643717 {
+		t.Errorf("DecodeDataKey(%X) = %X, %d, %v, want %X at 449460987526643717", withTS, got.Key, got.TS, ok, raw)
+	}
+
+	for _, key := range [][]byte{raw, []byte("z"), append(bytes.Clone(dataKey), 1, 2), []byte("zabc")} {
+		if got, ok := DecodeDataKey(key); ok {
+			t.Errorf("DecodeDataKey(%X) = %X, want not a data key", key, got.Key)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/meta/model"
+)
+
+// SchemaVersionEntry is a readable summary of one schema change, built from either
+// a schema diff (Diff:<version>) or a DDL history job.
+type SchemaVersionEntry struct {
+	Version    int64  `json:"version"`
+	Type       string `json:"type"`
+	SchemaID   int64  `json:"schema_id"`
+	TableID    int64  `json:"table_id"`
+	JobID      int64  `json:"job_id,omitempty"`
+	State      string `json:"state,omitempty"`
+	SchemaName string `json:"schema_name,omitempty"`
+	TableName  string `json:"table_name,omitempty"`
+	Query      string `json:"query,omitempty"`
+	FinishedTS uint64 `json:"finished_ts,omitempty"`
+	RowCount   int64  `json:"row_count,omitempty"` // the rows processed by the job, e.g., backfilled by ADD INDEX
+	Error      string `json:"error,omitempty"`     // the error the job failed or was rolled back by
+}
+
+// ddlJob is the subset of model.Job we need to describe a schema change.
+// Ref: https://github.com/pingcap/tidb/blob/master/pkg/meta/model/job.go
+type ddlJob struct {
+	ID         int64            `json:"id"`
+	Type       model.ActionType `json:"type"`
+	SchemaID   int64            `json:"schema_id"`
+	TableID    int64            `json:"table_id"`
+	SchemaName string           `json:"schema_name"`
+	TableName  string           `json:"table_name"`
+	State      model.JobState   `json:"state"`
+	Query      string           `json:"query"`
+	RowCount   int64            `json:"row_count"`
+	Error      *struct {
+		Code int    `json:"code"`
+		Msg  string `json:"message"`
+	} `json:"err"`
+	BinlogInfo *struct {
+		SchemaVersion int64  `json:"SchemaVersion"`
+		FinishedTS    uint64 `json:"FinishedTS"`
+	} `json:"binlog"`
+}
+
+// schemaDiff is the subset of model.SchemaDiff we need to describe a schema change.
+type schemaDiff struct {
+	Version  int64            `json:"version"`
+	Type     model.ActionType `json:"type"`
+	SchemaID int64            `json:"schema_id"`
+	TableID  int64            `json:"table_id"`
+}
+
+// DecodeSchemaVersion decodes the value of the SchemaVersionKey meta string.
+func DecodeSchemaVersion(value []byte) (int64, error) {
+	v, err := strconv.ParseInt(strings.TrimSpace(string(value)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q: %v", value, err)
+	}
+
+	return v, nil
+}
+
+// DecodeDDLJob decodes a DDL job (JSON encoded model.Job) stored in the DDL history or queued
+// in a DDL job list.
+func DecodeDDLJob(value []byte) (SchemaVersionEntry, error) {
+	var job ddlJob
+	if err := json.Unmarshal(value, &job); err != nil {
+		return SchemaVersionEntry{}, fmt.Errorf("failed to decode DDL job: %v", err)
+	}
+
+	entry := SchemaVersionEntry{
+		Type:       job.Type.String(),
+		SchemaID:   job.SchemaID,
+		TableID:    job.TableID,
+		JobID:      job.ID,
+		State:      job.State.String(),
+		SchemaName: job.SchemaName,
+		TableName:  job.TableName,
+		Query:      job.Query,
+		RowCount:   job.RowCount,
+	}
+	if job.Error != nil {
+		entry.Error = fmt.Sprintf("[%d] %s", job.Error.Code, job.Error.Msg)
+	}
+	if job.BinlogInfo != nil {
+		entry.Version = job.BinlogInfo.SchemaVersion
+		entry.FinishedTS = job.BinlogInfo.FinishedTS
+	}
+
+	return entry, nil
+}
+
+// DecodeSchemaDiff decodes a schema diff (JSON encoded model.SchemaDiff) stored under Diff:<version>.
+func DecodeSchemaDiff(value []byte) (SchemaVersionEntry, error) {
+	var diff schemaDiff
+	if err := json.Unmarshal(value, &diff); err != nil {
+		return SchemaVersionEntry{}, fmt.Errorf("failed to decode schema diff: %v", err)
+	}
+
+	return SchemaVersionEntry{
+		Version:  diff.Version,
+		Type:     diff.Type.String(),
+		SchemaID: diff.SchemaID,
+		TableID:  diff.TableID,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package codec
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// DiffDecodedValues compares two decoded values and returns a line for each difference,
+// e.g., per column for RowV2 values. It returns nil when they are equal.
+func DiffDecodedValues(expected, actual DecodedValue) []string {
+	if expected.Type != actual.Type {
+		return []string{fmt.Sprintf("type: expected %s, actual %s", expected.Type, actual.Type)}
+	}
+
+	switch expected.Type {
+	case TypeRowV2, TypeRowV1:
+		return diffRowV2(expected.Payload.(RowV2Data), actual.Payload.(RowV2Data))
+	case TypeIndex:
+		e, a := expected.Payload.([]string), actual.Payload.([]string)
+		if slices.Equal(e, a) {
+			return nil
+		}
+		return []string{fmt.Sprintf("index values: expected [%s], actual [%s]", strings.Join(e, ", "), strings.Join(a, ", "))}
+	default:
+		if reflect.DeepEqual(expected.Payload, actual.Payload) {
+			return nil
+		}
+		return []string{fmt.Sprintf("value: expected %v, actual %v", expected.Payload, actual.Payload)}
+	}
+}
+
+func diffRowV2(expected, actual RowV2Data) []string {
+	ids := make([]int64, 0, len(expected.Columns))
+	for id := range expected.Columns {
+		ids = append(ids, id)
+	}
+	for id := range actual.Columns {
+		if _, ok := expected.Columns[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	var diffs []string
+	for _, id := range ids {
+		e, inExpected := expected.Columns[id]
+		a, inActual := actual.Columns[id]
+		switch {
+		case !inActual:
+			diffs = append(diffs, fmt.Sprintf("ColID %d: expected %s, actual <missing>", id, e))
+		case !inExpected:
+			diffs = append(diffs, fmt.Sprintf("ColID %d: expected <missing>, actual %s", id, a))
+		case e != a:
+			diffs = append(diffs, fmt.Sprintf("ColID %d: expected %s, actual %s", id, e, a))
+		}
+	}
+
+	return diffs
+}
@@ -0,0 +1,30 @@
+package codec
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestDiffDecodedValues(t *testing.T) {
+	// RowV2 with ColID 2: "Aaliyah Mueller", ColID 3: 1 (the self-check sample)
+	row, _ := hex.DecodeString("80000200000002030f00100041616c69796168204d75656c6c657201")
+	// the same columns with ColID 3: 2
+	changed, _ := hex.DecodeString("80000200000002030f00100041616c69796168204d75656c6c657202")
+
+	if diffs := DiffDecodedValues(DecodeValue(row), DecodeValue(row)); diffs != nil {
+		t.Errorf("DiffDecodedValues() of the same value = %v, want nil", diffs)
+	}
+
+	diffs := DiffDecodedValues(DecodeValue(row), DecodeValue(changed))
+	if len(diffs) != 1 {
+		t.Fatalf("DiffDecodedValues() = %v, want one column difference", diffs)
+	}
+	if got := diffs[0]; got[:8] != "ColID 3:" {
+		t.Errorf("DiffDecodedValues() = %s, want a difference of ColID 3", got)
+	}
+
+	diffs = DiffDecodedValues(DecodeValue(row), DecodeValue([]byte{0x01, 0x02}))
+	if len(diffs) != 1 || diffs[0] != "type: expected row_v2, actual raw" {
+		t.Errorf("DiffDecodedValues() = %v, want a type difference", diffs)
+	}
+}
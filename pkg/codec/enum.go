@@ -0,0 +1,105 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseColumnElems returns the labels of an ENUM or a SET column type such as
+// enum('active','inactive'), in order. The labels are quoted as in SQL, with the quote doubled
+// or escaped by a backslash inside them. A type without the list has no labels.
+func parseColumnElems(mysqlType string) ([]string, error) {
+	s := strings.TrimSpace(mysqlType)
+	start := strings.IndexByte(s, '(')
+	if start < 0 {
+		return nil, nil
+	}
+	end := strings.LastIndexByte(s, ')')
+	if end < start {
+		return nil, fmt.Errorf("invalid column type %q: the labels aren't closed by )", mysqlType)
+	}
+
+	var elems []string
+	for list := s[start+1 : end]; ; {
+		list = strings.TrimLeft(list, " \t\n")
+		if list == "" {
+			break
+		}
+		if len(elems) > 0 {
+			if list[0] != ',' {
+				return nil, fmt.Errorf("invalid column type %q: want a comma between the labels", mysqlType)
+			}
+			list = strings.TrimLeft(list[1:], " \t\n")
+		}
+
+		elem, rest, ok := cutQuoted(list)
+		if !ok {
+			return nil, fmt.Errorf("invalid column type %q: want a quoted label", mysqlType)
+		}
+		elems, list = append(elems, elem), rest
+	}
+
+	return elems, nil
+}
+
+// cutQuoted cuts the SQL string in single or double quotes at the start of s.
+func cutQuoted(s string) (string, string, bool) {
+	if s == "" || (s[0] != '\'' && s[0] != '"') {
+		return "", s, false
+	}
+
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case c == quote && i+1 < len(s) && s[i+1] == quote:
+			i++
+			b.WriteByte(quote)
+		case c == quote:
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", s, false
+}
+
+// columnLabel returns the labels of the raw bytes of an ENUM or a SET column with labels: the
+// label of the 1-based ordinal of an ENUM, "" for the 0 of an invalid value, and the labels of
+// the bits of a SET, joined by commas. It reports false for another column, or when the value
+// is beyond the labels.
+func columnLabel(b []byte, c ColumnSchema) (string, bool) {
+	if len(c.Elems) == 0 || (c.Type != ColumnEnum && c.Type != ColumnSet) {
+		return "", false
+	}
+	n, ok := decodeRowV2Uint(b)
+	if !ok {
+		return "", false
+	}
+
+	if c.Type == ColumnEnum {
+		if n > uint64(len(c.Elems)) {
+			return "", false
+		}
+		if n == 0 {
+			return "", true
+		}
+		return c.Elems[n-1], true
+	}
+
+	if len(c.Elems) < 64 && n>>len(c.Elems) != 0 {
+		return "", false
+	}
+	var labels []string
+	for i, e := range c.Elems {
+		if n&(1<<i) != 0 {
+			labels = append(labels, e)
+		}
+	}
+
+	return strings.Join(labels, ","), true
+}
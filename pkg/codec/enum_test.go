@@ -0,0 +1,92 @@
+package codec
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseColumnElems(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"enum('active','inactive')", []string{"active", "inactive"}},
+		{`SET( 'a' , "b,c", 'it''s', 'x\'y' )`, []string{"a", "b,c", "it's", "x'y"}},
+		{"enum", nil},
+	}
+	for _, tt := range tests {
+		got, err := parseColumnElems(tt.input)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("parseColumnElems(%s) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"enum('a'", "enum(a,b)", "enum('a' 'b')", "enum('a)"} {
+		if _, err := parseColumnElems(input); err == nil {
+			t.Errorf("parseColumnElems(%s) error = nil, want error", input)
+		}
+	}
+}
+
+func TestDecodeEnumSetWithSchema(t *testing.T) {
+	schemas, err := ParseSchemas([]byte(`{"132": {
+		"1": {"name": "id", "type": "bigint", "handle": true},
+		"2": {"name": "status", "type": "enum('active','inactive','banned')"},
+		"3": {"name": "tags", "type": "set('red','green','blue')"},
+		"4": {"name": "kind", "type": "enum"}
+	}}`))
+	if err != nil {
+		t.Fatalf("ParseSchemas() error = %v", err)
+	}
+	schema := schemas[132]
+
+	decode := func(cols map[int64][]byte) []string {
+		t.Helper()
+		decoded, err := DecodeRowWithSchema(encodeSmallRowV2(cols), schema)
+		if err != nil {
+			t.Fatalf("DecodeRowWithSchema() error = %v", err)
+		}
+		var got []string
+		for _, c := range decoded[1:] {
+			got = append(got, c.Value)
+		}
+		return got
+	}
+
+	// the labels of the ordinal and the bits, and the number without the labels
+	got := decode(map[int64][]byte{2: {0x03}, 3: {0x05}, 4: {0x02}})
+	if want := []string{`"banned"`, `"red,blue"`, "2"}; !slices.Equal(got, want) {
+		t.Errorf("DecodeRowWithSchema() = %v, want %v", got, want)
+	}
+
+	// the 0 of an invalid ENUM value and the empty SET
+	got = decode(map[int64][]byte{2: {0x00}, 3: {0x00}, 4: {0x01}})
+	if want := []string{`""`, `""`, "1"}; !slices.Equal(got, want) {
+		t.Errorf("DecodeRowWithSchema() = %v, want %v", got, want)
+	}
+
+	// values beyond the labels
+	got = decode(map[int64][]byte{2: {0x04}, 3: {0x08}, 4: {0x01}})
+	if want := []string{"4", "8", "1"}; !slices.Equal(got, want) {
+		t.Errorf("DecodeRowWithSchema() = %v, want %v", got, want)
+	}
+
+	key, err := ParseKey("t132_r7")
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	values, err := DecodeRowValues(key, encodeSmallRowV2(map[int64][]byte{2: {0x01}, 3: {0x06}, 4: {0x02}}), schema)
+	if err != nil {
+		t.Fatalf("DecodeRowValues() error = %v", err)
+	}
+	var texts []string
+	for _, v := range values {
+		texts = append(texts, v.Text)
+	}
+	if want := []string{"7", "active", "green,blue", "2"}; !slices.Equal(texts, want) {
+		t.Errorf("DecodeRowValues() = %v, want %v", texts, want)
+	}
+	if _, err := DecodeRowValues(key, encodeSmallRowV2(map[int64][]byte{2: {0x09}}), schema); err == nil {
+		t.Error("DecodeRowValues() error = nil, want error for an ENUM value beyond the labels")
+	}
+}
@@ -0,0 +1,28 @@
+package codec
+
+// TableGroup is a run of consecutive keys of the same table in a scan result.
+type TableGroup struct {
+	TableID int64 // 0 for keys which are not table keys
+	Start   int   // index of the first key of the group
+	Count   int
+}
+
+// GroupByTable splits keys in key order into groups by their table ID. Since the keys of a
+// table are contiguous in key order, a new group starts whenever the table ID changes.
+func GroupByTable(keys [][]byte) []TableGroup {
+	var groups []TableGroup
+	for i, key := range keys {
+		var tableID int64
+		if parts, err := ParseKeyParts(key); err == nil {
+			tableID = parts.TableID
+		}
+
+		if n := len(groups); n > 0 && groups[n-1].TableID == tableID {
+			groups[n-1].Count++
+			continue
+		}
+		groups = append(groups, TableGroup{TableID: tableID, Start: i, Count: 1})
+	}
+
+	return groups
+}
@@ -0,0 +1,34 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupByTable(t *testing.T) {
+	var keys [][]byte
+	for _, k := range []string{
+		"t1_r1", "t1_r2", "t1_i1_abc_1",
+		"t5_r10",
+		"t132_r1", "t132_r2",
+	} {
+		raw, err := ParseKey(k)
+		if err != nil {
+			t.Fatalf("ParseKey(%s) error = %v", k, err)
+		}
+		keys = append(keys, raw)
+	}
+
+	expected := []TableGroup{
+		{TableID: 1, Start: 0, Count: 3},
+		{TableID: 5, Start: 3, Count: 1},
+		{TableID: 132, Start: 4, Count: 2},
+	}
+	if got := GroupByTable(keys); !reflect.DeepEqual(got, expected) {
+		t.Errorf("GroupByTable() = %+v, want %+v", got, expected)
+	}
+
+	if got := GroupByTable(nil); len(got) != 0 {
+		t.Errorf("GroupByTable(nil) = %+v, want no groups", got)
+	}
+}
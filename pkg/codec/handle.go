@@ -0,0 +1,321 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// Flags in the index value. See https://github.com/pingcap/tidb/blob/master/pkg/tablecodec/tablecodec.go
+const (
+	indexCommonHandleFlag byte = 127
+	indexPartitionIDFlag  byte = 126
+	indexVersionFlag      byte = 125
+	indexRestoredDataFlag byte = 0x80 // the restored data is a RowV2 row
+	indexUntouchedFlag    byte = '1'  // the last byte of an index value the transaction didn't change
+	maxOldIndexValueLen        = 9
+)
+
+// IndexHandle is the row an index entry points to.
+type IndexHandle struct {
+	TableID   int64  // physical table ID of the row, the partition ID for a global index
+	Handle    string // int handle or the clustered primary key values, e.g., {apple, 5}
+	RecordKey []byte
+}
+
+// indexValueHandle is the handle part of an index value.
+type indexValueHandle struct {
+	intHandle    []byte
+	commonHandle []byte
+	partitionID  []byte
+	restoredData []byte // the RowV2 row of the original values of new-collation strings
+	options      bool   // the value is of the new format with a version, handle, partition or restored segment
+	untouched    bool
+}
+
+// IsIndexKey reports whether the key is an index key (t{TableID}_i...).
+func IsIndexKey(key []byte) bool {
+	return len(key) >= 19 && key[0] == 't' && bytes.Equal(key[9:11], []byte(separator+"i"))
+}
+
+// ResolveIndexHandle returns the row which the index entry (key and value) points to.
+//
+// Without the table schema, the number of indexed columns is unknown, so the handle is
+// looked up in the value first (unique indexes and clustered primary keys store it there),
+// and otherwise the last datum of the key is taken as the int handle (non-unique indexes).
+// A non-unique index of a clustered table with a common handle can't be resolved this way.
+func ResolveIndexHandle(key, value []byte) (IndexHandle, error) {
+	return Options{}.ResolveIndexHandle(key, value)
+}
+
+// ResolveIndexHandle returns the row of an index entry like ResolveIndexHandle does, with the
+// handle printed by the Options.
+func (o Options) ResolveIndexHandle(key, value []byte) (IndexHandle, error) {
+	if !IsIndexKey(key) {
+		return IndexHandle{}, fmt.Errorf("key %X is not an index key", key)
+	}
+	_, tableID, err := tidbcodec.DecodeInt(key[1:9])
+	if err != nil {
+		return IndexHandle{}, fmt.Errorf("failed to decode table ID of %X: %v", key, err)
+	}
+
+	h, err := splitIndexValue(value)
+	if err != nil {
+		return IndexHandle{}, err
+	}
+
+	if len(h.partitionID) > 0 {
+		if _, tableID, err = tidbcodec.DecodeInt(h.partitionID); err != nil {
+			return IndexHandle{}, fmt.Errorf("failed to decode partition ID %X: %v", h.partitionID, err)
+		}
+	}
+
+	var handle []byte // memcomparable handle, the suffix of the record key
+	var handleStr string
+	switch {
+	case len(h.commonHandle) > 0:
+		datums, err := tidbcodec.Decode(h.commonHandle, 2)
+		if err != nil {
+			return IndexHandle{}, fmt.Errorf("failed to decode common handle %X: %v", h.commonHandle, err)
+		}
+		handle, handleStr = h.commonHandle, o.datumsString(datums)
+	case len(h.intHandle) >= 8: // a shorter one is the flag of a non-unique index, e.g., "0"
+		rowID := int64(binary.BigEndian.Uint64(h.intHandle))
+		handle, handleStr = tidbcodec.EncodeInt(nil, rowID), o.formatHandle(rowID)
+	default: // t{TableID}_i{IndexID} is 19 bytes, followed by the index values
+		rowID, err := intHandleInIndexKey(key[19:])
+		if err != nil {
+			return IndexHandle{}, fmt.Errorf("failed to resolve the handle of %X: %w", key, err)
+		}
+		handle, handleStr = tidbcodec.EncodeInt(nil, rowID), o.formatHandle(rowID)
+	}
+
+	recordKey := []byte{'t'}
+	recordKey = tidbcodec.EncodeInt(recordKey, tableID)
+	recordKey = append(recordKey, []byte(separator+"r")...)
+	recordKey = append(recordKey, handle...)
+
+	return IndexHandle{TableID: tableID, Handle: handleStr, RecordKey: recordKey}, nil
+}
+
+// IndexPartitionID returns the partition ID in the value of a global index entry of a
+// partitioned table, which is the physical table ID of the row the entry points to. The key
+// holds the ID of the partitioned table itself. It returns false for other entries.
+func IndexPartitionID(key, value []byte) (int64, bool) {
+	if !IsIndexKey(key) {
+		return 0, false
+	}
+	h, err := splitIndexValue(value)
+	if err != nil || len(h.partitionID) == 0 {
+		return 0, false
+	}
+	_, id, err := tidbcodec.DecodeInt(h.partitionID)
+	if err != nil {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// splitIndexValue parses both the old and the new index value formats into their segments. The
+// new format is the tail length, the options and the tail: the options are the version, the
+// common handle, the partition ID and the restored data, each optional but in this order, and
+// the tail is the padding, the int handle of a unique index and the untouched flag. A value
+// with bytes of none of them is an error.
+// Ref: https://github.com/pingcap/tidb/blob/master/pkg/tablecodec/tablecodec.go (GenIndexValuePortal)
+func splitIndexValue(value []byte) (indexValueHandle, error) {
+	h := indexValueHandle{untouched: isUntouchedIndexValue(value)}
+	if len(value) <= maxOldIndexValueLen { // old format, the value is the int handle or a flag
+		h.intHandle = value
+		return h, nil
+	}
+
+	tailLen := int(value[0])
+	if tailLen >= len(value) {
+		return h, fmt.Errorf("invalid index value %X: tail length %d", value, tailLen)
+	}
+	body, tail := value[1:len(value)-tailLen], value[len(value)-tailLen:]
+	if (tailLen == 0 || tailLen == 1) && len(body) >= 2 && body[0] == indexVersionFlag {
+		h.options, body = true, body[2:] // skip the version flag and the version
+	} else if len(tail) >= 8 {
+		h.intHandle = tail[:8]
+	}
+
+	if len(body) > 0 && body[0] == indexCommonHandleFlag {
+		if len(body) < 3 {
+			return h, fmt.Errorf("invalid index value %X: truncated common handle", value)
+		}
+		end := 3 + int(binary.BigEndian.Uint16(body[1:3]))
+		if end > len(body) {
+			return h, fmt.Errorf("invalid index value %X: truncated common handle", value)
+		}
+		h.commonHandle, h.options, body = body[3:end], true, body[end:]
+	}
+
+	if len(body) > 0 && body[0] == indexPartitionIDFlag {
+		if len(body) < 9 {
+			return h, fmt.Errorf("invalid index value %X: truncated partition ID", value)
+		}
+		h.partitionID, h.options, body = body[1:9], true, body[9:]
+	}
+
+	if len(body) == 0 {
+		return h, nil
+	}
+	if body[0] != indexRestoredDataFlag || len(body) < 6 {
+		return h, fmt.Errorf("invalid index value %X: unknown segment %X", value, body)
+	}
+	if end, err := rowV2DataEnd(body); err != nil || end != len(body) {
+		return h, fmt.Errorf("invalid index value %X: restored data %X isn't a row", value, body)
+	}
+	if _, err := parseRowV2Structure(body); err != nil {
+		return h, fmt.Errorf("invalid index value %X: restored data: %v", value, err)
+	}
+	h.restoredData, h.options = body, true
+
+	return h, nil
+}
+
+// IndexValueData is an index value without restored data: the handle of the row, which is only
+// in the value for a unique index or a clustered table, the partition of a global index, and
+// the untouched flag.
+type IndexValueData struct {
+	Handle      string `json:"handle,omitempty"`       // int handle or the clustered primary key values, "" when it's in the key
+	PartitionID int64  `json:"partition_id,omitempty"` // the physical table of the row, for a global index
+	// Untouched marks the entry written by a pessimistic transaction to lock a unique key it
+	// didn't change, which TiDB ignores when it reads the index.
+	Untouched bool `json:"untouched"`
+}
+
+// String returns the handle and the flags, e.g., handle 7, partition 200, untouched, or the
+// placeholder of a non-unique index whose handle is in the key.
+func (d IndexValueData) String() string {
+	s := "handle " + d.Handle
+	if d.Handle == "" {
+		s = "no handle (in the key)"
+	}
+	if d.PartitionID != 0 {
+		s += fmt.Sprintf(", partition %d", d.PartitionID)
+	}
+	if d.Untouched {
+		s += ", untouched"
+	}
+
+	return s
+}
+
+// decodeIndexValue decodes an index value by its layout, see splitIndexValue, so the handle and
+// the flags aren't scraped as memcomparable values. The restored data is decoded as a RowV2
+// row. It reports false for a value which isn't an index value.
+func (o Options) decodeIndexValue(value []byte) (DecodedValue, bool) {
+	h, err := splitIndexValue(value)
+	if err != nil {
+		return DecodedValue{}, false
+	}
+	if h.restoredData != nil {
+		row := o.decodeRowV2(h.restoredData)
+		row.Untouched = h.untouched
+		return DecodedValue{Type: TypeRowV2, Payload: row}, true
+	}
+
+	data := IndexValueData{Untouched: h.untouched}
+	// the old format is a placeholder of a non-unique index or the int handle, then the flag
+	if len(value) <= maxOldIndexValueLen {
+		switch {
+		case len(value) == 1 && (value[0] == '0' || h.untouched): // the placeholder of a non-unique index
+		case len(value) == 8 || len(value) == 9 && h.untouched:
+			data.Handle = o.formatHandle(int64(binary.BigEndian.Uint64(value)))
+		default:
+			return DecodedValue{}, false
+		}
+		return DecodedValue{Type: TypeIndexValue, Payload: data}, true
+	}
+
+	switch {
+	case len(h.commonHandle) > 0:
+		datums, err := tidbcodec.Decode(h.commonHandle, 2)
+		if err != nil {
+			return DecodedValue{}, false
+		}
+		data.Handle = o.datumsString(datums)
+	case len(h.intHandle) >= 8:
+		data.Handle = o.formatHandle(int64(binary.BigEndian.Uint64(h.intHandle)))
+	}
+	if len(h.partitionID) > 0 {
+		if _, data.PartitionID, err = tidbcodec.DecodeInt(h.partitionID); err != nil {
+			return DecodedValue{}, false
+		}
+	}
+
+	return DecodedValue{Type: TypeIndexValue, Payload: data}, true
+}
+
+// isUntouchedIndexValue reports whether an index value has the untouched flag, as TiDB tells:
+// the last byte of an old one, in the tail of a new one of a non-unique index, or the tail of a
+// unique one which is the int handle and the flag.
+func isUntouchedIndexValue(value []byte) bool {
+	if len(value) == 0 {
+		return false
+	}
+	if len(value) <= maxOldIndexValueLen {
+		return (len(value) == 1 || len(value) == 9) && value[len(value)-1] == indexUntouchedFlag
+	}
+
+	tailLen := int(value[0])
+	if tailLen < 8 { // non-unique index
+		return tailLen >= 1 && value[len(value)-1] == indexUntouchedFlag
+	}
+	return tailLen == 9
+}
+
+// indexRestoredData returns the restored data of an index value: for the strings of a new
+// collation, such as utf8mb4_general_ci, whose index key holds their sort keys, the original
+// values as a RowV2 row keyed by column ID. A string of a _bin collation is restored from its
+// key, so the row holds the number of its trailing spaces instead.
+func indexRestoredData(value []byte) ([]byte, bool) {
+	h, err := splitIndexValue(value)
+	if err != nil || h.restoredData == nil {
+		return nil, false
+	}
+
+	return h.restoredData, true
+}
+
+func intHandleInIndexKey(values []byte) (int64, error) {
+	datums, err := tidbcodec.Decode(values, 4)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode index values: %v", err)
+	}
+
+	// the handle follows at least one indexed column value
+	if len(datums) < 2 || datums[len(datums)-1].Kind() != types.KindInt64 {
+		return 0, fmt.Errorf("no int handle at the end of the index key (non-unique index of a clustered table?)")
+	}
+
+	return datums[len(datums)-1].GetInt64(), nil
+}
+
+// datumsString prints the values of a common handle in braces. The values of a primary key of
+// several columns are labeled in the order of the columns, e.g., {h1: apple, h2: 5}.
+func (o Options) datumsString(datums []types.Datum) string {
+	strs := make([]string, 0, len(datums))
+	for i, d := range datums {
+		if len(datums) > 1 {
+			strs = append(strs, handleLabel(i)+o.keyDatumString(d))
+			continue
+		}
+		strs = append(strs, o.keyDatumString(d))
+	}
+
+	return "{" + strings.Join(strs, ", ") + "}"
+}
+
+// handleLabel returns the label of the i-th value of a common handle, e.g., "h1: ".
+func handleLabel(i int) string {
+	return "h" + strconv.Itoa(i+1) + ": "
+}
@@ -0,0 +1,279 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+func TestResolveIndexHandle(t *testing.T) {
+	mustKey := func(spec KeySpec) []byte {
+		t.Helper()
+		k, err := spec.Encode()
+		if err != nil {
+			t.Fatalf("Encode(%+v) error = %v", spec, err)
+		}
+		return k
+	}
+	recordKey := func(tableID, rowID int64) []byte {
+		return mustKey(KeySpec{TableID: tableID, Kind: KindRecord, RowID: rowID})
+	}
+
+	// unique index: the int handle is the value
+	uniqueValue := binary.BigEndian.AppendUint64(nil, 42)
+
+	// clustered table with a varchar primary key: the common handle is in the value
+	commonHandle, err := tidbcodec.EncodeKey(time.UTC, nil, types.NewStringDatum("apple"))
+	if err != nil {
+		t.Fatalf("EncodeKey() error = %v", err)
+	}
+	commonValue := []byte{0x00, 127, 0x00, byte(len(commonHandle))}
+	commonValue = append(commonValue, commonHandle...)
+
+	// global index of a partitioned table: the partition ID follows the handle in the value
+	partitionValue := []byte{0x08, 126}
+	partitionValue = tidbcodec.EncodeInt(partitionValue, 200)
+	partitionValue = binary.BigEndian.AppendUint64(partitionValue, 7)
+
+	tests := []struct {
+		name     string
+		key      []byte
+		value    []byte
+		expected IndexHandle
+	}{
+		{
+			name:  "Non-unique index, handle in the key",
+			key:   mustKey(KeySpec{TableID: 1, Kind: KindIndex, IndexID: 2, Components: []KeyComponent{{ComponentString, "apple"}, {ComponentInt, "5"}}}),
+			value: []byte("0"),
+			expected: IndexHandle{
+				TableID: 1, Handle: "5", RecordKey: recordKey(1, 5),
+			},
+		},
+		{
+			name:  "Unique index, handle in the value",
+			key:   mustKey(KeySpec{TableID: 1, Kind: KindIndex, IndexID: 3, Components: []KeyComponent{{ComponentString, "apple"}}}),
+			value: uniqueValue,
+			expected: IndexHandle{
+				TableID: 1, Handle: "42", RecordKey: recordKey(1, 42),
+			},
+		},
+		{
+			name:  "Clustered common handle",
+			key:   mustKey(KeySpec{TableID: 10, Kind: KindIndex, IndexID: 2, Components: []KeyComponent{{ComponentInt, "3"}}}),
+			value: commonValue,
+			expected: IndexHandle{
+				TableID: 10, Handle: "{apple}", RecordKey: append(mustKey(KeySpec{TableID: 10, Kind: KindRecord})[:11], commonHandle...),
+			},
+		},
+		{
+			name:  "Global index with partition ID",
+			key:   mustKey(KeySpec{TableID: 100, Kind: KindIndex, IndexID: 1, Components: []KeyComponent{{ComponentInt, "9"}}}),
+			value: partitionValue,
+			expected: IndexHandle{
+				TableID: 200, Handle: "7", RecordKey: recordKey(200, 7),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveIndexHandle(tt.key, tt.value)
+			if err != nil {
+				t.Fatalf("ResolveIndexHandle() error = %v", err)
+			}
+			if got.TableID != tt.expected.TableID || got.Handle != tt.expected.Handle {
+				t.Errorf("ResolveIndexHandle() = %+v, want %+v", got, tt.expected)
+			}
+			if !bytes.Equal(got.RecordKey, tt.expected.RecordKey) {
+				t.Errorf("ResolveIndexHandle() record key = %X, want %X", got.RecordKey, tt.expected.RecordKey)
+			}
+		})
+	}
+}
+
+func TestResolveIndexHandleInvalid(t *testing.T) {
+	record, _ := ParseKey("t1_r5")
+	indexOnly, _ := KeySpec{TableID: 1, Kind: KindIndex, IndexID: 2, Components: []KeyComponent{{ComponentString, "apple"}}}.Encode()
+
+	invalid := []struct {
+		name  string
+		key   []byte
+		value []byte
+	}{
+		{"Record key", record, []byte("0")},
+		{"No handle in the key or value", indexOnly, []byte("0")},
+		{"Not a table key", []byte("mDDLJobHistory"), nil},
+	}
+
+	for _, tt := range invalid {
+		if _, err := ResolveIndexHandle(tt.key, tt.value); err == nil {
+			t.Errorf("ResolveIndexHandle(%s) error = nil, want error", tt.name)
+		}
+	}
+}
+
+func TestIndexPartitionID(t *testing.T) {
+	key, err := ParseKey("t100_i1_9")
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	global := []byte{0x08, indexPartitionIDFlag}
+	global = tidbcodec.EncodeInt(global, 200)
+	global = binary.BigEndian.AppendUint64(global, 7)
+
+	if id, ok := IndexPartitionID(key, global); !ok || id != 200 {
+		t.Errorf("IndexPartitionID() = %d, %v, want 200", id, ok)
+	}
+	// the partition ID follows the version flag of a clustered table
+	clustered := []byte{0x00, indexVersionFlag, 0x01, indexPartitionIDFlag}
+	clustered = tidbcodec.EncodeInt(clustered, 201)
+	if id, ok := IndexPartitionID(key, clustered); !ok || id != 201 {
+		t.Errorf("IndexPartitionID() = %d, %v, want 201", id, ok)
+	}
+
+	record, _ := ParseKey("t100_r7")
+	for name, kv := range map[string][2][]byte{
+		"unique index": {key, binary.BigEndian.AppendUint64(nil, 7)},
+		"record":       {record, global},
+		"truncated":    {key, global[:6]},
+		"non-unique":   {key, []byte("0")},
+	} {
+		if id, ok := IndexPartitionID(kv[0], kv[1]); ok {
+			t.Errorf("%s: IndexPartitionID() = %d, want false", name, id)
+		}
+	}
+}
+
+func TestDecodeIndexValue(t *testing.T) {
+	key, err := ParseKey("t10_i2_3")
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	commonHandle, err := tidbcodec.EncodeKey(time.UTC, nil, types.NewStringDatum("apple"))
+	if err != nil {
+		t.Fatalf("EncodeKey() error = %v", err)
+	}
+	clustered := append([]byte{0x01, indexCommonHandleFlag, 0x00, byte(len(commonHandle))}, commonHandle...)
+
+	tests := map[string]struct {
+		value []byte
+		want  IndexValueData
+	}{
+		"non-unique":           {[]byte("0"), IndexValueData{}},
+		"non-unique untouched": {[]byte("1"), IndexValueData{Untouched: true}},
+		"unique":               {binary.BigEndian.AppendUint64(nil, 7), IndexValueData{Handle: "7"}},
+		"unique untouched":     {append(binary.BigEndian.AppendUint64(nil, 7), '1'), IndexValueData{Handle: "7", Untouched: true}},
+		"clustered untouched":  {append(slices.Clone(clustered), '1'), IndexValueData{Handle: "{apple}", Untouched: true}},
+		"clustered":            {append([]byte{0x00}, clustered[1:]...), IndexValueData{Handle: "{apple}"}},
+	}
+	for name, tt := range tests {
+		v := DecodeValueForKey(key, tt.value)
+		if got, ok := v.Payload.(IndexValueData); v.Type != TypeIndexValue || !ok || got != tt.want {
+			t.Errorf("%s: DecodeValueForKey(%X) = %+v, want %+v", name, tt.value, v, tt.want)
+		}
+	}
+	if got, want := DecodeValueForKey(key, []byte("1")).String(), "no handle (in the key), untouched"; got != want {
+		t.Errorf("DecodeValueForKey() = %s, want %s", got, want)
+	}
+
+	// the restored data keeps the flag
+	restored := append([]byte{0x01}, encodeSmallRowV2(map[int64][]byte{2: []byte("Apple")})...)
+	v := DecodeValueForKey(key, append(restored, '1'))
+	if row, ok := v.Payload.(RowV2Data); v.Type != TypeRowV2 || !ok || !row.Untouched || row.Columns[2] != `"Apple"` {
+		t.Errorf("DecodeValueForKey() = %+v, want the untouched restored data", v)
+	}
+
+	// the values of other keys, and the ones which aren't index values, are decoded as usual
+	record, _ := ParseKey("t10_r1")
+	for _, kv := range [][2][]byte{{record, []byte("0")}, {key, []byte("abc")}} {
+		if got, want := DecodeValueForKey(kv[0], kv[1]), DecodeValue(kv[1]); !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeValueForKey(%X) = %+v, want %+v", kv[1], got, want)
+		}
+	}
+}
+
+func TestSplitIndexValue(t *testing.T) {
+	commonHandle, err := tidbcodec.EncodeKey(time.UTC, nil, types.NewStringDatum("apple"))
+	if err != nil {
+		t.Fatalf("EncodeKey() error = %v", err)
+	}
+	clustered := []byte{0x00, indexVersionFlag, 0x01, indexCommonHandleFlag, 0x00, byte(len(commonHandle))}
+	clustered = append(clustered, commonHandle...)
+	global := tidbcodec.EncodeInt([]byte{0x08, indexPartitionIDFlag}, 200)
+	global = binary.BigEndian.AppendUint64(global, 7)
+	clusteredGlobal := tidbcodec.EncodeInt(append(slices.Clone(clustered), indexPartitionIDFlag), 201)
+
+	// the new format is told by its layout without the key
+	for value, want := range map[string]IndexValueData{
+		string(clustered):       {Handle: "{apple}"},
+		string(global):          {Handle: "7", PartitionID: 200},
+		string(clusteredGlobal): {Handle: "{apple}", PartitionID: 201},
+	} {
+		v := DecodeValue([]byte(value))
+		if got, ok := v.Payload.(IndexValueData); v.Type != TypeIndexValue || !ok || got != want {
+			t.Errorf("DecodeValue(%X) = %+v, want %+v", value, v, want)
+		}
+	}
+
+	for name, value := range map[string][]byte{
+		"tail longer than the value": {0x20, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09},
+		"truncated common handle":    clustered[:len(clustered)-1],
+		"truncated partition ID":     append([]byte{0x08, indexPartitionIDFlag, 0x80, 0x00}, global[10:]...),
+		"unknown segment":            append(slices.Clone(clustered), 0x55),
+	} {
+		if h, err := splitIndexValue(value); err == nil {
+			t.Errorf("%s: splitIndexValue(%X) = %+v, want error", name, value, h)
+		}
+		if v := DecodeValue(value); v.Type == TypeIndexValue {
+			t.Errorf("%s: DecodeValue(%X) = %+v, want the heuristic", name, value, v)
+		}
+	}
+}
+
+func TestDecodeIndexRestoredData(t *testing.T) {
+	restored := encodeSmallRowV2(map[int64][]byte{2: []byte("Apple"), 3: {0x02}})
+	commonHandle, err := tidbcodec.EncodeKey(time.UTC, nil, types.NewStringDatum("k1"))
+	if err != nil {
+		t.Fatalf("EncodeKey() error = %v", err)
+	}
+
+	nonUnique := append([]byte{0x00}, restored...)
+	unique := append([]byte{0x08}, restored...) // the int handle follows the restored data
+	unique = binary.BigEndian.AppendUint64(unique, 42)
+	clustered := []byte{0x00, indexVersionFlag, 0x01, indexCommonHandleFlag, 0x00, byte(len(commonHandle))}
+	clustered = append(append(clustered, commonHandle...), restored...)
+	global := []byte{0x08, indexPartitionIDFlag}
+	global = append(tidbcodec.EncodeInt(global, 200), restored...)
+	global = binary.BigEndian.AppendUint64(global, 7)
+
+	for name, value := range map[string][]byte{"non-unique": nonUnique, "unique": unique, "clustered": clustered, "global": global} {
+		v := DecodeValue(value)
+		row, ok := v.Payload.(RowV2Data)
+		if v.Type != TypeRowV2 || !ok || row.Columns[2] != `"Apple"` || row.Columns[3] != "Int: 2 (Hex: 0x02)" {
+			t.Errorf("%s: DecodeValue() = %+v, want the restored columns", name, v)
+		}
+		if cols, err := RowV2RawColumns(value); err != nil || string(cols[2]) != "Apple" {
+			t.Errorf("%s: RowV2RawColumns() = %q, %v, want the restored columns", name, cols, err)
+		}
+	}
+
+	// the handle is still read from the value
+	key, err := ParseKey("t1_i2_apple")
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	if h, err := ResolveIndexHandle(key, unique); err != nil || h.Handle != "42" {
+		t.Errorf("ResolveIndexHandle() = %+v, %v, want handle 42", h, err)
+	}
+
+	// bytes after the restored data aren't taken for it
+	if v := DecodeValue(append(slices.Clone(nonUnique), 0xff)); v.Type == TypeRowV2 {
+		t.Errorf("DecodeValue() = %+v, want no restored data", v)
+	}
+}
@@ -0,0 +1,59 @@
+package codec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// previewBytes is the number of bytes shown from each end of a value in its hex preview.
+	previewBytes = 8
+
+	// DefaultMaxHexBytes is the number of bytes of a value printed as hex at most by default.
+	DefaultMaxHexBytes = 1 << 20
+)
+
+// previewHex returns the hex of a column value which can't be decoded. Longer values show
+// the first and the last bytes with the length, e.g., 0x0102030405060708...f1f2f3f4f5f6f7f8
+// (len=40), so the same corruption looks the same, or all of them with FullHex.
+func (o Options) previewHex(b []byte) string {
+	if len(b) <= 2*previewBytes {
+		return fmt.Sprintf("0x%x", b)
+	}
+	if o.FullHex {
+		return fmt.Sprintf("0x%s (len=%d)", o.cappedHex(b), len(b))
+	}
+
+	return fmt.Sprintf("0x%x...%x (len=%d)", b[:previewBytes], b[len(b)-previewBytes:], len(b))
+}
+
+// cappedHex returns the hex of b, cut after MaxHexBytes bytes and marked with "...".
+func (o Options) cappedHex(b []byte) string {
+	n := o.maxHexBytes()
+	if len(b) <= n {
+		return hex.EncodeToString(b)
+	}
+
+	return hex.EncodeToString(b[:n]) + "..."
+}
+
+// Hexdump formats b like `xxd` or `hexdump -C`: the offset, 16 bytes in hex, and the
+// printable ASCII characters of them. Every line starts with indent.
+func Hexdump(b []byte, indent string) string {
+	if len(b) == 0 {
+		return indent + "<empty>\n"
+	}
+
+	lines := strings.SplitAfter(hex.Dump(b), "\n")
+	var sb strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		sb.WriteString(indent)
+		sb.WriteString(line)
+	}
+
+	return sb.String()
+}
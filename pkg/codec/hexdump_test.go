@@ -0,0 +1,19 @@
+package codec
+
+import "testing"
+
+func TestHexdump(t *testing.T) {
+	// 18 bytes: a full line and a partial one
+	input := []byte("Aliyah Mueller\x00\x01\xff\x80")
+	expected := "" +
+		"  00000000  41 6c 69 79 61 68 20 4d  75 65 6c 6c 65 72 00 01  |Aliyah Mueller..|\n" +
+		"  00000010  ff 80                                             |..|\n"
+
+	if got := Hexdump(input, "  "); got != expected {
+		t.Errorf("Hexdump() =\n%s\nwant\n%s", got, expected)
+	}
+
+	if got := Hexdump(nil, ""); got != "<empty>\n" {
+		t.Errorf("Hexdump(nil) = %q, want <empty>", got)
+	}
+}
@@ -0,0 +1,54 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseColumnTypeHints parses the hints of column types like 4=datetime, a column ID and a
+// MySQL column type each.
+func ParseColumnTypeHints(specs []string) (map[int64]ColumnType, error) {
+	hints := make(map[int64]ColumnType, len(specs))
+	for _, spec := range specs {
+		id, mysqlType, err := parseColumnTypeHint(spec)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := ParseColumnType(mysqlType)
+		if err != nil {
+			return nil, err
+		}
+		hints[id] = typ
+	}
+
+	return hints, nil
+}
+
+// ColumnTypeHintNames returns the MySQL type names of the hints of column types by column ID,
+// e.g., DATETIME for 4=datetime, see ColumnTypeName. It skips the hints which aren't valid;
+// ParseColumnTypeHints reports them.
+func ColumnTypeHintNames(specs []string) map[int64]string {
+	names := make(map[int64]string, len(specs))
+	for _, spec := range specs {
+		if id, mysqlType, err := parseColumnTypeHint(spec); err == nil {
+			names[id] = ColumnTypeName(mysqlType)
+		}
+	}
+
+	return names
+}
+
+// parseColumnTypeHint splits a hint of a column type into the column ID and the MySQL type.
+func parseColumnTypeHint(spec string) (int64, string, error) {
+	idText, mysqlType, ok := strings.Cut(spec, "=")
+	if !ok {
+		return 0, "", fmt.Errorf("invalid column type %q: must be like 4=datetime", spec)
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(idText), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", fmt.Errorf("invalid column ID in %q", spec)
+	}
+
+	return id, mysqlType, nil
+}
@@ -0,0 +1,44 @@
+package codec
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+	"github.com/pingcap/tidb/pkg/types"
+)
+
+func TestColumnTypeHints(t *testing.T) {
+	hints, err := ParseColumnTypeHints([]string{"4=datetime", " 5 = int unsigned"})
+	if err != nil {
+		t.Fatalf("ParseColumnTypeHints() error = %v", err)
+	}
+	if hints[4] != ColumnDatetime || hints[5] != ColumnUint || len(hints) != 2 {
+		t.Errorf("ParseColumnTypeHints() = %v", hints)
+	}
+	for _, specs := range [][]string{{"4"}, {"x=int"}, {"0=int"}, {"4=geometry"}} {
+		if _, err := ParseColumnTypeHints(specs); err == nil {
+			t.Errorf("ParseColumnTypeHints(%q) error = nil, want an error", specs)
+		}
+	}
+
+	names := ColumnTypeHintNames([]string{"4=datetime", " 5 = int unsigned", "x=int"})
+	if names[4] != "DATETIME" || names[5] != "INT UNSIGNED" || len(names) != 2 {
+		t.Errorf("ColumnTypeHintNames() = %v, want DATETIME and INT UNSIGNED", names)
+	}
+
+	// a RowV2 value with the packed DATETIME 2024-05-01 10:00:00 as column 4
+	tm := types.NewTime(types.FromDate(2024, 5, 1, 10, 0, 0, 0), mysql.TypeDatetime, 0)
+	packed, err := tm.ToPackedUint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := append([]byte{0x80, 0x00, 0x01, 0x00, 0x00, 0x00, 0x04, 0x08, 0x00}, binary.LittleEndian.AppendUint64(nil, packed)...)
+
+	if got := (Options{}).decodeRowV2(row).Columns[4]; got == "2024-05-01 10:00:00" {
+		t.Errorf("decodeRowV2() without hints = %s, want the guess of the bytes", got)
+	}
+	if got := (Options{ColumnTypeHints: hints}).decodeRowV2(row).Columns[4]; got != "2024-05-01 10:00:00" {
+		t.Errorf("decodeRowV2() with 4=datetime = %s, want 2024-05-01 10:00:00", got)
+	}
+}
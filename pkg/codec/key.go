@@ -24,21 +24,44 @@ const separator = "_"
 //    a. '_' only for table prefix
 //    b. '_r', and optionally followed by RowID (digits) for row keys
 //    c. '_i', and optionally followed by IndexID (digits) for index keys
-// OK: t123, t123_,  t123_r, t123_r456, t123_i, t123_i789
+//    d. '_r_' or '_r{', followed by the primary key values of a common handle
+//    e. '_r{shard=N,inc=N}', the RowID of an AUTO_RANDOM primary key
+// OK: t123, t123_,  t123_r, t123_r456, t123_i, t123_i789, t123_r_abc_42, t123_r{h1: abc, h2: 42},
+//     t123_r{shard=5,inc=1}
 // NG: t123_r_, t123_r456_, t123_i_, t123_i789_
+// Keys starting with 'm' are TiDB meta keys, see meta.go for their readable form.
 
 // ParseKey parses a string representation of a TiDB key into its byte slice form as TiKV key.
+// A key in the escaped form of EscapeKey, e.g., t\200\000\000\000\000\000\000\204_r, is
+// unescaped as is.
 func ParseKey(input string) ([]byte, error) {
-	return parseKey(input, true)
+	return Options{}.ParseKey(input)
+}
+
+// ParseKey parses a key like ParseKey does, with the shard bits of an AUTO_RANDOM handle such
+// as t1_r{shard=5,inc=1} taken from the Options.
+func (o Options) ParseKey(input string) ([]byte, error) {
+	return o.parseKey(input, true)
 }
 
 func ParsePrefix(input string) ([]byte, error) {
-	return parseKey(input, false)
+	return Options{}.ParsePrefix(input)
 }
 
-func parseKey(input string, strict bool) ([]byte, error) {
+// ParsePrefix parses a scan prefix like ParsePrefix does, with the Options of ParseKey.
+func (o Options) ParsePrefix(input string) ([]byte, error) {
+	return o.parseKey(input, false)
+}
+
+func (o Options) parseKey(input string, strict bool) ([]byte, error) {
+	if isEscapedKey(input) {
+		return UnescapeKey(input)
+	}
+	if strings.HasPrefix(input, string(metaPrefix)) {
+		return parseMetaKey(input, strict)
+	}
 	if !strings.HasPrefix(input, "t") {
-		return nil, fmt.Errorf("key must start with 't': %s", input)
+		return nil, fmt.Errorf("key must start with 't' or 'm': %s", input)
 	}
 
 	if strict && strings.HasSuffix(input, "_") {
@@ -98,6 +121,35 @@ func parseKey(input string, strict bool) ([]byte, error) {
 	}
 
 	typeMarker := typePart[0:1]
+
+	// the common handle of a clustered table, the primary key values after "_r" as printed by
+	// DecodeKey, e.g., t55_r{h1: abc, h2: 42} or t55_r{abc, 42}, or separated like index values,
+	// e.g., t55_r_abc_42
+	if handle, ok := strings.CutPrefix(input[len(tablePart)+len(separator):], "r{"); ok {
+		values, ok := strings.CutSuffix(handle, "}")
+		if !ok || values == "" {
+			return nil, fmt.Errorf("invalid key format: the common handle must be values in {}: %s", input)
+		}
+		buf = append(buf, []byte(separator+"r")...)
+		// the int handle of an AUTO_RANDOM primary key, e.g., t1_r{shard=5,inc=1}, see
+		// Options.AutoRandomShardBits
+		if rowID, ok, err := o.parseAutoRandomHandle(values); ok {
+			if err != nil {
+				return nil, fmt.Errorf("invalid key format: %v: %s", err, input)
+			}
+			return tidbcodec.EncodeInt(buf, rowID), nil
+		}
+		parts := strings.Split(values, ", ")
+		for i := range parts {
+			parts[i] = strings.TrimPrefix(parts[i], handleLabel(i)) // the labels DecodeKey prints, e.g., h1:
+		}
+		return appendKeyValues(buf, parts, input)
+	}
+	if typePart == "r" && len(parts) > 2 && parts[2] != "" {
+		buf = append(buf, []byte(separator+"r")...)
+		return appendKeyValues(buf, parts[2:], input)
+	}
+
 	switch typeMarker {
 	case "r":
 		// row key
@@ -116,7 +168,7 @@ func parseKey(input string, strict bool) ([]byte, error) {
 			return nil, fmt.Errorf("invalid key for get: missing ID in key %s", input)
 		}
 
-		if len(parts) > 2 { // the input ends with incorrect values such as "t123_r_aaa"
+		if len(parts) > 2 { // the input ends with incorrect values such as "t123_i_aaa"
 			return nil, fmt.Errorf("invalid key format: values without ID: %s", input)
 		}
 
@@ -128,6 +180,12 @@ func parseKey(input string, strict bool) ([]byte, error) {
 	idStr := typePart[1:]
 	// we have RowID or IndexID to encode such as "t1_r123" or "t1_i456"
 	idVal, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil && typeMarker == "r" {
+		// the handle of an unsigned primary key is stored as its int64 bits, as TiDB does
+		if u, uerr := strconv.ParseUint(idStr, 10, 64); uerr == nil {
+			idVal, err = int64(u), nil
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("invalid ID(%s): %v", idStr, err)
 	}
@@ -152,129 +210,414 @@ func parseKey(input string, strict bool) ([]byte, error) {
 
 	// we have indexed values in the key such as "t128_i2_594692_3400463811"
 	if len(parts) > 2 {
-		var datums []types.Datum
+		return appendKeyValues(buf, parts[2:], input)
+	}
 
-		for _, part := range parts[2:] {
-			// input might end with the "_" or separator might be repeated in the input
-			// e.g., "t123_i456_789_" or "t123_i456_789__"
-			if part == "" { // expected command is scan since we return early if the input ends with "_" and the command is get.
-				continue
-			}
+	return buf, nil
+}
 
-			if n, err := strconv.ParseInt(part, 10, 64); err == nil {
-				datums = append(datums, types.NewIntDatum(n))
-			} else {
-				datums = append(datums, types.NewStringDatum(part))
-			}
+// appendKeyValues appends the memcomparable encoding of the values of a one-liner key, the
+// indexed values or the primary key values of a common handle.
+func appendKeyValues(buf []byte, values []string, input string) ([]byte, error) {
+	var datums []types.Datum
+	for _, v := range values {
+		// input might end with the "_" or separator might be repeated in the input
+		// e.g., "t123_i456_789_" or "t123_i456_789__"
+		if v == "" { // expected command is scan since we return early if the input ends with "_" and the command is get.
+			continue
 		}
 
-		if len(datums) > 0 {
-			var err error
-			typeCtx := types.DefaultStmtNoWarningContext.WithLocation(time.Local)
-			buf, err = tidbcodec.EncodeKey(typeCtx.Location(), buf, datums...)
-			if err != nil {
-				return nil, fmt.Errorf("failed to encode key: %v", err)
-			}
+		// e.g., 5, 18446744073709551615, 12.5, 2024-01-01, d:12.50 or abc, see parseIndexValue
+		d, err := parseIndexValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index value %q in key %s: %v", v, input, err)
 		}
+		datums = append(datums, d)
+	}
+	if len(datums) == 0 {
+		return buf, nil
+	}
+
+	typeCtx := types.DefaultStmtNoWarningContext.WithLocation(time.Local)
+	buf, err := tidbcodec.EncodeKey(typeCtx.Location(), buf, datums...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %v", err)
 	}
 
 	return buf, nil
 }
 
-func DecodeKey(key []byte) string {
-	if len(key) == 0 {
-		return ""
-	}
+// The kinds of the other keys decoded by DecodeKeyStructured, besides KindRecord and KindIndex.
+const (
+	KindTable KeyKind = "table" // t{TableID}
+	KindMeta  KeyKind = "meta"  // m..., see meta.go
+	KindRaw   KeyKind = "raw"   // not a TiDB key
+)
 
-	// 1. Table Prefix must start with 't'
-	if key[0] != 't' {
-		return hex.EncodeToString(key)
+// KeyInfo is the decoded form of a TiKV key, the structured counterpart of DecodeKey.
+type KeyInfo struct {
+	Kind       KeyKind
+	KeyspaceID *uint32 // the keyspace of an API V2 cluster, nil without one
+	TableID    int64
+	Handle     *RowHandle // only for record keys
+	IndexID    int64      // only for index keys, 0 when the key is too short to hold one (IDs start at 1)
+	TempIndex  bool       // the temporary index of an ADD INDEX in progress, IndexID is the new index
+	// IndexValues are the datums after the index ID, including the int handle of a
+	// non-unique index, which the key can't tell apart from the indexed values without
+	// the table schema. The strings are the sort keys of the key collation, see
+	// Options.Collation.
+	IndexValues []types.Datum
+	Meta        *MetaKey // only for meta keys
+	Rest        []byte   // the part of the key which couldn't be decoded
+
+	opts Options // the Options the key is printed with
+}
+
+// RowHandle is the handle of a record key.
+type RowHandle struct {
+	IntHandle    int64         // the row ID, unless the handle is a common handle
+	CommonHandle []types.Datum // the clustered primary key values, nil for an int handle
+
+	opts Options // the Options the handle is printed with
+}
+
+// String returns the handle as printed in keys: the row ID, or the primary key values in
+// braces, e.g., {h1: apple, h2: 5}.
+func (h RowHandle) String() string {
+	if h.CommonHandle != nil {
+		return h.opts.datumsString(h.CommonHandle)
 	}
+	return h.opts.formatHandle(h.IntHandle)
+}
 
-	keyWithoutT := key[1:]
+// DecodeKeyStructured decodes a TiKV key into its table, handle, index and meta parts. When a
+// part can't be decoded, it returns the error with the parts decoded so far and the rest of the
+// key in Rest, so the KeyInfo still prints as DecodeKey does.
+func DecodeKeyStructured(key []byte) (KeyInfo, error) {
+	return Options{}.DecodeKeyStructured(key)
+}
 
-	// 2. Decode TableID
-	// TiDB's ID is encoded with MemComparable format and Int, which is the length should be 8 bytes
-	if len(keyWithoutT) < 8 {
-		return hex.EncodeToString(key)
+// DecodeKeyStructured decodes a key like DecodeKeyStructured does. The KeyInfo and its handle
+// print with the Options.
+func (o Options) DecodeKeyStructured(key []byte) (KeyInfo, error) {
+	info, err := decodeKeyStructured(key)
+	info.opts = o
+	if info.Handle != nil {
+		info.Handle.opts = o
 	}
-	_, tableID, err := tidbcodec.DecodeInt(keyWithoutT)
-	if err != nil {
-		return hex.EncodeToString(key)
+
+	return info, err
+}
+
+func decodeKeyStructured(key []byte) (KeyInfo, error) {
+	// the keyspace prefix of API V2 clusters, see keyspace.go
+	if id, inner, ok := splitKeyspaceKey(key); ok {
+		info, err := decodeKeyStructured(inner)
+		info.KeyspaceID = &id
+		return info, err
 	}
 
-	remaining := keyWithoutT[8:]
+	if len(key) == 0 {
+		return KeyInfo{Kind: KindRaw}, fmt.Errorf("empty key")
+	}
 
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("t%d", tableID))
+	// meta keys have their own layout, see meta.go
+	if key[0] == metaPrefix {
+		mk, err := DecodeMetaKey(key)
+		if err != nil {
+			return KeyInfo{Kind: KindMeta, Rest: key}, err
+		}
+		return KeyInfo{Kind: KindMeta, Meta: &mk}, nil
+	}
 
-	// 3. Check if there is more data for row/index (_r or _i)
-	if len(remaining) < 2 {
-		return sb.String()
+	// 1. Table Prefix must start with 't'
+	// 2. TiDB's ID is encoded with MemComparable format and Int, which is the length should be 8 bytes
+	if key[0] != 't' || len(key) < 9 {
+		return KeyInfo{Kind: KindRaw, Rest: key}, fmt.Errorf("key %X is not a TiDB key", key)
+	}
+	_, tableID, err := tidbcodec.DecodeInt(key[1:9])
+	if err != nil {
+		return KeyInfo{Kind: KindRaw, Rest: key}, fmt.Errorf("failed to decode table ID of %X: %v", key, err)
 	}
 
-	// Check if it is '_r'
-	if bytes.HasPrefix(remaining, []byte("_r")) {
-		// Expected format: tablePrefix{TableID}_recordPrefixSep{RowID}
-		sb.WriteString("_r")
+	info := KeyInfo{Kind: KindTable, TableID: tableID}
+	remaining := key[9:]
+
+	switch {
+	case len(remaining) == 0:
+		return info, nil
 
-		// Extract RowID
+	case bytes.HasPrefix(remaining, []byte("_r")):
+		// Expected format: tablePrefix{TableID}_recordPrefixSep{RowID}
+		// An int handle is 8 bytes, longer ones are the common handle of a clustered table,
+		// the encoded primary key values
+		info.Kind = KindRecord
 		remaining = remaining[2:]
-		if len(remaining) >= 8 {
-			_, rowID, err := tidbcodec.DecodeInt(remaining)
-			if err == nil {
-				sb.WriteString(fmt.Sprintf("%d", rowID))
-				return sb.String()
+		if len(remaining) == 8 {
+			if _, rowID, err := tidbcodec.DecodeInt(remaining); err == nil {
+				info.Handle = &RowHandle{IntHandle: rowID}
+				return info, nil
+			}
+		}
+		if len(remaining) > 8 {
+			if datums, err := tidbcodec.Decode(remaining, 2); err == nil {
+				info.Handle = &RowHandle{CommonHandle: datums}
+				return info, nil
 			}
 		}
 
-		// If we reach here, it means there is no valid RowID
-		sb.WriteString(hex.EncodeToString(remaining))
-		return sb.String()
-	}
+		info.Rest = remaining
+		return info, fmt.Errorf("invalid handle %X in record key of table %d", remaining, tableID)
 
-	// Check if it is '_i'
-	if bytes.HasPrefix(remaining, []byte("_i")) {
+	case bytes.HasPrefix(remaining, []byte("_i")):
 		// Expected format: tablePrefix{TableID}_indexPrefixSep{IndexID}_indexedColumnsValue(_{RowID})
-		sb.WriteString("_i")
-
-		// Extract IndexID
+		info.Kind = KindIndex
 		remaining = remaining[2:]
-		if len(remaining) >= 8 {
-			_, indexID, err := tidbcodec.DecodeInt(remaining)
-			if err == nil {
-				sb.WriteString(fmt.Sprintf("%d", indexID))
-				remaining = remaining[8:]
-			} else { // unable to decode index ID
-				sb.WriteString("_???")
-			}
+		if len(remaining) == 0 { // t{TableID}_i, the prefix of all the indexes
+			return info, nil
 		}
+		if len(remaining) < 8 {
+			info.Rest = remaining
+			return info, fmt.Errorf("invalid index ID %X in index key of table %d", remaining, tableID)
+		}
+		_, info.IndexID, _ = tidbcodec.DecodeInt(remaining)
+		if info.IndexID&^indexIDMask == tempIndexPrefix { // see tempindex.go
+			info.IndexID, info.TempIndex = info.IndexID&indexIDMask, true
+		}
+		remaining = remaining[8:]
 
-		// Extract IndexedColumnsValue and optional RowID
 		if len(remaining) != 0 {
-			datum, err := tidbcodec.Decode(remaining, 10)
-			if err == nil && len(datum) > 0 {
-				for _, d := range datum {
-					sb.WriteString("_")
-					s, _ := d.ToString()
-					sb.WriteString(s)
-				}
-			} else { // failed to decode
-				sb.WriteString("_")
-				sb.WriteString(hex.EncodeToString(remaining))
+			datums, err := tidbcodec.Decode(remaining, 10)
+			if err != nil || len(datums) == 0 {
+				info.Rest = remaining
+				return info, fmt.Errorf("failed to decode the values of index %d of table %d: %v", info.IndexID, tableID, err)
 			}
+			info.IndexValues = datums
 		}
 
-		return sb.String()
+		return info, nil
+
+	default:
+		// the key is not valid format, that is unexpected
+		info.Rest = remaining
+		return info, fmt.Errorf("unexpected %X after table ID %d", remaining, tableID)
+	}
+}
+
+// String returns the readable form of the key, see DecodeKey.
+func (k KeyInfo) String() string {
+	var sb strings.Builder
+	if k.KeyspaceID != nil {
+		sb.WriteString(fmt.Sprintf("[keyspace %d] ", *k.KeyspaceID))
 	}
 
-	// if we reach here, the key is not valid format, that is unexpected
-	sb.WriteString("_")
-	sb.WriteString(hex.EncodeToString(remaining))
-	return fmt.Sprintf("t%d", tableID)
+	switch k.Kind {
+	case KindMeta:
+		if k.Meta != nil {
+			sb.WriteString(k.Meta.String())
+		} else {
+			sb.WriteString(decodeMetaKeyString(k.Rest))
+		}
+	case KindTable:
+		sb.WriteString(fmt.Sprintf("t%d", k.TableID))
+	case KindRecord:
+		sb.WriteString(fmt.Sprintf("t%d_r", k.TableID))
+		if k.Handle != nil {
+			sb.WriteString(k.Handle.String())
+		} else {
+			sb.WriteString(hex.EncodeToString(k.Rest))
+		}
+	case KindIndex:
+		sb.WriteString(fmt.Sprintf("t%d_i", k.TableID))
+		if k.IndexID != 0 {
+			sb.WriteString(fmt.Sprintf("%d", k.IndexID))
+		}
+		for _, d := range k.IndexValues {
+			sb.WriteString("_")
+			sb.WriteString(k.opts.keyDatumString(d))
+		}
+		if len(k.Rest) > 0 {
+			sb.WriteString("_")
+			sb.WriteString(hex.EncodeToString(k.Rest))
+		}
+		if k.TempIndex {
+			sb.WriteString(" (temp index)")
+		}
+	default:
+		sb.WriteString(hex.EncodeToString(k.Rest))
+	}
+
+	return sb.String()
+}
+
+// DecodeKey returns the readable form of a TiKV key, e.g., t132_r1, t126_i1_594692_3769634 or
+// mDB:2/Table:100. A key which isn't a TiDB key is printed as hex.
+func DecodeKey(key []byte) string {
+	return Options{}.DecodeKey(key)
+}
+
+// DecodeKey returns the readable form of a key like DecodeKey does, with the handles and the
+// strings printed by the Options.
+func (o Options) DecodeKey(key []byte) string {
+	info, _ := o.DecodeKeyStructured(key)
+	return info.String()
+}
+
+// formatHandle prints an int handle, as unsigned with UnsignedHandles or as the shard and the
+// increment with AutoRandomShardBits.
+func (o Options) formatHandle(rowID int64) string {
+	if o.AutoRandomShardBits > 0 {
+		if s, ok := o.formatAutoRandom(rowID); ok {
+			return s
+		}
+	}
+	if o.UnsignedHandles {
+		return strconv.FormatUint(uint64(rowID), 10)
+	}
+	return strconv.FormatInt(rowID, 10)
 }
 
 // PrettyPrintKey returns a hex representation of the given key for debugging.
 func PrettyPrintKey(key []byte) string {
+	return Options{}.PrettyPrintKey(key)
+}
+
+// PrettyPrintKey returns the hex of a key like PrettyPrintKey does. With Redact, the strings of
+// an index key or a common handle are masked.
+func (o Options) PrettyPrintKey(key []byte) string {
+	if o.Redact {
+		if s, ok := redactedKeyHex(key); ok {
+			return s
+		}
+	}
 	return fmt.Sprintf("%X", key)
 }
+
+// EscapeKey returns the key in the escaped form of TiKV logs and tikv-ctl: printable ASCII as
+// is, \n, \r, \t, \" and \\ escaped, and the other bytes as 3-digit octal escapes like \200.
+func EscapeKey(key []byte) string {
+	var sb strings.Builder
+	for _, c := range key {
+		switch {
+		case c == '\n':
+			sb.WriteString(`\n`)
+		case c == '\r':
+			sb.WriteString(`\r`)
+		case c == '\t':
+			sb.WriteString(`\t`)
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "\\%03o", c)
+		}
+	}
+
+	return sb.String()
+}
+
+// isEscapedKey reports whether the key is in the escaped form, which has a byte escape like \200
+// or \x80. The readable form has no backslash but in the string values of index keys.
+func isEscapedKey(input string) bool {
+	for i := 0; i+1 < len(input); i++ {
+		if input[i] != '\\' {
+			continue
+		}
+		if c := input[i+1]; (c >= '0' && c <= '7') || c == 'x' {
+			return true
+		}
+		i++ // the escaped character, e.g., the second backslash of \\
+	}
+
+	return false
+}
+
+// UnescapeKey parses a key in the escaped form of EscapeKey, e.g., t\200\000\000\000\000\000\000\204_r
+// copied from a TiKV log or tikv-ctl. It takes octal escapes of 1 to 3 digits, \xHH escapes,
+// \n, \r, \t, \", \' and \\, and the other bytes as they are.
+func UnescapeKey(s string) ([]byte, error) {
+	key := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			key = append(key, c)
+			continue
+		}
+		if i++; i == len(s) {
+			return nil, fmt.Errorf("escaped key %q ends with a backslash", s)
+		}
+
+		switch c = s[i]; c {
+		case 'n':
+			key = append(key, '\n')
+		case 'r':
+			key = append(key, '\r')
+		case 't':
+			key = append(key, '\t')
+		case '"', '\'', '\\':
+			key = append(key, c)
+		case 'x':
+			if i+2 >= len(s) {
+				return nil, fmt.Errorf("escaped key %q has a short \\x escape at %d", s, i-1)
+			}
+			b, err := hex.DecodeString(s[i+1 : i+3])
+			if err != nil {
+				return nil, fmt.Errorf("escaped key %q has an invalid \\x escape at %d", s, i-1)
+			}
+			key = append(key, b[0])
+			i += 2
+		default:
+			if c < '0' || c > '7' {
+				return nil, fmt.Errorf("escaped key %q has an unknown escape \\%c", s, c)
+			}
+			j := i
+			for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+				j++
+			}
+			n, _ := strconv.ParseUint(s[i:j], 8, 16)
+			if n > 0xff {
+				return nil, fmt.Errorf("escaped key %q has an octal escape \\%s out of the byte range", s, s[i:j])
+			}
+			key = append(key, byte(n))
+			i = j - 1
+		}
+	}
+
+	return key, nil
+}
+
+// PrefixEnd returns the smallest key which is greater than all keys having the prefix,
+// that is the exclusive end bound of a prefix scan. Trailing 0xFF bytes are dropped before
+// incrementing. It returns nil (no upper bound) when the prefix consists only of 0xFF bytes.
+func PrefixEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] != 0xFF {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	return nil
+}
+
+// ScanBounds parses the prefix and returns the start and the exclusive end bounds of the scan.
+// The end is nil when the scan has no upper bound.
+func ScanBounds(prefix string) (start, end []byte, err error) {
+	return Options{}.ScanBounds(prefix)
+}
+
+// ScanBounds returns the bounds of a prefix scan like ScanBounds does, with the prefix parsed
+// by the Options.
+func (o Options) ScanBounds(prefix string) (start, end []byte, err error) {
+	start, err = o.ParsePrefix(prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return start, PrefixEnd(start), nil
+}
@@ -60,6 +60,104 @@ func TestParseKey(t *testing.T) {
 	}
 }
 
+func TestParseKeyIndexValues(t *testing.T) {
+	dec := func(s string) types.Datum {
+		d, err := KeyComponent{Type: ComponentDecimal, Value: s}.datum()
+		if err != nil {
+			t.Fatalf("decimal datum(%s) error = %v", s, err)
+		}
+		return d
+	}
+	datetime := func(s string) types.Datum {
+		tm, err := types.ParseDatetime(types.DefaultStmtNoWarningContext, s)
+		if err != nil {
+			t.Fatalf("ParseDatetime(%s) error = %v", s, err)
+		}
+		return types.NewTimeDatum(tm)
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		datums []types.Datum // the values after t1_i2
+	}{
+		{"int", "t1_i2_-5", types.MakeDatums(int64(-5))},
+		{"unsigned", "t1_i2_18446744073709551615", types.MakeDatums(uint64(18446744073709551615))},
+		{"float", "t1_i2_12.5", types.MakeDatums(12.5)},
+		{"float with an exponent", "t1_i2_1e3", types.MakeDatums(1000.0)},
+		{"decimal", "t1_i2_d:12.50", []types.Datum{dec("12.50")}},
+		{"date", "t1_i2_2024-01-01", []types.Datum{datetime("2024-01-01")}},
+		{"datetime", "t1_i2_2024-01-01 10:20:30.5", []types.Datum{datetime("2024-01-01 10:20:30.5")}},
+		{"typed datetime", "t1_i2_t:2024-01-01", []types.Datum{datetime("2024-01-01")}},
+		{"typed string", "t1_i2_string:12.5", types.MakeDatums("12.5")},
+		{"string with a colon", "t1_i2_a:b", types.MakeDatums("a:b")},
+		{"not a date", "t1_i2_2024-13-45", types.MakeDatums("2024-13-45")},
+		{"mixed", "t1_i2_12.5_apple_7", types.MakeDatums(12.5, "apple", int64(7))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tidbcodec.EncodeInt(append(tidbcodec.EncodeInt([]byte{'t'}, 1), '_', 'i'), 2)
+			want, err := tidbcodec.EncodeKey(time.Local, want, tt.datums...)
+			if err != nil {
+				t.Fatalf("EncodeKey() error = %v", err)
+			}
+
+			got, err := ParseKey(tt.input)
+			if err != nil {
+				t.Fatalf("ParseKey(%s) error = %v", tt.input, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("ParseKey(%s) = %X, want %X", tt.input, got, want)
+			}
+		})
+	}
+
+	// a known type with a value it can't take is an error instead of a string
+	for _, input := range []string{"t1_i2_d:abc", "t1_i2_t:yesterday", "t1_i2_int:1.5"} {
+		if _, err := ParseKey(input); err == nil {
+			t.Errorf("ParseKey(%s) error = nil, want an invalid value error", input)
+		}
+	}
+}
+
+func TestParseKeyCommonHandle(t *testing.T) {
+	want := append(tidbcodec.EncodeInt([]byte{'t'}, 55), '_', 'r')
+	want, err := tidbcodec.EncodeKey(time.Local, want, types.MakeDatums("abc", int64(42))...)
+	if err != nil {
+		t.Fatalf("EncodeKey() error = %v", err)
+	}
+
+	// the form printed by DecodeKey round-trips
+	for _, input := range []string{`t55_r_"abc"_42`, "t55_r_abc_42", "t55_r{abc, 42}", "t55_r{h1: abc, h2: 42}", DecodeKey(want)} {
+		got, err := ParseKey(input)
+		if err != nil {
+			t.Fatalf("ParseKey(%s) error = %v", input, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("ParseKey(%s) = %X, want %X", input, got, want)
+		}
+	}
+
+	// a quoted value is a string even when it looks like a number
+	got, err := ParseKey(`t55_r_"42"`)
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	if decoded := DecodeKey(got); decoded != "t55_r{42}" {
+		t.Errorf("DecodeKey(ParseKey()) = %s, want t55_r{42}", decoded)
+	}
+	if parsed, _ := ParseKey("t55_r_42"); bytes.Equal(parsed, got) {
+		t.Errorf("ParseKey(t55_r_42) should encode an int, not the string \"42\"")
+	}
+
+	for _, input := range []string{"t55_r{abc, 42", "t55_r{}", "t55_r{d:abc}"} {
+		if _, err := ParseKey(input); err == nil {
+			t.Errorf("ParseKey(%s) error = nil, want error", input)
+		}
+	}
+}
+
 func TestParsePrefix(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -107,6 +205,63 @@ func TestParsePrefix(t *testing.T) {
 		}
 	}
 }
+
+func TestPrefixEnd(t *testing.T) {
+	tests := []struct {
+		prefix   []byte
+		expected []byte
+	}{
+		{[]byte("t"), []byte("u")},
+		{[]byte{0x74, 0x80, 0x5f, 0x72}, []byte{0x74, 0x80, 0x5f, 0x73}},
+		{[]byte{0x01, 0xFF}, []byte{0x02}},
+		{[]byte{0x01, 0xFF, 0xFF}, []byte{0x02}},
+		{[]byte{0xFF, 0xFF}, nil},
+		{[]byte{}, nil},
+	}
+
+	for _, tt := range tests {
+		got := PrefixEnd(tt.prefix)
+		if !bytes.Equal(got, tt.expected) || (tt.expected == nil) != (got == nil) {
+			t.Errorf("PrefixEnd(%X) = %X, want %X", tt.prefix, got, tt.expected)
+		}
+	}
+}
+
+func TestScanBounds(t *testing.T) {
+	tests := []struct {
+		prefix string
+		start  string
+		end    string
+	}{
+		{"t", "74", "75"},
+		{"t1", "748000000000000001", "748000000000000002"},
+		{"t1_r", "7480000000000000015F72", "7480000000000000015F73"},
+		{"t1_i2", "7480000000000000015F698000000000000002", "7480000000000000015F698000000000000003"},
+		// EncodeInt(-1) and EncodeInt(MaxInt64) end with 0xFF bytes, which must be carried over
+		{"t-1", "747FFFFFFFFFFFFFFF", "7480"},
+		{"t9223372036854775807", "74FFFFFFFFFFFFFFFF", "75"},
+		{"t1_r-1", "7480000000000000015F727FFFFFFFFFFFFFFF", "7480000000000000015F7280"},
+	}
+
+	for _, tt := range tests {
+		start, end, err := ScanBounds(tt.prefix)
+		if err != nil {
+			t.Errorf("ScanBounds(%s) error = %v", tt.prefix, err)
+			continue
+		}
+		if got := PrettyPrintKey(start); got != tt.start {
+			t.Errorf("ScanBounds(%s) start = %s, want %s", tt.prefix, got, tt.start)
+		}
+		if got := PrettyPrintKey(end); got != tt.end {
+			t.Errorf("ScanBounds(%s) end = %s, want %s", tt.prefix, got, tt.end)
+		}
+	}
+
+	if _, _, err := ScanBounds("x1"); err == nil {
+		t.Errorf("ScanBounds(x1) error = nil, want error")
+	}
+}
+
 func TestKeyEncodingConsistency(t *testing.T) {
 	inputKey1 := "t1"
 	inputKey2 := inputKey1 + "_r123"
@@ -118,6 +273,137 @@ func TestKeyEncodingConsistency(t *testing.T) {
 	}
 }
 
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, input := range []string{"t132_r1772018", "t126_i1_594692_3769634", "t1_i2_apple_5"} {
+		raw, err := ParseKey(input)
+		if err != nil {
+			t.Errorf("ParseKey(%s) error = %v", input, err)
+			continue
+		}
+		if got := DecodeKey(raw); got != input {
+			t.Errorf("DecodeKey(ParseKey(%s)) = %s", input, got)
+		}
+	}
+
+	raw, err := ParsePrefix("t132")
+	if err != nil || DecodeKey(raw) != "t132" {
+		t.Errorf("DecodeKey(ParsePrefix(t132)) = %s, %v", DecodeKey(raw), err)
+	}
+
+	// a record prefix keeps the separator bytes
+	raw, err = ParsePrefix("t132_r")
+	if err != nil {
+		t.Fatalf("ParsePrefix(t132_r) error = %v", err)
+	}
+	if got := EscapeKey(raw); got != `t\200\000\000\000\000\000\000\204_r` {
+		t.Errorf("EscapeKey(ParsePrefix(t132_r)) = %s", got)
+	}
+}
+
+func TestUnsignedHandleRoundTrip(t *testing.T) {
+	// the handle of a BIGINT UNSIGNED primary key is stored as its int64 bits
+	raw, err := ParseKey("t1_r18446744073709551615")
+	if err != nil {
+		t.Fatalf("ParseKey(t1_r18446744073709551615) error = %v", err)
+	}
+	want := tidbcodec.EncodeInt(append(tidbcodec.EncodeInt([]byte{'t'}, 1), '_', 'r'), -1)
+	if !bytes.Equal(raw, want) {
+		t.Errorf("ParseKey(t1_r18446744073709551615) = %X, want %X", raw, want)
+	}
+
+	if got := DecodeKey(raw); got != "t1_r-1" {
+		t.Errorf("DecodeKey() = %s, want t1_r-1 without UnsignedHandles", got)
+	}
+	opts := Options{UnsignedHandles: true}
+	for _, input := range []string{"t1_r18446744073709551615", "t1_r9223372036854775808", "t1_r1"} {
+		raw, err := ParseKey(input)
+		if err != nil {
+			t.Errorf("ParseKey(%s) error = %v", input, err)
+			continue
+		}
+		if got := opts.DecodeKey(raw); got != input {
+			t.Errorf("DecodeKey(ParseKey(%s)) = %s", input, got)
+		}
+	}
+
+	// unsigned index values keep their own flag and need no option
+	if raw, err := ParseKey("t1_i2_18446744073709551615_5"); err != nil || DecodeKey(raw) != "t1_i2_18446744073709551615_5" {
+		t.Errorf("DecodeKey(ParseKey(t1_i2_18446744073709551615_5)) = %s, %v", DecodeKey(raw), err)
+	}
+
+	// only row IDs may be unsigned
+	for _, input := range []string{"t18446744073709551615_r1", "t1_i18446744073709551615", "t1_r18446744073709551616"} {
+		if _, err := ParseKey(input); err == nil {
+			t.Errorf("ParseKey(%s) error = nil, want an out-of-range error", input)
+		}
+	}
+}
+
+func TestEscapeKey(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		expected string
+	}{
+		{[]byte("abc"), "abc"},
+		{[]byte{'t', 0x80, 0x00, 0xff}, `t\200\000\377`},
+		{[]byte("a\"b\\c\n\r\t"), `a\"b\\c\n\r\t`},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := EscapeKey(tt.input); got != tt.expected {
+			t.Errorf("EscapeKey(%X) = %s, want %s", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestUnescapeKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []byte
+	}{
+		{"abc", []byte("abc")},
+		{`t\200\000\377`, []byte{'t', 0x80, 0x00, 0xff}},
+		{`a\"b\\c\n\r\t\'`, []byte("a\"b\\c\n\r\t'")},
+		{`t\x80\x00_r\0011`, []byte{'t', 0x80, 0x00, '_', 'r', 0x01, '1'}},
+		{`\7_\12`, []byte{0x07, '_', 0x0a}},
+	}
+
+	for _, tt := range tests {
+		got, err := UnescapeKey(tt.input)
+		if err != nil {
+			t.Errorf("UnescapeKey(%s) error = %v", tt.input, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.expected) {
+			t.Errorf("UnescapeKey(%s) = %X, want %X", tt.input, got, tt.expected)
+		}
+	}
+
+	// the escaped form of EscapeKey parses back to the key
+	raw, _ := ParseKey("t132_i1_594692_3769634")
+	if got, err := UnescapeKey(EscapeKey(raw)); err != nil || !bytes.Equal(got, raw) {
+		t.Errorf("UnescapeKey(EscapeKey(%X)) = %X, %v", raw, got, err)
+	}
+
+	// ParseKey takes the escaped form as well
+	if got, err := ParseKey(EscapeKey(raw)); err != nil || !bytes.Equal(got, raw) {
+		t.Errorf("ParseKey(EscapeKey(%X)) = %X, %v", raw, got, err)
+	}
+	if got, err := ParsePrefix(`t\200\000\000\000\000\000\000\204_r`); err != nil || DecodeKey(got) != "t132_r" {
+		t.Errorf("ParsePrefix(escaped t132_r) = %X, %v", got, err)
+	}
+	if isEscapedKey(`t1_i1_a\\1`) || isEscapedKey(`t1_i1_a\b`) || !isEscapedKey(`t\x80`) {
+		t.Errorf("isEscapedKey() takes the wrong keys as escaped")
+	}
+
+	for _, input := range []string{`t\`, `t\q`, `t\x8`, `t\xzz`, `t\400`} {
+		if _, err := UnescapeKey(input); err == nil {
+			t.Errorf("UnescapeKey(%s) error = nil, want an error", input)
+		}
+	}
+}
+
 func TestDecodeKey(t *testing.T) {
 	// Ref: https://github.com/pingcap/tidb/blob/master/pkg/util/codec/codec_test.go
 	tests := []struct {
@@ -175,6 +461,40 @@ func TestDecodeKey(t *testing.T) {
 			},
 			expected: "t1_i2_apple",
 		},
+		{
+			name: "Common Handle Record Key (t1_r{h1: apple, h2: 5})",
+			setup: func() []byte {
+				// the clustered primary key (name varchar, id int) after _r
+				b := []byte{'t'}
+				b = tidbcodec.EncodeInt(b, 1)
+				b = append(b, '_', 'r')
+
+				var err error
+				b, err = tidbcodec.EncodeKey(time.UTC, b, types.MakeDatums("apple", 5)...)
+				if err != nil {
+					t.Fatalf("failed to encode key: %v", err)
+				}
+				return b
+			},
+			expected: "t1_r{h1: apple, h2: 5}",
+		},
+		{
+			name: "Common Handle of One Datum (t1_r{7})",
+			setup: func() []byte {
+				// an encoded datum starts with a flag byte, so it never has the 8 bytes of an int handle
+				b := []byte{'t'}
+				b = tidbcodec.EncodeInt(b, 1)
+				b = append(b, '_', 'r')
+
+				var err error
+				b, err = tidbcodec.EncodeKey(time.UTC, b, types.MakeDatums(7)...)
+				if err != nil {
+					t.Fatalf("failed to encode key: %v", err)
+				}
+				return b
+			},
+			expected: "t1_r{7}",
+		},
 		{
 			name: "Decode Hex Data",
 			setup: func() []byte {
@@ -212,3 +532,101 @@ func TestDecodeKey(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeKeyStructured(t *testing.T) {
+	mustParse := func(input string) []byte {
+		t.Helper()
+		key, err := ParseKey(input)
+		if err != nil {
+			t.Fatalf("ParseKey(%s) error = %v", input, err)
+		}
+		return key
+	}
+
+	info, err := DecodeKeyStructured(mustParse("t132_r7"))
+	if err != nil {
+		t.Fatalf("DecodeKeyStructured() error = %v", err)
+	}
+	if info.Kind != KindRecord || info.TableID != 132 || info.Handle == nil || info.Handle.IntHandle != 7 || info.Handle.CommonHandle != nil {
+		t.Errorf("DecodeKeyStructured(t132_r7) = %+v, want the int handle 7 of table 132", info)
+	}
+
+	// the index values keep their types
+	info, err = DecodeKeyStructured(mustParse("t126_i2_apple_-5_3.5"))
+	if err != nil {
+		t.Fatalf("DecodeKeyStructured() error = %v", err)
+	}
+	if info.Kind != KindIndex || info.TableID != 126 || info.IndexID != 2 || len(info.IndexValues) != 3 {
+		t.Fatalf("DecodeKeyStructured(t126_i2_apple_-5_3.5) = %+v, want 3 values of index 2", info)
+	}
+	if v := info.IndexValues[0]; v.Kind() != types.KindBytes || string(v.GetBytes()) != "apple" {
+		t.Errorf("index value 0 = %v, want the bytes apple", v)
+	}
+	if v := info.IndexValues[1]; v.Kind() != types.KindInt64 || v.GetInt64() != -5 {
+		t.Errorf("index value 1 = %v, want the int -5", v)
+	}
+	if v := info.IndexValues[2]; v.Kind() != types.KindFloat64 || v.GetFloat64() != 3.5 {
+		t.Errorf("index value 2 = %v, want the float 3.5", v)
+	}
+
+	// a common handle is the primary key values
+	common := append([]byte{'t'}, tidbcodec.EncodeInt(nil, 1)...)
+	common = append(common, '_', 'r')
+	common, err = tidbcodec.EncodeKey(time.UTC, common, types.MakeDatums("apple", 5)...)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	info, err = DecodeKeyStructured(common)
+	if err != nil || info.Handle == nil || len(info.Handle.CommonHandle) != 2 || info.Handle.CommonHandle[1].GetInt64() != 5 {
+		t.Errorf("DecodeKeyStructured() = %+v, %v, want the common handle {apple, 5}", info, err)
+	}
+
+	info, err = DecodeKeyStructured(EncodeKeyspaceKey(3, mustParse("mSchemaVersionKey")))
+	if err != nil || info.Kind != KindMeta || info.KeyspaceID == nil || *info.KeyspaceID != 3 || info.Meta.Key != MetaSchemaVersionKey {
+		t.Errorf("DecodeKeyStructured() = %+v, %v, want the meta key in keyspace 3", info, err)
+	}
+
+	// the undecodable part is kept with the error, and printed as hex like DecodeKey does
+	prefix, err := ParsePrefix("t1_r")
+	if err != nil {
+		t.Fatalf("ParsePrefix() error = %v", err)
+	}
+	broken := append(prefix, 0xff, 0xff)
+	info, err = DecodeKeyStructured(broken)
+	if err == nil || info.Kind != KindRecord || info.TableID != 1 || !bytes.Equal(info.Rest, []byte{0xff, 0xff}) {
+		t.Errorf("DecodeKeyStructured() = %+v, %v, want the error with the rest of the key", info, err)
+	}
+	if got := info.String(); got != "t1_rffff" {
+		t.Errorf("KeyInfo.String() = %s, want t1_rffff", got)
+	}
+
+	if _, err := DecodeKeyStructured([]byte{0x11, 0x22}); err == nil {
+		t.Error("DecodeKeyStructured() should fail for a key which isn't a TiDB key")
+	}
+
+	// the prefixes print as they are parsed
+	for _, prefix := range []string{"t5", "t5_r", "t5_i", "t5_i3", "m"} {
+		key, err := ParsePrefix(prefix)
+		if err != nil {
+			t.Fatalf("ParsePrefix(%s) error = %v", prefix, err)
+		}
+		if got := DecodeKey(key); got != prefix {
+			t.Errorf("DecodeKey(%X) = %s, want %s", key, got, prefix)
+		}
+	}
+}
+
+func TestDecodeKeyspaceKey(t *testing.T) {
+	key, err := ParseKey("t132_r1")
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+
+	if got := DecodeKey(EncodeKeyspaceKey(0x010203, key)); got != "[keyspace 66051] t132_r1" {
+		t.Errorf("DecodeKey() = %s, want the keyspace and the key", got)
+	}
+	// the keyspace of a key which isn't a table or meta key is unknown
+	if got := DecodeKey([]byte{'x', 0, 0, 1, 0xff}); got != "78000001ff" {
+		t.Errorf("DecodeKey() = %s, want hex", got)
+	}
+}
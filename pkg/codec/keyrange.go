@@ -0,0 +1,187 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// KeyParts is the structure of a table key split into its fields.
+type KeyParts struct {
+	TableID  int64
+	Kind     KeyKind // empty for the table prefix t{TableID} alone
+	RowID    int64   // only for KindRecord with HasRowID
+	HasRowID bool    // false for the record prefix or a common handle
+	IndexID  int64   // only for KindIndex
+	Rest     []byte  // bytes after the row/index ID, e.g., the encoded index values
+}
+
+// ParseKeyParts splits a table key like t{TableID}_r{RowID} or t{TableID}_i{IndexID}{values}.
+// It accepts prefixes as well as complete keys.
+func ParseKeyParts(key []byte) (KeyParts, error) {
+	// t + TableID (8 bytes)
+	if len(key) < 9 || key[0] != 't' {
+		return KeyParts{}, fmt.Errorf("not a table key: %X", key)
+	}
+
+	_, tableID, err := tidbcodec.DecodeInt(key[1:9])
+	if err != nil {
+		return KeyParts{}, fmt.Errorf("failed to decode table ID of %X: %v", key, err)
+	}
+	parts := KeyParts{TableID: tableID}
+
+	remaining := key[9:]
+	switch {
+	case len(remaining) == 0:
+		return parts, nil
+	case bytes.HasPrefix(remaining, []byte(separator+"r")):
+		parts.Kind = KindRecord
+		remaining = remaining[2:]
+		if len(remaining) == 8 { // int handle, a longer one is a common handle
+			_, parts.RowID, _ = tidbcodec.DecodeInt(remaining)
+			parts.HasRowID = true
+			return parts, nil
+		}
+	case bytes.HasPrefix(remaining, []byte(separator+"i")):
+		parts.Kind = KindIndex
+		remaining = remaining[2:]
+		if len(remaining) >= 8 {
+			_, parts.IndexID, _ = tidbcodec.DecodeInt(remaining[:8])
+			remaining = remaining[8:]
+		}
+	default:
+		return KeyParts{}, fmt.Errorf("unknown key type after table ID: %X", key)
+	}
+	parts.Rest = remaining
+
+	return parts, nil
+}
+
+// RangeKind is the access pattern a key range represents.
+type RangeKind string
+
+const (
+	RangePointGet      RangeKind = "point-get"
+	RangeFullTableScan RangeKind = "full-table-scan"
+	RangeTableScan     RangeKind = "table-range-scan"
+	RangeIndexScan     RangeKind = "index-range-scan"
+	RangeUnknown       RangeKind = "unknown"
+)
+
+// RangeInfo is the classification of a key range.
+type RangeInfo struct {
+	Kind    RangeKind
+	TableID int64
+	IndexID int64 // only for RangeIndexScan
+	Detail  string
+}
+
+// ClassifyRange classifies the key range [start, end) such as the one reported in an execution
+// plan or in TiKV logs. A nil start means -inf and a nil end means +inf. Like the ranges of an
+// execution plan, an open side stays within the table (and the index) of the bounded side.
+func ClassifyRange(start, end []byte) RangeInfo {
+	return Options{}.ClassifyRange(start, end)
+}
+
+// ClassifyRange classifies a key range like ClassifyRange does, with the keys printed by the
+// Options.
+func (o Options) ClassifyRange(start, end []byte) RangeInfo {
+	if start == nil && end == nil {
+		return RangeInfo{Kind: RangeUnknown, Detail: "the whole key space"}
+	}
+
+	bound := start
+	if bound == nil {
+		bound = end
+	}
+	p, err := ParseKeyParts(bound)
+	if err != nil {
+		return RangeInfo{Kind: RangeUnknown, Detail: "not a table key range"}
+	}
+	tableID := p.TableID
+
+	sKind, sIndexID, sOK := rangeBoundKind(start, tableID, false)
+	eKind, eIndexID, eOK := rangeBoundKind(end, tableID, true)
+	if !sOK || !eOK {
+		return RangeInfo{Kind: RangeUnknown, TableID: tableID, Detail: "spans more than one table"}
+	}
+	// an open side takes the kind of the other side
+	if start == nil {
+		sKind, sIndexID = eKind, eIndexID
+	}
+	if end == nil {
+		eKind, eIndexID = sKind, sIndexID
+	}
+
+	if sKind == KindRecord && start != nil && end != nil && isPointRange(start, end) {
+		if sp, _ := ParseKeyParts(start); sp.HasRowID || len(sp.Rest) > 0 {
+			return RangeInfo{Kind: RangePointGet, TableID: tableID, Detail: fmt.Sprintf("single row %s", o.DecodeKey(start))}
+		}
+	}
+
+	if sKind == KindIndex && eKind == KindIndex {
+		if sIndexID != eIndexID {
+			return RangeInfo{Kind: RangeUnknown, TableID: tableID, Detail: fmt.Sprintf("spans indexes %d to %d", sIndexID, eIndexID)}
+		}
+		return RangeInfo{Kind: RangeIndexScan, TableID: tableID, IndexID: sIndexID, Detail: fmt.Sprintf("index %d", sIndexID)}
+	}
+	if sKind == KindIndex || eKind == KindIndex {
+		return RangeInfo{Kind: RangeUnknown, TableID: tableID, Detail: "mixes record and index keys"}
+	}
+
+	recordPrefix := append([]byte{'t'}, tidbcodec.EncodeInt(nil, tableID)...)
+	recordPrefix = append(recordPrefix, []byte(separator+"r")...)
+	firstRecord := tidbcodec.EncodeInt(bytes.Clone(recordPrefix), math.MinInt64)
+	lastRecord := tidbcodec.EncodeInt(bytes.Clone(recordPrefix), math.MaxInt64)
+	coversStart := start == nil || bytes.Compare(start, firstRecord) <= 0
+	coversEnd := end == nil || bytes.Compare(end, lastRecord) > 0
+	if coversStart && coversEnd {
+		return RangeInfo{Kind: RangeFullTableScan, TableID: tableID, Detail: "all rows of the table"}
+	}
+
+	return RangeInfo{Kind: RangeTableScan, TableID: tableID, Detail: "part of the rows of the table"}
+}
+
+// rangeBoundKind returns the kind of a range bound within the table. The exclusive end of a
+// prefix (e.g., t{TableID}_s for the records, t{TableID}_i{IndexID+1} for an index) counts
+// as the end of that prefix. ok is false when the bound is outside the table.
+func rangeBoundKind(key []byte, tableID int64, isEnd bool) (kind KeyKind, indexID int64, ok bool) {
+	if key == nil {
+		return "", 0, true
+	}
+
+	tablePrefix := append([]byte{'t'}, tidbcodec.EncodeInt(nil, tableID)...)
+	if isEnd {
+		switch {
+		case bytes.Equal(key, PrefixEnd(tablePrefix)):
+			return KindRecord, 0, true // the records are the last part of the table
+		case bytes.Equal(key, append(bytes.Clone(tablePrefix), []byte(separator+"s")...)):
+			return KindRecord, 0, true
+		}
+	}
+
+	p, err := ParseKeyParts(key)
+	if err != nil || p.TableID != tableID {
+		return "", 0, false
+	}
+	if p.Kind == "" { // the table prefix itself sorts before the indexes and the records
+		return KindIndex, math.MinInt64, true
+	}
+	if isEnd && p.Kind == KindIndex && len(key) == len(tablePrefix)+10 { // t{TableID}_i{IndexID}
+		return KindIndex, p.IndexID - 1, true
+	}
+
+	return p.Kind, p.IndexID, true
+}
+
+// isPointRange reports whether [start, end) contains only start, which is how TiDB builds
+// the range of a point get.
+func isPointRange(start, end []byte) bool {
+	if len(end) == len(start)+1 && end[len(end)-1] == 0x00 && bytes.HasPrefix(end, start) {
+		return true
+	}
+
+	return bytes.Equal(end, PrefixEnd(start))
+}
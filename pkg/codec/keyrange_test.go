@@ -0,0 +1,78 @@
+package codec
+
+import (
+	"testing"
+)
+
+func TestParseKeyParts(t *testing.T) {
+	record, _ := ParseKey("t132_r5")
+	index, _ := ParseKey("t132_i2_apple_5")
+	table, _ := ParsePrefix("t132")
+
+	p, err := ParseKeyParts(record)
+	if err != nil || p.TableID != 132 || p.Kind != KindRecord || !p.HasRowID || p.RowID != 5 {
+		t.Errorf("ParseKeyParts(t132_r5) = %+v, %v", p, err)
+	}
+
+	p, err = ParseKeyParts(index)
+	if err != nil || p.TableID != 132 || p.Kind != KindIndex || p.IndexID != 2 || len(p.Rest) == 0 {
+		t.Errorf("ParseKeyParts(t132_i2_apple_5) = %+v, %v", p, err)
+	}
+
+	p, err = ParseKeyParts(table)
+	if err != nil || p.TableID != 132 || p.Kind != "" {
+		t.Errorf("ParseKeyParts(t132) = %+v, %v", p, err)
+	}
+
+	if _, err := ParseKeyParts([]byte("mDDLJobHistory")); err == nil {
+		t.Error("ParseKeyParts() should fail for a non-table key")
+	}
+}
+
+func TestClassifyRange(t *testing.T) {
+	mustKey := func(s string) []byte {
+		t.Helper()
+		k, err := ParsePrefix(s)
+		if err != nil {
+			t.Fatalf("ParsePrefix(%s) error = %v", s, err)
+		}
+		return k
+	}
+	row := mustKey("t132_r5")
+	recordPrefix := mustKey("t132_r")
+
+	tests := []struct {
+		name    string
+		start   []byte
+		end     []byte
+		kind    RangeKind
+		tableID int64
+		indexID int64
+	}{
+		{"Point get", row, append(mustKey("t132_r5"), 0x00), RangePointGet, 132, 0},
+		{"Point get with prefix next", row, PrefixEnd(row), RangePointGet, 132, 0},
+		{"Full table scan", recordPrefix, PrefixEnd(recordPrefix), RangeFullTableScan, 132, 0},
+		{"Full table scan by handles", mustKey("t132_r-9223372036854775808"), append(mustKey("t132_r9223372036854775807"), 0x00), RangeFullTableScan, 132, 0},
+		{"Full table scan to next table", recordPrefix, mustKey("t133"), RangeFullTableScan, 132, 0},
+		{"Unbounded", nil, nil, RangeUnknown, 0, 0},
+		{"Open-ended start", nil, mustKey("t132_r100"), RangeTableScan, 132, 0},
+		{"Open-ended end", mustKey("t132_r100"), nil, RangeTableScan, 132, 0},
+		{"Table range scan", mustKey("t132_r100"), mustKey("t132_r200"), RangeTableScan, 132, 0},
+		{"Index range", mustKey("t132_i2_apple"), mustKey("t132_i2_banana"), RangeIndexScan, 132, 2},
+		{"Whole index", mustKey("t132_i2"), mustKey("t132_i3"), RangeIndexScan, 132, 2},
+		{"Open-ended index range", mustKey("t132_i2_apple"), nil, RangeIndexScan, 132, 2},
+		{"Across tables", mustKey("t132_r1"), mustKey("t140_r1"), RangeUnknown, 132, 0},
+		{"Across indexes", mustKey("t132_i1"), mustKey("t132_i3_x"), RangeUnknown, 132, 0},
+		{"Not a table key", []byte("m"), []byte("n"), RangeUnknown, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyRange(tt.start, tt.end)
+			if got.Kind != tt.kind || got.TableID != tt.tableID || got.IndexID != tt.indexID {
+				t.Errorf("ClassifyRange(%X, %X) = %+v, want %s (table %d, index %d)",
+					tt.start, tt.end, got, tt.kind, tt.tableID, tt.indexID)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package codec
+
+// apiV2TxnPrefix is the mode prefix of transactional keys in API V2 (keyspace) clusters.
+// See https://github.com/tikv/rfcs/blob/master/text/0069-api-v2.md
+const apiV2TxnPrefix = 'x'
+
+// KeyspacePrefix returns the prefix of the transactional keys in the keyspace:
+// 'x' followed by the 3-byte big-endian keyspace ID.
+func KeyspacePrefix(keyspaceID uint32) []byte {
+	return []byte{apiV2TxnPrefix, byte(keyspaceID >> 16), byte(keyspaceID >> 8), byte(keyspaceID)}
+}
+
+// EncodeKeyspaceKey puts the key into the keyspace, as it is stored in TiKV.
+func EncodeKeyspaceKey(keyspaceID uint32, key []byte) []byte {
+	return append(KeyspacePrefix(keyspaceID), key...)
+}
+
+// splitKeyspaceKey splits a key as stored in an API V2 cluster, e.g., in TiKV logs, into its
+// keyspace and the key within it, printed as [keyspace 66051] t132_r1. It returns false unless
+// the key within the keyspace is a table or meta key.
+func splitKeyspaceKey(key []byte) (uint32, []byte, bool) {
+	if len(key) < 5 || key[0] != apiV2TxnPrefix || (key[4] != 't' && key[4] != metaPrefix) {
+		return 0, nil, false
+	}
+	id := uint32(key[1])<<16 | uint32(key[2])<<8 | uint32(key[3])
+
+	return id, key[4:], true
+}
@@ -0,0 +1,223 @@
+package codec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// TiDB stores its metadata (schema versions, DDL jobs, DB/table info, ...) under the 'm' prefix
+// with a small data structure layer on top of plain keys.
+// Ref: https://github.com/pingcap/tidb/blob/master/pkg/structure/type.go
+// - string: m + EncodeBytes(key) + EncodeUint('s')
+// - hash:   m + EncodeBytes(key) + EncodeUint('h') + EncodeBytes(field), with a hash meta key ending with 'H'
+// - list:   m + EncodeBytes(key) + EncodeUint('l') + EncodeInt(index), with a list meta key ending with 'L'
+const metaPrefix = 'm'
+
+// The readable form of meta keys, printed by DecodeKey and accepted by ParseKey:
+// - string data: m{key}, e.g., mSchemaVersionKey, mDiff:7
+// - hash data:   m{key}/{field}, e.g., mDBs/DB:2, mDB:2/Table:100, mDDLJobHistory/0x000000000000002a
+// - list data:   m{key}[{index}], e.g., mDDLJobList[0]
+// A hash field which isn't printable is written as 0x{hex}. The structure meta keys are printed with their type, e.g., mDB:2 (hash meta).
+// As a prefix, m scans all meta keys, m{key}/ all fields of a hash and m{key}[] all elements of a
+// list, e.g., mDDLJobList[] for the DDL job queue.
+const (
+	metaHashFieldSeparator = "/"
+	metaHexFieldPrefix     = "0x"
+	metaListPrefixSuffix   = "[]"
+)
+
+// MetaType is the structure type flag of a meta key.
+type MetaType byte
+
+const (
+	MetaStringMeta MetaType = 'S'
+	MetaStringData MetaType = 's'
+	MetaHashMeta   MetaType = 'H'
+	MetaHashData   MetaType = 'h'
+	MetaListMeta   MetaType = 'L'
+	MetaListData   MetaType = 'l'
+)
+
+// Well-known meta keys.
+// Ref: https://github.com/pingcap/tidb/blob/master/pkg/meta/meta.go
+const (
+	MetaSchemaVersionKey = "SchemaVersionKey"
+	MetaDDLJobHistoryKey = "DDLJobHistory"
+	metaSchemaDiffPrefix = "Diff"
+)
+
+// EncodeMetaStringKey returns the TiKV key of the meta string value named key.
+func EncodeMetaStringKey(key string) []byte {
+	buf := []byte{metaPrefix}
+	buf = tidbcodec.EncodeBytes(buf, []byte(key))
+	return tidbcodec.EncodeUint(buf, uint64(MetaStringData))
+}
+
+// EncodeMetaHashDataPrefix returns the prefix shared by all fields of the meta hash named key.
+func EncodeMetaHashDataPrefix(key string) []byte {
+	buf := []byte{metaPrefix}
+	buf = tidbcodec.EncodeBytes(buf, []byte(key))
+	return tidbcodec.EncodeUint(buf, uint64(MetaHashData))
+}
+
+// EncodeMetaHashDataKey returns the TiKV key of the field in the meta hash named key.
+func EncodeMetaHashDataKey(key string, field []byte) []byte {
+	return tidbcodec.EncodeBytes(EncodeMetaHashDataPrefix(key), field)
+}
+
+// SchemaDiffKey returns the name of the meta string which holds the schema diff of the version.
+func SchemaDiffKey(version int64) string {
+	return fmt.Sprintf("%s:%d", metaSchemaDiffPrefix, version)
+}
+
+// MetaKey is the decoded form of a TiDB meta key.
+type MetaKey struct {
+	Key   string   `json:"key"`
+	Type  MetaType `json:"type"`
+	Field []byte   `json:"field,omitempty"` // only for hash data
+	Index int64    `json:"index,omitempty"` // only for list data
+}
+
+// DecodeMetaKey decodes a TiKV key under the 'm' prefix.
+func DecodeMetaKey(key []byte) (MetaKey, error) {
+	if len(key) == 0 || key[0] != metaPrefix {
+		return MetaKey{}, fmt.Errorf("meta key must start with 'm'")
+	}
+
+	remaining, name, err := tidbcodec.DecodeBytes(key[1:], nil)
+	if err != nil {
+		return MetaKey{}, fmt.Errorf("failed to decode meta key name: %v", err)
+	}
+
+	remaining, flag, err := tidbcodec.DecodeUint(remaining)
+	if err != nil {
+		return MetaKey{}, fmt.Errorf("failed to decode meta type flag of %s: %v", name, err)
+	}
+
+	mk := MetaKey{Key: string(name), Type: MetaType(flag)}
+	switch mk.Type {
+	case MetaStringMeta, MetaStringData, MetaHashMeta, MetaListMeta:
+		// no further data
+	case MetaHashData:
+		remaining, mk.Field, err = tidbcodec.DecodeBytes(remaining, nil)
+		if err != nil {
+			return MetaKey{}, fmt.Errorf("failed to decode hash field of %s: %v", name, err)
+		}
+	case MetaListData:
+		remaining, mk.Index, err = tidbcodec.DecodeInt(remaining)
+		if err != nil {
+			return MetaKey{}, fmt.Errorf("failed to decode list index of %s: %v", name, err)
+		}
+	default:
+		return MetaKey{}, fmt.Errorf("unknown meta type flag %#x of %s", flag, name)
+	}
+
+	if len(remaining) != 0 {
+		return MetaKey{}, fmt.Errorf("unexpected %d trailing bytes in meta key %s", len(remaining), name)
+	}
+
+	return mk, nil
+}
+
+// String returns the readable form of the meta key, e.g., mDB:2/Table:100.
+func (mk MetaKey) String() string {
+	name := string(metaPrefix) + mk.Key
+	switch mk.Type {
+	case MetaStringMeta:
+		return name + " (string meta)"
+	case MetaHashMeta:
+		return name + " (hash meta)"
+	case MetaListMeta:
+		return name + " (list meta)"
+	case MetaHashData:
+		if len(mk.Field) > 0 && isLooksLikeString(mk.Field) && !strings.HasPrefix(string(mk.Field), metaHexFieldPrefix) {
+			return name + metaHashFieldSeparator + string(mk.Field)
+		}
+		return name + metaHashFieldSeparator + metaHexFieldPrefix + hex.EncodeToString(mk.Field)
+	case MetaListData:
+		return fmt.Sprintf("%s[%d]", name, mk.Index)
+	default:
+		return name
+	}
+}
+
+// decodeMetaKeyString returns the readable form of a meta key, or of a meta prefix accepted by
+// ParsePrefix (m, m{key}/, m{key}[]). It falls back to hex.
+func decodeMetaKeyString(key []byte) string {
+	if mk, err := DecodeMetaKey(key); err == nil {
+		return mk.String()
+	}
+
+	if len(key) == 1 {
+		return string(metaPrefix)
+	}
+	if remaining, name, err := tidbcodec.DecodeBytes(key[1:], nil); err == nil {
+		if remaining, flag, err := tidbcodec.DecodeUint(remaining); err == nil && len(remaining) == 0 {
+			switch MetaType(flag) {
+			case MetaHashData:
+				return string(metaPrefix) + string(name) + metaHashFieldSeparator
+			case MetaListData:
+				return string(metaPrefix) + string(name) + metaListPrefixSuffix
+			}
+		}
+	}
+
+	return hex.EncodeToString(key)
+}
+
+// parseMetaKey parses the readable form of a meta key. As a prefix (strict is false), m alone,
+// a hash without its field (m{key}/) and a list without its index (m{key}[]) are accepted too.
+func parseMetaKey(input string, strict bool) ([]byte, error) {
+	name := strings.TrimPrefix(input, string(metaPrefix))
+	if name == "" {
+		if strict {
+			return nil, fmt.Errorf("invalid key for get: 'm' is a prefix, not a specific key")
+		}
+		return []byte{metaPrefix}, nil
+	}
+
+	if key, field, ok := strings.Cut(name, metaHashFieldSeparator); ok {
+		if key == "" {
+			return nil, fmt.Errorf("invalid meta key format: %s", input)
+		}
+		if field == "" {
+			if strict {
+				return nil, fmt.Errorf("invalid key for get: meta hash %s is a prefix, not a specific key", input)
+			}
+			return EncodeMetaHashDataPrefix(key), nil
+		}
+		if h, isHex := strings.CutPrefix(field, metaHexFieldPrefix); isHex {
+			b, err := hex.DecodeString(h)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hex field(%s) of meta key %s: %v", field, input, err)
+			}
+			return EncodeMetaHashDataKey(key, b), nil
+		}
+		return EncodeMetaHashDataKey(key, []byte(field)), nil
+	}
+
+	if key, ok := strings.CutSuffix(name, metaListPrefixSuffix); ok && key != "" {
+		if strict {
+			return nil, fmt.Errorf("invalid key for get: meta list %s is a prefix, not a specific key", input)
+		}
+		buf := []byte{metaPrefix}
+		buf = tidbcodec.EncodeBytes(buf, []byte(key))
+		return tidbcodec.EncodeUint(buf, uint64(MetaListData)), nil
+	}
+	if key, index, ok := strings.Cut(name, "["); ok && strings.HasSuffix(index, "]") {
+		i, err := strconv.ParseInt(strings.TrimSuffix(index, "]"), 10, 64)
+		if err != nil || key == "" {
+			return nil, fmt.Errorf("invalid meta list key format: %s", input)
+		}
+		buf := []byte{metaPrefix}
+		buf = tidbcodec.EncodeBytes(buf, []byte(key))
+		buf = tidbcodec.EncodeUint(buf, uint64(MetaListData))
+		return tidbcodec.EncodeInt(buf, i), nil
+	}
+
+	return EncodeMetaStringKey(name), nil
+}
@@ -0,0 +1,286 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/tidb/pkg/meta/model"
+	pmodel "github.com/pingcap/tidb/pkg/parser/model"
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+func TestEncodeMetaStringKey(t *testing.T) {
+	// m + EncodeBytes("SchemaVersionKey") + EncodeUint('s')
+	expected := []byte{'m'}
+	expected = tidbcodec.EncodeBytes(expected, []byte("SchemaVersionKey"))
+	expected = tidbcodec.EncodeUint(expected, uint64('s'))
+
+	got := EncodeMetaStringKey(MetaSchemaVersionKey)
+	if !bytes.Equal(got, expected) {
+		t.Errorf("EncodeMetaStringKey() = %X, want %X", got, expected)
+	}
+}
+
+func TestDecodeMetaKey(t *testing.T) {
+	jobID := []byte{0, 0, 0, 0, 0, 0, 0, 42}
+
+	tests := []struct {
+		name     string
+		key      []byte
+		expected MetaKey
+		hasError bool
+	}{
+		{
+			name:     "String data (schema version)",
+			key:      EncodeMetaStringKey(MetaSchemaVersionKey),
+			expected: MetaKey{Key: "SchemaVersionKey", Type: MetaStringData},
+		},
+		{
+			name:     "String data (schema diff)",
+			key:      EncodeMetaStringKey(SchemaDiffKey(7)),
+			expected: MetaKey{Key: "Diff:7", Type: MetaStringData},
+		},
+		{
+			name:     "Hash data (DDL history)",
+			key:      EncodeMetaHashDataKey(MetaDDLJobHistoryKey, jobID),
+			expected: MetaKey{Key: "DDLJobHistory", Type: MetaHashData, Field: jobID},
+		},
+		{
+			name:     "Not a meta key",
+			key:      []byte("t123"),
+			hasError: true,
+		},
+		{
+			name: "Trailing garbage",
+			key:  append(EncodeMetaStringKey(MetaSchemaVersionKey), 0x01),
+			// string data must not have any field
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeMetaKey(tt.key)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("DecodeMetaKey() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeMetaKey() error = %v", err)
+			}
+
+			if got.Key != tt.expected.Key || got.Type != tt.expected.Type || !bytes.Equal(got.Field, tt.expected.Field) {
+				t.Errorf("DecodeMetaKey() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMetaKeyReadableForm(t *testing.T) {
+	// the readable forms survive ParseKey -> DecodeKey unchanged
+	for _, input := range []string{"mSchemaVersionKey", "mDiff:7", "mDBs/DB:2", "mDB:2/Table:100", "mDDLJobHistory/0x000000000000002a", "mDDLJobList[3]"} {
+		raw, err := ParseKey(input)
+		if err != nil {
+			t.Errorf("ParseKey(%s) error = %v", input, err)
+			continue
+		}
+		if got := DecodeKey(raw); got != input {
+			t.Errorf("DecodeKey(ParseKey(%s)) = %s", input, got)
+		}
+	}
+
+	raw, _ := ParseKey("mDB:2/Table:100")
+	if want := EncodeMetaHashDataKey("DB:2", []byte("Table:100")); !bytes.Equal(raw, want) {
+		t.Errorf("ParseKey(mDB:2/Table:100) = %X, want %X", raw, want)
+	}
+
+	// a hash prefix covers all of its fields
+	prefix, err := ParsePrefix("mDB:2/")
+	if err != nil || !bytes.HasPrefix(raw, prefix) || DecodeKey(prefix) != "mDB:2/" {
+		t.Errorf("ParsePrefix(mDB:2/) = %X, %v, want the prefix of %X", prefix, err, raw)
+	}
+	if _, err := ParseKey("mDB:2/"); err == nil {
+		t.Error("ParseKey(mDB:2/) error = nil, want error for a prefix")
+	}
+	if _, err := ParseKey("m"); err == nil {
+		t.Error("ParseKey(m) error = nil, want error for a prefix")
+	}
+
+	// a list prefix covers all of its elements
+	job, _ := ParseKey("mDDLJobList[3]")
+	prefix, err = ParsePrefix("mDDLJobList[]")
+	if err != nil || !bytes.HasPrefix(job, prefix) || DecodeKey(prefix) != "mDDLJobList[]" {
+		t.Errorf("ParsePrefix(mDDLJobList[]) = %X, %v, want the prefix of %X", prefix, err, job)
+	}
+	if _, err := ParseKey("mDDLJobList[]"); err == nil {
+		t.Error("ParseKey(mDDLJobList[]) error = nil, want error for a prefix")
+	}
+
+	// the structure meta key and keys which aren't meta keys
+	hashMeta := append([]byte{'m'}, tidbcodec.EncodeBytes(nil, []byte("DB:2"))...)
+	hashMeta = tidbcodec.EncodeUint(hashMeta, uint64(MetaHashMeta))
+	if got := DecodeKey(hashMeta); got != "mDB:2 (hash meta)" {
+		t.Errorf("DecodeKey(hash meta) = %s", got)
+	}
+	if got := DecodeKey([]byte{'m', 0x01}); got != "6d01" {
+		t.Errorf("DecodeKey(broken meta key) = %s, want hex", got)
+	}
+}
+
+func TestDecodeSchemaVersionEntries(t *testing.T) {
+	version, err := DecodeSchemaVersion([]byte("58"))
+	if err != nil || version != 58 {
+		t.Errorf("DecodeSchemaVersion() = %d, %v, want 58", version, err)
+	}
+	if _, err := DecodeSchemaVersion([]byte("abc")); err == nil {
+		t.Error("DecodeSchemaVersion() should fail for a non-numeric value")
+	}
+
+	diff, _ := json.Marshal(&model.SchemaDiff{Version: 58, Type: model.ActionAddIndex, SchemaID: 2, TableID: 132})
+	entry, err := DecodeSchemaDiff(diff)
+	if err != nil {
+		t.Fatalf("DecodeSchemaDiff() error = %v", err)
+	}
+	if entry.Version != 58 || entry.TableID != 132 || entry.Type != model.ActionAddIndex.String() {
+		t.Errorf("DecodeSchemaDiff() = %+v", entry)
+	}
+
+	job := &model.Job{
+		ID:         100,
+		Type:       model.ActionCreateTable,
+		SchemaID:   2,
+		TableID:    132,
+		SchemaName: "test",
+		TableName:  "authors",
+		State:      model.JobStateSynced,
+		Query:      "CREATE TABLE authors (id INT PRIMARY KEY)",
+		BinlogInfo: &model.HistoryInfo{SchemaVersion: 57, FinishedTS: 449460987526643717},
+	}
+	b, _ := json.Marshal(job)
+	entry, err = DecodeDDLJob(b)
+	if err != nil {
+		t.Fatalf("DecodeDDLJob() error = %v", err)
+	}
+	if entry.Version != 57 || entry.JobID != 100 || entry.TableName != "authors" ||
+		entry.Type != model.ActionCreateTable.String() || entry.State != model.JobStateSynced.String() ||
+		entry.FinishedTS != 449460987526643717 {
+		t.Errorf("DecodeDDLJob() = %+v", entry)
+	}
+}
+
+func TestDecodeMetaValue(t *testing.T) {
+	id := types.NewFieldType(mysql.TypeLonglong)
+	id.SetFlag(mysql.PriKeyFlag)
+	name := types.NewFieldType(mysql.TypeVarchar)
+	name.SetFlen(20)
+	tbl, _ := json.Marshal(&model.TableInfo{
+		ID:         132,
+		Name:       pmodel.NewCIStr("authors"),
+		PKIsHandle: true,
+		Columns: []*model.ColumnInfo{
+			{ID: 1, Name: pmodel.NewCIStr("id"), FieldType: *id},
+			{ID: 2, Name: pmodel.NewCIStr("name"), FieldType: *name},
+		},
+		Indices: []*model.IndexInfo{
+			{ID: 1, Name: pmodel.NewCIStr("idx_name"), Columns: []*model.IndexColumn{{Name: pmodel.NewCIStr("name")}}},
+		},
+	})
+	db, _ := json.Marshal(&model.DBInfo{ID: 2, Name: pmodel.NewCIStr("test")})
+	diff, _ := json.Marshal(&model.SchemaDiff{Version: 58, Type: model.ActionAddIndex, SchemaID: 2, TableID: 132})
+
+	v := DecodeValueForKey(EncodeMetaHashDataKey("DB:2", []byte("Table:132")), tbl)
+	meta, ok := v.Payload.(MetaValue)
+	if v.Type != TypeMeta || !ok {
+		t.Fatalf("DecodeValueForKey(table info) = %+v, want a meta value", v)
+	}
+	wantCols := []MetaColumn{{ID: 1, Name: "id", Type: "bigint(20)", Handle: true}, {ID: 2, Name: "name", Type: "varchar(20)"}}
+	if got, want := meta.String(), "table 132 `authors` (2 columns)"; got != want {
+		t.Errorf("MetaValue.String() = %s, want %s", got, want)
+	}
+	if len(meta.Columns) != 2 || meta.Columns[0] != wantCols[0] || meta.Columns[1] != wantCols[1] {
+		t.Errorf("MetaValue.Columns = %+v, want %+v", meta.Columns, wantCols)
+	}
+	if len(meta.Indexes) != 1 || meta.Indexes[0] != "1 idx_name (name)" {
+		t.Errorf("MetaValue.Indexes = %v, want [1 idx_name (name)]", meta.Indexes)
+	}
+
+	// the columns of a clustered primary key get their positions in it, as the pk of a schema file
+	clustered, _ := json.Marshal(&model.TableInfo{
+		ID:             140,
+		Name:           pmodel.NewCIStr("orders"),
+		IsCommonHandle: true,
+		Columns: []*model.ColumnInfo{
+			{ID: 1, Name: pmodel.NewCIStr("region"), Offset: 0, FieldType: *name},
+			{ID: 2, Name: pmodel.NewCIStr("id"), Offset: 1, FieldType: *id},
+		},
+		Indices: []*model.IndexInfo{{ID: 1, Name: pmodel.NewCIStr("PRIMARY"), Primary: true, Columns: []*model.IndexColumn{
+			{Name: pmodel.NewCIStr("id"), Offset: 1}, {Name: pmodel.NewCIStr("region"), Offset: 0},
+		}}},
+	})
+	meta, _ = DecodeValueForKey(EncodeMetaHashDataKey("DB:2", []byte("Table:140")), clustered).Payload.(MetaValue)
+	if len(meta.Columns) != 2 || meta.Columns[0].PK != 2 || meta.Columns[1].PK != 1 || meta.Columns[1].Handle {
+		t.Errorf("MetaValue.Columns = %+v, want region as pk 2 and id as pk 1", meta.Columns)
+	}
+
+	tests := []struct {
+		key   []byte
+		value []byte
+		want  string
+	}{
+		{EncodeMetaHashDataKey("DBs", []byte("DB:2")), db, "database 2 `test`"},
+		{EncodeMetaStringKey(MetaSchemaVersionKey), []byte("58"), "58"},
+		{EncodeMetaStringKey(SchemaDiffKey(58)), diff, "schema diff of version 58: add index (schema 2, table 132)"},
+		{EncodeMetaHashDataKey("DB:2", []byte("TID:132")), []byte("30001"), "30001"},
+	}
+	for _, tt := range tests {
+		v := DecodeValueForKey(tt.key, tt.value)
+		if v.Type != TypeMeta || v.String() != tt.want {
+			t.Errorf("DecodeValueForKey(%s) = %s %s, want %s", DecodeKey(tt.key), v.Type, v, tt.want)
+		}
+	}
+
+	// the DDL jobs queued and done
+	job, _ := json.Marshal(&model.Job{
+		ID:         101,
+		Type:       model.ActionAddIndex,
+		SchemaID:   2,
+		TableID:    132,
+		SchemaName: "test",
+		TableName:  "authors",
+		State:      model.JobStateRunning,
+		RowCount:   5000,
+		Query:      "ALTER TABLE authors ADD INDEX idx_name (name)",
+	})
+	queued, _ := ParseKey("mDDLJobAddIdxList[0]")
+	v = DecodeValueForKey(queued, job)
+	if want := "queued DDL job 101: add index test.authors (schema 2, table 132), state running"; v.Type != TypeMeta || v.String() != want {
+		t.Errorf("DecodeValueForKey(queued job) = %s, want %s", v, want)
+	}
+	if meta := v.Payload.(MetaValue); meta.Change.RowCount != 5000 || meta.Change.Query == "" {
+		t.Errorf("DecodeValueForKey(queued job) = %+v, want the row count and the query", meta.Change)
+	}
+	done, _ := json.Marshal(&model.Job{
+		ID: 101, Type: model.ActionAddIndex, SchemaID: 2, TableID: 132, SchemaName: "test", TableName: "authors",
+		State:      model.JobStateRollbackDone,
+		BinlogInfo: &model.HistoryInfo{SchemaVersion: 59},
+	})
+	done = bytes.Replace(done, []byte(`"err":null`), []byte(`"err":{"class":0,"code":1062,"message":"Duplicate entry '1' for key 'idx_name'"}`), 1)
+	v = DecodeValueForKey(EncodeMetaHashDataKey(MetaDDLJobHistoryKey, []byte{0, 0, 0, 0, 0, 0, 0, 101}), done)
+	if want := "DDL job 101 of version 59: add index test.authors (schema 2, table 132), state rollback done"; v.Type != TypeMeta || v.String() != want {
+		t.Errorf("DecodeValueForKey(history job) = %s, want %s", v, want)
+	}
+	if got := v.Payload.(MetaValue).Change.Error; !strings.Contains(got, "[1062] Duplicate entry") {
+		t.Errorf("DecodeValueForKey(history job) error = %s, want the duplicate entry", got)
+	}
+
+	// a value which isn't the table info falls back to DecodeValue
+	if v := DecodeValueForKey(EncodeMetaHashDataKey("DB:2", []byte("Table:132")), []byte{0x80, 0x00}); v.Type == TypeMeta {
+		t.Errorf("DecodeValueForKey(invalid table info) = %+v, want no meta value", v)
+	}
+}
@@ -0,0 +1,172 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/meta/model"
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+)
+
+// The meta keys which hold the database and the table infos: the field DB:{id} of the hash
+// DBs, and the field Table:{id} of the hash DB:{id} of the database of the table.
+// Ref: https://github.com/pingcap/tidb/blob/master/pkg/meta/meta.go
+const (
+	metaDBsKey         = "DBs"
+	metaDBPrefix       = "DB:"
+	metaTablePrefix    = "Table:"
+	metaKindDatabase   = "database"
+	metaKindTable      = "table"
+	metaKindVersion    = "schema_version"
+	metaKindSchemaDiff = "schema_diff"
+	metaKindDDLJob     = "ddl_job"
+	metaKindDDLQueued  = "ddl_job_queued"
+	metaKindText       = "text"
+)
+
+// The meta lists of the DDL jobs waiting to run, which TiDB before v6.2 keeps in the meta keys;
+// ADD INDEX jobs have a list of their own. The jobs done are moved to the DDLJobHistory hash,
+// each field the job ID as 8 bytes.
+const (
+	metaDDLJobListKey       = "DDLJobList"
+	metaDDLJobAddIdxListKey = "DDLJobAddIdxList"
+)
+
+// MetaValue is the decoded value of a meta key: the summary of a database or a table info, a
+// schema change, or the text of other values such as the auto IDs.
+type MetaValue struct {
+	Kind       string              `json:"kind"` // database, table, schema_version, schema_diff, ddl_job, ddl_job_queued or text
+	ID         int64               `json:"id,omitempty"`
+	Name       string              `json:"name,omitempty"`
+	Columns    []MetaColumn        `json:"columns,omitempty"`    // only for a table
+	Indexes    []string            `json:"indexes,omitempty"`    // only for a table, e.g., 1 idx_name (name)
+	Partitions []int64             `json:"partitions,omitempty"` // the physical table IDs of a partitioned table
+	Change     *SchemaVersionEntry `json:"change,omitempty"`     // only for a schema diff or a DDL job, done or queued
+	Text       string              `json:"text,omitempty"`       // the schema version or the text of another value
+}
+
+// MetaColumn is a column of a table info, as written in a schema file, see ParseSchemas.
+type MetaColumn struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Handle bool   `json:"handle,omitempty"`
+	PK     int    `json:"pk,omitempty"` // the position in a clustered primary key which isn't the handle
+}
+
+// String returns the value on one line, e.g., table 100 `t1` (3 columns), or the schema
+// version or the text as they are.
+func (v MetaValue) String() string {
+	switch v.Kind {
+	case metaKindDatabase:
+		return fmt.Sprintf("database %d `%s`", v.ID, v.Name)
+	case metaKindTable:
+		return fmt.Sprintf("table %d `%s` (%d columns)", v.ID, v.Name, len(v.Columns))
+	case metaKindSchemaDiff:
+		e := v.Change
+		return fmt.Sprintf("schema diff of version %d: %s (schema %d, table %d)", e.Version, e.Type, e.SchemaID, e.TableID)
+	case metaKindDDLJob:
+		e := v.Change
+		return fmt.Sprintf("DDL job %d of version %d: %s %s.%s (schema %d, table %d), state %s",
+			e.JobID, e.Version, e.Type, e.SchemaName, e.TableName, e.SchemaID, e.TableID, e.State)
+	case metaKindDDLQueued:
+		e := v.Change
+		return fmt.Sprintf("queued DDL job %d: %s %s.%s (schema %d, table %d), state %s",
+			e.JobID, e.Type, e.SchemaName, e.TableName, e.SchemaID, e.TableID, e.State)
+	default:
+		return v.Text
+	}
+}
+
+// decodeMetaValue decodes the value of a meta key by the key: the JSON of the database and table
+// infos, the schema diffs and the DDL jobs, and the schema version. Other values are printed as
+// text when they are text. It reports false for a value it can't decode.
+func (o Options) decodeMetaValue(mk MetaKey, value []byte) (MetaValue, bool) {
+	field := string(mk.Field)
+	switch {
+	case mk.Type == MetaHashData && mk.Key == metaDBsKey && strings.HasPrefix(field, metaDBPrefix):
+		var db model.DBInfo
+		if err := json.Unmarshal(value, &db); err != nil {
+			return MetaValue{}, false
+		}
+		return MetaValue{Kind: metaKindDatabase, ID: db.ID, Name: db.Name.O}, true
+
+	case mk.Type == MetaHashData && strings.HasPrefix(mk.Key, metaDBPrefix) && strings.HasPrefix(field, metaTablePrefix):
+		var tbl model.TableInfo
+		if err := json.Unmarshal(value, &tbl); err != nil {
+			return MetaValue{}, false
+		}
+		return tableMetaValue(&tbl), true
+
+	case mk.Type == MetaStringData && mk.Key == MetaSchemaVersionKey:
+		v, err := DecodeSchemaVersion(value)
+		if err != nil {
+			return MetaValue{}, false
+		}
+		return MetaValue{Kind: metaKindVersion, Text: strconv.FormatInt(v, 10)}, true
+
+	case mk.Type == MetaStringData && strings.HasPrefix(mk.Key, metaSchemaDiffPrefix+":"):
+		diff, err := DecodeSchemaDiff(value)
+		if err != nil {
+			return MetaValue{}, false
+		}
+		return MetaValue{Kind: metaKindSchemaDiff, Change: &diff}, true
+
+	case mk.Type == MetaHashData && mk.Key == MetaDDLJobHistoryKey:
+		job, err := DecodeDDLJob(value)
+		if err != nil {
+			return MetaValue{}, false
+		}
+		return MetaValue{Kind: metaKindDDLJob, Change: &job}, true
+
+	case mk.Type == MetaListData && (mk.Key == metaDDLJobListKey || mk.Key == metaDDLJobAddIdxListKey):
+		job, err := DecodeDDLJob(value)
+		if err != nil {
+			return MetaValue{}, false
+		}
+		return MetaValue{Kind: metaKindDDLQueued, Change: &job}, true
+	}
+
+	if !isLooksLikeString(value) {
+		return MetaValue{}, false
+	}
+
+	return MetaValue{Kind: metaKindText, Text: o.limitValue(string(value), false)}, true
+}
+
+// tableMetaValue summarizes a table info by the columns and the indexes, with the column types
+// as in SHOW CREATE TABLE, so the columns make a schema file when TiDB is down.
+func tableMetaValue(tbl *model.TableInfo) MetaValue {
+	v := MetaValue{Kind: metaKindTable, ID: tbl.ID, Name: tbl.Name.O}
+	pk := map[int]int{} // the offsets of the clustered primary key columns to their positions
+	if pkIndex := tbl.GetPrimaryKey(); tbl.IsCommonHandle && pkIndex != nil {
+		for i, c := range pkIndex.Columns {
+			pk[c.Offset] = i + 1
+		}
+	}
+	for i, col := range tbl.Columns {
+		v.Columns = append(v.Columns, MetaColumn{
+			ID:     col.ID,
+			Name:   col.Name.O,
+			Type:   col.FieldType.InfoSchemaStr(),
+			Handle: tbl.PKIsHandle && mysql.HasPriKeyFlag(col.GetFlag()),
+			PK:     pk[i],
+		})
+	}
+	for _, idx := range tbl.Indices {
+		cols := make([]string, 0, len(idx.Columns))
+		for _, c := range idx.Columns {
+			cols = append(cols, c.Name.O)
+		}
+		v.Indexes = append(v.Indexes, fmt.Sprintf("%d %s (%s)", idx.ID, idx.Name.O, strings.Join(cols, ", ")))
+	}
+	if tbl.Partition != nil {
+		for _, def := range tbl.Partition.Definitions {
+			v.Partitions = append(v.Partitions, def.ID)
+		}
+	}
+
+	return v
+}
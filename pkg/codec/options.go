@@ -0,0 +1,97 @@
+package codec
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Options are the settings of decoding and parsing keys and values, e.g., the collation of the
+// strings in index keys. The zero Options decode the data the way TiDB stores it by default,
+// which is what the package-level functions such as DecodeKey and DecodeValue do; the methods
+// of the same names decode with the Options. Callers with different Options don't affect each
+// other.
+type Options struct {
+	// Collation decodes the strings of index keys and common handles, e.g., utf8mb4_general_ci.
+	// It applies to every string of the key. "" is DefaultCollation.
+	Collation string
+	// UnsignedHandles prints the int handles as unsigned integers. TiDB stores the handle of a
+	// BIGINT UNSIGNED primary key as its int64 bits, so 18446744073709551615 and -1 are the same
+	// key and the key itself can't tell which one is meant.
+	UnsignedHandles bool
+	// AutoRandomShardBits prints the int handles as the shard and the increment of an
+	// AUTO_RANDOM primary key of this many shard bits, e.g., {shard=5,inc=1} instead of
+	// 1441151880758558721 for AUTO_RANDOM(5). 0 prints the row IDs as they are.
+	AutoRandomShardBits int
+	// FullHex prints the column values which can't be decoded as their complete hex instead of a
+	// preview of both ends, e.g., to compare corrupt values.
+	FullHex bool
+	// MaxHexBytes cuts the hex of a value after this many bytes, with their length, so a huge
+	// value doesn't take several times its size in memory. 0 is DefaultMaxHexBytes.
+	MaxHexBytes int
+	// MaxValueBytes cuts the decoded strings and JSON after this many bytes, with their total
+	// length, so a large BLOB or JSON column doesn't flood the output. 0 prints them whole.
+	MaxValueBytes int
+	// Redact masks the strings, the JSON and the bytes which can't be decoded in the values and
+	// the keys, e.g., while looking into production data during an incident. The numbers, the
+	// times, the column IDs and the structure of the keys stay readable.
+	Redact bool
+	// ColumnTypeHints decodes the RowV2 columns of these IDs by their type when no schema is
+	// given, e.g., a DATETIME column whose 8 bytes would pass as a BIGINT as well. The columns
+	// of other IDs are still decoded by their shape. See ParseColumnTypeHints.
+	ColumnTypeHints map[int64]ColumnType
+	// Charset transcodes the strings of the values which aren't UTF-8 from it, e.g., gbk for a
+	// table of CHARACTER SET gbk. The columns of a schema with a charset of their own keep it.
+	// "" is utf8mb4.
+	Charset string
+}
+
+// Validate reports the first option which isn't valid.
+func (o Options) Validate() error {
+	if o.Collation != "" {
+		if _, ok := collations[normalizeCollation(o.Collation)]; !ok {
+			return fmt.Errorf("unsupported collation %q: must be one of %s", o.Collation, strings.Join(slices.Sorted(maps.Keys(collations)), ", "))
+		}
+	}
+	if o.AutoRandomShardBits < 0 || o.AutoRandomShardBits > autoRandomShardBitsMax {
+		return fmt.Errorf("invalid AUTO_RANDOM shard bits %d: must be 1 to %d, or 0 to disable", o.AutoRandomShardBits, autoRandomShardBitsMax)
+	}
+	if o.MaxHexBytes < 0 {
+		return fmt.Errorf("invalid max hex bytes %d: must not be negative", o.MaxHexBytes)
+	}
+	if o.MaxValueBytes < 0 {
+		return fmt.Errorf("invalid max value bytes %d: must not be negative", o.MaxValueBytes)
+	}
+	if o.Charset != "" {
+		if _, err := parseCharset(o.Charset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keyCollation returns the collation of the strings in index keys.
+func (o Options) keyCollation() string {
+	if o.Collation == "" {
+		return DefaultCollation
+	}
+	return normalizeCollation(o.Collation)
+}
+
+// stringCharset returns the charset of the strings which aren't UTF-8.
+func (o Options) stringCharset() string {
+	if cs, err := parseCharset(o.Charset); err == nil {
+		return cs
+	}
+	return charsetUTF8
+}
+
+// maxHexBytes returns the bytes of a value printed as hex at most.
+func (o Options) maxHexBytes() int {
+	if o.MaxHexBytes <= 0 {
+		return DefaultMaxHexBytes
+	}
+	return o.MaxHexBytes
+}
@@ -0,0 +1,48 @@
+package codec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/pingcap/tidb/pkg/types"
+)
+
+// redactedHashLen is the number of hex digits of the SHA-256 kept in a mask.
+const redactedHashLen = 8
+
+// redacted returns the mask of a value, its length and the head of its SHA-256, e.g.,
+// <redacted len=15 sha256=1f2e3d4c>. Equal values get the same mask, so they can still be
+// compared across rows and index entries.
+func redacted(b []byte) string {
+	sum := sha256.Sum256(b)
+	return fmt.Sprintf("<redacted len=%d sha256=%s>", len(b), hex.EncodeToString(sum[:])[:redactedHashLen])
+}
+
+// redactedKeyHex returns the hex of a key with its string index values or common handle masked,
+// keeping the table, the index ID and the keyspace of the key. It returns false for a key
+// without strings to mask.
+func redactedKeyHex(key []byte) (string, bool) {
+	info, err := DecodeKeyStructured(key)
+	if err != nil {
+		return "", false
+	}
+
+	datums, n := info.IndexValues, 1+8+2+8 // t{TableID}_i{IndexID}
+	if info.Handle != nil {
+		datums, n = info.Handle.CommonHandle, 1+8+2 // t{TableID}_r
+	}
+	if !slices.ContainsFunc(datums, isStringDatum) {
+		return "", false
+	}
+	if info.KeyspaceID != nil {
+		n += 4
+	}
+
+	return fmt.Sprintf("%X%s", key[:n], redacted(key[n:])), true
+}
+
+func isStringDatum(d types.Datum) bool {
+	return d.Kind() == types.KindBytes || d.Kind() == types.KindString
+}
@@ -0,0 +1,52 @@
+package codec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	opts := Options{Redact: true}
+
+	mask := redacted([]byte("alice@example.com"))
+	if want := "<redacted len=17 sha256="; !strings.HasPrefix(mask, want) || len(mask) != len(want)+redactedHashLen+1 {
+		t.Fatalf("redacted() = %s, want the length and the hash", mask)
+	}
+
+	// strings are masked, even those which read as integers, while numbers stay
+	tests := []struct {
+		in   []byte
+		want string
+	}{
+		{[]byte("alice@example.com"), mask},
+		{[]byte("abcd"), redacted([]byte("abcd"))},
+		{[]byte{0x05}, "Int: 5 (Hex: 0x05)"},
+		{[]byte{0x2a}, redacted([]byte("*"))}, // 42 reads as "*" without a schema
+		{[]byte{0xff, 0x00, 0xfe, 0x01, 0xfd}, redacted([]byte{0xff, 0x00, 0xfe, 0x01, 0xfd})},
+	}
+	for _, tt := range tests {
+		if got := opts.trySmartDecode(tt.in); got != tt.want {
+			t.Errorf("trySmartDecode(%x) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	if got := opts.decodeColumn([]byte("alice@example.com"), ColumnString); got != mask {
+		t.Errorf("decodeColumn(string) = %s, want %s", got, mask)
+	}
+	if got := opts.decodeColumn([]byte{0x2a}, ColumnInt); got != "42" {
+		t.Errorf("decodeColumn(int) = %s, want 42", got)
+	}
+
+	// the index values of a key are masked, keeping the table, the index and the handle
+	key := collatedIndexKey(t, "alice", "utf8mb4_bin")
+	if got, want := opts.DecodeKey(key), "t1_i2_"+redacted([]byte("alice"))+"_5"; got != want {
+		t.Errorf("DecodeKey() = %s, want %s", got, want)
+	}
+	if got, want := opts.PrettyPrintKey(key), "7480000000000000015F698000000000000002<redacted len="; !strings.HasPrefix(got, want) {
+		t.Errorf("PrettyPrintKey() = %s, want the prefix %s", got, want)
+	}
+	rowKey, _ := ParseKey("t1_r5")
+	if got := opts.PrettyPrintKey(rowKey); got != "7480000000000000015F728000000000000005" {
+		t.Errorf("PrettyPrintKey() = %s, want the key of an int handle as it is", got)
+	}
+}
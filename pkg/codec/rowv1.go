@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// rowV1Flag is the flag of the varint datum of a column ID, which starts a Row Format V1 value.
+const rowV1Flag = 0x08
+
+// decodeRowV1 decodes a Row Format V1 value, written by TiDB before 4.0 and kept by clusters
+// upgraded since. It is the datums of the column IDs and the values in turn, encoded like
+// tidbcodec.EncodeValue, and times as their packed uint. It returns false unless the whole
+// value is made of such pairs.
+// Ref: https://github.com/pingcap/tidb/blob/master/pkg/tablecodec/tablecodec.go (EncodeOldRow)
+func (o Options) decodeRowV1(value []byte) (RowV2Data, bool) {
+	if len(value) == 0 || value[0] != rowV1Flag {
+		return RowV2Data{}, false
+	}
+
+	columns := make(map[int64]string)
+	for b := value; len(b) > 0; {
+		if b[0] != rowV1Flag {
+			return RowV2Data{}, false
+		}
+		rest, id, err := tidbcodec.DecodeOne(b)
+		if err != nil || id.GetInt64() <= 0 || len(rest) == 0 {
+			return RowV2Data{}, false
+		}
+		if _, ok := columns[id.GetInt64()]; ok {
+			return RowV2Data{}, false
+		}
+
+		rest, d, err := tidbcodec.DecodeOne(rest)
+		if err != nil {
+			return RowV2Data{}, false
+		}
+		columns[id.GetInt64()] = o.rowV1DatumString(d)
+		b = rest
+	}
+
+	return RowV2Data{Columns: columns}, true
+}
+
+// rowV1DatumString formats a value of a V1 row like trySmartDecode does a RowV2 column. The
+// datum carries its kind, but the times, the enums and the sets are uints all the same.
+func (o Options) rowV1DatumString(d types.Datum) string {
+	switch d.Kind() {
+	case types.KindNull:
+		return "NULL"
+	case types.KindInt64:
+		return fmt.Sprintf("Int: %d", d.GetInt64())
+	case types.KindUint64:
+		if t, ok := decodePackedTime(d.GetUint64()); ok {
+			return fmt.Sprintf("Uint: %d Datetime?: %s", d.GetUint64(), t)
+		}
+		return fmt.Sprintf("Uint: %d", d.GetUint64())
+	case types.KindFloat32, types.KindFloat64:
+		return strconv.FormatFloat(d.GetFloat64(), 'g', -1, 64)
+	case types.KindBytes, types.KindString:
+		b := d.GetBytes()
+		if len(b) == 0 {
+			return "NULL/Empty"
+		}
+		if o.Redact {
+			return redacted(b)
+		}
+		if text, ok := o.stringText(b, ""); ok {
+			return o.limitValue(text, true)
+		}
+		return o.previewHex(b)
+	case types.KindMysqlJSON:
+		if o.Redact {
+			return redacted(d.GetMysqlJSON().Value)
+		}
+		return o.limitValue(d.GetMysqlJSON().String(), false)
+	default:
+		s, err := d.ToString()
+		if err != nil {
+			return fmt.Sprintf("%v", d.GetValue())
+		}
+		return s
+	}
+}
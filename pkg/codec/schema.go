@@ -0,0 +1,418 @@
+package codec
+
+import (
+	"cmp"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// ColumnType is how the value of a column is encoded in a RowV2 value.
+type ColumnType string
+
+const (
+	ColumnInt       ColumnType = "int"
+	ColumnUint      ColumnType = "uint"
+	ColumnString    ColumnType = "string"
+	ColumnBlob      ColumnType = "blob"
+	ColumnDatetime  ColumnType = "datetime"
+	ColumnTimestamp ColumnType = "timestamp" // stored in UTC
+	ColumnTime      ColumnType = "time"      // a duration, stored as nanoseconds
+	ColumnJSON      ColumnType = "json"
+	ColumnDouble    ColumnType = "double" // float columns are stored as doubles too
+	ColumnDecimal   ColumnType = "decimal"
+	ColumnVector    ColumnType = "vector" // a VECTOR of float32
+	ColumnBit       ColumnType = "bit"    // stored as an unsigned integer
+	ColumnEnum      ColumnType = "enum"   // the 1-based ordinal of the label
+	ColumnSet       ColumnType = "set"    // a bit for each label
+)
+
+// IsNumeric reports whether the values of the type are numbers, written without quotes in SQL.
+func (t ColumnType) IsNumeric() bool {
+	switch t {
+	case ColumnInt, ColumnUint, ColumnDouble, ColumnDecimal:
+		return true
+	default:
+		return false
+	}
+}
+
+// columnTypes maps MySQL column types to the column type their RowV2 values are encoded as.
+var columnTypes = map[string]ColumnType{
+	"tinyint": ColumnInt, "smallint": ColumnInt, "mediumint": ColumnInt, "int": ColumnInt, "integer": ColumnInt,
+	"bigint": ColumnInt, "year": ColumnInt,
+	"uint": ColumnUint, "bit": ColumnBit, "enum": ColumnEnum, "set": ColumnSet,
+	"char": ColumnString, "varchar": ColumnString, "tinytext": ColumnString, "text": ColumnString,
+	"mediumtext": ColumnString, "longtext": ColumnString, "string": ColumnString,
+	"binary": ColumnBlob, "varbinary": ColumnBlob, "tinyblob": ColumnBlob, "blob": ColumnBlob,
+	"mediumblob": ColumnBlob, "longblob": ColumnBlob,
+	"date": ColumnDatetime, "datetime": ColumnDatetime, "timestamp": ColumnTimestamp, "time": ColumnTime,
+	"json": ColumnJSON, "float": ColumnDouble, "double": ColumnDouble, "real": ColumnDouble,
+	"decimal": ColumnDecimal, "numeric": ColumnDecimal, "vector": ColumnVector,
+}
+
+// ParseColumnType returns the column type of a MySQL column type such as varchar(64) or
+// int unsigned NOT NULL. The length, the precision and the attributes after the type, e.g., the
+// CHARACTER SET of a string, are ignored; see parseColumnCharset for the charset.
+func ParseColumnType(mysqlType string) (ColumnType, error) {
+	fields := strings.Fields(strings.ToLower(mysqlType))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("unsupported column type %q", mysqlType)
+	}
+	base, _, _ := strings.Cut(fields[0], "(")
+
+	t, ok := columnTypes[base]
+	if !ok {
+		return "", fmt.Errorf("unsupported column type %q", mysqlType)
+	}
+	if t == ColumnInt && slices.Contains(fields[1:], "unsigned") {
+		t = ColumnUint
+	}
+
+	return t, nil
+}
+
+// ColumnTypeName returns the name of a MySQL column type in upper case without the length and
+// the attributes, e.g., VARCHAR for varchar(64) CHARACTER SET gbk, and INT UNSIGNED for
+// int(10) unsigned.
+func ColumnTypeName(mysqlType string) string {
+	fields := strings.Fields(strings.ToUpper(mysqlType))
+	if len(fields) == 0 {
+		return ""
+	}
+	name, _, _ := strings.Cut(fields[0], "(")
+	if slices.Contains(fields[1:], "UNSIGNED") {
+		name += " UNSIGNED"
+	}
+
+	return name
+}
+
+// ColumnSchema describes a column of a table.
+type ColumnSchema struct {
+	ID     int64
+	Name   string
+	Type   ColumnType
+	Handle bool     // the integer primary key, stored in the record key instead of the value
+	PK     int      // the position of the column in a clustered primary key (a common handle), from 1
+	Elems  []string // the labels of an ENUM or a SET column, printed instead of the numbers
+	Width  int      // the N of a BIT(N) column
+	// the charset of a string column such as gbk, "" for the one of Options.Charset
+	Charset string
+	// the MySQL type name of the schema file, e.g., VARCHAR; "" for the bundled system tables
+	SQLType string
+}
+
+// TypeName returns the MySQL type name of the column, or the upper case of its column type
+// when the schema doesn't give one, e.g., STRING.
+func (c ColumnSchema) TypeName() string {
+	if c.SQLType != "" {
+		return c.SQLType
+	}
+	return strings.ToUpper(string(c.Type))
+}
+
+// TableSchema describes the columns of a table, enough to decode its RowV2 values.
+type TableSchema struct {
+	Name    string
+	TableID int64
+	Columns []ColumnSchema
+}
+
+// schemaFileColumn is a column in a schema file.
+type schemaFileColumn struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Handle bool   `json:"handle,omitempty"`
+	PK     int    `json:"pk,omitempty"`
+}
+
+// ParseSchemas parses a schema file: a JSON object keyed by table ID, then by column ID, e.g.,
+//
+//	{"132": {"1": {"name": "id", "type": "bigint", "handle": true}, "2": {"name": "name", "type": "varchar(64)"}}}
+//
+// The tables are named t{TableID} and their columns are sorted by column ID. "pk" gives the
+// position of a column in a clustered primary key which isn't a single integer, from 1, e.g.,
+//
+//	{"140": {"1": {"name": "id", "type": "bigint", "pk": 1}, "2": {"name": "region", "type": "varchar(8)", "pk": 2}}}
+func ParseSchemas(data []byte) (map[int64]TableSchema, error) {
+	var file map[string]map[string]schemaFileColumn
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid schema file: %v", err)
+	}
+
+	schemas := make(map[int64]TableSchema, len(file))
+	for tableKey, columns := range file {
+		tableID, err := strconv.ParseInt(tableKey, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid table ID %q in schema file", tableKey)
+		}
+
+		schema := TableSchema{Name: fmt.Sprintf("t%d", tableID), TableID: tableID}
+		for colKey, c := range columns {
+			colID, err := strconv.ParseInt(colKey, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid column ID %q of table %d in schema file", colKey, tableID)
+			}
+			typ, err := ParseColumnType(c.Type)
+			if err != nil {
+				return nil, fmt.Errorf("column %d of table %d: %w", colID, tableID, err)
+			}
+			name := c.Name
+			if name == "" {
+				name = fmt.Sprintf("col_%d", colID)
+			}
+			if c.PK < 0 || (c.PK > 0 && c.Handle) {
+				return nil, fmt.Errorf("column %d of table %d: invalid pk %d, the position in a clustered primary key which isn't the integer handle", colID, tableID, c.PK)
+			}
+			col := ColumnSchema{ID: colID, Name: name, Type: typ, Handle: c.Handle, PK: c.PK, SQLType: ColumnTypeName(c.Type)}
+			switch typ {
+			case ColumnEnum, ColumnSet:
+				col.Elems, err = parseColumnElems(c.Type)
+			case ColumnBit:
+				col.Width, err = parseBitWidth(c.Type)
+			case ColumnString:
+				col.Charset, err = parseColumnCharset(c.Type)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("column %d of table %d: %w", colID, tableID, err)
+			}
+			schema.Columns = append(schema.Columns, col)
+		}
+		slices.SortFunc(schema.Columns, func(a, b ColumnSchema) int { return cmp.Compare(a.ID, b.ID) })
+		for i, c := range schema.PrimaryKeyColumns() {
+			if c.PK != i+1 {
+				return nil, fmt.Errorf("table %d: the pk positions must be 1 to %d, each once", tableID, len(schema.PrimaryKeyColumns()))
+			}
+		}
+		schemas[tableID] = schema
+	}
+
+	return schemas, nil
+}
+
+// PrimaryKeyColumns returns the columns of the clustered primary key of the table in the order
+// of the key, or nil when the schema doesn't give one.
+func (s TableSchema) PrimaryKeyColumns() []ColumnSchema {
+	var cols []ColumnSchema
+	for _, c := range s.Columns {
+		if c.PK > 0 {
+			cols = append(cols, c)
+		}
+	}
+	slices.SortFunc(cols, func(a, b ColumnSchema) int { return cmp.Compare(a.PK, b.PK) })
+
+	return cols
+}
+
+// PrimaryKeyString returns the common handle of a record key with the names of the primary key
+// columns of the schema, e.g., id=5, region="us". It reports false when the key isn't a record
+// key of the table with a common handle of as many values as the schema has primary key columns.
+func (o Options) PrimaryKeyString(key []byte, schema TableSchema) (string, bool) {
+	cols := schema.PrimaryKeyColumns()
+	info, err := decodeKeyStructured(key)
+	if err != nil || info.Kind != KindRecord || info.TableID != schema.TableID || info.Handle == nil ||
+		len(cols) == 0 || len(info.Handle.CommonHandle) != len(cols) {
+		return "", false
+	}
+
+	strs := make([]string, 0, len(cols))
+	for i, d := range info.Handle.CommonHandle {
+		s := o.keyDatumString(d)
+		// the strings of a binary collation are the values themselves, so quote them as such
+		if isStringDatum(d) && !o.Redact && collations[o.keyCollation()] == collationBinary {
+			s = strconv.Quote(s)
+		}
+		strs = append(strs, cols[i].Name+"="+s)
+	}
+
+	return strings.Join(strs, ", "), true
+}
+
+// DecodedColumn is a column of a row decoded with its schema.
+type DecodedColumn struct {
+	ColumnSchema
+	Value string
+}
+
+// DecodeRowWithSchema decodes a RowV2 value into the columns of the schema, in the schema order.
+func DecodeRowWithSchema(value []byte, schema TableSchema) ([]DecodedColumn, error) {
+	return Options{}.DecodeRowWithSchema(value, schema)
+}
+
+// DecodeRowWithSchema decodes a RowV2 value like DecodeRowWithSchema does, with the strings of
+// the Options.
+func (o Options) DecodeRowWithSchema(value []byte, schema TableSchema) ([]DecodedColumn, error) {
+	raw, err := RowV2RawColumns(value)
+	if err != nil {
+		return nil, err
+	}
+
+	cols := make([]DecodedColumn, 0, len(schema.Columns))
+	for _, c := range schema.Columns {
+		col := DecodedColumn{ColumnSchema: c}
+		b, ok := raw[c.ID]
+		switch {
+		case c.Handle:
+			col.Value = "<handle, see the key>"
+		case !ok:
+			col.Value = "<not in the row, NULL/Default>"
+		case b == nil:
+			col.Value = "NULL"
+		default:
+			col.Value = o.decodeSchemaColumn(b, c)
+		}
+		cols = append(cols, col)
+	}
+
+	return cols, nil
+}
+
+// decodeSchemaColumn decodes the raw bytes of a column of a schema like decodeColumn, with the
+// labels of an ENUM or a SET, the charset of a string and the width of a BIT.
+func (o Options) decodeSchemaColumn(b []byte, c ColumnSchema) string {
+	if label, ok := columnLabel(b, c); ok {
+		return o.limitValue(label, true)
+	}
+	if c.Type == ColumnString && c.Charset != "" && !o.Redact {
+		if text, ok := o.charsetString(b, c.Charset); ok {
+			return o.limitValue(text, true)
+		}
+	}
+	if c.Type == ColumnBit {
+		if n, ok := decodeRowV2Bit(b, c.Width); ok {
+			return bitLiteral(n, c.Width)
+		}
+		return o.invalidColumn(b, c.Type)
+	}
+
+	return o.decodeColumn(b, c.Type)
+}
+
+// decodeColumn decodes the raw bytes of a RowV2 column of the type, falling back to hex when
+// the bytes don't fit the type.
+func (o Options) decodeColumn(b []byte, typ ColumnType) string {
+	if o.Redact && (typ == ColumnString || typ == ColumnBlob || typ == ColumnJSON || typ == ColumnVector) {
+		return redacted(b)
+	}
+
+	switch typ {
+	case ColumnInt:
+		if n, ok := decodeRowV2Int(b); ok {
+			return fmt.Sprintf("%d", n)
+		}
+	case ColumnUint, ColumnEnum, ColumnSet:
+		if n, ok := decodeRowV2Uint(b); ok {
+			return fmt.Sprintf("%d", n)
+		}
+	case ColumnString:
+		if text, ok := o.charsetString(b, ""); ok {
+			return o.limitValue(text, true)
+		}
+		return o.limitValue(string(b), true)
+	case ColumnBlob:
+		if isLooksLikeString(b) {
+			return o.limitValue(string(b), true)
+		}
+		return fmt.Sprintf("0x%x (len=%d)", b, len(b))
+	case ColumnDatetime, ColumnTimestamp:
+		if packed, ok := decodeRowV2Uint(b); ok {
+			var t types.Time
+			if err := t.FromPackedUint(packed); err == nil {
+				if typ == ColumnTimestamp {
+					return t.String() + " UTC"
+				}
+				return t.String()
+			}
+		}
+	case ColumnTime:
+		if d, ok := decodeRowV2Duration(b); ok {
+			return d
+		}
+	case ColumnBit:
+		if n, ok := decodeRowV2Bit(b, 0); ok {
+			return bitLiteral(n, 0)
+		}
+	case ColumnVector:
+		if v, ok := decodeVector(b); ok {
+			return o.formatVector(b, v)
+		}
+	case ColumnJSON:
+		if len(b) > 0 {
+			if s, ok := safeDecodeJson(b); ok {
+				return o.limitValue(s, false)
+			}
+		}
+	case ColumnDouble:
+		if _, f, err := tidbcodec.DecodeFloat(b); len(b) == 8 && err == nil {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	case ColumnDecimal:
+		if rest, dec, _, _, err := tidbcodec.DecodeDecimal(b); err == nil && len(rest) == 0 {
+			return dec.String()
+		}
+	}
+
+	return o.invalidColumn(b, typ)
+}
+
+// invalidColumn prints the raw bytes of a column which don't fit its type.
+func (o Options) invalidColumn(b []byte, typ ColumnType) string {
+	if o.Redact {
+		return fmt.Sprintf("Invalid %s %s", typ, redacted(b))
+	}
+	return fmt.Sprintf("Invalid %s (Hex: 0x%x)", typ, b)
+}
+
+// decodeRowV2Int decodes the compact little-endian signed integer of RowV2 (1, 2, 4 or 8 bytes).
+func decodeRowV2Int(b []byte) (int64, bool) {
+	n, ok := decodeRowV2Uint(b)
+	if !ok {
+		return 0, false
+	}
+
+	switch len(b) { // sign-extend the compact form
+	case 1:
+		return int64(int8(n)), true
+	case 2:
+		return int64(int16(n)), true
+	case 4:
+		return int64(int32(n)), true
+	default:
+		return int64(n), true
+	}
+}
+
+// decodeRowV2Duration decodes a TIME value, the nanoseconds of the duration as a RowV2 signed
+// integer, into HH:MM:SS.ffffff. The hours go beyond 24 and the duration may be negative.
+func decodeRowV2Duration(b []byte) (string, bool) {
+	n, ok := decodeRowV2Int(b)
+	if !ok {
+		return "", false
+	}
+
+	return types.Duration{Duration: time.Duration(n), Fsp: types.MaxFsp}.String(), true
+}
+
+// decodeRowV2Uint decodes the compact little-endian integer of RowV2 (1, 2, 4 or 8 bytes).
+func decodeRowV2Uint(b []byte) (uint64, bool) {
+	switch len(b) {
+	case 1:
+		return uint64(b[0]), true
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(b)), true
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b)), true
+	case 8:
+		return binary.LittleEndian.Uint64(b), true
+	default:
+		return 0, false
+	}
+}
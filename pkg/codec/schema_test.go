@@ -0,0 +1,298 @@
+package codec
+
+import (
+	"encoding/binary"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// encodeSmallRowV2 builds a RowV2 value with 1-byte column IDs and 2-byte offsets. Nil values
+// are NULL columns.
+func encodeSmallRowV2(cols map[int64][]byte) []byte {
+	var ids, nullIDs []int64
+	for _, id := range slices.Sorted(maps.Keys(cols)) {
+		if cols[id] == nil {
+			nullIDs = append(nullIDs, id)
+		} else {
+			ids = append(ids, id)
+		}
+	}
+
+	b := []byte{0x80, 0x00}
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(ids)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(nullIDs)))
+	for _, id := range append(ids, nullIDs...) {
+		b = append(b, byte(id))
+	}
+
+	var data []byte
+	for _, id := range ids {
+		data = append(data, cols[id]...)
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(data)))
+	}
+
+	return append(b, data...)
+}
+
+func TestDecodeRowWithSchemaSystemTable(t *testing.T) {
+	schema, err := SystemTable("tidb_background_subtask")
+	if err != nil {
+		t.Fatalf("SystemTable() error = %v", err)
+	}
+	if schema.TableID != 281474976710650 {
+		t.Errorf("TableID = %d, want 281474976710650", schema.TableID)
+	}
+
+	created := types.NewTime(types.FromDate(2024, 1, 2, 3, 4, 5, 0), mysql.TypeTimestamp, 0)
+	packed, err := created.ToPackedUint()
+	if err != nil {
+		t.Fatalf("ToPackedUint() error = %v", err)
+	}
+	summary, err := types.ParseBinaryJSONFromString(`{"row_count": 10}`)
+	if err != nil {
+		t.Fatalf("ParseBinaryJSONFromString() error = %v", err)
+	}
+
+	value := encodeSmallRowV2(map[int64][]byte{
+		2:  {0x01},                                        // step
+		3:  {},                                            // namespace
+		4:  []byte("ddl/backfill/112"),                    // task_key
+		5:  binary.LittleEndian.AppendUint16(nil, 300),    // ddl_physical_tid
+		7:  nil,                                           // exec_id
+		9:  []byte("running"),                             // state
+		12: binary.LittleEndian.AppendUint64(nil, packed), // create_time
+		13: {0xff},                                        // start_time
+		19: append([]byte{summary.TypeCode}, summary.Value...),
+	})
+
+	cols, err := DecodeRowWithSchema(value, schema)
+	if err != nil {
+		t.Fatalf("DecodeRowWithSchema() error = %v", err)
+	}
+	if len(cols) != len(schema.Columns) {
+		t.Fatalf("DecodeRowWithSchema() = %d columns, want %d", len(cols), len(schema.Columns))
+	}
+
+	got := make(map[string]string, len(cols))
+	for _, c := range cols {
+		got[c.Name] = c.Value
+	}
+	expected := map[string]string{
+		"id":               "<handle, see the key>",
+		"step":             "1",
+		"namespace":        `""`,
+		"task_key":         `"ddl/backfill/112"`,
+		"ddl_physical_tid": "300",
+		"exec_id":          "NULL",
+		"state":            `"running"`,
+		"create_time":      "2024-01-02 03:04:05 UTC",
+		"start_time":       "-1",
+		"summary":          `{"row_count": 10}`,
+		"meta":             "<not in the row, NULL/Default>",
+	}
+	for name, want := range expected {
+		if got[name] != want {
+			t.Errorf("column %s = %s, want %s", name, got[name], want)
+		}
+	}
+
+	if _, err := SystemTable("user"); err == nil {
+		t.Error("SystemTable(user) error = nil, want error")
+	}
+	if _, err := DecodeRowWithSchema([]byte("not a row"), schema); err == nil {
+		t.Error("DecodeRowWithSchema() error = nil, want error for a non-RowV2 value")
+	}
+}
+
+func TestParseSchemas(t *testing.T) {
+	schemas, err := ParseSchemas([]byte(`{"132": {
+		"3": {"name": "price", "type": "decimal(10,2)"},
+		"1": {"name": "id", "type": "bigint", "handle": true},
+		"2": {"name": "name", "type": "VARCHAR(64)"},
+		"4": {"name": "score", "type": "double"},
+		"5": {"name": "qty", "type": "int unsigned"}
+	}}`))
+	if err != nil {
+		t.Fatalf("ParseSchemas() error = %v", err)
+	}
+	schema, ok := schemas[132]
+	if !ok || schema.Name != "t132" || schema.TableID != 132 {
+		t.Fatalf("ParseSchemas() = %+v, want table 132", schemas)
+	}
+	expected := []ColumnSchema{
+		{ID: 1, Name: "id", Type: ColumnInt, Handle: true, SQLType: "BIGINT"},
+		{ID: 2, Name: "name", Type: ColumnString, SQLType: "VARCHAR"},
+		{ID: 3, Name: "price", Type: ColumnDecimal, SQLType: "DECIMAL"},
+		{ID: 4, Name: "score", Type: ColumnDouble, SQLType: "DOUBLE"},
+		{ID: 5, Name: "qty", Type: ColumnUint, SQLType: "INT UNSIGNED"},
+	}
+	if !reflect.DeepEqual(schema.Columns, expected) {
+		t.Errorf("Columns = %+v, want %+v", schema.Columns, expected)
+	}
+
+	price, err := tidbcodec.EncodeDecimal(nil, types.NewDecFromStringForTest("12.50"), 10, 2)
+	if err != nil {
+		t.Fatalf("EncodeDecimal() error = %v", err)
+	}
+	value := encodeSmallRowV2(map[int64][]byte{
+		2: []byte("apple"),
+		3: price,
+		4: tidbcodec.EncodeFloat(nil, 0.25),
+		5: {0xff},
+	})
+	cols, err := DecodeRowWithSchema(value, schema)
+	if err != nil {
+		t.Fatalf("DecodeRowWithSchema() error = %v", err)
+	}
+	got := make([]string, 0, len(cols))
+	for _, c := range cols {
+		got = append(got, c.Value)
+	}
+	if want := []string{"<handle, see the key>", `"apple"`, "12.50", "0.25", "255"}; !slices.Equal(got, want) {
+		t.Errorf("DecodeRowWithSchema() = %v, want %v", got, want)
+	}
+
+	for _, input := range []string{`[]`, `{"t1": {}}`, `{"1": {"x": {"name": "a", "type": "int"}}}`, `{"1": {"1": {"name": "a", "type": "geometry"}}}`} {
+		if _, err := ParseSchemas([]byte(input)); err == nil {
+			t.Errorf("ParseSchemas(%s) error = nil, want error", input)
+		}
+	}
+}
+
+func TestPrimaryKeyString(t *testing.T) {
+	schemas, err := ParseSchemas([]byte(`{"140": {
+		"1": {"name": "id", "type": "bigint", "pk": 1},
+		"3": {"name": "region", "type": "varchar(8)", "pk": 2},
+		"2": {"name": "note", "type": "text"}
+	}}`))
+	if err != nil {
+		t.Fatalf("ParseSchemas() error = %v", err)
+	}
+	schema := schemas[140]
+	if got := schema.PrimaryKeyColumns(); len(got) != 2 || got[0].Name != "id" || got[1].Name != "region" {
+		t.Fatalf("PrimaryKeyColumns() = %v, want id and region", got)
+	}
+
+	// the clustered primary key (id, region) of the row id=5, region="us"
+	handle, err := tidbcodec.EncodeKey(nil, nil, types.MakeDatums(5, "us")...)
+	if err != nil {
+		t.Fatalf("EncodeKey() error = %v", err)
+	}
+	key := append(tidbcodec.EncodeInt([]byte{'t'}, 140), '_', 'r')
+	key = append(key, handle...)
+	if got, ok := (Options{}).PrimaryKeyString(key, schema); !ok || got != `id=5, region="us"` {
+		t.Errorf("PrimaryKeyString() = %s, %v, want id=5, region=\"us\"", got, ok)
+	}
+	if got, ok := (Options{Redact: true}).PrimaryKeyString(key, schema); !ok || got != "id=5, region="+redacted([]byte("us")) {
+		t.Errorf("PrimaryKeyString() with Redact = %s, %v, want the region masked", got, ok)
+	}
+
+	// neither an int handle, another table nor an index key is named after the columns
+	for _, input := range []string{"t140_r5", "t141_r{5, us}", "t140_i1_5"} {
+		other, err := ParseKey(input)
+		if err != nil {
+			t.Fatalf("ParseKey(%s) error = %v", input, err)
+		}
+		if got, ok := (Options{}).PrimaryKeyString(other, schema); ok {
+			t.Errorf("PrimaryKeyString(%s) = %s, want false", input, got)
+		}
+	}
+
+	for _, input := range []string{
+		`{"1": {"1": {"name": "a", "type": "int", "pk": 2}}}`,
+		`{"1": {"1": {"name": "a", "type": "int", "pk": 1}, "2": {"name": "b", "type": "int", "pk": 1}}}`,
+		`{"1": {"1": {"name": "a", "type": "int", "pk": 1, "handle": true}}}`,
+	} {
+		if _, err := ParseSchemas([]byte(input)); err == nil {
+			t.Errorf("ParseSchemas(%s) error = nil, want error", input)
+		}
+	}
+}
+
+func TestParseColumnType(t *testing.T) {
+	tests := map[string]ColumnType{
+		"varchar(64)":                         ColumnString,
+		"text CHARACTER SET gbk":              ColumnString,
+		"varchar(20) charset latin1 NOT NULL": ColumnString,
+		"int unsigned NOT NULL":               ColumnUint,
+		"bigint(20) unsigned zerofill":        ColumnUint,
+		"INT NOT NULL DEFAULT 0":              ColumnInt,
+		"decimal(10, 2)":                      ColumnDecimal,
+		"enum('a b','c')":                     ColumnEnum,
+	}
+	for input, want := range tests {
+		if got, err := ParseColumnType(input); err != nil || got != want {
+			t.Errorf("ParseColumnType(%s) = %s, %v, want %s", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "geometry", "unsigned int"} {
+		if _, err := ParseColumnType(input); err == nil {
+			t.Errorf("ParseColumnType(%q) error = nil, want error", input)
+		}
+	}
+}
+
+func TestColumnTypeName(t *testing.T) {
+	tests := map[string]string{
+		"varchar(64)":                  "VARCHAR",
+		"text CHARACTER SET gbk":       "TEXT",
+		"bigint(20) unsigned zerofill": "BIGINT UNSIGNED",
+		"decimal(10, 2)":               "DECIMAL",
+		"enum('a b','c')":              "ENUM",
+		"":                             "",
+	}
+	for input, want := range tests {
+		if got := ColumnTypeName(input); got != want {
+			t.Errorf("ColumnTypeName(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	schemas, err := ParseSchemas([]byte(`{"1": {"1": {"name": "id", "type": "bigint", "handle": true}, "2": {"name": "name", "type": "varchar(64)"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schemas[1].Columns[1].TypeName(); got != "VARCHAR" {
+		t.Errorf("TypeName() of a varchar(64) column = %s, want VARCHAR", got)
+	}
+	// the bundled system tables have no MySQL types
+	if got := (ColumnSchema{Type: ColumnBlob}).TypeName(); got != "BLOB" {
+		t.Errorf("TypeName() without a MySQL type = %s, want BLOB", got)
+	}
+}
+
+func TestDecodeColumnTime(t *testing.T) {
+	if typ, err := ParseColumnType("TIME(3)"); err != nil || typ != ColumnTime {
+		t.Fatalf("ParseColumnType(TIME(3)) = %s, %v, want time", typ, err)
+	}
+
+	d := time.Hour + 2*time.Minute + 3*time.Second + 500*time.Millisecond
+	neg := -(838*time.Hour + 59*time.Minute + 59*time.Second) // the minimum of TIME
+	tests := []struct {
+		b    []byte
+		want string
+	}{
+		{binary.LittleEndian.AppendUint64(nil, uint64(d)), "01:02:03.500000"},
+		{binary.LittleEndian.AppendUint64(nil, uint64(neg)), "-838:59:59.000000"},
+		{[]byte{0x00}, "00:00:00.000000"},
+		{[]byte{0x01, 0x02, 0x03}, "Invalid time (Hex: 0x010203)"},
+	}
+	for _, tt := range tests {
+		if got := (Options{}).decodeColumn(tt.b, ColumnTime); got != tt.want {
+			t.Errorf("decodeColumn(%x) = %s, want %s", tt.b, got, tt.want)
+		}
+	}
+
+	if got, err := columnText(tests[0].b, ColumnTime); err != nil || got != "01:02:03.500000" {
+		t.Errorf("columnText() = %s, %v, want 01:02:03.500000", got, err)
+	}
+	if got := CompareColumnValues(tests[1].b, tests[0].b, ColumnTime); got != -1 {
+		t.Errorf("CompareColumnValues() = %d, want the negative duration first", got)
+	}
+}
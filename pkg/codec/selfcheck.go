@@ -0,0 +1,76 @@
+package codec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// selfCheckKeys are logical keys which must survive ParseKey -> DecodeKey unchanged.
+var selfCheckKeys = []string{
+	"t132_r1772018",
+	"t128_i2_594692_3400463811",
+	"t1_i2_apple",
+}
+
+// selfCheckRowV2 is a Row Format V2 value taken from a real TiDB cluster.
+// ColID 2: "Aaliyah Mueller", ColID 3: 1 (Int)
+const selfCheckRowV2 = "80000200000002030f00100041616c69796168204d75656c6c657201"
+
+// SelfCheck decodes a set of round-tripped samples and reports an error if the result
+// doesn't have the expected shape. It guards against silent mis-decoding after the
+// TiDB codec dependency is bumped.
+func SelfCheck() error {
+	for _, input := range selfCheckKeys {
+		raw, err := ParseKey(input)
+		if err != nil {
+			return fmt.Errorf("failed to parse sample key %s: %w", input, err)
+		}
+
+		if got := DecodeKey(raw); got != input {
+			return fmt.Errorf("key round-trip mismatch: encoded %s (%X) but decoded %s", input, raw, got)
+		}
+	}
+
+	rowV2, err := hex.DecodeString(selfCheckRowV2)
+	if err != nil {
+		return fmt.Errorf("invalid sample row value: %w", err)
+	}
+	expectedRow := RowV2Data{
+		Columns: map[int64]string{
+			2: fmt.Sprintf("%q", "Aaliyah Mueller"),
+			3: "Int: 1 (Hex: 0x01)",
+		},
+	}
+	if err := checkDecodedValue("row v2", rowV2, TypeRowV2, expectedRow); err != nil {
+		return err
+	}
+
+	typeCtx := types.DefaultStmtNoWarningContext.WithLocation(time.Local)
+	indexValue, err := tidbcodec.EncodeKey(typeCtx.Location(), nil, types.MakeDatums(100, "abc")...)
+	if err != nil {
+		return fmt.Errorf("failed to encode sample index value: %w", err)
+	}
+	if err := checkDecodedValue("index", indexValue, TypeIndex, []string{"100", "abc"}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func checkDecodedValue(name string, value []byte, expectedType ValueType, expectedPayload any) error {
+	got := DecodeValue(value)
+	if got.Type != expectedType {
+		return fmt.Errorf("%s sample decoded as %s, expected %s", name, got.Type, expectedType)
+	}
+
+	if !reflect.DeepEqual(got.Payload, expectedPayload) {
+		return fmt.Errorf("%s sample payload mismatch: got %v, expected %v", name, got.Payload, expectedPayload)
+	}
+
+	return nil
+}
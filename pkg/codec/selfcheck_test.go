@@ -0,0 +1,37 @@
+package codec
+
+import "testing"
+
+func TestSelfCheck(t *testing.T) {
+	if err := SelfCheck(); err != nil {
+		t.Fatalf("SelfCheck() error = %v", err)
+	}
+}
+
+func TestSelfCheckIgnoresOptions(t *testing.T) {
+	// the options of the commands, which change the decoded output, don't reach SelfCheck
+	opts := Options{Collation: "utf8mb4_general_ci", AutoRandomShardBits: 5, MaxValueBytes: 3, Redact: true,
+		ColumnTypeHints: map[int64]ColumnType{3: ColumnDatetime}}
+	key, err := opts.ParseKey("t1_r5")
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	if got := opts.DecodeKey(key); got == "t1_r5" {
+		t.Errorf("DecodeKey() = %s, want the AUTO_RANDOM handle", got)
+	}
+	if err := SelfCheck(); err != nil {
+		t.Fatalf("SelfCheck() error = %v", err)
+	}
+}
+
+func TestCheckDecodedValueMismatch(t *testing.T) {
+	// wrong type
+	if err := checkDecodedValue("raw", []byte{0xff, 0xff, 0xff}, TypeIndex, nil); err == nil {
+		t.Error("checkDecodedValue() should fail when the type doesn't match")
+	}
+
+	// wrong payload
+	if err := checkDecodedValue("raw", []byte{0xff, 0xff, 0xff}, TypeRaw, "000000"); err == nil {
+		t.Error("checkDecodedValue() should fail when the payload doesn't match")
+	}
+}
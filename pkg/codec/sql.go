@@ -0,0 +1,206 @@
+package codec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// sqlStringEscaper escapes a string literal of MySQL, see
+// https://dev.mysql.com/doc/refman/8.0/en/string-literals.html
+var sqlStringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+
+// RowValue is a column of a record decoded for an export: its value as text, e.g., 12.50 or the
+// bytes of a string, NULL, or missing from a row written before the column was added.
+type RowValue struct {
+	Column  ColumnSchema
+	Text    string
+	Null    bool
+	Missing bool
+}
+
+// DecodeRowValues decodes a record, the key and its RowV2 value, into the columns of the schema
+// in the schema order. The handle column is taken from the key. A value which doesn't fit its
+// column type fails the row.
+func DecodeRowValues(key, value []byte, schema TableSchema) ([]RowValue, error) {
+	return Options{}.DecodeRowValues(key, value, schema)
+}
+
+// DecodeRowValues decodes a record like DecodeRowValues does, with the key and the fallback
+// charset of the Options.
+func (o Options) DecodeRowValues(key, value []byte, schema TableSchema) ([]RowValue, error) {
+	info, err := o.DecodeKeyStructured(key)
+	if err != nil {
+		return nil, err
+	}
+	if info.Kind != KindRecord || info.TableID != schema.TableID {
+		return nil, fmt.Errorf("%s is not a record key of table %d", info, schema.TableID)
+	}
+	raw, err := RowV2RawColumns(value)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]RowValue, 0, len(schema.Columns))
+	for _, c := range schema.Columns {
+		v := RowValue{Column: c}
+		b, ok := raw[c.ID]
+		switch {
+		case c.Handle:
+			if info.Handle.CommonHandle != nil {
+				return nil, fmt.Errorf("handle column %s of a record key with a common handle %s", c.Name, info.Handle)
+			}
+			v.Text = strconv.FormatInt(info.Handle.IntHandle, 10)
+			if c.Type == ColumnUint {
+				v.Text = strconv.FormatUint(uint64(info.Handle.IntHandle), 10)
+			}
+		case !ok:
+			v.Missing = true
+		case b == nil:
+			v.Null = true
+		case c.Elems != nil:
+			label, ok := columnLabel(b, c)
+			if !ok {
+				return nil, fmt.Errorf("column %s (ColID %d): value 0x%x beyond the labels of the %s", c.Name, c.ID, b, c.Type)
+			}
+			v.Text = label
+		case c.Type == ColumnString:
+			v.Text = string(b)
+			if text, ok := o.charsetString(b, c.Charset); ok {
+				v.Text = text
+			}
+		case c.Type == ColumnBit:
+			n, ok := decodeRowV2Bit(b, c.Width)
+			if !ok {
+				return nil, fmt.Errorf("column %s (ColID %d): value 0x%x doesn't fit BIT(%d)", c.Name, c.ID, b, c.Width)
+			}
+			v.Text = string(bitBytes(n, c.Width))
+		default:
+			if v.Text, err = columnText(b, c.Type); err != nil {
+				return nil, fmt.Errorf("column %s (ColID %d): %w", c.Name, c.ID, err)
+			}
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// SQLInsert returns the INSERT statement of a record, the key and its RowV2 value decoded with
+// the schema, e.g., INSERT INTO `t132` (`id`, `name`) VALUES (1, 'Aaliyah');. A column missing
+// from the row, added after the row was written, is left to its default. A TIMESTAMP is written
+// in UTC as stored, so the statements are to be run with time_zone '+00:00'.
+func SQLInsert(key, value []byte, schema TableSchema) (string, error) {
+	return Options{}.SQLInsert(key, value, schema)
+}
+
+// SQLInsert returns the INSERT statement of a record like SQLInsert does, with the row decoded
+// by the Options.
+func (o Options) SQLInsert(key, value []byte, schema TableSchema) (string, error) {
+	values, err := o.DecodeRowValues(key, value, schema)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(values))
+	lits := make([]string, 0, len(values))
+	for _, v := range values {
+		if v.Missing {
+			continue
+		}
+		names = append(names, quoteSQLIdentifier(v.Column.Name))
+		lits = append(lits, sqlLiteral(v))
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);",
+		quoteSQLIdentifier(schema.Name), strings.Join(names, ", "), strings.Join(lits, ", ")), nil
+}
+
+// sqlLiteral returns the value as a SQL literal: numbers as they are, binary columns as X'...',
+// BIT columns as b'...' and the others as escaped strings.
+func sqlLiteral(v RowValue) string {
+	switch {
+	case v.Null:
+		return "NULL"
+	case v.Column.Type.IsNumeric():
+		return v.Text
+	case v.Column.Type == ColumnBit:
+		var n uint64
+		for _, c := range []byte(v.Text) {
+			n = n<<8 | uint64(c)
+		}
+		return bitLiteral(n, v.Column.Width)
+	case v.Column.Type == ColumnBlob:
+		if v.Text == "" {
+			return "''"
+		}
+		return "X'" + hex.EncodeToString([]byte(v.Text)) + "'"
+	default:
+		return quoteSQLString(v.Text)
+	}
+}
+
+// columnText decodes the raw bytes of a RowV2 column of the type into the text of its value:
+// the number, the bytes of a string, a blob or a BIT, the time (a TIMESTAMP in UTC), the JSON or
+// the elements of a VECTOR.
+func columnText(b []byte, typ ColumnType) (string, error) {
+	switch typ {
+	case ColumnInt:
+		if n, ok := decodeRowV2Int(b); ok {
+			return strconv.FormatInt(n, 10), nil
+		}
+	case ColumnUint, ColumnEnum, ColumnSet:
+		if n, ok := decodeRowV2Uint(b); ok {
+			return strconv.FormatUint(n, 10), nil
+		}
+	case ColumnString, ColumnBlob:
+		return string(b), nil
+	case ColumnBit:
+		if n, ok := decodeRowV2Uint(b); ok {
+			return string(bitBytes(n, 0)), nil
+		}
+	case ColumnVector:
+		if v, ok := decodeVector(b); ok {
+			return vectorText(v, ","), nil
+		}
+	case ColumnDatetime, ColumnTimestamp:
+		if packed, ok := decodeRowV2Uint(b); ok {
+			var t types.Time
+			if err := t.FromPackedUint(packed); err == nil {
+				return t.String(), nil
+			}
+		}
+	case ColumnTime:
+		if d, ok := decodeRowV2Duration(b); ok {
+			return d, nil
+		}
+	case ColumnJSON:
+		if len(b) > 0 {
+			if s, ok := safeDecodeJson(b); ok {
+				return s, nil
+			}
+		}
+	case ColumnDouble:
+		if _, f, err := tidbcodec.DecodeFloat(b); len(b) == 8 && err == nil {
+			return strconv.FormatFloat(f, 'g', -1, 64), nil
+		}
+	case ColumnDecimal:
+		if rest, dec, _, _, err := tidbcodec.DecodeDecimal(b); err == nil && len(rest) == 0 {
+			return dec.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid %s 0x%x", typ, b)
+}
+
+func quoteSQLString(s string) string {
+	return "'" + sqlStringEscaper.Replace(s) + "'"
+}
+
+func quoteSQLIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
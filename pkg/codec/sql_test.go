@@ -0,0 +1,71 @@
+package codec
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+func TestSQLInsert(t *testing.T) {
+	schema, err := ParseSchemas([]byte(`{"132": {
+		"1": {"name": "id", "type": "bigint", "handle": true},
+		"2": {"name": "name", "type": "varchar(64)"},
+		"3": {"name": "score", "type": "int"},
+		"4": {"name": "price", "type": "decimal(10,2)"},
+		"5": {"name": "data", "type": "blob"},
+		"6": {"name": "created", "type": "datetime"},
+		"7": {"name": "note", "type": "text"},
+		"8": {"name": "added", "type": "int"}
+	}}`))
+	if err != nil {
+		t.Fatalf("ParseSchemas() error = %v", err)
+	}
+
+	dec := types.NewDecFromStringForTest("12.50")
+	price, err := tidbcodec.EncodeDecimal(nil, dec, 10, 2)
+	if err != nil {
+		t.Fatalf("EncodeDecimal() error = %v", err)
+	}
+	created := types.NewTime(types.FromDate(2024, 1, 2, 3, 4, 5, 0), mysql.TypeDatetime, 0)
+	packed, err := created.ToPackedUint()
+	if err != nil {
+		t.Fatalf("ToPackedUint() error = %v", err)
+	}
+
+	key, err := ParseKey("t132_r7")
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	// the column 8 was added after the row was written, so it isn't in the row
+	value := encodeSmallRowV2(map[int64][]byte{
+		2: []byte("O'Brien\n"),
+		3: {0xfe},
+		4: price,
+		5: {0x00, 0xff},
+		6: binary.LittleEndian.AppendUint64(nil, packed),
+		7: nil,
+	})
+
+	got, err := SQLInsert(key, value, schema[132])
+	if err != nil {
+		t.Fatalf("SQLInsert() error = %v", err)
+	}
+	want := "INSERT INTO `t132` (`id`, `name`, `score`, `price`, `data`, `created`, `note`) " +
+		`VALUES (7, 'O\'Brien\n', -2, 12.50, X'00ff', '2024-01-02 03:04:05', NULL);`
+	if got != want {
+		t.Errorf("SQLInsert() =\n%s\nwant\n%s", got, want)
+	}
+
+	// a value which doesn't fit the column type fails the row
+	if _, err := SQLInsert(key, encodeSmallRowV2(map[int64][]byte{3: {1, 2, 3}}), schema[132]); err == nil {
+		t.Error("SQLInsert() should fail for an int of 3 bytes")
+	}
+	// so does a key of another table
+	other, _ := ParseKey("t133_r1")
+	if _, err := SQLInsert(other, value, schema[132]); err == nil {
+		t.Error("SQLInsert() should fail for a key of another table")
+	}
+}
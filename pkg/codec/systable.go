@@ -0,0 +1,102 @@
+package codec
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// maxInt48 is the base of the reserved table IDs of the DDL system tables in the mysql schema.
+const maxInt48 = 0x0000FFFFFFFFFFFF
+
+// systemTables are the mysql system tables with fixed table IDs (see pkg/ddl/constant.go of
+// TiDB). Their columns are created in order, so the column IDs start from 1.
+var systemTables = map[string]TableSchema{
+	"tidb_ddl_job": {
+		Name:    "tidb_ddl_job",
+		TableID: maxInt48 - 1,
+		Columns: []ColumnSchema{
+			{ID: 1, Name: "job_id", Type: ColumnInt, Handle: true},
+			{ID: 2, Name: "reorg", Type: ColumnInt},
+			{ID: 3, Name: "schema_ids", Type: ColumnString},
+			{ID: 4, Name: "table_ids", Type: ColumnString},
+			{ID: 5, Name: "job_meta", Type: ColumnBlob},
+			{ID: 6, Name: "type", Type: ColumnInt},
+			{ID: 7, Name: "processing", Type: ColumnInt},
+		},
+	},
+	"tidb_ddl_reorg": {
+		Name:    "tidb_ddl_reorg",
+		TableID: maxInt48 - 2,
+		Columns: []ColumnSchema{
+			{ID: 1, Name: "job_id", Type: ColumnInt},
+			{ID: 2, Name: "ele_id", Type: ColumnInt},
+			{ID: 3, Name: "ele_type", Type: ColumnBlob},
+			{ID: 4, Name: "start_key", Type: ColumnBlob},
+			{ID: 5, Name: "end_key", Type: ColumnBlob},
+			{ID: 6, Name: "physical_id", Type: ColumnInt},
+			{ID: 7, Name: "reorg_meta", Type: ColumnBlob},
+		},
+	},
+	"tidb_ddl_history": {
+		Name:    "tidb_ddl_history",
+		TableID: maxInt48 - 3,
+		Columns: []ColumnSchema{
+			{ID: 1, Name: "job_id", Type: ColumnInt, Handle: true},
+			{ID: 2, Name: "job_meta", Type: ColumnBlob},
+			{ID: 3, Name: "db_name", Type: ColumnString},
+			{ID: 4, Name: "table_name", Type: ColumnString},
+			{ID: 5, Name: "schema_ids", Type: ColumnString},
+			{ID: 6, Name: "table_ids", Type: ColumnString},
+			{ID: 7, Name: "create_time", Type: ColumnDatetime},
+		},
+	},
+	"tidb_background_subtask": {
+		Name:    "tidb_background_subtask",
+		TableID: maxInt48 - 5,
+		Columns: backgroundSubtaskColumns,
+	},
+	"tidb_background_subtask_history": {
+		Name:    "tidb_background_subtask_history",
+		TableID: maxInt48 - 6,
+		Columns: backgroundSubtaskColumns,
+	},
+}
+
+// backgroundSubtaskColumns are shared by tidb_background_subtask and its history table.
+var backgroundSubtaskColumns = []ColumnSchema{
+	{ID: 1, Name: "id", Type: ColumnInt, Handle: true},
+	{ID: 2, Name: "step", Type: ColumnInt},
+	{ID: 3, Name: "namespace", Type: ColumnString},
+	{ID: 4, Name: "task_key", Type: ColumnString},
+	{ID: 5, Name: "ddl_physical_tid", Type: ColumnInt},
+	{ID: 6, Name: "type", Type: ColumnInt},
+	{ID: 7, Name: "exec_id", Type: ColumnString},
+	{ID: 8, Name: "exec_expired", Type: ColumnTimestamp},
+	{ID: 9, Name: "state", Type: ColumnString},
+	{ID: 10, Name: "checkpoint", Type: ColumnBlob},
+	{ID: 11, Name: "concurrency", Type: ColumnInt},
+	{ID: 12, Name: "create_time", Type: ColumnTimestamp},
+	{ID: 13, Name: "start_time", Type: ColumnInt},
+	{ID: 14, Name: "state_update_time", Type: ColumnInt},
+	{ID: 15, Name: "end_time", Type: ColumnTimestamp},
+	{ID: 16, Name: "meta", Type: ColumnBlob},
+	{ID: 17, Name: "ordinal", Type: ColumnInt},
+	{ID: 18, Name: "error", Type: ColumnBlob},
+	{ID: 19, Name: "summary", Type: ColumnJSON},
+}
+
+// SystemTable returns the bundled schema of a mysql system table.
+func SystemTable(name string) (TableSchema, error) {
+	schema, ok := systemTables[name]
+	if !ok {
+		return TableSchema{}, fmt.Errorf("unknown system table %q: must be one of %v", name, SystemTableNames())
+	}
+
+	return schema, nil
+}
+
+// SystemTableNames returns the names of the bundled system tables in order.
+func SystemTableNames() []string {
+	return slices.Sorted(maps.Keys(systemTables))
+}
@@ -0,0 +1,193 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+// The temporary index of an ADD INDEX in progress with the fast reorg, which takes the writes
+// to the new index during the backfill until they are merged into it. Its ID is the one of the
+// new index with tempIndexPrefix set.
+// Ref: https://github.com/pingcap/tidb/blob/master/pkg/tablecodec/tablecodec.go
+const (
+	tempIndexPrefix int64 = 0x7fff000000000000
+	indexIDMask     int64 = 0xffffffffffff
+)
+
+// The flags in front of each operation in a temporary index value.
+const (
+	tempIndexFlagNormal byte = iota
+	tempIndexFlagNonDistinctNormal
+	tempIndexFlagDeleted
+	tempIndexFlagNonDistinctDeleted
+)
+
+// tempIndexPartitionIDFlag is in front of the partition ID of a deleted global index entry.
+const tempIndexPartitionIDFlag byte = 'p'
+
+// tempIndexStages are the key versions at the end of each operation: the DDL stage it was
+// written in.
+var tempIndexStages = map[byte]string{'d': "delete-only", 'b': "backfill", 'm': "merge"}
+
+// TempIndexOp is an operation on the original index recorded by a temporary index entry.
+type TempIndexOp struct {
+	Delete    bool          `json:"delete"`
+	Distinct  bool          `json:"distinct"`               // the entry of a unique index, which keeps each operation
+	Stage     string        `json:"stage"`                  // the DDL stage the operation was written in, e.g., backfill
+	Value     *DecodedValue `json:"value,omitempty"`        // the original index value, for a put
+	Handle    string        `json:"handle,omitempty"`       // the handle deleted, for a unique index
+	Partition int64         `json:"partition_id,omitempty"` // the partition of the handle, for a global index
+}
+
+// String returns the operation on one line, e.g., put (backfill): 7 or delete (merge, unique):
+// handle 7.
+func (op TempIndexOp) String() string {
+	var sb strings.Builder
+	if op.Delete {
+		sb.WriteString("delete")
+	} else {
+		sb.WriteString("put")
+	}
+	sb.WriteString(" (" + op.Stage)
+	if op.Distinct {
+		sb.WriteString(", unique")
+	}
+	sb.WriteString(")")
+
+	switch {
+	case op.Value != nil:
+		sb.WriteString(": " + op.Value.String())
+	case op.Handle != "":
+		sb.WriteString(": handle " + op.Handle)
+		if op.Partition != 0 {
+			sb.WriteString(fmt.Sprintf(" of partition %d", op.Partition))
+		}
+	}
+
+	return sb.String()
+}
+
+// isTempIndexKey reports whether the key is an entry of the temporary index of an ADD INDEX.
+func isTempIndexKey(key []byte) bool {
+	if !IsIndexKey(key) {
+		return false
+	}
+	_, id, err := tidbcodec.DecodeInt(key[11:19])
+
+	return err == nil && id&^indexIDMask == tempIndexPrefix
+}
+
+// DecodeValueForKey decodes the value like DecodeValue does, except the values which only
+// their key tells apart: the operations of a temporary index entry, see TempIndexOp, the
+// handle and the flags of an index value, see IndexValueData, and the meta values, see
+// MetaValue.
+func DecodeValueForKey(key, value []byte) DecodedValue {
+	return Options{}.DecodeValueForKey(key, value)
+}
+
+// DecodeValueForKey decodes a value like DecodeValueForKey does, with the Options of
+// DecodeValue.
+func (o Options) DecodeValueForKey(key, value []byte) DecodedValue {
+	if len(key) > 0 && key[0] == metaPrefix && len(value) > 0 {
+		if mk, err := DecodeMetaKey(key); err == nil {
+			if v, ok := o.decodeMetaValue(mk, value); ok {
+				return DecodedValue{Type: TypeMeta, Payload: v}
+			}
+		}
+	}
+	if isTempIndexKey(key) && len(value) > 0 {
+		if ops, err := o.decodeTempIndexValue(value); err == nil {
+			return DecodedValue{Type: TypeTempIndex, Payload: ops}
+		}
+	}
+	if IsIndexKey(key) && !isTempIndexKey(key) {
+		if v, ok := o.decodeIndexValue(value); ok {
+			return v
+		}
+	}
+
+	return o.DecodeValue(value)
+}
+
+// decodeTempIndexValue decodes the operations of a temporary index value, oldest first. Each
+// one is a flag, its value or handle, and the key version of its stage. An entry of a unique
+// index keeps every operation, the one of a non-unique index keeps the last only.
+func (o Options) decodeTempIndexValue(value []byte) ([]TempIndexOp, error) {
+	var ops []TempIndexOp
+	for len(value) > 0 {
+		var op TempIndexOp
+		flag, b := value[0], value[1:]
+		switch flag {
+		case tempIndexFlagNormal:
+			if len(b) < 2 || len(b) < 3+int(binary.BigEndian.Uint16(b)) {
+				return nil, fmt.Errorf("truncated temp index value %X", value)
+			}
+			n := int(binary.BigEndian.Uint16(b))
+			v := o.tempIndexOriginalValue(b[2 : 2+n])
+			op.Value, op.Distinct, b = &v, true, b[2+n:]
+		case tempIndexFlagNonDistinctNormal:
+			if len(b) < 1 {
+				return nil, fmt.Errorf("truncated temp index value %X", value)
+			}
+			v := o.tempIndexOriginalValue(b[:len(b)-1])
+			op.Value, b = &v, b[len(b)-1:]
+		case tempIndexFlagDeleted:
+			if len(b) < 2 || len(b) < 3+int(binary.BigEndian.Uint16(b)) {
+				return nil, fmt.Errorf("truncated temp index value %X", value)
+			}
+			n := int(binary.BigEndian.Uint16(b))
+			handle, err := o.tempIndexHandle(b[2 : 2+n])
+			if err != nil {
+				return nil, err
+			}
+			op.Handle, op.Delete, op.Distinct, b = handle, true, true, b[2+n:]
+			if len(b) >= 10 && b[0] == tempIndexPartitionIDFlag {
+				if _, op.Partition, err = tidbcodec.DecodeInt(b[1:9]); err != nil {
+					return nil, fmt.Errorf("invalid partition ID in temp index value %X: %v", value, err)
+				}
+				b = b[9:]
+			}
+		case tempIndexFlagNonDistinctDeleted:
+			op.Delete = true
+		default:
+			return nil, fmt.Errorf("unknown flag %d in temp index value %X", flag, value)
+		}
+
+		if len(b) == 0 {
+			return nil, fmt.Errorf("no key version in temp index value %X", value)
+		}
+		stage, ok := tempIndexStages[b[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown key version %q in temp index value %X", b[0], value)
+		}
+		op.Stage, value = stage, b[1:]
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// tempIndexOriginalValue decodes the original index value of a put, see decodeIndexValue.
+func (o Options) tempIndexOriginalValue(b []byte) DecodedValue {
+	if v, ok := o.decodeIndexValue(b); ok {
+		return v
+	}
+	return o.DecodeValue(b)
+}
+
+// tempIndexHandle returns the handle of a deleted unique index entry: an int handle of 8 bytes,
+// or else a common handle.
+func (o Options) tempIndexHandle(b []byte) (string, error) {
+	if len(b) == 8 {
+		return o.formatHandle(int64(binary.BigEndian.Uint64(b))), nil
+	}
+	datums, err := tidbcodec.Decode(b, 2)
+	if err != nil || len(datums) == 0 {
+		return "", fmt.Errorf("failed to decode the handle %X of a temp index value: %v", b, err)
+	}
+
+	return o.datumsString(datums), nil
+}
@@ -0,0 +1,70 @@
+package codec
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+)
+
+func TestDecodeTempIndex(t *testing.T) {
+	mustParse := func(input string) []byte {
+		t.Helper()
+		key, err := ParseKey(input)
+		if err != nil {
+			t.Fatalf("ParseKey(%s) error = %v", input, err)
+		}
+		return key
+	}
+	key := tidbcodec.EncodeInt(append(tidbcodec.EncodeInt([]byte{'t'}, 100), '_', 'i'), tempIndexPrefix|2)
+	key = append(key, mustParse("t100_i2_apple_5")[19:]...)
+
+	if got, want := DecodeKey(key), "t100_i2_apple_5 (temp index)"; got != want {
+		t.Errorf("DecodeKey() = %s, want %s", got, want)
+	}
+	if info, err := DecodeKeyStructured(key); err != nil || info.IndexID != 2 || !info.TempIndex {
+		t.Errorf("DecodeKeyStructured() = %+v, %v, want the temp index of index 2", info, err)
+	}
+
+	// a unique index keeps each operation: the put of the backfill, then the delete of the merge
+	handle := binary.BigEndian.AppendUint64(nil, 7)
+	unique := append([]byte{tempIndexFlagNormal, 0x00, 0x08}, handle...)
+	unique = append(unique, 'b', tempIndexFlagDeleted, 0x00, 0x08)
+	unique = append(append(unique, handle...), 'm')
+	v := DecodeValueForKey(key, unique)
+	ops, ok := v.Payload.([]TempIndexOp)
+	if v.Type != TypeTempIndex || !ok || len(ops) != 2 {
+		t.Fatalf("DecodeValueForKey() = %+v, want two operations", v)
+	}
+	if got, want := v.String(), "put (backfill, unique): handle 7; delete (merge, unique): handle 7"; got != want {
+		t.Errorf("DecodeValueForKey() = %s, want %s", got, want)
+	}
+
+	// a deleted entry of a global index holds the partition of the handle
+	global := append([]byte{tempIndexFlagDeleted, 0x00, 0x08}, handle...)
+	global = append(tidbcodec.EncodeInt(append(global, tempIndexPartitionIDFlag), 200), 'd')
+	ops, _ = DecodeValueForKey(key, global).Payload.([]TempIndexOp)
+	if want := []TempIndexOp{{Delete: true, Distinct: true, Stage: "delete-only", Handle: "7", Partition: 200}}; !reflect.DeepEqual(ops, want) {
+		t.Errorf("DecodeValueForKey() = %+v, want %+v", ops, want)
+	}
+
+	// a non-unique index keeps the last operation only
+	if got := DecodeValueForKey(key, []byte{tempIndexFlagNonDistinctNormal, '0', 'b'}).String(); got != "put (backfill): no handle (in the key)" {
+		t.Errorf("DecodeValueForKey() = %s, want put (backfill): no handle (in the key)", got)
+	}
+	if got := DecodeValueForKey(key, []byte{tempIndexFlagNonDistinctDeleted, 'm'}).String(); got != "delete (merge)" {
+		t.Errorf("DecodeValueForKey() = %s, want delete (merge)", got)
+	}
+
+	// the values of other keys and the ones which aren't of a temp index are decoded as usual
+	for _, kv := range [][2][]byte{
+		{mustParse("t100_i2_apple_5"), []byte{tempIndexFlagNonDistinctDeleted, 'm'}},
+		{key, []byte{tempIndexFlagNonDistinctDeleted, 'x'}},
+		{key, unique[:5]},
+	} {
+		if got, want := DecodeValueForKey(kv[0], kv[1]), DecodeValue(kv[1]); !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeValueForKey(%X) = %+v, want %+v", kv[1], got, want)
+		}
+	}
+}
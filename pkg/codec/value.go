@@ -5,9 +5,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 	"unicode/utf8"
 
+	"github.com/pingcap/tidb/pkg/parser/mysql"
 	"github.com/pingcap/tidb/pkg/types"
 	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
 )
@@ -15,10 +19,14 @@ import (
 type ValueType string
 
 const (
-	TypeNull  ValueType = "null"
-	TypeRowV2 ValueType = "row_v2"
-	TypeIndex ValueType = "index"
-	TypeRaw   ValueType = "raw"
+	TypeNull       ValueType = "null"
+	TypeRowV2      ValueType = "row_v2"
+	TypeRowV1      ValueType = "row_v1" // the columns in RowV2Data as well
+	TypeIndex      ValueType = "index"
+	TypeIndexValue ValueType = "index_value" // the handle and the flags of an index value, see DecodeValueForKey
+	TypeTempIndex  ValueType = "temp_index"  // the operations of a temporary index entry, see DecodeValueForKey
+	TypeMeta       ValueType = "meta"        // the value of a meta key, see DecodeValueForKey
+	TypeRaw        ValueType = "raw"
 )
 
 type DecodedValue struct {
@@ -26,10 +34,45 @@ type DecodedValue struct {
 	Payload interface{} `json:"payload"`
 }
 
+// String flattens the value into one line: the RowV2 columns as colID=value pairs sorted by
+// column ID and separated by semicolons, the values of an index as a comma-separated list, the
+// operations of a temporary index separated by semicolons, and raw values as hex. A null value
+// is empty.
+func (v DecodedValue) String() string {
+	switch p := v.Payload.(type) {
+	case RowV2Data:
+		pairs := make([]string, 0, len(p.Columns))
+		for _, id := range slices.Sorted(maps.Keys(p.Columns)) {
+			pairs = append(pairs, fmt.Sprintf("%d=%s", id, p.Columns[id]))
+		}
+		return strings.Join(pairs, ";")
+	case []string:
+		return strings.Join(p, ", ")
+	case []TempIndexOp:
+		ops := make([]string, 0, len(p))
+		for _, op := range p {
+			ops = append(ops, op.String())
+		}
+		return strings.Join(ops, "; ")
+	case string:
+		return p
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", p)
+	}
+}
+
 type RowV2Data struct {
 	Columns map[int64]string `json:"columns"` // ColID -> ValueString
+	// Untouched marks the restored data of an index value with the untouched flag, see
+	// IndexValueData
+	Untouched bool `json:"untouched,omitempty"`
 }
 
+// rowV2FlagLarge is the flag bit of a RowV2 value with 4-byte column IDs and offsets.
+const rowV2FlagLarge = 0x01
+
 const padding = "    " // 4 spaces
 
 func FormatWithPadding(format string, a ...any) string {
@@ -38,6 +81,12 @@ func FormatWithPadding(format string, a ...any) string {
 
 // DecodeValue decodes the given value into a human-readable string.
 func DecodeValue(value []byte) DecodedValue {
+	return Options{}.DecodeValue(value)
+}
+
+// DecodeValue decodes a value like DecodeValue does, with the strings, the hex and the column
+// types of the Options.
+func (o Options) DecodeValue(value []byte) DecodedValue {
 	if len(value) == 0 {
 		return DecodedValue{Type: TypeNull, Payload: nil}
 	}
@@ -46,21 +95,38 @@ func DecodeValue(value []byte) DecodedValue {
 	if value[0] == 0x80 {
 		return DecodedValue{
 			Type:    TypeRowV2,
-			Payload: decodeRowV2(value),
+			Payload: o.decodeRowV2(value),
 		}
 	}
 
-	// Check if the index with row format v2 (maybe this index includes string values)
-	// first byte should be 0x00 (TailLen). See https://github.com/pingcap/tidb/blob/master/pkg/tablecodec/tablecodec.go#L1503-L1552
-	if len(value) > 1 && value[0] == 0x00 && value[1] == 0x80 {
+	// Check if the index with row format v2 (maybe this index includes string values): the
+	// original strings of a new collation after the handle segments, see indexRestoredData and
+	// https://github.com/pingcap/tidb/blob/master/pkg/tablecodec/tablecodec.go#L1503-L1552
+	index, indexErr := splitIndexValue(value)
+	if indexErr == nil && index.restoredData != nil {
+		if v, ok := o.decodeIndexValue(value); ok {
+			return v
+		}
+	}
+
+	// Row format v1 of the tables written before TiDB 4.0, which would pass as index values too
+	if row, ok := o.decodeRowV1(value); ok {
 		return DecodedValue{
-			Type:    TypeRowV2,
-			Payload: decodeRowV2(value[1:]),
+			Type:    TypeRowV1,
+			Payload: row,
+		}
+	}
+
+	// An index value of the new format by its layout, see splitIndexValue. The old format is
+	// told apart by its key only, see DecodeValueForKey.
+	if indexErr == nil && index.options {
+		if v, ok := o.decodeIndexValue(value); ok {
+			return v
 		}
 	}
 
 	// Try decoding as index value
-	if v, found := scrapeMemComparable(value); found {
+	if v, found := o.scrapeMemComparable(value); found {
 		return DecodedValue{
 			Type:    TypeIndex,
 			Payload: v,
@@ -70,11 +136,58 @@ func DecodeValue(value []byte) DecodedValue {
 	// Try minimal decoding for other formats or fall back to hex
 	return DecodedValue{
 		Type:    TypeRaw,
-		Payload: hex.EncodeToString(value),
+		Payload: o.rawHex(value),
+	}
+}
+
+// rawHex returns the hex payload of a raw value, cut after Options.MaxHexBytes bytes with the
+// length of the value, or masked with Options.Redact.
+func (o Options) rawHex(value []byte) string {
+	if o.Redact {
+		return redacted(value)
+	}
+	if len(value) <= o.maxHexBytes() {
+		return hex.EncodeToString(value)
 	}
+	return fmt.Sprintf("%s (len=%d)", o.cappedHex(value), len(value))
 }
 
-func scrapeMemComparable(data []byte) ([]string, bool) {
+// limitValue returns the text of a decoded string or JSON value, quoted when quote is set, cut
+// after Options.MaxValueBytes bytes at a character boundary and marked with the total length.
+func (o Options) limitValue(s string, quote bool) string {
+	total := len(s)
+	cut := o.MaxValueBytes > 0 && total > o.MaxValueBytes
+	if cut {
+		n := o.MaxValueBytes
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		s = s[:n]
+	}
+	if quote {
+		s = fmt.Sprintf("%q", s)
+	}
+	if cut {
+		s += fmt.Sprintf(" (truncated, total %d bytes)", total)
+	}
+
+	return s
+}
+
+// RowV2RawColumns returns the raw bytes of each column of a RowV2 value, or of the restored data
+// of an index value, keyed by column ID.
+func RowV2RawColumns(value []byte) (map[int64][]byte, error) {
+	if len(value) > 0 && value[0] == 0x80 {
+		return parseRowV2Structure(value)
+	}
+	if restored, ok := indexRestoredData(value); ok { // index value with row format v2
+		return parseRowV2Structure(restored)
+	}
+
+	return nil, fmt.Errorf("not a row format v2 value")
+}
+
+func (o Options) scrapeMemComparable(data []byte) ([]string, bool) {
 	if len(data) == 0 {
 		return nil, false
 	}
@@ -83,13 +196,12 @@ func scrapeMemComparable(data []byte) ([]string, bool) {
 	var found bool
 	// try decode the data one by one
 	for i := 0; i < len(data); {
-		datums, err := tidbcodec.Decode(data[i:], 1)
+		datums, err := safeDecodeDatum(data[i:])
 
 		if err == nil && len(datums) > 0 {
 			// successfully decoded
 			d := datums[0]
-			str, _ := d.ToString()
-			foundValues = append(foundValues, str)
+			foundValues = append(foundValues, o.keyDatumString(d))
 			found = true
 
 			// calculate the length of decoded data
@@ -112,67 +224,83 @@ func scrapeMemComparable(data []byte) ([]string, bool) {
 	return nil, false
 }
 
-func decodeRowV2(val []byte) RowV2Data {
+func (o Options) decodeRowV2(val []byte) RowV2Data {
 	result := make(map[int64]string)
 
 	// mapping column ID to raw data
 	cols, err := parseRowV2Structure(val)
 	if err == nil {
 		for i, raw := range cols {
-			result[i] = trySmartDecode(raw)
+			if typ, ok := o.ColumnTypeHints[i]; ok && raw != nil {
+				result[i] = o.decodeColumn(raw, typ)
+			} else {
+				result[i] = o.trySmartDecode(raw)
+			}
 		}
-	} else { // not row v2 data format
+	} else if o.Redact { // not row v2 data format
+		result[-1] = redacted(val)
+	} else {
 		result[-1] = hex.EncodeToString(val)
 	}
 
 	return RowV2Data{Columns: result}
 }
 
+// parseRowV2Structure splits a RowV2 value into the raw bytes of each column, keyed by column ID.
+// NULL columns are listed in the row without any data and are mapped to nil, unlike an empty
+// value (e.g., an empty string) which is an empty non-nil slice.
+// Ref: https://github.com/pingcap/tidb/blob/master/pkg/util/rowcodec/row.go
+// Layout: 0x80 | flag | numNotNull (u16) | numNull (u16) | not-null IDs | null IDs | offsets of the not-null columns | data
+// The IDs and offsets take 1 and 2 bytes, or 4 and 4 bytes with the large flag.
 func parseRowV2Structure(data []byte) (map[int64][]byte, error) {
 	const expectedLength = 6 // minimal length for RowV2
 	if len(data) < expectedLength {
 		return nil, fmt.Errorf("data too short. expected length %d but actual %d", expectedLength, len(data))
 	}
 
-	numSmall := binary.LittleEndian.Uint16(data[2:4])
-	numLarge := binary.LittleEndian.Uint16(data[4:6])
+	// small rows have 1-byte column IDs and 2-byte offsets, large rows 4-byte ones
+	idSize, offsetSize := 1, 2
+	if data[1]&rowV2FlagLarge != 0 {
+		idSize, offsetSize = 4, 4
+	}
+	readUint := func(b []byte, size int) int {
+		switch size {
+		case 1:
+			return int(b[0])
+		case 2:
+			return int(binary.LittleEndian.Uint16(b))
+		default:
+			return int(binary.LittleEndian.Uint32(b))
+		}
+	}
+
+	numNotNull := int(binary.LittleEndian.Uint16(data[2:4]))
+	numNull := int(binary.LittleEndian.Uint16(data[4:6]))
 
 	cursor := 6
 	colMap := make(map[int64][]byte)
 
-	smallIDs := make([]int64, numSmall)
-	for i := 0; i < int(numSmall); i++ {
-		if cursor >= len(data) {
-			return nil, fmt.Errorf("unexpected end of data while reading small column IDs")
+	ids := make([]int64, 0, numNotNull+numNull)
+	for i := 0; i < numNotNull+numNull; i++ {
+		if cursor+idSize > len(data) {
+			return nil, fmt.Errorf("unexpected end of data while reading column IDs")
 		}
-		smallIDs[i] = int64(data[cursor])
-		cursor++
+		ids = append(ids, int64(readUint(data[cursor:], idSize)))
+		cursor += idSize
 	}
 
-	largeIDs := make([]int64, numLarge)
-	const OffsetLarge = 4
-	for i := 0; i < int(numLarge); i++ {
-		if cursor+OffsetLarge >= len(data) {
-			return nil, fmt.Errorf("unexpected end of data while reading large column IDs")
-		}
-		largeIDs[i] = int64(binary.LittleEndian.Uint32(data[cursor : cursor+OffsetLarge]))
-		cursor += OffsetLarge
-	}
-	allIDs := append(smallIDs, largeIDs...)
-
-	numCols := len(allIDs)
-	offsets := make([]int, numCols)
-	for i := range numCols {
-		if cursor+2 > len(data) {
+	offsets := make([]int, numNotNull)
+	for i := range numNotNull {
+		if cursor+offsetSize > len(data) {
 			return nil, fmt.Errorf("unexpected end of data while reading column offsets")
 		}
-		offsets[i] = int(binary.LittleEndian.Uint16(data[cursor : cursor+2]))
-		cursor += 2
+		offsets[i] = readUint(data[cursor:], offsetSize)
+		cursor += offsetSize
 	}
 
 	valueStartBase := cursor
 	previousOffset := 0
-	for i, id := range allIDs {
+	for i, id := range ids[:numNotNull] {
 		endOffset := offsets[i]
 
 		// Check bounds
@@ -195,21 +323,50 @@ func parseRowV2Structure(data []byte) (map[int64][]byte, error) {
 		previousOffset = endOffset
 	}
 
+	for _, id := range ids[numNotNull:] {
+		colMap[id] = nil
+	}
+
 	return colMap, nil
 }
 
-func trySmartDecode(b []byte) string {
+func (o Options) trySmartDecode(b []byte) string {
+	if b == nil {
+		return "NULL"
+	}
 	if len(b) == 0 {
 		return "NULL/Empty"
 	}
 
+	// 0. Maybe a DECIMAL, whose length tells it from JSON, which is 9 bytes at least
+	decimal, isDecimal := decodeDecimalGuess(b)
+	if isDecimal && len(b) != 4 && len(b) != 8 {
+		return "Decimal: " + decimal
+	}
+
+	// Maybe a VECTOR, whose dimension matches its length. One of 0 or 1 dimension has the
+	// length of an integer, so it is left to the integer below
+	if len(b) > 8 {
+		if v, ok := decodeVector(b); ok {
+			return "Vector: " + o.formatVector(b, v)
+		}
+	}
+
 	// 1. Check if it's JSON (Object or Array)
 	if b[0] == 0x01 || b[0] == 0x03 { // Object or Array
 		if jsonStr, ok := safeDecodeJson(b); ok {
-			return jsonStr
+			if o.Redact {
+				return redacted(b)
+			}
+			return o.limitValue(jsonStr, false)
 		}
 	}
 
+	// a string read as an integer or a time would show its bytes, so mask it first
+	if _, ok := o.stringText(b, ""); o.Redact && ok {
+		return redacted(b)
+	}
+
 	// 2. Check if it's integer (small int/int/bigint)
 	var intValStr string
 	isInteger := false
@@ -228,26 +385,86 @@ func trySmartDecode(b []byte) string {
 		isInteger = true
 	}
 
-	// 3. Check if it's string
-	if isLooksLikeString(b) {
-		strVal := fmt.Sprintf("%q", string(b))
+	// 3. Maybe a DATE/DATETIME/TIMESTAMP packed into 8 bytes. RowV2 has no column types, so
+	// keep the integer too and let the user pick
+	if len(b) == 8 {
+		if t, ok := decodePackedTime(binary.LittleEndian.Uint64(b)); ok {
+			if isDecimal {
+				return fmt.Sprintf("Int: %s Datetime?: %s Decimal?: %s (Hex: 0x%x)", intValStr, t, decimal, b)
+			}
+			return fmt.Sprintf("Int: %s Datetime?: %s (Hex: 0x%x)", intValStr, t, b)
+		}
+	}
+
+	// an integer whose bytes fit a DECIMAL too, e.g., a DECIMAL(10,2) of 8 bytes
+	if isDecimal {
+		return fmt.Sprintf("Int: %s Decimal?: %s (Hex: 0x%x)", intValStr, decimal, b)
+	}
+
+	// 4. Check if it's string
+	if text, ok := o.stringText(b, ""); ok {
+		strVal := o.limitValue(text, true)
 		if isInteger {
 			return fmt.Sprintf("Int: %s Str: %s", intValStr, strVal)
 		}
 		return strVal
 	}
 
-	// 4. Maybe the data is not string but integer-ish.
+	// 5. Maybe the data is not string but integer-ish.
 	if isInteger {
 		return fmt.Sprintf("Int: %s (Hex: 0x%x)", intValStr, b)
 	}
 
-	// 5. Fallback to hex representation
-	if len(b) <= 8 {
-		return fmt.Sprintf("0x%x", b)
+	// 6. Fallback to hex representation
+	if o.Redact {
+		return redacted(b)
 	}
+	return o.previewHex(b)
+}
+
+// decodeDecimalGuess decodes the bytes as a MyDecimal, the precision and the frac followed by
+// the digits, when they take exactly the size of the precision, e.g., 123.45 of a DECIMAL(10,2).
+func decodeDecimalGuess(b []byte) (string, bool) {
+	if len(b) < 3 || b[0] == 0 || b[0] > mysql.MaxDecimalWidth || b[1] > mysql.MaxDecimalScale || b[1] > b[0] {
+		return "", false
+	}
+	if size, err := types.DecimalBinSize(int(b[0]), int(b[1])); err != nil || size != len(b)-2 {
+		return "", false
+	}
+	rest, dec, _, _, err := tidbcodec.DecodeDecimal(b)
+	if err != nil || len(rest) != 0 {
+		return "", false
+	}
+
+	return dec.String(), true
+}
+
+// decodePackedTime decodes the packed form of types.Time (ymdhms << 24 | microsecond) when all
+// of its fields are in range, e.g., 2024-01-02 03:04:05. A huge integer can pass as well.
+func decodePackedTime(packed uint64) (string, bool) {
+	var t types.Time
+	if packed == 0 || t.FromPackedUint(packed) != nil {
+		return "", false
+	}
+
+	if t.Year() < 1000 || t.Year() > 9999 || t.Month() < 1 || t.Month() > 12 || t.Day() < 1 || t.Day() > 31 ||
+		t.Hour() > 23 || t.Minute() > 59 || t.Second() > 59 || t.Microsecond() > 999999 {
+		return "", false
+	}
+
+	return t.String(), true
+}
+
+// safeDecodeDatum decodes the memcomparable datum at the head of the bytes. Some flags, e.g., the
+// one of JSON, panic on bytes which aren't of the datum, which the scraping tries anyway.
+func safeDecodeDatum(b []byte) (datums []types.Datum, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic during datum decode: %v", r)
+		}
+	}()
 
-	return fmt.Sprintf("0x%x... (len=%d)", b[:8], len(b))
+	return tidbcodec.Decode(b, 1)
 }
 
 func safeDecodeJson(b []byte) (result string, ok bool) {
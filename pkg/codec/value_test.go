@@ -1,12 +1,16 @@
 package codec
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/pingcap/tidb/pkg/parser/mysql"
 	"github.com/pingcap/tidb/pkg/types"
 	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
 )
@@ -133,12 +137,161 @@ func TestDecodeValue(t *testing.T) {
 }
 
 // scrapeMemComparable の単体テスト (内部ロジックの確認)
+func TestParseRowV2StructureNullColumns(t *testing.T) {
+	// ColID 2: "abc", ColID 3: NULL, ColID 4: 5 (Int)
+	// 80 00 | 2 not-null | 1 null | IDs 02 04, then 03 | offsets 3, 4 | "abc" 05
+	row, _ := hex.DecodeString("8000020001000204030300040061626305")
+
+	cols, err := parseRowV2Structure(row)
+	if err != nil {
+		t.Fatalf("parseRowV2Structure() error = %v", err)
+	}
+	expected := map[int64][]byte{2: []byte("abc"), 3: nil, 4: {0x05}}
+	if !reflect.DeepEqual(cols, expected) {
+		t.Errorf("parseRowV2Structure() = %v, want %v", cols, expected)
+	}
+
+	got := DecodeValue(row).Payload.(RowV2Data).Columns
+	if got[3] != "NULL" || got[2] != `"abc"` || got[4] != "Int: 5 (Hex: 0x05)" {
+		t.Errorf("DecodeValue() columns = %v, want ColID 3 NULL", got)
+	}
+
+	// an empty string is stored as a not-null column without data
+	row, _ = hex.DecodeString("800001000000020000")
+	if cols, err := parseRowV2Structure(row); err != nil || cols[2] == nil || len(cols[2]) != 0 {
+		t.Errorf("parseRowV2Structure() = %v, %v, want an empty ColID 2", cols, err)
+	}
+
+	// the null IDs must be complete
+	if _, err := parseRowV2Structure([]byte{0x80, 0x00, 0x00, 0x00, 0x02, 0x00, 0x03}); err == nil {
+		t.Error("parseRowV2Structure() error = nil, want error for truncated null IDs")
+	}
+}
+
+func TestParseRowV2StructureLargeRow(t *testing.T) {
+	// a large row (flag 0x01) has 4-byte column IDs and offsets:
+	// ColID 300: 70000 bytes, ColID 2: 7 (Int), ColID 5: NULL
+	big := make([]byte, 70000)
+	for i := range big {
+		big[i] = 'a'
+	}
+	row := []byte{0x80, 0x01}
+	row = binary.LittleEndian.AppendUint16(row, 2) // not-null
+	row = binary.LittleEndian.AppendUint16(row, 1) // null
+	for _, id := range []uint32{2, 300, 5} {
+		row = binary.LittleEndian.AppendUint32(row, id)
+	}
+	row = binary.LittleEndian.AppendUint32(row, 1)
+	row = binary.LittleEndian.AppendUint32(row, 1+uint32(len(big)))
+	row = append(row, 0x07)
+	row = append(row, big...)
+
+	cols, err := parseRowV2Structure(row)
+	if err != nil {
+		t.Fatalf("parseRowV2Structure() error = %v", err)
+	}
+	if len(cols) != 3 || !bytes.Equal(cols[2], []byte{0x07}) || !bytes.Equal(cols[300], big) || cols[5] != nil {
+		t.Errorf("parseRowV2Structure() = %d columns (ColID 2 %X, ColID 300 %d bytes), want 7, 70000 bytes and NULL", len(cols), cols[2], len(cols[300]))
+	}
+	if _, ok := cols[5]; !ok {
+		t.Error("parseRowV2Structure() lost the NULL ColID 5")
+	}
+
+	// the last offset points past the end of a truncated value
+	if _, err := parseRowV2Structure(row[:len(row)-1]); err == nil {
+		t.Error("parseRowV2Structure() error = nil, want error for a truncated large row")
+	}
+}
+
+func TestTrySmartDecodeDatetime(t *testing.T) {
+	dt := types.NewTime(types.FromDate(2024, 1, 2, 3, 4, 5, 0), mysql.TypeDatetime, 0)
+	packed, err := dt.ToPackedUint()
+	if err != nil {
+		t.Fatalf("ToPackedUint() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{
+			name:     "packed datetime",
+			input:    binary.LittleEndian.AppendUint64(nil, packed),
+			expected: fmt.Sprintf("Int: %d Datetime?: 2024-01-02 03:04:05 (Hex: 0x%x)", int64(packed), binary.LittleEndian.AppendUint64(nil, packed)),
+		},
+		{
+			name:     "bigint",
+			input:    binary.LittleEndian.AppendUint64(nil, 1772018),
+			expected: "Int: 1772018 (Hex: 0xf2091b0000000000)",
+		},
+		{
+			name:     "negative bigint",
+			input:    binary.LittleEndian.AppendUint64(nil, 0xffffffffffffffff),
+			expected: "Int: -1 (Hex: 0xffffffffffffffff)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Options{}).trySmartDecode(tt.input); got != tt.expected {
+				t.Errorf("trySmartDecode() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTrySmartDecodeHex(t *testing.T) {
+	// 40 bytes which are neither a string nor an integer
+	b := make([]byte, 40)
+	for i := range b {
+		b[i] = 0x80 + byte(i)
+	}
+	corrupt := bytes.Clone(b)
+	corrupt[20] = 0
+
+	// the preview shows both ends, so values differing at the tail look different
+	if got, want := (Options{}).trySmartDecode(b), "0x8081828384858687...a0a1a2a3a4a5a6a7 (len=40)"; got != want {
+		t.Errorf("trySmartDecode() = %s, want %s", got, want)
+	}
+	if got, want := (Options{}).trySmartDecode(b[:16]), "0x808182838485868788898a8b8c8d8e8f"; got != want {
+		t.Errorf("trySmartDecode() = %s, want %s", got, want)
+	}
+	if (Options{}).trySmartDecode(corrupt) != (Options{}).trySmartDecode(b) {
+		t.Error("trySmartDecode() previews should be the same for values differing in the middle")
+	}
+
+	opts := Options{FullHex: true}
+	if got, want := opts.trySmartDecode(b), fmt.Sprintf("0x%x (len=40)", b); got != want {
+		t.Errorf("trySmartDecode() = %s, want %s", got, want)
+	}
+	if opts.trySmartDecode(corrupt) == opts.trySmartDecode(b) {
+		t.Error("trySmartDecode() with full hex should tell the values differing in the middle apart")
+	}
+
+	// the hex is capped for huge values, with full hex and for raw values
+	opts.MaxHexBytes = 4
+	if got, want := opts.trySmartDecode(b), "0x80818283... (len=40)"; got != want {
+		t.Errorf("trySmartDecode() = %s, want %s", got, want)
+	}
+	if got, want := opts.DecodeValue([]byte{0xff, 0xfe, 0xfd, 0xfc, 0xfb}).Payload, "fffefdfc... (len=5)"; got != want {
+		t.Errorf("DecodeValue() payload = %v, want %s", got, want)
+	}
+	if got, want := opts.DecodeValue([]byte{0xff, 0xfe, 0xfd}).Payload, "fffefd"; got != want {
+		t.Errorf("DecodeValue() payload = %v, want %s", got, want)
+	}
+
+	if err := (Options{MaxHexBytes: -1}).Validate(); err == nil {
+		t.Error("Validate() with max hex bytes -1 should fail")
+	}
+}
+
 func TestScrapeMemComparable(t *testing.T) {
 	// normal
 	datums := types.MakeDatums(10, 20)
 	b, _ := tidbcodec.EncodeKey(nil, nil, datums...)
 
-	vals, found := scrapeMemComparable(b)
+	vals, found := (Options{}).scrapeMemComparable(b)
 	if !found {
 		t.Fatal("Should be found")
 	}
@@ -148,14 +301,14 @@ func TestScrapeMemComparable(t *testing.T) {
 
 	// 異常系: 全てゴミ
 	garbage := []byte{0xff, 0xff}
-	_, foundGarbage := scrapeMemComparable(garbage)
+	_, foundGarbage := (Options{}).scrapeMemComparable(garbage)
 	if foundGarbage {
 		t.Error("Should not find anything in garbage")
 	}
 
 	// abnormal, append invalid prefix
 	mixed := append([]byte{0xaa, 0xbb}, b...)
-	valsMixed, foundMixed := scrapeMemComparable(mixed)
+	valsMixed, foundMixed := (Options{}).scrapeMemComparable(mixed)
 	if !foundMixed {
 		t.Error("Should find values in mixed data")
 	}
@@ -163,3 +316,115 @@ func TestScrapeMemComparable(t *testing.T) {
 		t.Errorf("Mixed values mismatch: %v", valsMixed)
 	}
 }
+
+func TestDecodedValueString(t *testing.T) {
+	tests := []struct {
+		v        DecodedValue
+		expected string
+	}{
+		{DecodedValue{Type: TypeNull}, ""},
+		{DecodedValue{Type: TypeRaw, Payload: "ff00"}, "ff00"},
+		{DecodedValue{Type: TypeRowV2, Payload: RowV2Data{Columns: map[int64]string{10: "x", 2: "NULL"}}}, "2=NULL;10=x"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.v.String(); got != tt.expected {
+			t.Errorf("String(%+v) = %q, want %q", tt.v, got, tt.expected)
+		}
+	}
+}
+
+func TestMaxValueBytes(t *testing.T) {
+	opts := Options{MaxValueBytes: 5}
+	tests := []struct {
+		input    []byte
+		expected string
+	}{
+		{[]byte("Aaliyah Mueller"), `"Aaliy" (truncated, total 15 bytes)`},
+		{[]byte("Ali"), `"Ali"`},
+		{[]byte("Aaaあい"), `"Aaa" (truncated, total 9 bytes)`}, // cut before the character split at 5 bytes
+	}
+	for _, tt := range tests {
+		if got := opts.trySmartDecode(tt.input); got != tt.expected {
+			t.Errorf("trySmartDecode(%s) = %s, want %s", tt.input, got, tt.expected)
+		}
+	}
+	if got, want := opts.decodeColumn([]byte("Aaliyah Mueller"), ColumnString), `"Aaliy" (truncated, total 15 bytes)`; got != want {
+		t.Errorf("decodeColumn() = %s, want %s", got, want)
+	}
+
+	if err := (Options{MaxValueBytes: -1}).Validate(); err == nil {
+		t.Error("Validate() with max value bytes -1 should fail")
+	}
+}
+
+func TestDecodeRowV1(t *testing.T) {
+	// a row as TiDB before 4.0 wrote it: the column IDs and the values in turn
+	created := types.NewTime(types.FromDate(2024, 1, 2, 3, 4, 5, 0), mysql.TypeDatetime, 0)
+	packed, err := created.ToPackedUint()
+	if err != nil {
+		t.Fatalf("ToPackedUint() error = %v", err)
+	}
+	rowV1, err := tidbcodec.EncodeValue(time.UTC, nil,
+		types.NewIntDatum(2), types.NewStringDatum("Aaliyah Mueller"),
+		types.NewIntDatum(3), types.NewIntDatum(-7),
+		types.NewIntDatum(4), types.NewUintDatum(packed),
+		types.NewIntDatum(5), types.NewDatum(nil),
+		types.NewIntDatum(6), types.NewFloat64Datum(1.5),
+	)
+	if err != nil {
+		t.Fatalf("EncodeValue() error = %v", err)
+	}
+
+	got := DecodeValue(rowV1)
+	want := RowV2Data{Columns: map[int64]string{
+		2: `"Aaliyah Mueller"`,
+		3: "Int: -7",
+		4: fmt.Sprintf("Uint: %d Datetime?: 2024-01-02 03:04:05", packed),
+		5: "NULL",
+		6: "1.5",
+	}}
+	if got.Type != TypeRowV1 || !reflect.DeepEqual(got.Payload, want) {
+		t.Errorf("DecodeValue(row v1) = %s %v, want %s %v", got.Type, got.Payload, TypeRowV1, want)
+	}
+
+	// an index value isn't made of column ID and value pairs
+	indexValue, _ := tidbcodec.EncodeValue(time.UTC, nil, types.NewIntDatum(2))
+	if got := DecodeValue(indexValue); got.Type == TypeRowV1 {
+		t.Errorf("DecodeValue(%X) = %s, want not a V1 row", indexValue, got.Type)
+	}
+}
+
+func TestTrySmartDecodeDecimal(t *testing.T) {
+	encode := func(s string, precision, frac int) []byte {
+		t.Helper()
+		dec := new(types.MyDecimal)
+		if err := dec.FromString([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+		b, err := tidbcodec.EncodeDecimal(nil, dec, precision, frac)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	// a DECIMAL(10,2) takes 7 bytes, not the size of an integer
+	if got := (Options{}).trySmartDecode(encode("123.45", 10, 2)); got != "Decimal: 123.45" {
+		t.Errorf("trySmartDecode(DECIMAL(10,2)) = %s, want Decimal: 123.45", got)
+	}
+	if got := (Options{}).trySmartDecode(encode("-0.5", 65, 30)); got != "Decimal: -0.500000000000000000000000000000" {
+		t.Errorf("trySmartDecode(DECIMAL(65,30)) = %s", got)
+	}
+
+	// a DECIMAL(12,2) takes 8 bytes, which may be a BIGINT as well
+	b := encode("9876543210.12", 12, 2)
+	if got := (Options{}).trySmartDecode(b); !strings.Contains(got, "Decimal?: 9876543210.12") || !strings.HasPrefix(got, "Int: ") {
+		t.Errorf("trySmartDecode(DECIMAL(12,2)) = %s, want both the integer and the decimal", got)
+	}
+
+	// the size must fit the precision
+	if got := (Options{}).trySmartDecode(append(encode("123.45", 10, 2), 0)); strings.Contains(got, "Decimal") {
+		t.Errorf("trySmartDecode(DECIMAL(10,2) and a byte) = %s, want no decimal", got)
+	}
+}
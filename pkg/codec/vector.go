@@ -0,0 +1,60 @@
+package codec
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxVectorDims is the largest dimension of a TiDB VECTOR column.
+const maxVectorDims = 16383
+
+// decodeVector decodes a VECTOR value as TiDB stores it: the dimension as a little-endian
+// uint32, then each element as a little-endian float32. It reports false when the length
+// doesn't match the dimension or an element is NaN or infinite, which TiDB rejects.
+func decodeVector(b []byte) ([]float32, bool) {
+	if len(b) < 4 {
+		return nil, false
+	}
+	dims := binary.LittleEndian.Uint32(b)
+	if dims > maxVectorDims || len(b) != 4+4*int(dims) {
+		return nil, false
+	}
+
+	v := make([]float32, dims)
+	for i := range v {
+		f := math.Float32frombits(binary.LittleEndian.Uint32(b[4+4*i:]))
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+			return nil, false
+		}
+		v[i] = f
+	}
+
+	return v, true
+}
+
+// vectorText writes the elements of a vector as TiDB does, e.g., [0.12,-3.4], with sep between
+// them.
+func vectorText(v []float32, sep string) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+
+	return b.String()
+}
+
+// formatVector prints a vector for a person, e.g., [0.12, -3.4] (dims=2).
+func (o Options) formatVector(b []byte, v []float32) string {
+	if o.Redact {
+		return redacted(b)
+	}
+
+	return o.limitValue(vectorText(v, ", "), false) + " (dims=" + strconv.Itoa(len(v)) + ")"
+}
@@ -0,0 +1,49 @@
+package codec
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// encodeVector encodes the elements the way TiDB stores a VECTOR value.
+func encodeVector(v ...float32) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, uint32(len(v)))
+	for _, f := range v {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(f))
+	}
+	return b
+}
+
+func TestDecodeVector(t *testing.T) {
+	b := encodeVector(0.12, -3.4, 5)
+	if got, want := (Options{}).trySmartDecode(b), "Vector: [0.12, -3.4, 5] (dims=3)"; got != want {
+		t.Errorf("trySmartDecode() = %s, want %s", got, want)
+	}
+	if got, want := (Options{}).decodeColumn(b, ColumnVector), "[0.12, -3.4, 5] (dims=3)"; got != want {
+		t.Errorf("decodeColumn() = %s, want %s", got, want)
+	}
+	if got, err := columnText(b, ColumnVector); err != nil || got != "[0.12,-3.4,5]" {
+		t.Errorf("columnText() = %s, %v, want [0.12,-3.4,5]", got, err)
+	}
+
+	// a vector of one dimension is decoded by the schema only, its length is the one of a BIGINT
+	if got := (Options{}).decodeColumn(encodeVector(1.5), ColumnVector); got != "[1.5] (dims=1)" {
+		t.Errorf("decodeColumn() = %s, want [1.5] (dims=1)", got)
+	}
+	if got := (Options{}).decodeColumn(encodeVector(), ColumnVector); got != "[] (dims=0)" {
+		t.Errorf("decodeColumn() = %s, want [] (dims=0)", got)
+	}
+
+	for _, b := range [][]byte{
+		encodeVector(1, 2)[:11],                 // shorter than the dimension
+		append(encodeVector(1, 2), 0, 0, 0, 0),  // longer than the dimension
+		encodeVector(1, float32(math.NaN())),    // NaN
+		encodeVector(1, float32(math.Inf(-1))),  // infinity
+		{0x0c, 0x00, 0x00, 0x00, 'a', 'b', 'c'}, // not a vector at all
+	} {
+		if v, ok := decodeVector(b); ok {
+			t.Errorf("decodeVector(%x) = %v, want false", b, v)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// The binary output frames each key-value pair as:
+//
+//	+----------------+-----------+------------------+-------------+
+//	| key length     | key       | value length     | value       |
+//	| uint32, BE     | raw bytes | uint32, BE       | raw bytes   |
+//	+----------------+-----------+------------------+-------------+
+//
+// Frames follow each other without a separator or a header, and the stream ends at EOF.
+// Keys and values are written as stored in TiKV, neither decoded nor encoded, so a
+// consumer reads the exact bytes back.
+
+// BinaryWriter writes key-value pairs in the length-prefixed binary framing.
+// Writes are buffered, so Flush must be called after the last pair.
+type BinaryWriter struct {
+	w   *bufio.Writer
+	hdr [4]byte
+}
+
+func NewBinaryWriter(w io.Writer) *BinaryWriter {
+	return &BinaryWriter{w: bufio.NewWriter(w)}
+}
+
+// Write writes one frame.
+func (w *BinaryWriter) Write(key, value []byte) error {
+	for _, b := range [][]byte{key, value} {
+		if uint64(len(b)) > math.MaxUint32 {
+			return fmt.Errorf("%d bytes exceed the frame limit", len(b))
+		}
+		binary.BigEndian.PutUint32(w.hdr[:], uint32(len(b)))
+		if _, err := w.w.Write(w.hdr[:]); err != nil {
+			return err
+		}
+		if _, err := w.w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush writes the buffered frames to the underlying writer.
+func (w *BinaryWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// BinaryReader reads key-value pairs written by BinaryWriter.
+type BinaryReader struct {
+	r *bufio.Reader
+}
+
+func NewBinaryReader(r io.Reader) *BinaryReader {
+	return &BinaryReader{r: bufio.NewReader(r)}
+}
+
+// Read reads the next frame. It returns io.EOF at the end of the stream, and
+// io.ErrUnexpectedEOF when the stream ends in the middle of a frame.
+func (r *BinaryReader) Read() (key, value []byte, err error) {
+	key, err = r.readField()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	value, err = r.readField()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, value, nil
+}
+
+func (r *BinaryReader) readField() ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r.r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, binary.BigEndian.Uint32(hdr[:]))
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return b, nil
+}
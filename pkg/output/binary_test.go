@@ -0,0 +1,61 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	pairs := [][2][]byte{
+		{[]byte("t\x80\x00\x00\x00\x00\x00\x00\x01_r\x80\x00\x00\x00\x00\x00\x00\x01"), []byte{0x80, 0x00, 0x02}},
+		{[]byte("key-with-empty-value"), {}},
+		{[]byte{0x00, 0xFF, '\n'}, bytes.Repeat([]byte{0xAB}, 70000)},
+	}
+
+	var buf bytes.Buffer
+	w := NewBinaryWriter(&buf)
+	for _, p := range pairs {
+		if err := w.Write(p[0], p[1]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// the first frame: key length, key, value length, value
+	first := buf.Bytes()[:4+len(pairs[0][0])+4+len(pairs[0][1])]
+	expected := append([]byte{0, 0, 0, byte(len(pairs[0][0]))}, pairs[0][0]...)
+	expected = append(expected, 0, 0, 0, 3, 0x80, 0x00, 0x02)
+	if !bytes.Equal(first, expected) {
+		t.Errorf("first frame = %X, want %X", first, expected)
+	}
+
+	r := NewBinaryReader(&buf)
+	for i, p := range pairs {
+		key, value, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read() #%d error = %v", i, err)
+		}
+		if !bytes.Equal(key, p[0]) || !bytes.Equal(value, p[1]) {
+			t.Errorf("Read() #%d = %X, (%d bytes), want %X, (%d bytes)", i, key, len(value), p[0], len(p[1]))
+		}
+	}
+	if _, _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read() at the end error = %v, want EOF", err)
+	}
+}
+
+func TestBinaryReaderTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewBinaryWriter(&buf)
+	_ = w.Write([]byte("key"), []byte("value"))
+	_ = w.Flush()
+
+	truncated := buf.Bytes()[:buf.Len()-2]
+	if _, _, err := NewBinaryReader(bytes.NewReader(truncated)).Read(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Read() error = %v, want ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,120 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+// csvHeader is the first columns of the CSV output, followed by a col_<id> column for each
+// RowV2 column ID.
+var csvHeader = []string{"index", "key", "hex", "value_type", "value"}
+
+// csvHeaderRows is the number of rows buffered to learn the RowV2 column IDs of the header.
+const csvHeaderRows = 1000
+
+// CSVWriter writes each Record as a row of index,key,hex,value_type,value after a header row,
+// with the RowV2 columns flattened into col_<id> columns sorted by column ID. The other values
+// are flattened into the value cell by DecodedValue.String.
+//
+// The header is written once the first csvHeaderRows rows are buffered, or at Flush, with the
+// column IDs of these rows. A later RowV2 column missing from the header is kept in the value
+// cell as a colID=value pair. Writes are buffered, so Flush must be called after the last record.
+type CSVWriter struct {
+	w          *csv.Writer
+	headerRows int
+	pending    []csvRow // buffered until the header is written
+	columns    []int64  // the column IDs of the header
+	header     bool
+}
+
+type csvRow struct {
+	index  int
+	record Record
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w), headerRows: csvHeaderRows}
+}
+
+// Write writes the record with its 1-based index in the result, after the header.
+func (w *CSVWriter) Write(index int, r Record) error {
+	if w.header {
+		return w.w.Write(w.row(index, r))
+	}
+
+	w.pending = append(w.pending, csvRow{index: index, record: r})
+	if len(w.pending) < w.headerRows {
+		return nil
+	}
+	return w.writePending()
+}
+
+// writePending writes the header with the column IDs of the buffered rows, and the rows.
+func (w *CSVWriter) writePending() error {
+	ids := make(map[int64]struct{})
+	for _, p := range w.pending {
+		if row, ok := p.record.Value.Payload.(codec.RowV2Data); ok {
+			for id := range row.Columns {
+				ids[id] = struct{}{}
+			}
+		}
+	}
+	w.columns = slices.Sorted(maps.Keys(ids))
+
+	header := slices.Clone(csvHeader)
+	for _, id := range w.columns {
+		header = append(header, fmt.Sprintf("col_%d", id))
+	}
+	if err := w.w.Write(header); err != nil {
+		return err
+	}
+	w.header = true
+
+	for _, p := range w.pending {
+		if err := w.w.Write(w.row(p.index, p.record)); err != nil {
+			return err
+		}
+	}
+	w.pending = nil
+
+	return nil
+}
+
+func (w *CSVWriter) row(index int, r Record) []string {
+	cells := []string{strconv.Itoa(index), r.Key, r.Hex, string(r.Value.Type), ""}
+	data, ok := r.Value.Payload.(codec.RowV2Data)
+	if !ok {
+		cells[4] = r.Value.String()
+		return append(cells, make([]string, len(w.columns))...)
+	}
+
+	extra := maps.Clone(data.Columns)
+	for _, id := range w.columns {
+		cells = append(cells, data.Columns[id])
+		delete(extra, id)
+	}
+	if len(extra) > 0 {
+		cells[4] = codec.DecodedValue{Type: codec.TypeRowV2, Payload: codec.RowV2Data{Columns: extra}}.String()
+	}
+
+	return cells
+}
+
+// Flush writes the header and the buffered rows, if not yet, to the underlying writer. A result
+// without rows is the header alone.
+func (w *CSVWriter) Flush() error {
+	if !w.header {
+		if err := w.writePending(); err != nil {
+			return err
+		}
+	}
+
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,93 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"slices"
+	"testing"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+func TestCSVWriterWithoutRows(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf)
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got, want := buf.String(), "index,key,hex,value_type,value\n"; got != want {
+		t.Errorf("CSV without rows = %q, want the header %q", got, want)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	key := []byte{0x74, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x84, 0x5f, 0x72, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}
+	value, _ := hex.DecodeString("80000200000002030f00100041616c69796168204d75656c6c657201")
+
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf)
+	if err := w.Write(1, NewRecord(codec.Options{}, key, value)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	// a value with a comma and a newline is quoted
+	quoted := Record{Key: "t1_r2", Hex: "74", Value: codec.DecodedValue{Type: codec.TypeIndex, Payload: []string{"a,b", "c\nd"}}}
+	if err := w.Write(2, quoted); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll(%s) error = %v", buf.String(), err)
+	}
+	if want := append(slices.Clone(csvHeader), "col_2", "col_3"); len(rows) != 3 || !slices.Equal(rows[0], want) {
+		t.Fatalf("CSV rows = %q, want the header with the columns and 2 rows", rows)
+	}
+	if want := []string{"1", "t132_r1", "7480000000000000845F728000000000000001", "row_v2", "", `"Aaliyah Mueller"`, "Int: 1 (Hex: 0x01)"}; !slices.Equal(rows[1], want) {
+		t.Errorf("row 1 = %q, want %q", rows[1], want)
+	}
+	if want := []string{"2", "t1_r2", "74", "index", "a,b, c\nd", "", ""}; !slices.Equal(rows[2], want) {
+		t.Errorf("row 2 = %q, want the index values", rows[2])
+	}
+}
+
+func TestCSVWriterLateColumns(t *testing.T) {
+	row := func(cols map[int64]string) Record {
+		return Record{Key: "t1_r1", Hex: "74", Value: codec.DecodedValue{Type: codec.TypeRowV2, Payload: codec.RowV2Data{Columns: cols}}}
+	}
+
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf)
+	w.headerRows = 2
+	for i, cols := range []map[int64]string{{2: "a"}, {3: "b"}, {2: "c", 4: "d"}} {
+		if err := w.Write(i+1, row(cols)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll(%s) error = %v", buf.String(), err)
+	}
+	// the header has the columns of the buffered rows, a later one stays in the value cell
+	want := [][]string{
+		append(slices.Clone(csvHeader), "col_2", "col_3"),
+		{"1", "t1_r1", "74", "row_v2", "", "a", ""},
+		{"2", "t1_r1", "74", "row_v2", "", "", "b"},
+		{"3", "t1_r1", "74", "row_v2", "4=d", "c", ""},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("CSV rows = %q, want %q", rows, want)
+	}
+	for i := range want {
+		if !slices.Equal(rows[i], want[i]) {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
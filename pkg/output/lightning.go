@@ -0,0 +1,73 @@
+package output
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+// lightningNull is the NULL of the TiDB Lightning CSV defaults, written without quotes.
+const lightningNull = `\N`
+
+// lightningEscaper escapes a quoted field for the backslash-escape of the TiDB Lightning CSV
+// defaults, so any bytes of a string or a blob read back as they are.
+var lightningEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+
+// LightningWriter writes the rows of a table as a CSV file TiDB Lightning imports with its
+// default [mydumper.csv] settings: separated by commas, a header of the column names, numbers
+// as they are, the other values quoted and backslash-escaped, and NULL as \N. A column missing
+// from a row, added after the row was written, is written as NULL too, as CSV has no default.
+// Writes are buffered, so Flush must be called after the last row.
+type LightningWriter struct {
+	w       *bufio.Writer
+	started bool
+}
+
+func NewLightningWriter(w io.Writer) *LightningWriter {
+	return &LightningWriter{w: bufio.NewWriter(w)}
+}
+
+// Write writes a row, the header of its column names before the first one. The rows must have
+// the columns of the same schema.
+func (w *LightningWriter) Write(values []codec.RowValue) error {
+	if !w.started {
+		names := make([]string, 0, len(values))
+		for _, v := range values {
+			names = append(names, lightningQuote(v.Column.Name))
+		}
+		if err := w.writeLine(names); err != nil {
+			return err
+		}
+		w.started = true
+	}
+
+	fields := make([]string, 0, len(values))
+	for _, v := range values {
+		switch {
+		case v.Null || v.Missing:
+			fields = append(fields, lightningNull)
+		case v.Column.Type.IsNumeric():
+			fields = append(fields, v.Text)
+		default:
+			fields = append(fields, lightningQuote(v.Text))
+		}
+	}
+
+	return w.writeLine(fields)
+}
+
+func (w *LightningWriter) writeLine(fields []string) error {
+	_, err := w.w.WriteString(strings.Join(fields, ",") + "\n")
+	return err
+}
+
+// Flush writes any buffered rows to the underlying writer.
+func (w *LightningWriter) Flush() error {
+	return w.w.Flush()
+}
+
+func lightningQuote(s string) string {
+	return `"` + lightningEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,37 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+func TestLightningWriter(t *testing.T) {
+	id := codec.ColumnSchema{ID: 1, Name: "id", Type: codec.ColumnInt, Handle: true}
+	name := codec.ColumnSchema{ID: 2, Name: "name", Type: codec.ColumnString}
+	data := codec.ColumnSchema{ID: 3, Name: "data", Type: codec.ColumnBlob}
+	added := codec.ColumnSchema{ID: 4, Name: "added", Type: codec.ColumnInt}
+
+	var buf bytes.Buffer
+	w := NewLightningWriter(&buf)
+	rows := [][]codec.RowValue{
+		{{Column: id, Text: "1"}, {Column: name, Text: `O"Brien\` + "\n"}, {Column: data, Text: "\x00\xff"}, {Column: added, Missing: true}},
+		{{Column: id, Text: "2"}, {Column: name, Text: ""}, {Column: data, Null: true}, {Column: added, Text: "-3"}},
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := `"id","name","data","added"` + "\n" +
+		`1,"O\"Brien\\\n","\0` + "\xff" + `",\N` + "\n" +
+		`2,"",\N,-3` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package output
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// The msgpack output writes each key-value pair as a MessagePack map of six entries,
+//
+//	{"index": int, "key": str, "raw_key": bin, "value_type": str, "value": str, "raw_value": bin}
+//
+// with the fields of ResultRecord. The maps follow each other without a separator, and the
+// stream ends at EOF, so a streaming unpacker reads them one by one.
+
+// MsgpackWriter writes ResultRecords as MessagePack maps. Writes are buffered, so Flush must
+// be called after the last record.
+type MsgpackWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func NewMsgpackWriter(w io.Writer) *MsgpackWriter {
+	return &MsgpackWriter{w: bufio.NewWriter(w)}
+}
+
+// Write writes a record as one map.
+func (w *MsgpackWriter) Write(r ResultRecord) error {
+	b := append(w.buf[:0], 0x86) // fixmap of 6
+	b = appendMsgpackString(b, "index")
+	b = appendMsgpackInt(b, int64(r.Index))
+	b = appendMsgpackString(b, "key")
+	b = appendMsgpackString(b, r.Key)
+	b = appendMsgpackString(b, "raw_key")
+	b = appendMsgpackBinary(b, r.RawKey)
+	b = appendMsgpackString(b, "value_type")
+	b = appendMsgpackString(b, r.ValueType)
+	b = appendMsgpackString(b, "value")
+	b = appendMsgpackString(b, r.Value)
+	b = appendMsgpackString(b, "raw_value")
+	b = appendMsgpackBinary(b, r.RawValue)
+	w.buf = b
+
+	_, err := w.w.Write(b)
+	return err
+}
+
+// Flush writes the buffered records to the underlying writer.
+func (w *MsgpackWriter) Flush() error {
+	return w.w.Flush()
+}
+
+func appendMsgpackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= 0x7f:
+		return append(b, byte(n))
+	case n >= -32 && n < 0:
+		return append(b, byte(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+	}
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+
+	return append(b, s...)
+}
+
+// appendMsgpackBinary appends the bytes as bin, or nil when there are none, e.g., the value of
+// --keys-only.
+func appendMsgpackBinary(b []byte, v []byte) []byte {
+	switch n := len(v); {
+	case v == nil:
+		return append(b, 0xc0)
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+
+	return append(b, v...)
+}
@@ -0,0 +1,114 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestMsgpackWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewMsgpackWriter(&buf)
+	if err := w.Write(ResultRecord{Index: 1, Key: "k", RawKey: []byte{0x6b}, ValueType: "raw", Value: "0x01", RawValue: []byte{0x01}}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Write(ResultRecord{Index: 300, Key: "k", RawKey: []byte{0x6b}, ValueType: "null"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var want []byte
+	for _, r := range []struct {
+		index      []byte
+		typ, value string
+		rawValue   []byte
+	}{
+		{[]byte{0x01}, "\xa3raw", "\xa40x01", []byte{0xc4, 0x01, 0x01}},
+		{[]byte{0xd2, 0x00, 0x00, 0x01, 0x2c}, "\xa4null", "\xa0", []byte{0xc0}},
+	} {
+		want = append(want, 0x86)
+		want = append(append(want, "\xa5index"...), r.index...)
+		want = append(want, "\xa3key\xa1k"...)
+		want = append(want, "\xa7raw_key\xc4\x01k"...)
+		want = append(append(want, "\xaavalue_type"...), r.typ...)
+		want = append(append(want, "\xa5value"...), r.value...)
+		want = append(append(want, "\xa9raw_value"...), r.rawValue...)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("output = % x\nwant     % x", buf.Bytes(), want)
+	}
+}
+
+func TestAppendMsgpackString(t *testing.T) {
+	tests := []struct {
+		n      int
+		header []byte
+	}{
+		{31, []byte{0xbf}},
+		{32, []byte{0xd9, 32}},
+		{256, []byte{0xda, 0x01, 0x00}},
+		{1 << 16, []byte{0xdb, 0x00, 0x01, 0x00, 0x00}},
+	}
+
+	for _, tt := range tests {
+		s := strings.Repeat("a", tt.n)
+		got := appendMsgpackString(nil, s)
+		if !bytes.HasPrefix(got, tt.header) || len(got) != len(tt.header)+tt.n {
+			t.Errorf("appendMsgpackString(%d bytes) header = % x, want % x", tt.n, got[:min(len(got), 5)], tt.header)
+		}
+	}
+}
+
+// TestMsgpackWriterDecode reads the output back with vmihailenco/msgpack, a streaming unpacker
+// of its own, so the maps are checked against the format rather than against the writer.
+func TestMsgpackWriterDecode(t *testing.T) {
+	records := []ResultRecord{
+		{Index: 1, Key: "t1_r1", RawKey: []byte{0x74, 0x01}, ValueType: "raw", Value: "0x01", RawValue: []byte{0x01}},
+		{Index: 300, Key: "t1_r2", RawKey: []byte{0x74, 0x02}, ValueType: "null"},                                       // no raw value, as with --keys-only
+		{Index: 1 << 40, Key: strings.Repeat("k", 300), RawKey: bytes.Repeat([]byte{0xff}, 70000), ValueType: "row_v2"}, // the longer headers
+		{Index: 70000, Key: "名前", RawKey: []byte{}, Value: strings.Repeat("v", 1<<16), RawValue: bytes.Repeat([]byte{0x80}, 256)},
+	}
+	var buf bytes.Buffer
+	w := NewMsgpackWriter(&buf)
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	type record struct {
+		Index     int64  `msgpack:"index"`
+		Key       string `msgpack:"key"`
+		RawKey    []byte `msgpack:"raw_key"`
+		ValueType string `msgpack:"value_type"`
+		Value     string `msgpack:"value"`
+		RawValue  []byte `msgpack:"raw_value"`
+	}
+	dec := msgpack.NewDecoder(&buf)
+	for i, want := range records {
+		var got record
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("Decode() of record %d error = %v", i, err)
+		}
+		if got.Index != int64(want.Index) || got.Key != want.Key || !bytes.Equal(got.RawKey, want.RawKey) ||
+			got.ValueType != want.ValueType || got.Value != want.Value || !bytes.Equal(got.RawValue, want.RawValue) {
+			t.Errorf("record %d = %.60v, want %.60v", i, got, want)
+		}
+		if (got.RawValue == nil) != (want.RawValue == nil) {
+			t.Errorf("record %d raw_value = %v, want nil only without a value", i, got.RawValue)
+		}
+	}
+	var extra map[string]any
+	if err := dec.Decode(&extra); !errors.Is(err, io.EOF) {
+		t.Errorf("Decode() after the last record = %v, %v, want EOF", extra, err)
+	}
+}
@@ -0,0 +1,362 @@
+package output
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+// parquetMagic starts and ends a Parquet file.
+const parquetMagic = "PAR1"
+
+// parquetRowGroupRows is the number of rows kept in memory and written as a row group at a time.
+const parquetRowGroupRows = 10000
+
+// The values of the Parquet format used here, as numbered in its Thrift definition.
+const (
+	parquetInt64     = 2 // Type
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8   = 0  // ConvertedType
+	parquetUint64 = 14 //
+
+	parquetOptional = 1 // FieldRepetitionType
+	parquetPlain    = 0 // Encoding
+	parquetRLE      = 3 //
+	parquetDataPage = 0 // PageType
+)
+
+// ParquetWriter writes the rows of a table as a Parquet file, a column a schema column in the
+// schema order, for analytical tools such as DuckDB or Spark. The columns are OPTIONAL: a NULL
+// and a column missing from the row are nulls. Integers are INT64 (UINT_64 for the unsigned
+// ones) and doubles DOUBLE. The others are BYTE_ARRAY, UTF8 but for the binary columns, with a
+// decimal or a time as its text, e.g., 12.50 or 2024-01-02 03:04:05. The file is written
+// uncompressed with the plain encoding, a row group every parquetRowGroupRows rows, and is
+// complete once Flush writes the footer.
+type ParquetWriter struct {
+	w         *bufio.Writer
+	offset    int64 // bytes written so far
+	columns   []codec.ColumnSchema
+	chunks    []parquetChunk // the columns of the row group being built
+	rows      int            // rows of the row group being built
+	rowGroups [][]byte       // the encoded RowGroup structs written
+	numRows   int64
+}
+
+// parquetChunk holds the values of a column in a row group.
+type parquetChunk struct {
+	defined []bool // the definition levels: false for null
+	values  []byte // the non-null values in the plain encoding
+}
+
+func NewParquetWriter(w io.Writer, columns []codec.ColumnSchema) *ParquetWriter {
+	return &ParquetWriter{w: bufio.NewWriter(w), columns: columns, chunks: make([]parquetChunk, len(columns))}
+}
+
+// Write adds a row, which must have the columns of the writer.
+func (w *ParquetWriter) Write(values []codec.RowValue) error {
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("row has %d columns, want %d", len(values), len(w.columns))
+	}
+
+	// encode the row first, so a value which fails leaves the columns as they were
+	encoded := make([][]byte, len(values))
+	for i, v := range values {
+		if v.Null || v.Missing {
+			continue
+		}
+		b, err := appendParquetValue(nil, v)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", v.Column.Name, err)
+		}
+		encoded[i] = b
+	}
+	for i, b := range encoded {
+		c := &w.chunks[i]
+		c.defined = append(c.defined, b != nil)
+		c.values = append(c.values, b...)
+	}
+
+	if w.rows++; w.rows >= parquetRowGroupRows {
+		return w.writeRowGroup()
+	}
+	return nil
+}
+
+// Flush writes the rows left and the footer, which ends the file. It must be called once after
+// the last row.
+func (w *ParquetWriter) Flush() error {
+	if w.offset == 0 {
+		if err := w.write([]byte(parquetMagic)); err != nil {
+			return err
+		}
+	}
+	if w.rows > 0 {
+		if err := w.writeRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	footer := w.fileMetaData()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	if err := w.write(append(footer, parquetMagic...)); err != nil {
+		return err
+	}
+
+	return w.w.Flush()
+}
+
+func (w *ParquetWriter) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	return err
+}
+
+// writeRowGroup writes the columns of the row group being built as one data page each.
+func (w *ParquetWriter) writeRowGroup() error {
+	if w.offset == 0 {
+		if err := w.write([]byte(parquetMagic)); err != nil {
+			return err
+		}
+	}
+
+	var group thriftWriter
+	var total int64
+	group.beginStruct()
+	group.fieldList(1, thriftStruct, len(w.columns))
+	for i, c := range w.chunks {
+		levels := appendRLELevels(nil, c.defined)
+		page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+		page = append(append(page, levels...), c.values...)
+		if len(page) > math.MaxInt32 {
+			return fmt.Errorf("column %s: %d bytes of a row group, more than a page can hold", w.columns[i].Name, len(page))
+		}
+
+		var header thriftWriter
+		header.beginStruct()
+		header.fieldI32(1, parquetDataPage)
+		header.fieldI32(2, int32(len(page)))
+		header.fieldI32(3, int32(len(page)))
+		header.fieldStruct(5)
+		header.fieldI32(1, int32(w.rows))
+		header.fieldI32(2, parquetPlain)
+		header.fieldI32(3, parquetRLE)
+		header.fieldI32(4, parquetRLE)
+		header.endStruct()
+		header.endStruct()
+
+		start := w.offset
+		if err := w.write(header.buf); err != nil {
+			return err
+		}
+		if err := w.write(page); err != nil {
+			return err
+		}
+		size := w.offset - start
+		total += size
+
+		group.beginStruct() // ColumnChunk
+		group.fieldI64(2, start)
+		group.fieldStruct(3)
+		group.fieldI32(1, parquetType(w.columns[i].Type))
+		group.fieldList(2, thriftI32, 2)
+		group.i32(parquetPlain)
+		group.i32(parquetRLE)
+		group.fieldList(3, thriftBinary, 1)
+		group.binary(w.columns[i].Name)
+		group.fieldI32(4, 0) // UNCOMPRESSED
+		group.fieldI64(5, int64(w.rows))
+		group.fieldI64(6, size)
+		group.fieldI64(7, size)
+		group.fieldI64(9, start)
+		group.endStruct()
+		group.endStruct()
+
+		w.chunks[i] = parquetChunk{}
+	}
+	group.fieldI64(2, total)
+	group.fieldI64(3, int64(w.rows))
+	group.endStruct()
+
+	w.rowGroups = append(w.rowGroups, group.buf)
+	w.numRows += int64(w.rows)
+	w.rows = 0
+
+	return nil
+}
+
+// fileMetaData encodes the FileMetaData struct of the footer.
+func (w *ParquetWriter) fileMetaData() []byte {
+	var t thriftWriter
+	t.beginStruct()
+	t.fieldI32(1, 1)
+	t.fieldList(2, thriftStruct, len(w.columns)+1)
+	t.beginStruct()
+	t.fieldBinary(4, "schema")
+	t.fieldI32(5, int32(len(w.columns)))
+	t.endStruct()
+	for _, c := range w.columns {
+		t.beginStruct()
+		t.fieldI32(1, parquetType(c.Type))
+		t.fieldI32(3, parquetOptional)
+		t.fieldBinary(4, c.Name)
+		switch c.Type {
+		case codec.ColumnUint:
+			t.fieldI32(6, parquetUint64)
+		case codec.ColumnString, codec.ColumnDatetime, codec.ColumnTimestamp, codec.ColumnTime, codec.ColumnJSON,
+			codec.ColumnDecimal, codec.ColumnEnum, codec.ColumnSet, codec.ColumnVector:
+			t.fieldI32(6, parquetUTF8)
+		}
+		t.endStruct()
+	}
+	t.fieldI64(3, w.numRows)
+	t.fieldList(4, thriftStruct, len(w.rowGroups))
+	for _, g := range w.rowGroups {
+		t.buf = append(t.buf, g...)
+	}
+	t.fieldBinary(6, "tikv-reader")
+	t.endStruct()
+
+	return t.buf
+}
+
+// parquetType returns the physical type of the column type.
+func parquetType(typ codec.ColumnType) int32 {
+	switch typ {
+	case codec.ColumnInt, codec.ColumnUint:
+		return parquetInt64
+	case codec.ColumnDouble:
+		return parquetDouble
+	default:
+		return parquetByteArray
+	}
+}
+
+// appendParquetValue appends the value in the plain encoding of its physical type.
+func appendParquetValue(b []byte, v codec.RowValue) ([]byte, error) {
+	switch v.Column.Type {
+	case codec.ColumnInt:
+		n, err := strconv.ParseInt(v.Text, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint64(b, uint64(n)), nil
+	case codec.ColumnUint:
+		n, err := strconv.ParseUint(v.Text, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint64(b, n), nil
+	case codec.ColumnDouble:
+		f, err := strconv.ParseFloat(v.Text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(f)), nil
+	default:
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(v.Text)))
+		return append(b, v.Text...), nil
+	}
+}
+
+// appendRLELevels appends the definition levels of bit width 1 as the RLE runs of the
+// RLE/bit-packing hybrid encoding.
+func appendRLELevels(b []byte, defined []bool) []byte {
+	for i := 0; i < len(defined); {
+		j := i
+		for j < len(defined) && defined[j] == defined[i] {
+			j++
+		}
+		b = binary.AppendUvarint(b, uint64(j-i)<<1)
+		if defined[i] {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+		i = j
+	}
+
+	return b
+}
+
+// The element types of the Thrift compact protocol used here.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the structs of the Parquet metadata with the Thrift compact protocol. A
+// struct, but for a struct field, is begun by beginStruct, and every struct ends by endStruct.
+type thriftWriter struct {
+	buf  []byte
+	last []int16 // the last field ID of each open struct, the innermost last
+}
+
+func (t *thriftWriter) beginStruct() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(v int32) {
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) binary(s string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+func (t *thriftWriter) fieldI32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.i32(v)
+}
+
+func (t *thriftWriter) fieldI64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) fieldBinary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.binary(s)
+}
+
+func (t *thriftWriter) fieldStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.last = append(t.last, 0)
+}
+
+// fieldList begins a list field of n elements, to be written after it.
+func (t *thriftWriter) fieldList(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elem)
+	} else {
+		t.buf = append(t.buf, 0xf0|elem)
+		t.buf = binary.AppendUvarint(t.buf, uint64(n))
+	}
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf = append(t.buf, 0)
+	t.last = t.last[:len(t.last)-1]
+}
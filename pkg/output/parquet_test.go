@@ -0,0 +1,180 @@
+package output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/deprecated"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+func TestParquetWriter(t *testing.T) {
+	id := codec.ColumnSchema{ID: 1, Name: "id", Type: codec.ColumnInt, Handle: true}
+	name := codec.ColumnSchema{ID: 2, Name: "name", Type: codec.ColumnString}
+
+	var buf bytes.Buffer
+	w := NewParquetWriter(&buf, []codec.ColumnSchema{id, name})
+	rows := 2*parquetRowGroupRows + 1
+	for i := range rows {
+		row := []codec.RowValue{{Column: id, Text: strconv.Itoa(i)}, {Column: name, Text: "n" + strconv.Itoa(i)}}
+		if i%3 == 0 {
+			row[1] = codec.RowValue{Column: name, Null: true}
+		}
+		if err := w.Write(row); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	// a row which doesn't fit fails without adding a part of it
+	if err := w.Write([]codec.RowValue{{Column: id, Text: "x"}, {Column: name, Text: "y"}}); err == nil {
+		t.Error("Write() should fail with a non-integer value of an integer column")
+	}
+	if err := w.Write([]codec.RowValue{{Column: id, Text: "1"}}); err == nil {
+		t.Error("Write() should fail with a row of other columns")
+	}
+	if len(w.chunks[0].defined) != 1 || len(w.chunks[1].defined) != 1 {
+		t.Errorf("columns hold %d and %d values, want the last row only", len(w.chunks[0].defined), len(w.chunks[1].defined))
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if len(w.rowGroups) != 3 || w.numRows != int64(rows) {
+		t.Errorf("wrote %d row groups of %d rows, want 3 of %d", len(w.rowGroups), w.numRows, rows)
+	}
+
+	b := buf.Bytes()
+	if !bytes.HasPrefix(b, []byte(parquetMagic)) || !bytes.HasSuffix(b, []byte(parquetMagic)) {
+		t.Fatalf("file = %q...%q, want it between the magic", b[:4], b[len(b)-4:])
+	}
+	footer := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	if got := b[len(b)-8-footer : len(b)-8]; !bytes.Equal(got, w.fileMetaData()) {
+		t.Errorf("footer of %d bytes doesn't match the file metadata", footer)
+	}
+}
+
+// TestParquetWriterReadBack reads the file back with parquet-go, an implementation of its own,
+// so the file is checked against the format rather than against the writer.
+func TestParquetWriterReadBack(t *testing.T) {
+	cols := []codec.ColumnSchema{
+		{ID: 1, Name: "id", Type: codec.ColumnInt, Handle: true},
+		{ID: 2, Name: "qty", Type: codec.ColumnUint},
+		{ID: 3, Name: "score", Type: codec.ColumnDouble},
+		{ID: 4, Name: "name", Type: codec.ColumnString},
+		{ID: 5, Name: "price", Type: codec.ColumnDecimal},
+		{ID: 6, Name: "data", Type: codec.ColumnBlob},
+	}
+	var buf bytes.Buffer
+	w := NewParquetWriter(&buf, cols)
+	rows := parquetRowGroupRows + 2
+	for i := range rows {
+		n := strconv.Itoa(i)
+		row := []codec.RowValue{
+			{Column: cols[0], Text: "-" + n},
+			{Column: cols[1], Text: "18446744073709551615"},
+			{Column: cols[2], Text: n + ".5"},
+			{Column: cols[3], Text: "名前" + n},
+			{Column: cols[4], Text: n + ".50"},
+			{Column: cols[5], Text: "\x00\xff"},
+		}
+		if i%2 == 1 {
+			row[3] = codec.RowValue{Column: cols[3], Null: true}
+			row[5] = codec.RowValue{Column: cols[5], Missing: true}
+		}
+		if err := w.Write(row); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("parquet.OpenFile() error = %v", err)
+	}
+	if f.NumRows() != int64(rows) || len(f.RowGroups()) != 2 {
+		t.Errorf("file has %d rows in %d row groups, want %d in 2", f.NumRows(), len(f.RowGroups()), rows)
+	}
+	fields := f.Schema().Fields()
+	for i, c := range cols {
+		if fields[i].Name() != c.Name || !fields[i].Optional() {
+			t.Errorf("field %d = %s (optional %t), want the optional %s", i, fields[i].Name(), fields[i].Optional(), c.Name)
+		}
+	}
+	for i, want := range map[int]*deprecated.ConvertedType{1: ptr(deprecated.Uint64), 3: ptr(deprecated.UTF8), 5: nil} {
+		if got := fields[i].Type().ConvertedType(); (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Errorf("%s has the converted type %v, want %v", fields[i].Name(), got, want)
+		}
+	}
+
+	type record struct {
+		ID    *int64   `parquet:"id,optional"`
+		Qty   *uint64  `parquet:"qty,optional"`
+		Score *float64 `parquet:"score,optional"`
+		Name  *string  `parquet:"name,optional"`
+		Price *string  `parquet:"price,optional"`
+		Data  []byte   `parquet:"data,optional"`
+	}
+	got, err := parquet.Read[record](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("parquet.Read() error = %v", err)
+	}
+	if len(got) != rows {
+		t.Fatalf("parquet.Read() = %d rows, want %d", len(got), rows)
+	}
+	for _, i := range []int{0, 1, parquetRowGroupRows, parquetRowGroupRows + 1} {
+		r, n := got[i], strconv.Itoa(i)
+		if r.ID == nil || *r.ID != -int64(i) || r.Qty == nil || *r.Qty != 18446744073709551615 ||
+			r.Score == nil || *r.Score != float64(i)+0.5 || r.Price == nil || *r.Price != n+".50" {
+			t.Errorf("row %d = %+v, want the numbers and the decimal text of row %d", i, r, i)
+		}
+		if i%2 == 1 {
+			if r.Name != nil || r.Data != nil {
+				t.Errorf("row %d = %v, %v, want the NULL and the missing column as nulls", i, r.Name, r.Data)
+			}
+			continue
+		}
+		if r.Name == nil || *r.Name != "名前"+n || !bytes.Equal(r.Data, []byte{0x00, 0xff}) {
+			t.Errorf("row %d = %v, %x, want 名前%d and 00ff", i, r.Name, r.Data, i)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestAppendRLELevels(t *testing.T) {
+	got := appendRLELevels(nil, []bool{true, true, true, false, true})
+	if want := []byte{3 << 1, 1, 1 << 1, 0, 1 << 1, 1}; !bytes.Equal(got, want) {
+		t.Errorf("appendRLELevels() = %v, want %v", got, want)
+	}
+}
+
+func TestThriftWriter(t *testing.T) {
+	var tw thriftWriter
+	tw.beginStruct()
+	tw.fieldI32(1, -1)
+	tw.fieldBinary(4, "ab")
+	tw.fieldStruct(20)
+	tw.fieldI64(1, 300)
+	tw.endStruct()
+	tw.fieldList(21, thriftI32, 2)
+	tw.i32(1)
+	tw.i32(2)
+	tw.endStruct()
+
+	want := []byte{
+		0x15, 0x01, // field 1, i32 -1 zigzag
+		0x38, 0x02, 'a', 'b', // field 4 (delta 3), binary
+		0x0c, 0x28, // field 20 (delta 16, long form), struct
+		0x16, 0xd8, 0x04, 0x00, // field 1, i64 300, end
+		0x19, 0x25, 0x02, 0x04, // field 21 (delta 1), list of 2 i32
+		0x00,
+	}
+	if !bytes.Equal(tw.buf, want) {
+		t.Errorf("thriftWriter = % x, want % x", tw.buf, want)
+	}
+}
@@ -0,0 +1,154 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestProtoWriter(t *testing.T) {
+	records := []ResultRecord{
+		{Index: 1, Key: "t1_r1", RawKey: []byte{0x74, 0x01}, ValueType: "raw", Value: "0x01", RawValue: []byte{0x01}},
+		{Index: 2, Key: "t1_r2", RawKey: []byte{0x74, 0x02}, ValueType: "null"},
+	}
+
+	var buf bytes.Buffer
+	w := NewProtoWriter(&buf)
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	b := buf.Bytes()
+	for _, want := range records {
+		size, n := protowire.ConsumeVarint(b)
+		if n < 0 || uint64(len(b)-n) < size {
+			t.Fatalf("bad length prefix in % x", b)
+		}
+		msg := b[n : n+int(size)]
+		b = b[n+int(size):]
+
+		var got ResultRecord
+		for len(msg) > 0 {
+			num, typ, n := protowire.ConsumeTag(msg)
+			msg = msg[n:]
+			if typ == protowire.VarintType {
+				v, n := protowire.ConsumeVarint(msg)
+				got.Index, msg = int(v), msg[n:]
+				continue
+			}
+			v, n := protowire.ConsumeBytes(msg)
+			msg = msg[n:]
+			switch num {
+			case protoKey:
+				got.Key = string(v)
+			case protoRawKey:
+				got.RawKey = v
+			case protoValueType:
+				got.ValueType = string(v)
+			case protoValue:
+				got.Value = string(v)
+			case protoRawValue:
+				got.RawValue = v
+			}
+		}
+		if got.Index != want.Index || got.Key != want.Key || !bytes.Equal(got.RawKey, want.RawKey) ||
+			got.ValueType != want.ValueType || got.Value != want.Value || !bytes.Equal(got.RawValue, want.RawValue) {
+			t.Errorf("message = %+v, want %+v", got, want)
+		}
+	}
+	if len(b) != 0 {
+		t.Errorf("%d bytes left after the messages", len(b))
+	}
+}
+
+// recordDescriptor builds the Record message of the documented schema for dynamicpb.
+func recordDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Type:     typ.Enum(),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			JsonName: proto.String(name),
+		}
+	}
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("tikvreader.proto"),
+		Package: proto.String("tikvreader"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Record"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("index", 1, descriptorpb.FieldDescriptorProto_TYPE_UINT64),
+				field("key", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("raw_key", 3, descriptorpb.FieldDescriptorProto_TYPE_BYTES),
+				field("value_type", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("value", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("raw_value", 6, descriptorpb.FieldDescriptorProto_TYPE_BYTES),
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile() error = %v", err)
+	}
+
+	return file.Messages().ByName("Record")
+}
+
+// TestProtoWriterDecode reads the output back with protodelim and the Record schema of the
+// protobuf module, so the messages are checked against the schema rather than the writer.
+func TestProtoWriterDecode(t *testing.T) {
+	records := []ResultRecord{
+		{Index: 1, Key: "t1_r1", RawKey: []byte{0x74, 0x01}, ValueType: "raw", Value: "0x01", RawValue: []byte{0x01}},
+		{Index: 300, Key: "名前", RawKey: []byte{0x74, 0x02}, ValueType: "null"},
+		{Index: 1 << 40, Key: "t1_r3", RawKey: bytes.Repeat([]byte{0xff}, 70000), ValueType: "row_v2", Value: "2=a;3=1"},
+	}
+	var buf bytes.Buffer
+	w := NewProtoWriter(&buf)
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	desc := recordDescriptor(t)
+	fields := desc.Fields()
+	r := bufio.NewReader(&buf)
+	opts := protodelim.UnmarshalOptions{MaxSize: -1}
+	for i, want := range records {
+		msg := dynamicpb.NewMessage(desc)
+		if err := opts.UnmarshalFrom(r, msg); err != nil {
+			t.Fatalf("UnmarshalFrom() of record %d error = %v", i, err)
+		}
+		get := func(name protoreflect.Name) protoreflect.Value { return msg.Get(fields.ByName(name)) }
+		if get("index").Uint() != uint64(want.Index) || get("key").String() != want.Key || !bytes.Equal(get("raw_key").Bytes(), want.RawKey) ||
+			get("value_type").String() != want.ValueType || get("value").String() != want.Value || !bytes.Equal(get("raw_value").Bytes(), want.RawValue) {
+			t.Errorf("record %d = %.80v, want %.80v", i, msg, want)
+		}
+		if unknown := msg.GetUnknown(); len(unknown) > 0 {
+			t.Errorf("record %d has fields outside the schema: % x", i, unknown)
+		}
+	}
+	if err := opts.UnmarshalFrom(r, dynamicpb.NewMessage(desc)); !errors.Is(err, io.EOF) {
+		t.Errorf("UnmarshalFrom() after the last record error = %v, want EOF", err)
+	}
+}
@@ -0,0 +1,74 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+// Record is a decoded key-value pair written by the machine-readable outputs.
+type Record struct {
+	Key      string             `json:"key"`
+	Hex      string             `json:"hex"`
+	Value    codec.DecodedValue `json:"value"`
+	Checksum string             `json:"checksum,omitempty"` // the row checksum check, for the RowV2 values having one
+	// the physical table ID of the row of a global index entry, see codec.IndexPartitionID
+	PartitionID int64 `json:"partition_id,omitempty"`
+}
+
+// NewRecord decodes the given raw key and value into a Record with the codec options.
+func NewRecord(opts codec.Options, key, value []byte) Record {
+	r := Record{
+		Key:   opts.DecodeKey(key),
+		Hex:   opts.PrettyPrintKey(key),
+		Value: opts.DecodeValueForKey(key, value),
+	}
+	if c, ok := codec.VerifyRowChecksum(key, value); ok {
+		r.Checksum = c.String()
+	}
+	if id, ok := codec.IndexPartitionID(key, value); ok {
+		r.PartitionID = id
+	}
+
+	return r
+}
+
+// ResultRecord is a key-value pair in the msgpack and the proto outputs: the decoded key and
+// value as in the other outputs, with the raw bytes of both.
+type ResultRecord struct {
+	Index     int
+	Key       string
+	RawKey    []byte
+	ValueType string
+	Value     string // the value flattened by DecodedValue.String
+	RawValue  []byte
+}
+
+// NewResultRecord decodes the given raw key and value into a ResultRecord with the codec options.
+func NewResultRecord(opts codec.Options, index int, key, value []byte) ResultRecord {
+	decoded := opts.DecodeValueForKey(key, value)
+	return ResultRecord{
+		Index:     index,
+		Key:       opts.DecodeKey(key),
+		RawKey:    key,
+		ValueType: string(decoded.Type),
+		Value:     decoded.String(),
+		RawValue:  value,
+	}
+}
+
+// JSONLinesWriter writes each Record as a single JSON document followed by a newline.
+type JSONLinesWriter struct {
+	enc *json.Encoder
+}
+
+func NewJSONLinesWriter(w io.Writer) *JSONLinesWriter {
+	return &JSONLinesWriter{enc: json.NewEncoder(w)}
+}
+
+// Write encodes the record. The encoder issues a single Write call per record,
+// so SocketWriter sends each line again whole when it has to reconnect.
+func (w *JSONLinesWriter) Write(r Record) error {
+	return w.enc.Encode(r)
+}
@@ -0,0 +1,37 @@
+package output
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+func TestRecordJSON(t *testing.T) {
+	key := []byte{0x74, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x84, 0x5f, 0x72, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}
+	value, _ := hex.DecodeString("80000200000002030f00100041616c69796168204d75656c6c657201")
+
+	b, err := json.Marshal(NewRecord(codec.Options{}, key, value))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	var got struct {
+		Key   string `json:"key"`
+		Value struct {
+			Type    string `json:"type"`
+			Payload struct {
+				Columns map[string]string `json:"columns"`
+			} `json:"payload"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", b, err)
+	}
+
+	// the column IDs are the keys of the columns object
+	if got.Key != "t132_r1" || got.Value.Type != "row_v2" || got.Value.Payload.Columns["2"] != `"Aaliyah Mueller"` {
+		t.Errorf("unexpected record JSON: %s", b)
+	}
+}
@@ -0,0 +1,128 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	defaultSocketRetries = 3
+	defaultSocketBackoff = 500 * time.Millisecond
+)
+
+// SocketWriter writes to a Unix domain socket or a named pipe (FIFO).
+// When a write fails, it reconnects and sends the whole buffer again on the new connection a
+// few times before giving up, so the new peer never starts in the middle of a record.
+type SocketWriter struct {
+	ctx        context.Context
+	path       string
+	maxRetries int
+	backoff    time.Duration
+	conn       io.WriteCloser
+}
+
+// DialSocket connects to the Unix socket or opens the named pipe at path.
+// Opening a named pipe blocks until a reader opens the other end.
+// Cancelling ctx stops a write that waits to reconnect.
+func DialSocket(ctx context.Context, path string) (*SocketWriter, error) {
+	s := &SocketWriter{
+		ctx:        ctx,
+		path:       path,
+		maxRetries: defaultSocketRetries,
+		backoff:    defaultSocketBackoff,
+	}
+
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *SocketWriter) connect() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat socket %s: %w", s.path, err)
+	}
+
+	if info.Mode()&os.ModeNamedPipe != 0 {
+		f, err := os.OpenFile(s.path, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open named pipe %s: %w", s.path, err)
+		}
+		s.conn = f
+		return nil
+	}
+
+	conn, err := net.Dial("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("failed to connect to socket %s: %w", s.path, err)
+	}
+	s.conn = conn
+
+	return nil
+}
+
+// Write implements io.Writer. p is sent whole on one connection: after a failed write the
+// partly written p is dropped with the old connection and sent again from the start on the new one.
+func (s *SocketWriter) Write(p []byte) (int, error) {
+	err := fmt.Errorf("not connected") // a failed write before left no connection
+	for i := 0; ; i++ {
+		if s.conn != nil {
+			n, werr := s.conn.Write(p)
+			if werr == nil {
+				return n, nil
+			}
+			err = werr
+			_ = s.conn.Close()
+			s.conn = nil
+		}
+		if i == s.maxRetries {
+			break
+		}
+
+		slog.Warn("write to socket failed, reconnecting",
+			slog.String("path", s.path), slog.Int("attempt", i+1), slog.String("error", err.Error()))
+		if werr := s.wait(); werr != nil {
+			return 0, fmt.Errorf("failed to write to socket %s: %w", s.path, werr)
+		}
+		if cerr := s.connect(); cerr != nil {
+			err = cerr
+		}
+	}
+
+	return 0, fmt.Errorf("failed to write to socket %s after %d retries: %w", s.path, s.maxRetries, err)
+}
+
+// wait sleeps for the backoff, or returns early with the error of ctx when it's cancelled.
+func (s *SocketWriter) wait() error {
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	t := time.NewTimer(s.backoff)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Close closes the connection. It does nothing when a failed write left no connection.
+func (s *SocketWriter) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
@@ -0,0 +1,208 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/types"
+	tidbcodec "github.com/pingcap/tidb/pkg/util/codec"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+func TestSocketWriterStreamsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tikv-reader.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", path, err)
+	}
+	defer ln.Close()
+
+	received := make(chan []Record, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		var records []Record
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var r Record
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				break
+			}
+			records = append(records, r)
+		}
+		received <- records
+	}()
+
+	sink, err := DialSocket(context.Background(), path)
+	if err != nil {
+		t.Fatalf("DialSocket() error = %v", err)
+	}
+
+	key1, _ := codec.ParseKey("t1_r1")
+	key2, _ := codec.ParseKey("t1_r2")
+	value, _ := tidbcodec.EncodeKey(nil, nil, types.MakeDatums(100)...)
+
+	w := NewJSONLinesWriter(sink)
+	for _, key := range [][]byte{key1, key2} {
+		if err := w.Write(NewRecord(codec.Options{}, key, value)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	records := <-received
+	if len(records) != 2 {
+		t.Fatalf("received %d records, want 2", len(records))
+	}
+	if records[0].Key != "t1_r1" || records[1].Key != "t1_r2" {
+		t.Errorf("unexpected keys: %s, %s", records[0].Key, records[1].Key)
+	}
+	if records[0].Value.Type != codec.TypeIndex {
+		t.Errorf("unexpected value type: %s", records[0].Value.Type)
+	}
+}
+
+func TestDialSocketMissingPath(t *testing.T) {
+	if _, err := DialSocket(context.Background(), filepath.Join(t.TempDir(), "missing.sock")); err == nil {
+		t.Error("DialSocket() should fail for a missing socket")
+	}
+}
+
+// droppingConn takes writes until n bytes, then fails as a dropped connection does.
+// The bytes of the failed write never reach the peer that went away.
+type droppingConn struct {
+	n      int
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (c *droppingConn) Write(p []byte) (int, error) {
+	if len(p) > c.n {
+		return c.n, errors.New("broken pipe")
+	}
+	c.buf.Write(p)
+	c.n -= len(p)
+	return len(p), nil
+}
+
+func (c *droppingConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+// jsonLines returns the lines of b, failing the test for a line that isn't a whole JSON document.
+func jsonLines(t *testing.T, name string, b []byte) []string {
+	t.Helper()
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Errorf("%s received %q, which isn't a whole record: %v", name, scanner.Text(), err)
+		}
+		lines = append(lines, scanner.Text())
+	}
+	if len(b) > 0 && b[len(b)-1] != '\n' {
+		t.Errorf("%s received %q, which ends in the middle of a line", name, b)
+	}
+	return lines
+}
+
+func TestSocketWriterResendsTheRecordAfterADrop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tikv-reader.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", path, err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		b, _ := io.ReadAll(conn)
+		received <- b
+	}()
+
+	first := []byte(`{"key":"t1_r1"}` + "\n")
+	second := []byte(`{"key":"t1_r2"}` + "\n")
+	// the connection drops 5 bytes into the second record
+	dropped := &droppingConn{n: len(first) + 5}
+	s := &SocketWriter{ctx: context.Background(), path: path, maxRetries: 1, conn: dropped}
+	for _, record := range [][]byte{first, second} {
+		if n, err := s.Write(record); err != nil || n != len(record) {
+			t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(record))
+		}
+	}
+	if !dropped.closed {
+		t.Error("Write() should close the dropped connection")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got, want := jsonLines(t, "dropped connection", dropped.buf.Bytes()), []string{string(first[:len(first)-1])}; !slices.Equal(got, want) {
+		t.Errorf("dropped connection received %q, want %q", got, want)
+	}
+	if got, want := jsonLines(t, "new connection", <-received), []string{string(second[:len(second)-1])}; !slices.Equal(got, want) {
+		t.Errorf("new connection received %q, want the whole second record %q", got, want)
+	}
+}
+
+func TestSocketWriterGivesUpWithoutAConnection(t *testing.T) {
+	s := &SocketWriter{path: filepath.Join(t.TempDir(), "gone.sock"), maxRetries: 2, conn: &droppingConn{n: 3}}
+	if n, err := s.Write([]byte("0123456789")); err == nil || n != 0 {
+		t.Fatalf("Write() = %d, %v, want 0 and an error", n, err)
+	}
+	if s.conn != nil {
+		t.Error("Write() should forget the failed connection")
+	}
+	// neither another write nor Close trips over the closed connection
+	if _, err := s.Write([]byte("x")); err == nil {
+		t.Error("Write() without a connection error = nil, want error")
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestSocketWriterStopsWaitingOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := &SocketWriter{ctx: ctx, path: filepath.Join(t.TempDir(), "gone.sock"), maxRetries: 3, backoff: time.Hour, conn: &droppingConn{}}
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Write([]byte("x"))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Write() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write() kept waiting for the backoff after the context was cancelled")
+	}
+}
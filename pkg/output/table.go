@@ -0,0 +1,69 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+const (
+	// tableValueWidth is the number of characters of a value shown in the table output.
+	tableValueWidth = 60
+
+	// tableBlockRows is the number of rows aligned together. Each block starts with the header,
+	// so a long scan is printed without keeping all of its rows.
+	tableBlockRows = 1000
+)
+
+// TableWriter writes the Records as an aligned table of the index, the key, the value type
+// and the value flattened by DecodedValue.String, cut after tableValueWidth characters.
+// Writes are buffered until a block is full, so Flush must be called after the last record.
+type TableWriter struct {
+	w         *tabwriter.Writer
+	blockRows int
+	rows      int
+}
+
+func NewTableWriter(w io.Writer) *TableWriter {
+	return &TableWriter{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0), blockRows: tableBlockRows}
+}
+
+// Write writes the record with its 1-based index in the result.
+func (w *TableWriter) Write(index int, r Record) error {
+	if w.rows%w.blockRows == 0 {
+		if w.rows > 0 {
+			if err := w.w.Flush(); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w.w, "#\tKEY\tTYPE\tVALUE"); err != nil {
+			return err
+		}
+	}
+	w.rows++
+
+	_, err := fmt.Fprintf(w.w, "%d\t%s\t%s\t%s\n", index, tableCell(r.Key, 0), r.Value.Type, tableCell(r.Value.String(), tableValueWidth))
+	return err
+}
+
+// Flush aligns and writes the buffered rows to the underlying writer.
+func (w *TableWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// tableCell keeps s on one line of its cell, cut after width characters unless width is 0.
+func tableCell(s string, width int) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return ' '
+		}
+		return r
+	}, s)
+	if width == 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+
+	return string([]rune(s)[:width-3]) + "..."
+}
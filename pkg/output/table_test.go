@@ -0,0 +1,42 @@
+package output
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+func TestTableWriter(t *testing.T) {
+	key := []byte{0x74, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x84, 0x5f, 0x72, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}
+	value, _ := hex.DecodeString("80000200000002030f00100041616c69796168204d75656c6c657201")
+
+	var buf bytes.Buffer
+	w := NewTableWriter(&buf)
+	w.blockRows = 2
+	records := []Record{
+		NewRecord(codec.Options{}, key, value),
+		// tabs and newlines would break the table, and a long value is cut
+		{Key: "t1_i1_a\tb", Value: codec.DecodedValue{Type: codec.TypeIndex, Payload: []string{"c\nd"}}},
+		{Key: "t2_r1", Value: codec.DecodedValue{Type: codec.TypeRaw, Payload: strings.Repeat("ab", 40)}},
+	}
+	for i, r := range records {
+		if err := w.Write(i+1, r); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := `#  KEY        TYPE    VALUE
+1  t132_r1    row_v2  2="Aaliyah Mueller";3=Int: 1 (Hex: 0x01)
+2  t1_i1_a b  index   c d
+#  KEY    TYPE  VALUE
+3  t2_r1  raw   ` + strings.Repeat("ab", 28) + "a...\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
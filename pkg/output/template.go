@@ -0,0 +1,58 @@
+package output
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+// TemplateRecord is the data a format template is executed with for each pair, e.g.,
+// {{.Index}} {{.Key}} {{.Value.Type}}. The Value prints as DecodedValue.String, and its
+// Payload holds the decoded value as in the JSON output.
+type TemplateRecord struct {
+	Index  int // 1-based index in the result
+	Key    string
+	HexKey string
+	Value  codec.DecodedValue
+}
+
+// ParseTemplate parses a format template of Go text/template.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("format").Option("missingkey=error").Parse(text)
+}
+
+// TemplateWriter writes each Record executed with a template, as a line of its own unless the
+// template ends with a newline. Writes are buffered, so Flush must be called after the last
+// record.
+type TemplateWriter struct {
+	w    *bufio.Writer
+	tmpl *template.Template
+	line strings.Builder
+}
+
+func NewTemplateWriter(w io.Writer, tmpl *template.Template) *TemplateWriter {
+	return &TemplateWriter{w: bufio.NewWriter(w), tmpl: tmpl}
+}
+
+// Write executes the template with the record and its 1-based index in the result. A record
+// failing the template isn't written at all.
+func (w *TemplateWriter) Write(index int, r Record) error {
+	w.line.Reset()
+	if err := w.tmpl.Execute(&w.line, TemplateRecord{Index: index, Key: r.Key, HexKey: r.Hex, Value: r.Value}); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(w.line.String(), "\n") {
+		w.line.WriteByte('\n')
+	}
+
+	_, err := w.w.WriteString(w.line.String())
+	return err
+}
+
+// Flush writes the buffered lines to the underlying writer.
+func (w *TemplateWriter) Flush() error {
+	return w.w.Flush()
+}
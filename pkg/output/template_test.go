@@ -0,0 +1,58 @@
+package output
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+func TestTemplateWriter(t *testing.T) {
+	key := []byte{0x74, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x84, 0x5f, 0x72, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}
+	value, _ := hex.DecodeString("80000200000002030f00100041616c69796168204d75656c6c657201")
+
+	tmpl, err := ParseTemplate(`{{.Index}} {{.Key}} {{.HexKey}} {{.Value.Type}} {{index .Value.Payload.Columns 2}}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewTemplateWriter(&buf, tmpl)
+	if err := w.Write(1, NewRecord(codec.Options{}, key, value)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	// the payload of an index value has no columns, so the template fails and writes nothing
+	index := Record{Key: "t1_i1_a", Hex: "74", Value: codec.DecodedValue{Type: codec.TypeIndex, Payload: []string{"a"}}}
+	if err := w.Write(2, index); err == nil {
+		t.Error("Write() should fail when the template doesn't fit the record")
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if got, want := buf.String(), "1 t132_r1 7480000000000000845F728000000000000001 row_v2 \"Aaliyah Mueller\"\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	// the value prints flattened, and a template ending with a newline gets no other
+	tmpl, err = ParseTemplate("{{.Key}}={{.Value}}\n")
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	buf.Reset()
+	w = NewTemplateWriter(&buf, tmpl)
+	if err := w.Write(1, index); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got, want := buf.String(), "t1_i1_a=a\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	if _, err := ParseTemplate("{{.Key"); err == nil {
+		t.Error("ParseTemplate() should fail for an unclosed action")
+	}
+}
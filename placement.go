@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+func runPlacement(ctx context.Context, cmd *cli.Command) error {
+	f := parseFlags(cmd)
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	tableID := cmd.Int64("table")
+	if tableID <= 0 {
+		return fmt.Errorf("table ID must be greater than 0")
+	}
+
+	slog.Info("Starting placement operation",
+		slog.Int64("table_id", tableID), slog.String("pd_endpoints", fmt.Sprintf("%v", f.PDEndpoints)))
+
+	cli, err := client.NewTiKVClientContext(ctx, f.PDEndpoints, f.clientOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PD server(%v): %w", f.PDEndpoints, err)
+	}
+	defer cli.Close()
+	slog.Info("connected to PD servers", slog.String("pd_addr", fmt.Sprintf("%v", f.PDEndpoints)))
+
+	placement, err := cli.GetTablePlacement(ctx, tableID)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Bool("json") {
+		return printJSON(placement)
+	}
+
+	PrintTablePlacement(placement)
+
+	return nil
+}
+
+func PrintTablePlacement(p *client.TablePlacement) {
+	PrintSeparatorLine(60)
+	fmt.Printf("Table: %d\n", p.TableID)
+	if p.Default {
+		fmt.Printf("  No placement rules for the table, the PD default rules (group %q) apply\n", p.GroupID)
+	} else {
+		fmt.Printf("  Rule group: %s\n", p.GroupID)
+	}
+	fmt.Printf("  Leader: %s\n", p.Leader)
+	for _, r := range p.Rules {
+		fmt.Printf("Rule: %s\n", r.ID)
+		fmt.Printf("  Role: %s x %d\n", r.Role, r.Count)
+		fmt.Printf("  Range: %s - %s\n", ruleBound(r.StartKey, "-inf"), ruleBound(r.EndKey, "+inf"))
+		if len(r.Constraints) > 0 {
+			fmt.Printf("  Constraints: %s\n", strings.Join(r.Constraints, ", "))
+		}
+		if len(r.LocationLabels) > 0 {
+			fmt.Printf("  Location labels: %s\n", strings.Join(r.LocationLabels, ", "))
+		}
+		if r.IsolationLevel != "" {
+			fmt.Printf("  Isolation level: %s\n", r.IsolationLevel)
+		}
+	}
+	PrintSeparatorLine(60)
+}
+
+func ruleBound(key, unbounded string) string {
+	if key == "" {
+		return unbounded
+	}
+
+	return key
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/urfave/cli/v3"
+)
+
+// defaultRegionLimit is the number of regions listed for region --prefix by default.
+const defaultRegionLimit = 100
+
+// regionPeerRecord is a peer of a region in the JSON output of region.
+type regionPeerRecord struct {
+	ID      uint64 `json:"id"`
+	StoreID uint64 `json:"store_id"`
+	Role    string `json:"role"`
+	Down    bool   `json:"down,omitempty"`
+	Pending bool   `json:"pending,omitempty"`
+}
+
+// regionRecord is a region in the JSON output of region. The bounds are empty at the start
+// and the end of the key space.
+type regionRecord struct {
+	ID          uint64             `json:"id"`
+	StartKey    string             `json:"start_key"`
+	StartKeyHex string             `json:"start_key_hex"`
+	EndKey      string             `json:"end_key"`
+	EndKeyHex   string             `json:"end_key_hex"`
+	ConfVer     uint64             `json:"conf_ver"`
+	Version     uint64             `json:"version"`
+	Leader      uint64             `json:"leader_store_id"`
+	Peers       []regionPeerRecord `json:"peers"`
+}
+
+func runRegion(ctx context.Context, cmd *cli.Command) error {
+	f := parseFlags(cmd)
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	keyInput, prefix := cmd.String("key"), cmd.String("prefix")
+	if (keyInput == "") == (prefix == "") {
+		return fmt.Errorf("exactly one of --key or --prefix is required")
+	}
+	limit := cmd.Int("limit")
+	if limit <= 0 {
+		return fmt.Errorf("limit must be greater than 0")
+	}
+
+	var key, start, end []byte
+	var err error
+	if keyInput != "" {
+		if key, err = parseRegionKey(f.Codec, keyInput); err != nil {
+			return err
+		}
+	} else if start, end, err = f.Codec.ScanBounds(prefix); err != nil {
+		return fmt.Errorf("invalid --prefix: %w", err)
+	}
+
+	slog.Info("Starting region operation",
+		slog.String("key", keyInput), slog.String("prefix", prefix), slog.String("pd_endpoints", fmt.Sprintf("%v", f.PDEndpoints)))
+
+	cli, err := client.NewTiKVClientContext(ctx, f.PDEndpoints, f.clientOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PD server(%v): %w", f.PDEndpoints, err)
+	}
+	defer cli.Close()
+	slog.Info("connected to PD servers", slog.String("pd_addr", fmt.Sprintf("%v", f.PDEndpoints)))
+
+	var regions []client.Region
+	if key != nil {
+		region, err := cli.LocateRegion(ctx, key)
+		if err != nil {
+			return err
+		}
+		regions = append(regions, region)
+	} else {
+		if regions, err = cli.ScanRegions(ctx, start, end, limit); err != nil {
+			return err
+		}
+		if len(regions) == limit {
+			slog.Info("the region list reached the limit, the prefix may span more regions", slog.Int("limit", limit))
+		}
+	}
+
+	if cmd.Bool("json") {
+		records := make([]regionRecord, 0, len(regions))
+		for _, r := range regions {
+			records = append(records, newRegionRecord(f.Codec, r))
+		}
+		if key != nil {
+			return printJSON(records[0])
+		}
+		return printJSON(records)
+	}
+
+	for _, r := range regions {
+		PrintRegion(f.Codec, r)
+	}
+	if key == nil {
+		fmt.Printf("Regions: %d\n", len(regions))
+	}
+
+	return nil
+}
+
+// parseRegionKey takes a key like t132_r1 or m... as well as a hex key from the logs.
+func parseRegionKey(opts codec.Options, input string) ([]byte, error) {
+	if isSupportedKey(input) {
+		key, err := opts.ParseKey(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --key: %w", err)
+		}
+		return key, nil
+	}
+
+	key, err := decodeHexInput(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --key: %w", err)
+	}
+
+	return key, nil
+}
+
+func newRegionRecord(opts codec.Options, r client.Region) regionRecord {
+	rec := regionRecord{
+		ID:          r.ID,
+		StartKey:    opts.DecodeKey(r.StartKey),
+		StartKeyHex: fmt.Sprintf("%X", r.StartKey),
+		EndKey:      opts.DecodeKey(r.EndKey),
+		EndKeyHex:   fmt.Sprintf("%X", r.EndKey),
+		ConfVer:     r.ConfVer,
+		Version:     r.Version,
+		Leader:      r.Leader,
+		Peers:       []regionPeerRecord{},
+	}
+	for _, p := range r.Peers {
+		rec.Peers = append(rec.Peers, regionPeerRecord{ID: p.ID, StoreID: p.StoreID, Role: p.Role, Down: p.Down, Pending: p.Pending})
+	}
+
+	return rec
+}
+
+func PrintRegion(opts codec.Options, r client.Region) {
+	PrintSeparatorLine(60)
+	fmt.Printf("Region: %d\n", r.ID)
+	fmt.Printf("  Start key: %s\n", regionBound(opts, r.StartKey, "-inf"))
+	fmt.Printf("  End key: %s\n", regionBound(opts, r.EndKey, "+inf"))
+	fmt.Printf("  Epoch: conf_ver %d, version %d\n", r.ConfVer, r.Version)
+	if r.Leader == 0 {
+		fmt.Printf("  Leader: unknown\n")
+	} else {
+		fmt.Printf("  Leader: store %d\n", r.Leader)
+	}
+	for _, p := range r.Peers {
+		var status string
+		if p.Down {
+			status += ", down"
+		}
+		if p.Pending {
+			status += ", pending"
+		}
+		fmt.Printf("  Peer: %d on store %d (%s%s)\n", p.ID, p.StoreID, p.Role, status)
+	}
+	PrintSeparatorLine(60)
+}
+
+func regionBound(opts codec.Options, key []byte, unbounded string) string {
+	if len(key) == 0 {
+		return unbounded
+	}
+
+	return fmt.Sprintf("%s (%s)", opts.DecodeKey(key), opts.PrettyPrintKey(key))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+// resolvedRow is the row an index entry of the scan points to.
+type resolvedRow struct {
+	handle codec.IndexHandle
+	value  []byte
+	found  bool
+	err    error // set when the handle couldn't be resolved from the index entry
+}
+
+// resolveHandles follows the handle of each index entry to its record. Rows of the
+// entries which are not index entries are left as the zero value.
+func resolveHandles(ctx context.Context, cli *client.TiKVClient, opts codec.Options, keys, values [][]byte, ts uint64) ([]resolvedRow, error) {
+	rows := make([]resolvedRow, len(keys))
+	var recordKeys [][]byte
+	for i := range keys {
+		if !codec.IsIndexKey(keys[i]) {
+			continue
+		}
+		h, err := opts.ResolveIndexHandle(keys[i], values[i])
+		if err != nil {
+			rows[i].err = err
+			continue
+		}
+		rows[i].handle = h
+		recordKeys = append(recordKeys, h.RecordKey)
+	}
+	if len(recordKeys) == 0 {
+		return rows, nil
+	}
+
+	slog.Warn("resolving handles reads a row for each index entry", slog.Int("rows", len(recordKeys)))
+	records, err := cli.BatchGetWithTS(ctx, recordKeys, ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the rows of the index entries: %w", err)
+	}
+
+	for i := range rows {
+		if rows[i].err != nil || rows[i].handle.RecordKey == nil {
+			continue
+		}
+		rows[i].value, rows[i].found = records[string(rows[i].handle.RecordKey)]
+	}
+
+	return rows, nil
+}
+
+func printResolvedRow(opts codec.Options, r resolvedRow, typeNames map[int64]string, indent string) {
+	switch {
+	case r.err != nil:
+		fmt.Printf("%sRow: <unresolved: %v>\n", indent, r.err)
+		return
+	case r.handle.RecordKey == nil: // not an index entry
+		return
+	}
+
+	fmt.Printf("%sRow: %s (handle %s)\n", indent, opts.DecodeKey(r.handle.RecordKey), r.handle.Handle)
+	fmt.Printf("%s  Hex: %s\n", indent, opts.PrettyPrintKey(r.handle.RecordKey))
+	if !r.found {
+		fmt.Printf("%s  <row not found>\n", indent)
+		return
+	}
+	PrintDecodedValue(opts.DecodeValue(r.value), typeNames, indent+"  ")
+}
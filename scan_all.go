@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/sgykfjsm/tikv-reader/pkg/output"
+)
+
+// streamScan prints each key-value pair of a scan --all while scanning, so the memory stays
+// bounded however many keys there are. The output is the same as without --all, except that
+// the text output prints the count at the end. It returns the last key printed.
+func streamScan(ctx context.Context, cli *client.TiKVClient, f *TiKVReaderFlags, start, end []byte, opts client.ScanOptions) ([]byte, error) {
+	var write func(index int, key, value []byte) error
+	var finish func(rows int) error
+	var stats *scanStats // set before finish with --stats
+	switch f.Output {
+	case outputJSON:
+		// the same indented array as printJSON, written element by element; with --stats, the
+		// array is the records of the same object as scanResult
+		open, indent := "[", ""
+		if f.Stats {
+			open, indent = "{\n  \"records\": [", "  "
+		}
+		write = func(index int, key, value []byte) error {
+			b, err := json.MarshalIndent(output.NewRecord(f.Codec, key, value), indent+"  ", "  ")
+			if err != nil {
+				return err
+			}
+			sep := ","
+			if index == 1 {
+				sep = open
+			}
+			_, err = fmt.Printf("%s\n%s  %s", sep, indent, b)
+			return err
+		}
+		finish = func(rows int) error {
+			closing := "\n" + indent + "]"
+			if rows == 0 {
+				closing = open + "]"
+			}
+			if !f.Stats {
+				_, err := fmt.Println(closing)
+				return err
+			}
+
+			b, err := json.MarshalIndent(stats, "  ", "  ")
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Printf("%s,\n  \"stats\": %s\n}\n", closing, b)
+			return err
+		}
+	case outputCSV:
+		w := output.NewCSVWriter(os.Stdout)
+		write = func(index int, key, value []byte) error {
+			return w.Write(index, output.NewRecord(f.Codec, key, value))
+		}
+		finish = func(int) error { return w.Flush() }
+	case outputTemplate:
+		w := output.NewTemplateWriter(os.Stdout, f.formatTemplate)
+		write = func(index int, key, value []byte) error {
+			return w.Write(index, output.NewRecord(f.Codec, key, value))
+		}
+		finish = func(int) error { return w.Flush() }
+	case outputSQL:
+		w := newSQLWriter(os.Stdout, f)
+		write = func(_ int, key, value []byte) error {
+			return w.Write(key, value)
+		}
+		finish = func(int) error { return w.Flush() }
+	case outputMsgpack:
+		w := output.NewMsgpackWriter(os.Stdout)
+		write = func(index int, key, value []byte) error {
+			return w.Write(output.NewResultRecord(f.Codec, index, key, value))
+		}
+		finish = func(int) error { return w.Flush() }
+	case outputProto:
+		w := output.NewProtoWriter(os.Stdout)
+		write = func(index int, key, value []byte) error {
+			return w.Write(output.NewResultRecord(f.Codec, index, key, value))
+		}
+		finish = func(int) error { return w.Flush() }
+	case outputExport:
+		e := newTableExporter(f)
+		write = func(_ int, key, value []byte) error {
+			return e.Write(key, value)
+		}
+		finish = func(int) error { return e.Close() }
+	case outputTable:
+		w := output.NewTableWriter(os.Stdout)
+		write = func(index int, key, value []byte) error {
+			return w.Write(index, output.NewRecord(f.Codec, key, value))
+		}
+		finish = func(int) error { return w.Flush() }
+	default:
+		write = func(index int, key, value []byte) error {
+			printScanRow(index, key, value, f)
+			return nil
+		}
+		finish = func(rows int) error {
+			PrintSeparatorLine(60)
+			fmt.Printf("Scan completed successfully. Retrieved %d key-value pairs.\n", rows)
+			printScanStats(stats)
+			return nil
+		}
+	}
+
+	var last []byte
+	rows := 0
+	summary, err := cli.ScanRangeFunc(ctx, start, end, opts, func(k, v []byte) error {
+		rows++
+		last = append(last[:0], k...)
+		if f.KeysOnly {
+			v = nil
+		}
+		if err := write(rows, k, v); err != nil {
+			return fmt.Errorf("failed to write key %s: %w", f.Codec.DecodeKey(k), err)
+		}
+		return nil
+	})
+	if err != nil {
+		// keep what was printed a valid document, and tell where to go on
+		if f.Output != outputText {
+			_ = finish(rows)
+		}
+		if last != nil {
+			slog.Warn("scan failed, go on with --start-after",
+				slog.String("last_key", f.Codec.DecodeKey(last)), slog.String("start_after", fmt.Sprintf("%X", last)))
+		}
+		return nil, fmt.Errorf("failed to scan keys: %w", err)
+	}
+	stats = newScanStats(ctx, cli, f, start, end, opts, summary, last)
+	if err := finish(rows); err != nil {
+		return nil, fmt.Errorf("failed to write the scan result: %w", err)
+	}
+
+	if f.Output == outputText {
+		printTimeBudgetReached(f, summary)
+	} else if summary.TimeBudgetReached {
+		slog.Warn("scan stopped at the time budget, the result is partial",
+			slog.Duration("max_scan_duration", f.MaxScanDuration), slog.Int("rows", summary.Rows))
+	}
+	printResumeKey(f, opts, summary, last)
+	logFilterSummary(f, summary)
+	if f.Output != outputText && f.Output != outputJSON {
+		logScanStats(stats)
+	}
+	slog.Info("streamed scan result", slog.String("output", f.Output), slog.Int("records", rows))
+
+	return last, nil
+}
+
+// parseStartAfter parses --start-after: a key like t1_r100, or else the hex bytes of a key as
+// printed when a scan stops, which round-trip any key exactly.
+func parseStartAfter(opts codec.Options, input string) ([]byte, error) {
+	if isSupportedKey(input) {
+		key, err := opts.ParseKey(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --start-after: %w", err)
+		}
+		return key, nil
+	}
+
+	key, err := decodeHexInput(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --start-after: %w", err)
+	}
+
+	return key, nil
+}
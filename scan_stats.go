@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+	"github.com/sgykfjsm/tikv-reader/pkg/output"
+)
+
+// scanStats is the summary printed by scan --stats.
+type scanStats struct {
+	Keys       int   `json:"keys"`        // pairs returned
+	ValueBytes int64 `json:"value_bytes"` // value bytes read, including the pairs skipped by --filter
+	DurationMS int64 `json:"duration_ms"`
+	Regions    *int  `json:"regions"` // regions of the part of the range read, nil when PD couldn't tell
+}
+
+// scanResult is the JSON output of scan --stats, the records with the statistics.
+type scanResult struct {
+	Records []output.Record `json:"records"`
+	Stats   *scanStats      `json:"stats"`
+}
+
+// newScanStats summarizes a finished scan for --stats, or returns nil without it. The regions
+// are counted by PD over the part of the range the scan read: up to the last key when it
+// stopped before the end, or else the whole range.
+func newScanStats(ctx context.Context, cli *client.TiKVClient, f *TiKVReaderFlags, start, end []byte, opts client.ScanOptions, summary client.ScanSummary, last []byte) *scanStats {
+	if !f.Stats {
+		return nil
+	}
+
+	stats := &scanStats{Keys: summary.Rows, ValueBytes: summary.Bytes, DurationMS: summary.Elapsed.Milliseconds()}
+
+	if opts.StartAfter != nil {
+		start = append(slices.Clone(opts.StartAfter), 0)
+	}
+	if last != nil && (summary.Rows >= opts.Limit || summary.TimeBudgetReached) {
+		end = append(slices.Clone(last), 0)
+	}
+	regions, err := cli.ScanRegions(ctx, start, end, math.MaxInt)
+	if err != nil {
+		slog.Warn("failed to count the regions of the scan", slog.String("error", err.Error()))
+		return stats
+	}
+	n := len(regions)
+	stats.Regions = &n
+
+	return stats
+}
+
+// printScanStats prints the statistics after the text output of a scan.
+func printScanStats(stats *scanStats) {
+	if stats == nil {
+		return
+	}
+
+	regions := "unknown"
+	if stats.Regions != nil {
+		regions = fmt.Sprintf("%d", *stats.Regions)
+	}
+	fmt.Printf("Scan statistics:\n")
+	fmt.Printf("  Keys: %d\n", stats.Keys)
+	fmt.Printf("  Value bytes: %d\n", stats.ValueBytes)
+	fmt.Printf("  Duration: %s\n", time.Duration(stats.DurationMS)*time.Millisecond)
+	fmt.Printf("  Regions: %s\n", regions)
+}
+
+// logScanStats logs the statistics of the outputs which have no room for them, such as CSV.
+func logScanStats(stats *scanStats) {
+	if stats == nil {
+		return
+	}
+
+	attrs := []any{slog.Int("keys", stats.Keys), slog.Int64("value_bytes", stats.ValueBytes), slog.Int64("duration_ms", stats.DurationMS)}
+	if stats.Regions != nil {
+		attrs = append(attrs, slog.Int("regions", *stats.Regions))
+	}
+	slog.Info("scan statistics", attrs...)
+}
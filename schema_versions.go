@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+	"github.com/urfave/cli/v3"
+)
+
+func runSchemaVersions(ctx context.Context, cmd *cli.Command) error {
+	f := parseFlags(cmd)
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	limit := f.Limit
+	if limit <= 0 {
+		return fmt.Errorf("limit must be greater than 0")
+	}
+
+	slog.Info("Starting schema-versions operation",
+		slog.String("pd_endpoints", fmt.Sprintf("%v", f.PDEndpoints)), slog.Int("limit", limit))
+
+	cli, err := client.NewTiKVClientContext(ctx, f.PDEndpoints, f.clientOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PD server(%v): %w", f.PDEndpoints, err)
+	}
+	defer cli.Close()
+	slog.Info("connected to PD servers", slog.String("pd_addr", fmt.Sprintf("%v", f.PDEndpoints)))
+	logSnapshotTS(f.SnapshotTS)
+
+	value, err := cli.GetWithTS(ctx, codec.EncodeMetaStringKey(codec.MetaSchemaVersionKey), f.SnapshotTS)
+	if err != nil {
+		if client.IsKeyNotFound(err) {
+			fmt.Println("Schema version key not found. The cluster may not be managed by TiDB or has never run DDL.")
+			return nil
+		}
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	current, err := codec.DecodeSchemaVersion(value)
+	if err != nil {
+		return err
+	}
+
+	PrintSeparatorLine(60)
+	fmt.Printf("Current schema version: %d\n", current)
+
+	diffs, err := getSchemaDiffs(ctx, cli, current, limit, f.SnapshotTS)
+	if err != nil {
+		return err
+	}
+	PrintSeparatorLine(60)
+	fmt.Printf("Recent schema diffs (oldest first):\n")
+	if len(diffs) == 0 {
+		fmt.Printf("  (no schema diffs found)\n")
+	}
+	for _, e := range diffs {
+		fmt.Printf("  Version %d: %s (schema %d, table %d)\n", e.Version, e.Type, e.SchemaID, e.TableID)
+	}
+
+	jobs, err := getDDLHistory(ctx, cli, limit, f.SnapshotTS)
+	if err != nil {
+		return err
+	}
+	PrintSeparatorLine(60)
+	fmt.Printf("Recent DDL history (oldest first):\n")
+	if len(jobs) == 0 {
+		// TiDB v6.2+ moved the DDL history from the meta hash to the mysql.tidb_ddl_history table
+		fmt.Printf("  (no DDL history in meta keys; newer clusters keep it in mysql.tidb_ddl_history)\n")
+	}
+	for _, e := range jobs {
+		fmt.Printf("  Version %d: %s %s.%s (schema %d, table %d), job %d, state %s\n",
+			e.Version, e.Type, e.SchemaName, e.TableName, e.SchemaID, e.TableID, e.JobID, e.State)
+		if e.FinishedTS != 0 {
+			fmt.Printf("    Finished at: %s\n", client.FormatTSO(e.FinishedTS))
+		}
+		if e.Query != "" {
+			fmt.Printf("    Query: %s\n", e.Query)
+		}
+	}
+	PrintSeparatorLine(60)
+
+	return nil
+}
+
+// getSchemaDiffs reads up to limit schema diffs up to the current version, oldest first.
+func getSchemaDiffs(ctx context.Context, cli *client.TiKVClient, current int64, limit int, ts uint64) ([]codec.SchemaVersionEntry, error) {
+	var versions []int64
+	for v := current; v > 0 && len(versions) < limit; v-- {
+		versions = append(versions, v)
+	}
+	slices.Reverse(versions)
+
+	keys := make([][]byte, 0, len(versions))
+	for _, v := range versions {
+		keys = append(keys, codec.EncodeMetaStringKey(codec.SchemaDiffKey(v)))
+	}
+
+	values, err := cli.BatchGetWithTS(ctx, keys, ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema diffs: %w", err)
+	}
+
+	var entries []codec.SchemaVersionEntry
+	for i, key := range keys {
+		value, ok := values[string(key)]
+		if !ok { // schema diffs are removed by GC, so old versions might be missing
+			continue
+		}
+
+		entry, err := codec.DecodeSchemaDiff(value)
+		if err != nil {
+			slog.Warn("failed to decode schema diff", slog.Int64("version", versions[i]), slog.String("error", err.Error()))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// getDDLHistory reads the newest limit DDL jobs from the meta DDL history, oldest first.
+func getDDLHistory(ctx context.Context, cli *client.TiKVClient, limit int, ts uint64) ([]codec.SchemaVersionEntry, error) {
+	_, values, err := cli.ScanReverseWithTS(ctx, codec.EncodeMetaHashDataPrefix(codec.MetaDDLJobHistoryKey), limit, ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan DDL history: %w", err)
+	}
+
+	var entries []codec.SchemaVersionEntry
+	for _, value := range values {
+		entry, err := codec.DecodeDDLJob(value)
+		if err != nil {
+			slog.Warn("failed to decode DDL job", slog.String("error", err.Error()))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	slices.Reverse(entries)
+
+	return entries, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/codec"
+)
+
+// The orders of scan --sort-by besides col:<id>.
+const (
+	sortByKey   = "key"
+	sortByRowID = "rowid"
+)
+
+// scanSort is the order of --sort-by: by the key, the row ID or the value of a column.
+type scanSort struct {
+	by    string // sortByKey, sortByRowID or "col"
+	colID int64
+}
+
+// parseSortBy parses --sort-by: key, rowid or col:<id>.
+func parseSortBy(input string) (scanSort, error) {
+	switch input {
+	case sortByKey, sortByRowID:
+		return scanSort{by: input}, nil
+	}
+
+	id, ok := strings.CutPrefix(input, "col:")
+	if !ok {
+		return scanSort{}, fmt.Errorf("unknown order %q: must be %s, %s or col:<id>", input, sortByKey, sortByRowID)
+	}
+	colID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return scanSort{}, fmt.Errorf("invalid column ID in %q: %v", input, err)
+	}
+
+	return scanSort{by: "col", colID: colID}, nil
+}
+
+// sortPair is a pair of the scan result with the value it is sorted by.
+type sortPair struct {
+	key, value []byte
+	rowID      int64
+	col        []byte // the raw column value, nil for NULL
+	colType    codec.ColumnType
+	has        bool // whether the pair has the row ID or the column
+}
+
+// sortPairs orders the pairs of a scan by --sort-by, stably, so pairs of the same value stay in
+// key order. The pairs without the row ID or the column, e.g., index entries or rows of other
+// tables, go last, and a NULL column goes first, as in MySQL.
+func sortPairs(f *TiKVReaderFlags, keys, values [][]byte) {
+	if f.sortBy.by == "" {
+		return
+	}
+
+	pairs := make([]sortPair, len(keys))
+	for i := range keys {
+		p := sortPair{key: keys[i], value: values[i]}
+		switch f.sortBy.by {
+		case sortByRowID:
+			p.rowID, p.has = recordRowID(keys[i])
+		case "col":
+			p.col, p.colType, p.rowID, p.has = sortColumn(f, keys[i], values[i])
+		}
+		pairs[i] = p
+	}
+
+	slices.SortStableFunc(pairs, func(a, b sortPair) int {
+		switch {
+		case f.sortBy.by == sortByKey:
+			return bytes.Compare(a.key, b.key)
+		case a.has != b.has:
+			if a.has {
+				return -1
+			}
+			return 1
+		case !a.has:
+			return 0
+		case f.sortBy.by == sortByRowID || a.colType == handleColumn || b.colType == handleColumn:
+			return f.Codec.CompareRowIDs(a.rowID, b.rowID)
+		case a.col == nil || b.col == nil:
+			return boolCompare(a.col != nil, b.col != nil)
+		default:
+			return codec.CompareColumnValues(a.col, b.col, a.colType)
+		}
+	})
+
+	for i, p := range pairs {
+		keys[i], values[i] = p.key, p.value
+	}
+}
+
+// handleColumn marks the column of --sort-by which is the int handle, taken from the key.
+const handleColumn codec.ColumnType = "handle"
+
+// sortColumn returns the raw value and the type of the --sort-by column of the pair. The type
+// is known from the schema, else it is empty. The handle column of the schema is the row ID of
+// the key.
+func sortColumn(f *TiKVReaderFlags, key, value []byte) ([]byte, codec.ColumnType, int64, bool) {
+	var typ codec.ColumnType
+	if schema := f.schemaFor(key); schema != nil {
+		for _, c := range schema.Columns {
+			if c.ID != f.sortBy.colID {
+				continue
+			}
+			if c.Handle {
+				rowID, ok := recordRowID(key)
+				return nil, handleColumn, rowID, ok
+			}
+			typ = c.Type
+		}
+	}
+
+	cols, err := codec.RowV2RawColumns(value)
+	if err != nil {
+		return nil, "", 0, false
+	}
+	b, ok := cols[f.sortBy.colID]
+	return b, typ, 0, ok
+}
+
+// recordRowID returns the int handle of a record key.
+func recordRowID(key []byte) (int64, bool) {
+	info, err := codec.DecodeKeyStructured(key)
+	if err != nil || info.Kind != codec.KindRecord || info.Handle.CommonHandle != nil {
+		return 0, false
+	}
+	return info.Handle.IntHandle, true
+}
+
+func boolCompare(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
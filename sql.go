@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// sqlHeader makes the TIMESTAMP literals, written in UTC as stored, read back as they are.
+const sqlHeader = "SET time_zone = '+00:00';\n"
+
+// sqlWriter writes the records of --output sql as INSERT statements, decoded with the schema
+// of --schema or --system-table. A pair which can't be turned into one, e.g., an index entry
+// or a row of a table without a schema, is written as a comment instead, so the output can be
+// replayed as a whole. Writes are buffered, so Flush must be called after the last pair.
+type sqlWriter struct {
+	w       *bufio.Writer
+	f       *TiKVReaderFlags
+	started bool
+	skipped int
+}
+
+func newSQLWriter(w io.Writer, f *TiKVReaderFlags) *sqlWriter {
+	return &sqlWriter{w: bufio.NewWriter(w), f: f}
+}
+
+func (w *sqlWriter) Write(key, value []byte) error {
+	if !w.started {
+		if _, err := w.w.WriteString(sqlHeader); err != nil {
+			return err
+		}
+		w.started = true
+	}
+
+	var stmt string
+	var err error
+	if schema := w.f.schemaFor(key); schema == nil {
+		err = fmt.Errorf("no schema of the table")
+	} else {
+		stmt, err = w.f.Codec.SQLInsert(key, value, *schema)
+	}
+	if err != nil {
+		w.skipped++
+		stmt = fmt.Sprintf("-- skipped %s: %v", w.f.Codec.DecodeKey(key), err)
+	}
+
+	_, err = fmt.Fprintln(w.w, stmt)
+	return err
+}
+
+// Flush writes the buffered statements to the underlying writer, and logs the skipped pairs.
+func (w *sqlWriter) Flush() error {
+	if w.skipped > 0 {
+		slog.Warn("some pairs are not turned into INSERT statements, see the comments in the output", slog.Int("skipped", w.skipped))
+	}
+
+	return w.w.Flush()
+}
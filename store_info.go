@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sgykfjsm/tikv-reader/pkg/client"
+	"github.com/urfave/cli/v3"
+)
+
+func runStoreInfo(ctx context.Context, cmd *cli.Command) error {
+	f := parseFlags(cmd)
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	ctx, cancel := f.withTimeout(ctx)
+	defer cancel()
+
+	storeID := cmd.Uint64("store-id")
+	if storeID == 0 {
+		return fmt.Errorf("store ID must be greater than 0")
+	}
+
+	slog.Info("Starting store-info operation",
+		slog.Uint64("store_id", storeID), slog.String("pd_endpoints", fmt.Sprintf("%v", f.PDEndpoints)))
+
+	cli, err := client.NewTiKVClientContext(ctx, f.PDEndpoints, f.clientOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to PD server(%v): %w", f.PDEndpoints, err)
+	}
+	defer cli.Close()
+	slog.Info("connected to PD servers", slog.String("pd_addr", fmt.Sprintf("%v", f.PDEndpoints)))
+
+	store, err := cli.GetStore(ctx, storeID)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Bool("json") {
+		return printJSON(store)
+	}
+
+	PrintStoreInfo(store)
+
+	return nil
+}
+
+func PrintStoreInfo(s *client.StoreInfo) {
+	PrintSeparatorLine(60)
+	fmt.Printf("Store: %d\n", s.Store.ID)
+	fmt.Printf("  Address: %s\n", s.Store.Address)
+	fmt.Printf("  Status address: %s\n", s.Store.StatusAddress)
+	fmt.Printf("  State: %s\n", s.Store.StateName)
+	fmt.Printf("  Version: %s (%s)\n", s.Store.Version, s.Store.GitHash)
+	for _, l := range s.Store.Labels {
+		fmt.Printf("  Label: %s=%s\n", l.Key, l.Value)
+	}
+	fmt.Printf("Status:\n")
+	fmt.Printf("  Capacity: %s\n", s.Status.Capacity)
+	fmt.Printf("  Available: %s\n", s.Status.Available)
+	fmt.Printf("  Leaders: %d (size %d MiB, score %.2f, weight %.2f)\n",
+		s.Status.LeaderCount, s.Status.LeaderSize, s.Status.LeaderScore, s.Status.LeaderWeight)
+	fmt.Printf("  Regions: %d (size %d MiB, score %.2f, weight %.2f)\n",
+		s.Status.RegionCount, s.Status.RegionSize, s.Status.RegionScore, s.Status.RegionWeight)
+	fmt.Printf("  Started at: %s (uptime %s)\n", s.Status.StartTS.Format(time.RFC3339), s.Status.Uptime)
+	fmt.Printf("  Last heartbeat: %s (%s ago)\n",
+		s.Status.LastHeartbeatTS.Format(time.RFC3339), time.Since(s.Status.LastHeartbeatTS).Round(time.Second))
+	PrintSeparatorLine(60)
+}